@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/agent"
+	"github.com/iliyian/aliyun-spot-manager/internal/config"
+	"github.com/iliyian/aliyun-spot-manager/internal/version"
+	log "github.com/sirupsen/logrus"
+)
+
+// runAgent runs the on-instance agent until interrupted and returns the exit code
+func runAgent() int {
+	cfg, err := config.LoadAgent()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load agent configuration: %v\n", err)
+		return 1
+	}
+	setupLogging(&config.Config{
+		LogLevel:      cfg.LogLevel,
+		LogFormat:     cfg.LogFormat,
+		LogFile:       cfg.LogFile,
+		LogFileLevel:  cfg.LogFileLevel,
+		LogFileFormat: cfg.LogFileFormat,
+	})
+
+	log.Infof("Starting aliyun-spot-manager agent %s", version.String())
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := agent.New(cfg).Run(ctx); err != nil {
+		log.Errorf("Agent failed: %v", err)
+		return 1
+	}
+	return 0
+}
+
+// runAgentToken prints the heartbeat token of the agent on each given
+// instance and returns the exit code
+func runAgentToken(instanceIDs []string) int {
+	if len(instanceIDs) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: aliyun-spot-manager agent-token <instance-id>...")
+		return 2
+	}
+	secret := os.Getenv("AGENT_SECRET")
+	if secret == "" {
+		fmt.Fprintln(os.Stderr, "AGENT_SECRET is not set")
+		return 1
+	}
+	for _, instanceID := range instanceIDs {
+		fmt.Printf("%s\t%s\n", instanceID, agent.Token(secret, instanceID))
+	}
+	return 0
+}
@@ -0,0 +1,43 @@
+// Package spotmanagerpb is the gRPC API of the spot manager, for Go services
+// that control it programmatically. The messages and stubs are generated from
+// spotmanager.proto.
+package spotmanagerpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative spotmanager.proto
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// TokenCredentials sends the API token with every call
+type TokenCredentials string
+
+// GetRequestMetadata implements credentials.PerRPCCredentials
+func (t TokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+// RequireTransportSecurity implements credentials.PerRPCCredentials. The
+// token may go over plaintext, the API usually listens on a private network.
+func (t TokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// Dial connects to the gRPC API at addr, e.g. "localhost:9090", authenticating
+// with token. The connection is plaintext unless opts set transport credentials.
+//
+//	conn, err := spotmanagerpb.Dial("localhost:9090", token)
+//	if err != nil { ... }
+//	defer conn.Close()
+//	client := spotmanagerpb.NewSpotManagerClient(conn)
+//	resp, err := client.ListInstances(ctx, &spotmanagerpb.ListInstancesRequest{})
+func Dial(addr, token string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	opts = append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithPerRPCCredentials(TokenCredentials(token)),
+	}, opts...)
+	return grpc.Dial(addr, opts...)
+}
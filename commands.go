@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	"github.com/iliyian/aliyun-spot-manager/internal/config"
+	"github.com/iliyian/aliyun-spot-manager/internal/monitor"
+	log "github.com/sirupsen/logrus"
+)
+
+// runCommand runs a one-shot command and returns the exit code
+func runCommand(opts *options) int {
+	if opts.command == "start" && len(opts.args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: aliyun-spot-manager start <instance-id>")
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+	setupLogging(cfg)
+	// Only warnings on the console unless asked for, the output goes to stdout
+	if os.Getenv("LOG_LEVEL") == "" {
+		log.SetLevel(log.WarnLevel)
+	}
+
+	mon, err := monitor.New(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create monitor: %v\n", err)
+		return 1
+	}
+	defer mon.Close()
+
+	if err := dispatchCommand(mon, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "%s failed: %v\n", opts.command, err)
+		return 1
+	}
+	return 0
+}
+
+// dispatchCommand runs a one-shot command against the monitor
+func dispatchCommand(mon *monitor.Monitor, opts *options) error {
+	switch opts.command {
+	case "check":
+		if err := mon.RefreshInstances(); err != nil {
+			return err
+		}
+		return mon.Check()
+	case "list":
+		if err := mon.RefreshInstances(); err != nil {
+			return err
+		}
+		return printInstances(os.Stdout, mon.Instances(false), opts.format)
+	case "billing":
+		// Billing items are matched to the discovered instances
+		if err := mon.RefreshInstances(); err != nil {
+			return err
+		}
+		summary, err := mon.QueryBilling(strings.Join(opts.args, " "))
+		if err != nil {
+			return err
+		}
+		return printBilling(os.Stdout, summary, opts.format)
+	case "traffic":
+		summary, err := mon.QueryTraffic(strings.Join(opts.args, " "))
+		if err != nil {
+			return err
+		}
+		return printTraffic(os.Stdout, summary, opts.format)
+	case "start":
+		if err := mon.RefreshInstances(); err != nil {
+			return err
+		}
+		if err := mon.StartInstanceAndWait(context.Background(), opts.args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Instance %s started\n", opts.args[0])
+		return nil
+	default:
+		return fmt.Errorf("unknown command: %s", opts.command)
+	}
+}
+
+// printInstances prints instances as a table or JSON
+func printInstances(w io.Writer, instances []*aliyun.SpotInstance, format string) error {
+	if format == "json" {
+		return writeJSON(w, instances)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tREGION\tZONE\tTYPE\tSTATUS\tPUBLIC IP")
+	for _, inst := range instances {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", inst.InstanceID, inst.InstanceName,
+			inst.RegionID, inst.ZoneID, inst.InstanceType, inst.Status, inst.PublicIPAddress)
+	}
+	return tw.Flush()
+}
+
+// printBilling prints a billing summary as text, CSV or JSON
+func printBilling(w io.Writer, summary *aliyun.BillingSummary, format string) error {
+	if format != "text" {
+		return summary.Export(w, format)
+	}
+
+	period := summary.BillingCycle
+	if summary.PeriodLabel != "" {
+		period = fmt.Sprintf("%s (%s ~ %s)", summary.PeriodLabel,
+			summary.StartTime.Format("2006-01-02"), summary.EndTime.Format("2006-01-02"))
+	}
+	fmt.Fprintf(w, "Billing %s\n\n", period)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "INSTANCE\tNAME\tREGION\tHOURS\tAMOUNT\tESTIMATE")
+	for _, inst := range summary.Instances {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%.1f\t%.2f\t%.2f\n", inst.InstanceID, inst.InstanceName,
+			inst.Region, inst.RunningHours, inst.TotalAmount, inst.MonthlyEstimate)
+	}
+	for _, product := range summary.Products {
+		fmt.Fprintf(tw, "%s\t\t\t\t%.2f\t\n", product.ProductName, product.TotalAmount)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\nTotal: ¥%.2f\n", summary.TotalAmount)
+	if summary.PeriodLabel == "" && !summary.Closed {
+		fmt.Fprintf(w, "Monthly estimate: ¥%.2f\n", summary.MonthlyEstimate)
+	}
+	return nil
+}
+
+// printTraffic prints a traffic summary as text or JSON
+func printTraffic(w io.Writer, summary *aliyun.TrafficSummary, format string) error {
+	switch format {
+	case "json":
+		return writeJSON(w, summary)
+	case "csv":
+		return fmt.Errorf("csv is only supported for billing")
+	}
+
+	period := summary.BillingCycle
+	if summary.PeriodLabel != "" {
+		period = fmt.Sprintf("%s (%s ~ %s)", summary.PeriodLabel,
+			summary.StartTime.Format("2006-01-02"), summary.EndTime.Format("2006-01-02"))
+	}
+	fmt.Fprintf(w, "Traffic %s\n\n", period)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "REGION\tTRAFFIC")
+	for _, detail := range summary.RegionDetails {
+		fmt.Fprintf(tw, "%s\t%s\n", detail.BusinessRegionId, aliyun.FormatTrafficSize(detail.Traffic))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\nChina mainland: %s\n", aliyun.FormatTrafficSize(summary.ChinaMainland.Traffic))
+	fmt.Fprintf(w, "Non-China mainland: %s\n", aliyun.FormatTrafficSize(summary.NonChinaMainland.Traffic))
+	fmt.Fprintf(w, "Total: %s\n", aliyun.FormatTrafficSize(summary.TotalTraffic))
+	return nil
+}
+
+// writeJSON writes v as indented JSON
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// envFlags are command line flags overriding an environment variable
+var envFlags = []struct {
+	name  string
+	env   string
+	usage string
+}{
+	{"config", "CONFIG_FILE", "JSON config file"},
+	{"log-level", "LOG_LEVEL", "log level: debug, info, warn or error"},
+	{"log-format", "LOG_FORMAT", "log format: text or json"},
+	{"check-interval", "CHECK_INTERVAL", "seconds between instance checks"},
+	{"regions", "REGIONS", "comma-separated regions to scan, empty for all"},
+	{"data-dir", "DATA_DIR", "state store directory"},
+	{"api-listen", "API_LISTEN", "HTTP API listen address"},
+}
+
+// commands are the subcommands, "run" being the default
+var commands = map[string]string{
+	"run":         "run the monitor daemon (default)",
+	"check":       "discover instances, check them once and exit",
+	"list":        "print the discovered spot instances",
+	"billing":     "print billing for [range], e.g. \"last week\" or 2024-11",
+	"traffic":     "print CDT traffic for [range] [region]",
+	"start":       "start a stopped instance <id> and wait for it",
+	"validate":    "check the configuration and exit",
+	"config":      "print the effective configuration",
+	"version":     "print the version and build information",
+	"agent":       "run on a spot instance: watch for reclaims, report heartbeats",
+	"agent-token": "print the heartbeat token of the agent on each instance <id>...",
+}
+
+// options are the command line settings that are not configuration
+type options struct {
+	envFile     string
+	command     string
+	args        []string // arguments of the command
+	format      string   // output format of list, billing and traffic
+	testMessage bool
+}
+
+// parseFlags parses the command line into the subcommand with its arguments
+// and returns the environment overrides of the flags given
+func parseFlags(args []string) (*options, map[string]string, error) {
+	fs := flag.NewFlagSet("aliyun-spot-manager", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: aliyun-spot-manager [flags] [command] [args]\n\nCommands:")
+		names := make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(fs.Output(), "  %-11s %s\n", name, commands[name])
+		}
+		fmt.Fprintln(fs.Output(), "\nFlags:")
+		fs.PrintDefaults()
+	}
+
+	opts := &options{}
+	validate := false
+	showVersion := false
+	simulate := false
+	fs.StringVar(&opts.envFile, "env-file", ".env", "environment file to load")
+	fs.StringVar(&opts.format, "format", "text", "output format: text or json, csv for billing")
+	fs.BoolVar(&validate, "validate", false, "same as the validate command")
+	fs.BoolVar(&showVersion, "version", false, "same as the version command")
+	fs.BoolVar(&opts.testMessage, "test-message", false, "send a Telegram test message when validating")
+	fs.BoolVar(&simulate, "simulate", false, "use in-memory demo instances instead of the Aliyun API (sets SIMULATE)")
+
+	values := make(map[string]*string, len(envFlags))
+	for _, f := range envFlags {
+		values[f.name] = fs.String(f.name, "", fmt.Sprintf("%s (overrides %s)", f.usage, f.env))
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	opts.command = "run"
+	if validate {
+		opts.command = "validate"
+	}
+	if showVersion {
+		opts.command = "version"
+	}
+	if fs.NArg() > 0 {
+		opts.command = fs.Arg(0)
+		if _, ok := commands[opts.command]; !ok {
+			fs.Usage()
+			return nil, nil, fmt.Errorf("unknown command: %s", opts.command)
+		}
+		// Flags may follow the command too
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return nil, nil, err
+		}
+		opts.args = fs.Args()
+	}
+	if opts.format != "text" && opts.format != "json" && opts.format != "csv" {
+		return nil, nil, fmt.Errorf("unknown format: %s", opts.format)
+	}
+
+	overrides := make(map[string]string)
+	if simulate {
+		overrides["SIMULATE"] = "true"
+	}
+	fs.Visit(func(f *flag.Flag) {
+		for _, ef := range envFlags {
+			if ef.name == f.Name {
+				overrides[ef.env] = *values[f.Name]
+			}
+		}
+	})
+	return opts, overrides, nil
+}
+
+// applyOverrides sets the environment variables given on the command line,
+// taking precedence over the environment and the env file
+func applyOverrides(overrides map[string]string) {
+	for env, value := range overrides {
+		os.Setenv(env, value)
+	}
+}
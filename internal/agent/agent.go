@@ -0,0 +1,208 @@
+// Package agent runs on a spot instance itself: it watches the metadata
+// service for an announced reclaim, reports heartbeats to the monitor and
+// runs a graceful-shutdown script before the instance is reclaimed.
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// requestTimeout bounds metadata and controller requests
+const requestTimeout = 5 * time.Second
+
+// Agent polls the spot termination time and reports to the monitor
+type Agent struct {
+	cfg    *config.AgentConfig
+	client *http.Client
+
+	instanceID string
+	// Announced reclaim time, zero until the metadata service reports one
+	terminationTime time.Time
+
+	// The shutdown script running in the background
+	script sync.WaitGroup
+}
+
+// New creates an agent
+func New(cfg *config.AgentConfig) *Agent {
+	return &Agent{
+		cfg:        cfg,
+		client:     &http.Client{Timeout: requestTimeout},
+		instanceID: cfg.InstanceID,
+	}
+}
+
+// Run polls until ctx is cancelled, then waits for a running shutdown script
+func (a *Agent) Run(ctx context.Context) error {
+	if a.instanceID == "" {
+		id, err := a.metadata(ctx, "instance-id")
+		if err != nil {
+			return fmt.Errorf("failed to read instance ID from metadata, set AGENT_INSTANCE_ID: %w", err)
+		}
+		a.instanceID = id
+	}
+	log.Infof("Agent running on instance %s, polling %s every %ds", a.instanceID, a.cfg.MetadataURL, a.cfg.PollInterval)
+	defer a.script.Wait()
+
+	poll := time.NewTicker(time.Duration(a.cfg.PollInterval) * time.Second)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(time.Duration(a.cfg.HeartbeatInterval) * time.Second)
+	defer heartbeat.Stop()
+
+	a.poll(ctx)
+	a.sendHeartbeat(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("Agent stopped")
+			return nil
+		case <-poll.C:
+			if a.poll(ctx) {
+				a.sendHeartbeat(ctx)
+			}
+		case <-heartbeat.C:
+			a.sendHeartbeat(ctx)
+		}
+	}
+}
+
+// poll checks the termination time and reports whether a reclaim was just announced
+func (a *Agent) poll(ctx context.Context) bool {
+	if !a.terminationTime.IsZero() {
+		return false
+	}
+
+	value, err := a.metadata(ctx, "instance/spot/termination-time")
+	if err != nil {
+		log.Warnf("Failed to poll termination time: %v", err)
+		return false
+	}
+	if value == "" {
+		return false
+	}
+	terminationTime, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		log.Warnf("Invalid termination time %q: %v", value, err)
+		return false
+	}
+
+	a.terminationTime = terminationTime
+	log.Warnf("Instance %s will be reclaimed at %s, in %s",
+		a.instanceID, terminationTime.Local().Format("15:04:05"), time.Until(terminationTime).Round(time.Second))
+	a.runShutdownScript()
+	return true
+}
+
+// metadata reads a metadata item, empty if the service has none (404)
+func (a *Agent) metadata(ctx context.Context, path string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.cfg.MetadataURL+"/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return "", err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return strings.TrimSpace(string(body)), nil
+	case http.StatusNotFound:
+		return "", nil
+	default:
+		return "", fmt.Errorf("metadata service returned status %d", resp.StatusCode)
+	}
+}
+
+// sendHeartbeat reports the agent alive, and the reclaim once announced, to the monitor
+// A failed heartbeat is only logged, the next one retries.
+func (a *Agent) sendHeartbeat(ctx context.Context) {
+	if a.cfg.ControllerURL == "" {
+		return
+	}
+
+	payload := map[string]interface{}{"instance_id": a.instanceID}
+	if !a.terminationTime.IsZero() {
+		payload["termination_time"] = a.terminationTime
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Warnf("Failed to encode heartbeat: %v", err)
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.cfg.ControllerURL+"/api/agent/heartbeat", bytes.NewReader(body))
+	if err != nil {
+		log.Warnf("Failed to create heartbeat request: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.cfg.Token)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		log.Warnf("Failed to send heartbeat: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		log.Warnf("Controller rejected heartbeat: status %d", resp.StatusCode)
+		return
+	}
+	log.Debugf("Heartbeat sent for instance %s", a.instanceID)
+}
+
+// runShutdownScript runs AGENT_SHUTDOWN_SCRIPT in the background
+// It isn't cancelled with the agent, the system stopping services during
+// the reclaim must not cut the graceful shutdown short.
+func (a *Agent) runShutdownScript() {
+	if a.cfg.ShutdownScript == "" {
+		return
+	}
+
+	a.script.Add(1)
+	go func() {
+		defer a.script.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(a.cfg.ShutdownTimeout)*time.Second)
+		defer cancel()
+
+		cmd := exec.CommandContext(ctx, "sh", "-c", a.cfg.ShutdownScript)
+		cmd.Env = append(os.Environ(),
+			"SPOT_INSTANCE_ID="+a.instanceID,
+			"SPOT_TERMINATION_TIME="+a.terminationTime.UTC().Format(time.RFC3339),
+		)
+		log.Infof("Running shutdown script: %s", a.cfg.ShutdownScript)
+		output, err := cmd.CombinedOutput()
+		if len(output) > 0 {
+			log.Infof("Shutdown script output:\n%s", strings.TrimSpace(string(output)))
+		}
+		if err != nil {
+			log.Errorf("Shutdown script failed: %v", err)
+			return
+		}
+		log.Info("Shutdown script finished")
+	}()
+}
@@ -0,0 +1,16 @@
+package agent
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Token returns the heartbeat token of the agent on an instance, derived
+// from the monitor's AGENT_SECRET. Each instance gets its own token, so an
+// agent can only report for the instance it runs on.
+func Token(secret, instanceID string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(instanceID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
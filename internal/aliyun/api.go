@@ -0,0 +1,61 @@
+package aliyun
+
+import (
+	"context"
+	"time"
+)
+
+// InstanceAPI is the ECS API the monitor manages spot instances with
+type InstanceAPI interface {
+	DiscoverAllSpotInstances(ctx context.Context, opts DiscoverOptions) ([]*SpotInstance, error)
+	GetInstance(ctx context.Context, regionID, instanceID string) (*SpotInstance, error)
+	GetInstanceStatus(ctx context.Context, regionID, instanceID string) (string, error)
+	StartInstance(ctx context.Context, regionID, instanceID string) error
+	StartInstances(ctx context.Context, regionID string, instanceIDs []string) (map[string]error, error)
+	StopInstance(ctx context.Context, regionID, instanceID string, force bool) error
+	RebootInstance(ctx context.Context, regionID, instanceID string) error
+	SetInternetBandwidth(ctx context.Context, regionID, instanceID string, mbps int) error
+	GetScheduledEvents(ctx context.Context, regionID string, instanceIDs []string) ([]*SystemEvent, error)
+	GetInterruptionHistory(ctx context.Context, regionID, instanceID string, since time.Time) ([]*SystemEvent, error)
+	GetOnDemandPrice(ctx context.Context, regionID, zoneID, instanceType string) (float64, error)
+	GetSpotPriceHistory(ctx context.Context, regionID, instanceType, osType string, start time.Time) ([]SpotPrice, error)
+	ReplaceInstance(ctx context.Context, regionID, instanceID string, opts ReplaceOptions) (*SpotInstance, error)
+	MoveEIP(ctx context.Context, regionID, fromInstanceID, toInstanceID string) (string, error)
+	DescribeEIP(ctx context.Context, regionID, eip string) (*EIP, error)
+	AssociateEIP(ctx context.Context, regionID, allocationID, instanceID string) error
+	RunCommand(ctx context.Context, regionID, instanceID, osType, script string, timeout time.Duration) (*CommandResult, error)
+	DescribeDisks(ctx context.Context, regionID, instanceID string) ([]*Disk, error)
+	CreateInstanceSnapshots(ctx context.Context, regionID, instanceID, reason string) ([]*Snapshot, error)
+	DescribeSnapshots(ctx context.Context, regionID, instanceID string) ([]*Snapshot, error)
+	DeleteSnapshot(ctx context.Context, regionID, snapshotID string) error
+	DescribeSecurityGroupRules(ctx context.Context, regionID string, groupIDs []string) ([]SecurityGroupRule, error)
+	GetCPUCredits(ctx context.Context, regionID, instanceID string) (*CPUCredits, error)
+	GetInstanceMetrics(ctx context.Context, regionID, instanceID string) (*InstanceMetrics, error)
+	CaptureLaunchConfig(ctx context.Context, regionID, instanceID string) (*LaunchConfig, error)
+	SaveLaunchTemplate(ctx context.Context, cfg *LaunchConfig) error
+	UpdateCredentials(creds Credentials)
+}
+
+// BillingAPI is the BSS API the monitor queries spend with
+type BillingAPI interface {
+	QueryBilling(ctx context.Context, instances []InstanceInfo, cycle string) (*BillingSummary, error)
+	QueryBillingByDateRange(ctx context.Context, instances []InstanceInfo, start, end time.Time, label string) (*BillingSummary, error)
+	QueryDailyBilling(ctx context.Context, instances []InstanceInfo, start, end time.Time) ([]DailyBilling, error)
+	QueryBillingHistory(ctx context.Context, instances []InstanceInfo, nMonths int) ([]*BillingSummary, error)
+	QueryBandwidthReconciliation(ctx context.Context, instances []InstanceInfo, traffic *TrafficSummary) (*BandwidthReconciliation, error)
+	UpdateCredentials(creds Credentials) error
+}
+
+// TrafficAPI is the CDT API the monitor queries internet traffic with
+type TrafficAPI interface {
+	QueryInternetTraffic(ctx context.Context) (*TrafficSummary, error)
+	QueryInternetTrafficByTimeRange(ctx context.Context, startTime, endTime time.Time) (*TrafficSummary, error)
+	ApplyForecast(ctx context.Context, summary *TrafficSummary, method string) error
+	UpdateCredentials(creds Credentials) error
+}
+
+var (
+	_ InstanceAPI = (*ECSClient)(nil)
+	_ BillingAPI  = (*BillingClient)(nil)
+	_ TrafficAPI  = (*TrafficClient)(nil)
+)
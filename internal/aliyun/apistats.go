@@ -0,0 +1,135 @@
+package aliyun
+
+import (
+	"errors"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	sdkerrors "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
+)
+
+// LatencyBuckets are the upper bounds of the API latency histogram
+var LatencyBuckets = []time.Duration{
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+}
+
+// APICallStats are the recorded calls of one API in one region
+type APICallStats struct {
+	API       string
+	Region    string // empty for global APIs (BSS, CDT, DescribeRegions)
+	Calls     int
+	Errors    int
+	Throttled int
+	Codes     map[string]int // result code -> calls, "OK" for successes
+	Total     time.Duration
+	Max       time.Duration
+	Buckets   []int // calls per LatencyBuckets bound, plus one slower than all
+}
+
+// Average returns the mean latency of the calls
+func (s APICallStats) Average() time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Calls)
+}
+
+// apiStats records latency and result codes of every SDK call attempt
+type apiStats struct {
+	calls map[string]*APICallStats // api + "|" + region
+	mu    sync.Mutex
+}
+
+// newAPIStats creates an empty recorder
+func newAPIStats() *apiStats {
+	return &apiStats{calls: make(map[string]*APICallStats)}
+}
+
+// record adds one call attempt
+func (s *apiStats) record(api, region string, latency time.Duration, err error) {
+	key := api + "|" + region
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats, ok := s.calls[key]
+	if !ok {
+		stats = &APICallStats{
+			API:     api,
+			Region:  region,
+			Codes:   make(map[string]int),
+			Buckets: make([]int, len(LatencyBuckets)+1),
+		}
+		s.calls[key] = stats
+	}
+
+	stats.Calls++
+	stats.Total += latency
+	if latency > stats.Max {
+		stats.Max = latency
+	}
+	bucket := sort.Search(len(LatencyBuckets), func(i int) bool { return latency <= LatencyBuckets[i] })
+	stats.Buckets[bucket]++
+
+	stats.Codes[resultCode(err)]++
+	if err != nil {
+		stats.Errors++
+		if IsRetryable(err) {
+			stats.Throttled++
+		}
+	}
+}
+
+// snapshot returns a copy of the stats sorted by API and region
+func (s *apiStats) snapshot() []APICallStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]APICallStats, 0, len(s.calls))
+	for _, stats := range s.calls {
+		c := *stats
+		c.Codes = make(map[string]int, len(stats.Codes))
+		for code, n := range stats.Codes {
+			c.Codes[code] = n
+		}
+		c.Buckets = append([]int(nil), stats.Buckets...)
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].API != list[j].API {
+			return list[i].API < list[j].API
+		}
+		return list[i].Region < list[j].Region
+	})
+	return list
+}
+
+// resultCode returns the code a call attempt is counted under
+func resultCode(err error) string {
+	if err == nil {
+		return "OK"
+	}
+	if code := ErrorCode(err); code != "" {
+		return code
+	}
+	var clientErr *sdkerrors.ClientError
+	if errors.As(err, &clientErr) {
+		return clientErr.ErrorCode()
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return "NetworkTimeout"
+		}
+		return "NetworkError"
+	}
+	return "Error"
+}
@@ -1,9 +1,14 @@
 package aliyun
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk"
 	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
 	"github.com/aliyun/alibaba-cloud-sdk-go/services/bssopenapi"
 	log "github.com/sirupsen/logrus"
@@ -27,155 +32,487 @@ type InstanceBillingSummary struct {
 	InstanceID   string
 	InstanceName string
 	Region       string
-	InstanceSpec string  // 实例规格
+	InstanceSpec string // 实例规格
+	Group        string // 实例分组，未分组为空
 	Items        []BillingItem
 	TotalAmount  float64
 	RunningHours float64 // 运行小时数
 	HourlyCost   float64 // 平均每小时费用
+
+	MonthlyEstimate float64 // 月度估算
+
+	ComputeAmount  float64 // 计算资源 (云服务器配置) 费用
+	OnDemandAmount float64 // 同样运行时长按量付费的计算费用，未查询到价格为 0
+}
+
+// ProductBillingSummary represents billing of a non-ECS product (EIP, CDT, snapshots...)
+// Unlike instances, all resources of the product in the account are included
+type ProductBillingSummary struct {
+	ProductCode string
+	ProductName string
+	Items       []BillingItem // 按计费项合并
+	Resources   int           // 产生扣费的资源数
+	TotalAmount float64
 }
 
 // BillingSummary represents the billing summary for the current month
 type BillingSummary struct {
-	StartTime           time.Time
-	EndTime             time.Time
-	BillingCycle        string  // 账单周期 (YYYY-MM)
-	ElapsedDays         int     // 本月已过天数
-	TotalRunningHours   float64 // 总运行小时数
-	Instances           []InstanceBillingSummary
-	TotalAmount         float64
-	MonthlyEstimate     float64 // 月度估算
-	EstimateMethod      string  // 估算方法说明
+	StartTime         time.Time
+	EndTime           time.Time
+	BillingCycle      string  // 账单周期 (YYYY-MM)
+	PeriodLabel       string  // 非整月查询时的区间说明 (今天、昨天等)
+	ElapsedDays       int     // 本月已过天数 (已结束账期为整月天数)
+	Closed            bool    // 账期已结束 (查询的是过去的月份)
+	TotalRunningHours float64 // 总运行小时数
+	Instances         []InstanceBillingSummary
+	Products          []ProductBillingSummary // 非 ECS 产品，按金额降序
+	InstanceAmount    float64                 // 实例合计
+	OtherAmount       float64                 // 非 ECS 产品合计
+	TotalAmount       float64
+	MonthlyEstimate   float64 // 月度估算
+	OnDemandAmount    float64 // 按量付费计算费用 (仅含查询到价格的实例)
+	SavedAmount       float64 // 相比按量付费节省的计算费用
+	EstimateMethod    string  // 估算方法说明
+	Group             string  // 只统计该分组时的分组名
 }
 
 // BillingClient wraps the Aliyun BSS client
 type BillingClient struct {
-	client *bssopenapi.Client
+	client    *bssopenapi.Client
+	transport *Transport
+	mu        sync.RWMutex
+
+	// Product codes queried for billing summaries, always including "ecs"
+	products []string
+
+	// How the monthly estimate is calculated, one of the Estimate* methods
+	estimateMethod string
+
+	// Retries throttled calls, nil calls once
+	resilience *Resilience
 }
 
 // NewBillingClient creates a new BSS client
-func NewBillingClient(accessKeyID, accessKeySecret string) (*BillingClient, error) {
+func NewBillingClient(creds Credentials, transport *Transport) (*BillingClient, error) {
 	// BSS API uses cn-hangzhou as the default region
-	client, err := bssopenapi.NewClientWithAccessKey("cn-hangzhou", accessKeyID, accessKeySecret)
+	client, err := bssopenapi.NewClientWithOptions("cn-hangzhou", sdk.NewConfig(), creds.credential())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create BSS client: %w", err)
 	}
+	transport.apply(&client.Client)
 
 	return &BillingClient{
-		client: client,
+		client:         client,
+		transport:      transport,
+		products:       []string{"ecs"},
+		estimateMethod: EstimateHourly,
 	}, nil
 }
 
+// SetResilience sets the retry layer of API calls
+func (c *BillingClient) SetResilience(r *Resilience) {
+	c.resilience = r
+}
+
+// SetProducts sets the product codes (e.g. "eip", "cdt", "snapshot") included
+// in billing summaries besides "ecs"
+func (c *BillingClient) SetProducts(products []string) {
+	list := []string{"ecs"}
+	for _, product := range products {
+		product = strings.ToLower(strings.TrimSpace(product))
+		if product != "" && !containsString(list, product) {
+			list = append(list, product)
+		}
+	}
+	c.mu.Lock()
+	c.products = list
+	c.mu.Unlock()
+}
+
+// queryProducts fetches billing items of all configured products for a cycle,
+// at daily granularity if billingDate is set
+func (c *BillingClient) queryProducts(ctx context.Context, cycle, billingDate string) ([]bssopenapi.Item, error) {
+	c.mu.RLock()
+	products := c.products
+	c.mu.RUnlock()
+
+	var items []bssopenapi.Item
+	for _, product := range products {
+		productItems, err := c.queryInstanceBill(ctx, product, cycle, billingDate)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, productItems...)
+	}
+	return items, nil
+}
+
+// UpdateCredentials re-creates the BSS client with new credentials
+func (c *BillingClient) UpdateCredentials(creds Credentials) error {
+	client, err := bssopenapi.NewClientWithOptions("cn-hangzhou", sdk.NewConfig(), creds.credential())
+	if err != nil {
+		return fmt.Errorf("failed to create BSS client: %w", err)
+	}
+	c.transport.apply(&client.Client)
+
+	c.mu.Lock()
+	c.client = client
+	c.mu.Unlock()
+	return nil
+}
+
+// getClient returns the current BSS client
+func (c *BillingClient) getClient() *bssopenapi.Client {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.client
+}
+
 // InstanceInfo contains basic instance information for billing display
 type InstanceInfo struct {
 	InstanceID   string
 	InstanceName string
 	RegionID     string
+
+	ExpectedHoursPerDay float64 // 预计每日运行小时数，用于月度估算，0 为未设置
+	Group               string  // 实例分组，未分组为空
 }
 
-// QueryBilling queries billing for the specified instances for the current month
-// Note: Aliyun API returns monthly cumulative data, so we query the current month's data
+// QueryBilling queries billing for the specified instances for a billing cycle (YYYY-MM),
+// or the current month if cycle is empty
+// Note: Aliyun API returns monthly cumulative data, so we query the whole cycle's data
 // and calculate monthly estimate based on actual running time (ServicePeriod in seconds)
-func (c *BillingClient) QueryBilling(instances []InstanceInfo) (*BillingSummary, error) {
+func (c *BillingClient) QueryBilling(ctx context.Context, instances []InstanceInfo, cycle string) (*BillingSummary, error) {
 	now := time.Now()
-	// Start of current month
-	startTime := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	if cycle == "" {
+		cycle = now.Format("2006-01")
+	}
+	// Start of the cycle
+	startTime, err := time.ParseInLocation("2006-01", cycle, now.Location())
+	if err != nil {
+		return nil, fmt.Errorf("invalid billing cycle %q, expected YYYY-MM", cycle)
+	}
+	endTime := startTime.AddDate(0, 1, 0)
+
+	log.Debugf("Querying billing for %d instances, billing cycle: %s", len(instances), cycle)
+
+	items, err := c.queryProducts(ctx, cycle, "")
+	if err != nil {
+		return nil, err
+	}
+
+	result := summarizeBilling(instances, [][]bssopenapi.Item{items})
+	result.StartTime = startTime
+	result.BillingCycle = cycle
+	if now.Before(endTime) {
+		result.EndTime = now
+		// Calculate elapsed days this month
+		result.ElapsedDays = now.Day()
+		method := c.getEstimateMethod()
+		var trailing *BillingSummary
+		if method == EstimateTrailing {
+			trailing, err = c.queryTrailing(ctx, instances, now)
+			if err != nil {
+				log.Warnf("Failed to query trailing %d days for the monthly estimate, estimating by elapsed days: %v", trailingEstimateDays, err)
+				method = EstimateDays
+			}
+		}
+		applyMonthlyEstimate(result, instances, method, trailing, endTime.AddDate(0, 0, -1).Day()-now.Day())
+	} else {
+		// A past cycle is complete, its total is the actual monthly cost
+		result.EndTime = endTime
+		result.ElapsedDays = endTime.AddDate(0, 0, -1).Day()
+		result.Closed = true
+		result.MonthlyEstimate = result.TotalAmount
+		result.EstimateMethod = "账期已结束，为整月实际扣费"
+		for i := range result.Instances {
+			result.Instances[i].MonthlyEstimate = result.Instances[i].TotalAmount
+		}
+	}
+
+	log.Infof("Found billing for %d instances (%s), total: %.4f, running hours: %.2f, monthly estimate: %.2f",
+		len(result.Instances), cycle, result.TotalAmount, result.TotalRunningHours, result.MonthlyEstimate)
+
+	return result, nil
+}
+
+// QueryBillingByDateRange queries billing at daily granularity for every day from start to end (inclusive)
+// label describes the range for display, e.g. "今天" or "最近7天"
+func (c *BillingClient) QueryBillingByDateRange(ctx context.Context, instances []InstanceInfo, start, end time.Time, label string) (*BillingSummary, error) {
+	log.Debugf("Querying daily billing for %d instances from %s to %s",
+		len(instances), start.Format("2006-01-02"), end.Format("2006-01-02"))
+
+	// Each day is queried separately so running time can be summed per day
+	_, batches, err := c.queryDays(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	result := summarizeBilling(instances, batches)
+	result.StartTime = start
+	result.EndTime = end
+	result.BillingCycle = start.Format("2006-01")
+	result.PeriodLabel = label
+	result.ElapsedDays = len(batches)
+	method := c.getEstimateMethod()
+	if method == EstimateTrailing {
+		// A range has no trailing window of its own, its daily average is the closest
+		method = EstimateDays
+	}
+	applyMonthlyEstimate(result, instances, method, nil, 0)
+
+	log.Infof("Found billing for %d instances (%s), total: %.4f, running hours: %.2f",
+		len(result.Instances), label, result.TotalAmount, result.TotalRunningHours)
+
+	return result, nil
+}
+
+// DailyBilling is the spend of a single day
+type DailyBilling struct {
+	Date       time.Time
+	Amount     float64
+	ByInstance map[string]float64 // 实例名称 (其他产品为产品名称) -> 当日费用
+	ByItem     map[string]float64 // "实例名称 · 计费项" -> 当日费用
+}
+
+// MaxDailyBillingDays caps the days QueryDailyBilling covers, one API call per day and product
+const MaxDailyBillingDays = 62
+
+// QueryDailyBilling queries the spend of each day from start to end (inclusive), oldest first
+func (c *BillingClient) QueryDailyBilling(ctx context.Context, instances []InstanceInfo, start, end time.Time) ([]DailyBilling, error) {
+	days, batches, err := c.queryDays(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	result := dailyBillings(instances, days, batches)
+	log.Infof("Queried daily billing for %d days", len(days))
+	return result, nil
+}
+
+// dailyBillings summarizes one batch of billing items per day
+func dailyBillings(instances []InstanceInfo, days []time.Time, batches [][]bssopenapi.Item) []DailyBilling {
+	result := make([]DailyBilling, len(days))
+	for i, day := range days {
+		summary := summarizeBilling(instances, batches[i:i+1])
+		daily := DailyBilling{
+			Date:       day,
+			Amount:     summary.TotalAmount,
+			ByInstance: make(map[string]float64),
+			ByItem:     make(map[string]float64),
+		}
+		for _, inst := range summary.Instances {
+			daily.ByInstance[inst.InstanceName] += inst.TotalAmount
+			for _, item := range inst.Items {
+				daily.ByItem[inst.InstanceName+" · "+item.BillingItemName] += item.PretaxAmount
+			}
+		}
+		for _, product := range summary.Products {
+			name := product.ProductName
+			if name == "" {
+				name = product.ProductCode
+			}
+			daily.ByInstance[name] += product.TotalAmount
+			for _, item := range product.Items {
+				daily.ByItem[name+" · "+item.BillingItemName] += item.PretaxAmount
+			}
+		}
+		result[i] = daily
+	}
+	return result
+}
+
+// queryDays fetches the billing items of every day from start to end (inclusive),
+// returning the days and one batch of items per day
+func (c *BillingClient) queryDays(ctx context.Context, start, end time.Time) ([]time.Time, [][]bssopenapi.Item, error) {
+	startDay := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	endDay := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, end.Location())
+	if endDay.Before(startDay) {
+		return nil, nil, fmt.Errorf("invalid date range: %s ~ %s", startDay.Format("2006-01-02"), endDay.Format("2006-01-02"))
+	}
+
+	var days []time.Time
+	var batches [][]bssopenapi.Item
+	for day := startDay; !day.After(endDay); day = day.AddDate(0, 0, 1) {
+		items, err := c.queryProducts(ctx, day.Format("2006-01"), day.Format("2006-01-02"))
+		if err != nil {
+			return nil, nil, err
+		}
+		days = append(days, day)
+		batches = append(batches, items)
+	}
+	return days, batches, nil
+}
+
+// billingPageSize is the largest page QueryInstanceBill accepts
+const billingPageSize = 300
+
+// maxBillingPages caps the pages fetched per query (300 000 items), guarding
+// against a TotalCount that keeps growing or an API that never runs out of pages
+const maxBillingPages = 1000
+
+// queryInstanceBill fetches billing items of a product (e.g. "ecs") for a cycle (YYYY-MM)
+// If billingDate (YYYY-MM-DD) is set, items are queried at daily granularity for that day
+// All pages are fetched up to maxBillingPages
+func (c *BillingClient) queryInstanceBill(ctx context.Context, productCode, cycle, billingDate string) ([]bssopenapi.Item, error) {
+	var items []bssopenapi.Item
+	totalCount := 0
+	for page := 1; ; page++ {
+		if page > maxBillingPages {
+			log.Warnf("Billing query for %s cycle %s %s stopped after %d pages, %d of %d items fetched, totals are incomplete",
+				productCode, cycle, billingDate, maxBillingPages, len(items), totalCount)
+			return items, nil
+		}
+
+		request := bssopenapi.CreateQueryInstanceBillRequest()
+		request.Scheme = "https"
+		request.BillingCycle = cycle
+		request.ProductCode = productCode
+		request.IsBillingItem = requests.NewBoolean(true)
+		request.PageSize = requests.NewInteger(billingPageSize)
+		request.PageNum = requests.NewInteger(page)
+		if billingDate != "" {
+			request.Granularity = "DAILY"
+			request.BillingDate = billingDate
+		}
 
-	log.Debugf("Querying billing for %d instances, current month %s",
-		len(instances), now.Format("2006-01"))
+		var response *bssopenapi.QueryInstanceBillResponse
+		err := c.resilience.call(ctx, "", "QueryInstanceBill", func() (err error) {
+			response, err = c.getClient().QueryInstanceBill(request)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query %s instance bill for cycle %s (page %d): %w", productCode, cycle, page, err)
+		}
 
+		pageItems := response.Data.Items.Item
+		items = append(items, pageItems...)
+		totalCount = response.Data.TotalCount
+		if len(pageItems) < billingPageSize || len(items) >= totalCount {
+			break
+		}
+	}
+
+	if len(items) < totalCount {
+		log.Warnf("Billing query for %s cycle %s %s returned %d of %d items", productCode, cycle, billingDate, len(items), totalCount)
+	}
+	log.Debugf("Got %d %s billing items from API for cycle %s %s", len(items), productCode, cycle, billingDate)
+
+	return items, nil
+}
+
+// summarizeBilling groups billing items by tracked instance
+// Each batch covers one billing period (a month or a day); running time is
+// deduplicated within a batch and summed across batches
+func summarizeBilling(instances []InstanceInfo, batches [][]bssopenapi.Item) *BillingSummary {
 	// Create instance ID to info map for quick lookup
 	instanceMap := make(map[string]InstanceInfo)
 	for _, inst := range instances {
 		instanceMap[inst.InstanceID] = inst
 	}
 
-	// Query current month's billing cycle
-	cycle := now.Format("2006-01")
-
 	// Group billing items by instance
 	instanceBillings := make(map[string]*InstanceBillingSummary)
-	
+
+	// Group items of other products by product code, counting distinct resources
+	productBillings := make(map[string]*ProductBillingSummary)
+	productResources := make(map[string]map[string]bool)
+
 	// Track running seconds per instance (to avoid duplicate counting)
 	// Each instance has multiple billing items with the same ServicePeriod
 	instanceRunningSeconds := make(map[string]float64)
 
-	log.Debugf("Querying billing cycle: %s", cycle)
+	for _, items := range batches {
+		batchRunningSeconds := make(map[string]float64)
 
-	// Query instance bill
-	request := bssopenapi.CreateQueryInstanceBillRequest()
-	request.Scheme = "https"
-	request.BillingCycle = cycle
-	request.ProductCode = "ecs"
-	request.IsBillingItem = requests.NewBoolean(true)
-	request.PageSize = requests.NewInteger(300)
-	request.PageNum = requests.NewInteger(1)
+		for _, item := range items {
+			if code := strings.ToLower(item.ProductCode); code != "" && code != "ecs" {
+				product, exists := productBillings[code]
+				if !exists {
+					product = &ProductBillingSummary{ProductCode: code, ProductName: item.ProductName}
+					productBillings[code] = product
+					productResources[code] = make(map[string]bool)
+				}
+				product.addItem(BillingItem{
+					InstanceID:      item.InstanceID,
+					Region:          item.Region,
+					ProductCode:     code,
+					ProductDetail:   item.ProductDetail,
+					BillingItemName: item.BillingItem,
+					PretaxAmount:    item.PretaxAmount,
+					Currency:        item.Currency,
+				})
+				if item.InstanceID != "" {
+					productResources[code][item.InstanceID] = true
+				}
+				continue
+			}
 
-	response, err := c.client.QueryInstanceBill(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query instance bill for cycle %s: %w", cycle, err)
-	}
+			// Skip if not in our instance list
+			instInfo, exists := instanceMap[item.InstanceID]
+			if !exists {
+				continue
+			}
 
-	log.Debugf("Got %d billing items from API for cycle %s", len(response.Data.Items.Item), cycle)
+			// Debug log to see actual API response fields
+			log.Debugf("Billing item: InstanceID=%s, InstanceSpec=%s, BillingItem=%s, ServicePeriod=%s, PretaxAmount=%.4f",
+				item.InstanceID, item.InstanceSpec, item.BillingItem, item.ServicePeriod, item.PretaxAmount)
+
+			summary, exists := instanceBillings[item.InstanceID]
+			if !exists {
+				summary = &InstanceBillingSummary{
+					InstanceID:   item.InstanceID,
+					InstanceName: instInfo.InstanceName,
+					Region:       instInfo.RegionID,
+					InstanceSpec: item.InstanceSpec,
+					Group:        instInfo.Group,
+					Items:        []BillingItem{},
+					TotalAmount:  0,
+				}
+				instanceBillings[item.InstanceID] = summary
+			}
 
-	for _, item := range response.Data.Items.Item {
-		// Skip if not in our instance list
-		instInfo, exists := instanceMap[item.InstanceID]
-		if !exists {
-			continue
-		}
+			// Update InstanceSpec if not set
+			if summary.InstanceSpec == "" && item.InstanceSpec != "" {
+				summary.InstanceSpec = item.InstanceSpec
+			}
 
-		// Debug log to see actual API response fields
-		log.Debugf("Billing item: InstanceID=%s, InstanceSpec=%s, BillingItem=%s, ServicePeriod=%s, PretaxAmount=%.4f",
-			item.InstanceID, item.InstanceSpec, item.BillingItem, item.ServicePeriod, item.PretaxAmount)
-
-		summary, exists := instanceBillings[item.InstanceID]
-		if !exists {
-			summary = &InstanceBillingSummary{
-				InstanceID:   item.InstanceID,
-				InstanceName: instInfo.InstanceName,
-				Region:       instInfo.RegionID,
-				InstanceSpec: item.InstanceSpec,
-				Items:        []BillingItem{},
-				TotalAmount:  0,
+			// Parse ServicePeriod for running time calculation
+			// Only count once per instance (avoid duplicate counting from multiple billing items)
+			// Note: Only count instances with ServicePeriodUnit "秒" (seconds) for spot instances
+			// Instances with "天" (days) are typically prepaid/subscription instances
+			if item.ServicePeriod != "" && item.ServicePeriodUnit == "秒" {
+				if seconds, err := parseServicePeriod(item.ServicePeriod, item.ServicePeriodUnit); err == nil {
+					// Only update if this is a larger value (in case different billing items have different periods)
+					if seconds > batchRunningSeconds[item.InstanceID] {
+						batchRunningSeconds[item.InstanceID] = seconds
+					}
+				}
 			}
-			instanceBillings[item.InstanceID] = summary
-		}
 
-		// Update InstanceSpec if not set
-		if summary.InstanceSpec == "" && item.InstanceSpec != "" {
-			summary.InstanceSpec = item.InstanceSpec
-		}
+			// Format billing item name with InstanceSpec for compute resources
+			billingItemName := formatBillingItemName(item.BillingItem, item.InstanceSpec)
 
-		// Parse ServicePeriod for running time calculation
-		// Only count once per instance (avoid duplicate counting from multiple billing items)
-		// Note: Only count instances with ServicePeriodUnit "秒" (seconds) for spot instances
-		// Instances with "天" (days) are typically prepaid/subscription instances
-		if item.ServicePeriod != "" && item.ServicePeriodUnit == "秒" {
-			if seconds, err := parseServicePeriod(item.ServicePeriod, item.ServicePeriodUnit); err == nil {
-				// Only update if this is a larger value (in case different billing items have different periods)
-				if seconds > instanceRunningSeconds[item.InstanceID] {
-					instanceRunningSeconds[item.InstanceID] = seconds
-				}
+			if item.BillingItem == "云服务器配置" {
+				summary.ComputeAmount += item.PretaxAmount
 			}
-		}
 
-		// Format billing item name with InstanceSpec for compute resources
-		billingItemName := formatBillingItemName(item.BillingItem, item.InstanceSpec)
-
-		billingItem := BillingItem{
-			InstanceID:      item.InstanceID,
-			InstanceName:    instInfo.InstanceName,
-			Region:          instInfo.RegionID,
-			ProductCode:     item.ProductCode,
-			ProductDetail:   item.ProductDetail,
-			BillingItemName: billingItemName,
-			InstanceSpec:    item.InstanceSpec,
-			PretaxAmount:    item.PretaxAmount,
-			Currency:        item.Currency,
+			summary.addItem(BillingItem{
+				InstanceID:      item.InstanceID,
+				InstanceName:    instInfo.InstanceName,
+				Region:          instInfo.RegionID,
+				ProductCode:     item.ProductCode,
+				ProductDetail:   item.ProductDetail,
+				BillingItemName: billingItemName,
+				InstanceSpec:    item.InstanceSpec,
+				PretaxAmount:    item.PretaxAmount,
+				Currency:        item.Currency,
+			})
 		}
 
-		summary.Items = append(summary.Items, billingItem)
-		summary.TotalAmount += item.PretaxAmount
+		for id, seconds := range batchRunningSeconds {
+			instanceRunningSeconds[id] += seconds
+		}
 	}
 
 	// Calculate total running seconds from per-instance data (deduplicated)
@@ -183,18 +520,10 @@ func (c *BillingClient) QueryBilling(instances []InstanceInfo) (*BillingSummary,
 	for _, seconds := range instanceRunningSeconds {
 		totalRunningSeconds += seconds
 	}
-	
-	// Calculate elapsed days this month
-	elapsedDays := now.Day()
-	totalRunningHours := totalRunningSeconds / 3600
 
 	// Build final summary
 	result := &BillingSummary{
-		StartTime:         startTime,
-		EndTime:           now,
-		BillingCycle:      cycle,
-		ElapsedDays:       elapsedDays,
-		TotalRunningHours: totalRunningHours,
+		TotalRunningHours: totalRunningSeconds / 3600,
 		Instances:         make([]InstanceBillingSummary, 0, len(instanceBillings)),
 		TotalAmount:       0,
 	}
@@ -208,41 +537,59 @@ func (c *BillingClient) QueryBilling(instances []InstanceInfo) (*BillingSummary,
 			}
 		}
 		result.Instances = append(result.Instances, *summary)
-		result.TotalAmount += summary.TotalAmount
+		result.InstanceAmount += summary.TotalAmount
 	}
 
-	// Calculate monthly estimate based on sum of per-instance hourly costs
-	// This assumes all instances run 24/7 for a full month
-	var totalHourlyCost float64
-	for _, inst := range result.Instances {
-		if inst.HourlyCost > 0 {
-			totalHourlyCost += inst.HourlyCost
-		}
+	for code, product := range productBillings {
+		product.Resources = len(productResources[code])
+		result.Products = append(result.Products, *product)
+		result.OtherAmount += product.TotalAmount
 	}
-	
-	if totalHourlyCost > 0 {
-		// Sum of all instance hourly costs × 720 hours
-		result.MonthlyEstimate = totalHourlyCost * 30 * 24
-		result.EstimateMethod = fmt.Sprintf("按每小时费用总和: ¥%.4f/小时 × 720小时", totalHourlyCost)
-	} else if result.TotalAmount > 0 {
-		// Fallback: use elapsed days this month
-		if elapsedDays > 0 {
-			dailyRate := result.TotalAmount / float64(elapsedDays)
-			result.MonthlyEstimate = dailyRate * 30
-			result.EstimateMethod = fmt.Sprintf("按已过天数: ¥%.4f/天 × 30天", dailyRate)
+	sort.Slice(result.Products, func(i, j int) bool {
+		return result.Products[i].TotalAmount > result.Products[j].TotalAmount
+	})
+
+	result.TotalAmount = result.InstanceAmount + result.OtherAmount
+	return result
+}
+
+// addItem adds a billing item, merging it with an existing item of the same name
+func (s *ProductBillingSummary) addItem(item BillingItem) {
+	s.TotalAmount += item.PretaxAmount
+	for i := range s.Items {
+		if s.Items[i].BillingItemName == item.BillingItemName {
+			s.Items[i].PretaxAmount += item.PretaxAmount
+			return
 		}
 	}
+	s.Items = append(s.Items, item)
+}
 
-	log.Infof("Found billing for %d instances, total: %.4f, running hours: %.2f, monthly estimate: %.2f",
-		len(result.Instances), result.TotalAmount, totalRunningHours, result.MonthlyEstimate)
+// addItem adds a billing item, merging it with an existing item of the same name
+// (daily queries return the same billing item once per day)
+func (s *InstanceBillingSummary) addItem(item BillingItem) {
+	s.TotalAmount += item.PretaxAmount
+	for i := range s.Items {
+		if s.Items[i].BillingItemName == item.BillingItemName {
+			s.Items[i].PretaxAmount += item.PretaxAmount
+			return
+		}
+	}
+	s.Items = append(s.Items, item)
+}
 
-	return result, nil
+// SavedPercent returns the savings as a share of the pay-as-you-go cost
+func (s *BillingSummary) SavedPercent() float64 {
+	if s.OnDemandAmount <= 0 {
+		return 0
+	}
+	return s.SavedAmount / s.OnDemandAmount * 100
 }
 
 // QueryBillingByHours is deprecated, use QueryBilling instead
 // Kept for backward compatibility
-func (c *BillingClient) QueryBillingByHours(instances []InstanceInfo, hours int) (*BillingSummary, error) {
-	return c.QueryBilling(instances)
+func (c *BillingClient) QueryBillingByHours(ctx context.Context, instances []InstanceInfo, hours int) (*BillingSummary, error) {
+	return c.QueryBilling(ctx, instances, "")
 }
 
 // parseServicePeriod parses ServicePeriod string and converts to seconds based on unit
@@ -252,7 +599,7 @@ func parseServicePeriod(servicePeriod, unit string) (float64, error) {
 	if err != nil {
 		return 0, err
 	}
-	
+
 	// Convert to seconds based on unit
 	switch unit {
 	case "天":
@@ -308,4 +655,4 @@ func formatBillingItemName(billingItem, instanceSpec string) string {
 		}
 		return "其他费用"
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,88 @@
+package aliyun
+
+import "sort"
+
+// UngroupedName labels instances without a group in sectioned reports
+const UngroupedName = "未分组"
+
+// BillingGroupSummary is the billing of the instances of one group
+type BillingGroupSummary struct {
+	Name            string
+	Instances       []InstanceBillingSummary
+	TotalAmount     float64
+	MonthlyEstimate float64
+}
+
+// GroupInstances sections the instances of a summary by group, sorted by
+// name with ungrouped instances last. Returns nil if no instance has a group.
+func (s *BillingSummary) GroupInstances() []BillingGroupSummary {
+	grouped := false
+	for _, inst := range s.Instances {
+		if inst.Group != "" {
+			grouped = true
+			break
+		}
+	}
+	if !grouped {
+		return nil
+	}
+
+	byName := make(map[string]*BillingGroupSummary)
+	for _, inst := range s.Instances {
+		name := inst.Group
+		if name == "" {
+			name = UngroupedName
+		}
+		group, ok := byName[name]
+		if !ok {
+			group = &BillingGroupSummary{Name: name}
+			byName[name] = group
+		}
+		group.Instances = append(group.Instances, inst)
+		group.TotalAmount += inst.TotalAmount
+		group.MonthlyEstimate += inst.MonthlyEstimate
+	}
+
+	groups := make([]BillingGroupSummary, 0, len(byName))
+	for _, group := range byName {
+		groups = append(groups, *group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if (groups[i].Name == UngroupedName) != (groups[j].Name == UngroupedName) {
+			return groups[j].Name == UngroupedName
+		}
+		return groups[i].Name < groups[j].Name
+	})
+	return groups
+}
+
+// FilterGroup returns the summary of the instances of a single group
+// Other products are billed per account, not per instance, and are left out.
+func (s *BillingSummary) FilterGroup(group string) *BillingSummary {
+	filtered := *s
+	filtered.Group = group
+	filtered.Instances = nil
+	filtered.Products = nil
+	filtered.InstanceAmount = 0
+	filtered.OtherAmount = 0
+	filtered.TotalRunningHours = 0
+	filtered.MonthlyEstimate = 0
+	filtered.OnDemandAmount = 0
+	filtered.SavedAmount = 0
+
+	for _, inst := range s.Instances {
+		if inst.Group != group {
+			continue
+		}
+		filtered.Instances = append(filtered.Instances, inst)
+		filtered.InstanceAmount += inst.TotalAmount
+		filtered.TotalRunningHours += inst.RunningHours
+		filtered.MonthlyEstimate += inst.MonthlyEstimate
+		if inst.OnDemandAmount > 0 {
+			filtered.OnDemandAmount += inst.OnDemandAmount
+			filtered.SavedAmount += inst.OnDemandAmount - inst.ComputeAmount
+		}
+	}
+	filtered.TotalAmount = filtered.InstanceAmount
+	return &filtered
+}
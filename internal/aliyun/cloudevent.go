@@ -0,0 +1,105 @@
+package aliyun
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EventStateChange is published when an instance changes status, e.g. to Stopped
+const EventStateChange = "Instance:StateChange"
+
+// SpotInterruptionLead is how long before the reclaim a spot interruption is announced
+const SpotInterruptionLead = 5 * time.Minute
+
+// CloudEvent is an ECS system event pushed by EventBridge or a CloudMonitor
+// event subscription
+type CloudEvent struct {
+	Name       string // e.g. Instance:StateChange
+	InstanceID string
+	RegionID   string
+	State      string // new status of Instance:StateChange
+	Time       time.Time
+}
+
+// rawCloudEvent covers both EventBridge (CloudEvents) and CloudMonitor webhook bodies
+type rawCloudEvent struct {
+	// EventBridge
+	Type     string          `json:"type"`
+	RegionID string          `json:"aliyunregionid"`
+	Data     json.RawMessage `json:"data"`
+
+	// CloudMonitor
+	Name          string          `json:"name"`
+	MonitorRegion string          `json:"regionId"`
+	Content       json.RawMessage `json:"content"`
+
+	Time json.RawMessage `json:"time"` // RFC3339 or Unix milliseconds
+}
+
+// cloudEventData is the event payload of both formats
+type cloudEventData struct {
+	ResourceID string `json:"resourceId"`
+	InstanceID string `json:"instanceId"`
+	State      string `json:"state"`
+}
+
+// ParseCloudEvent parses an ECS system event from an EventBridge HTTP target
+// or a CloudMonitor event subscription webhook
+func ParseCloudEvent(body []byte) (*CloudEvent, error) {
+	var raw rawCloudEvent
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("invalid event: %w", err)
+	}
+
+	event := &CloudEvent{
+		Name:     strings.TrimPrefix(raw.Type, "ecs:"),
+		RegionID: raw.RegionID,
+		Time:     parseEventTime(raw.Time),
+	}
+	payload := raw.Data
+	if raw.Name != "" {
+		event.Name = raw.Name
+		event.RegionID = raw.MonitorRegion
+		payload = raw.Content
+	}
+	if event.Name == "" {
+		return nil, fmt.Errorf("event has no type")
+	}
+
+	// CloudMonitor may send the content as a JSON string
+	var content string
+	if json.Unmarshal(payload, &content) == nil {
+		payload = json.RawMessage(content)
+	}
+	var data cloudEventData
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, &data); err != nil {
+			return nil, fmt.Errorf("invalid event data: %w", err)
+		}
+	}
+	event.InstanceID = data.InstanceID
+	if event.InstanceID == "" {
+		event.InstanceID = data.ResourceID
+	}
+	event.State = data.State
+	if event.InstanceID == "" {
+		return nil, fmt.Errorf("event %s has no instance ID", event.Name)
+	}
+	return event, nil
+}
+
+// parseEventTime parses an RFC3339 or Unix millisecond event time, now if missing
+func parseEventTime(value json.RawMessage) time.Time {
+	var s string
+	if json.Unmarshal(value, &s) == nil {
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			return t
+		}
+	} else if ms, err := strconv.ParseInt(string(value), 10, 64); err == nil {
+		return time.UnixMilli(ms)
+	}
+	return time.Now()
+}
@@ -0,0 +1,131 @@
+package aliyun
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/iliyian/aliyun-spot-manager/internal/tracing"
+)
+
+// commandPollInterval is how often a running Cloud Assistant command is polled
+const commandPollInterval = 3 * time.Second
+
+// finalInvocationStatuses are the statuses of a finished command on an instance
+var finalInvocationStatuses = map[string]bool{
+	"Success":    true,
+	"Failed":     true,
+	"Timeout":    true,
+	"Error":      true,
+	"Invalid":    true,
+	"Aborted":    true,
+	"Cancelled":  true,
+	"Terminated": true,
+	"Stopped":    true,
+}
+
+// CommandResult is the outcome of a Cloud Assistant command on an instance
+type CommandResult struct {
+	InvokeID  string
+	Status    string // Success, Failed, Timeout, Error, ...
+	ExitCode  int64
+	Output    string
+	ErrorInfo string // why the command couldn't run, e.g. the agent is offline
+	Duration  time.Duration
+}
+
+// Succeeded reports whether the command ran and exited with 0
+func (r *CommandResult) Succeeded() bool {
+	return r.Status == "Success" && r.ExitCode == 0
+}
+
+// RunCommand runs a script on an instance through Cloud Assistant and waits
+// for it to finish, a shell script on Linux and PowerShell on Windows.
+// The Cloud Assistant agent must be running on the instance.
+func (c *ECSClient) RunCommand(ctx context.Context, regionID, instanceID, osType, script string, timeout time.Duration) (result *CommandResult, err error) {
+	ctx, span := tracing.Start(ctx, "ecs.RunCommand", tracing.Instance(regionID, instanceID)...)
+	defer func() { tracing.End(span, err) }()
+
+	client, err := c.getClient(regionID)
+	if err != nil {
+		return nil, err
+	}
+
+	request := ecs.CreateRunCommandRequest()
+	request.Scheme = "https"
+	request.RegionId = regionID
+	request.InstanceId = &[]string{instanceID}
+	request.Type = "RunShellScript"
+	if osType == "windows" {
+		request.Type = "RunPowerShellScript"
+	}
+	request.CommandContent = script
+	request.ContentEncoding = "PlainText"
+	request.Timeout = requests.NewInteger(int(timeout.Seconds()))
+	request.Name = "spot-manager-bootstrap"
+
+	started := time.Now()
+	var response *ecs.RunCommandResponse
+	err = c.resilience.call(ctx, regionID, "RunCommand", func() (err error) {
+		response, err = client.RunCommand(request)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to run command on %s: %w", instanceID, err)
+	}
+
+	// The command times out on the instance; allow some slack for reporting
+	deadline := time.Now().Add(timeout + time.Minute)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(commandPollInterval):
+		}
+
+		result, err := c.invocationResult(ctx, client, regionID, instanceID, response.InvokeId)
+		if err != nil {
+			return nil, err
+		}
+		if result != nil && finalInvocationStatuses[result.Status] {
+			result.Duration = time.Since(started)
+			return result, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("command %s on %s did not finish in %s", response.InvokeId, instanceID, timeout)
+		}
+	}
+}
+
+// invocationResult returns the result of a command on an instance, nil before it is reported
+func (c *ECSClient) invocationResult(ctx context.Context, client *ecs.Client, regionID, instanceID, invokeID string) (*CommandResult, error) {
+	request := ecs.CreateDescribeInvocationResultsRequest()
+	request.Scheme = "https"
+	request.RegionId = regionID
+	request.InvokeId = invokeID
+	request.InstanceId = instanceID
+	request.ContentEncoding = "PlainText"
+
+	var response *ecs.DescribeInvocationResultsResponse
+	err := c.resilience.call(ctx, regionID, "DescribeInvocationResults", func() (err error) {
+		response, err = client.DescribeInvocationResults(request)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get result of command %s: %w", invokeID, err)
+	}
+
+	results := response.Invocation.InvocationResults.InvocationResult
+	if len(results) == 0 {
+		return nil, nil
+	}
+	return &CommandResult{
+		InvokeID:  invokeID,
+		Status:    results[0].InvocationStatus,
+		ExitCode:  results[0].ExitCode,
+		Output:    results[0].Output,
+		ErrorInfo: results[0].ErrorInfo,
+	}, nil
+}
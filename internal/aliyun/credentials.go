@@ -0,0 +1,49 @@
+package aliyun
+
+import (
+	"fmt"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/auth"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/auth/credentials"
+	"github.com/iliyian/aliyun-spot-manager/internal/config"
+)
+
+// Credentials selects how Aliyun API calls are authenticated
+// The SDK refreshes the STS tokens of role credentials before they expire.
+type Credentials struct {
+	AccessKeyID     string
+	AccessKeySecret string
+
+	// RoleArn is a RAM role assumed with the AccessKey through STS AssumeRole
+	RoleArn             string
+	RoleSessionName     string
+	RoleSessionDuration int // seconds
+
+	// EcsRamRole is the RAM role of the ECS instance the monitor runs on,
+	// whose credentials come from the instance metadata service
+	EcsRamRole string
+}
+
+// credential returns the SDK credential for the configured method
+func (c Credentials) credential() auth.Credential {
+	switch {
+	case c.EcsRamRole != "":
+		return credentials.NewEcsRamRoleCredential(c.EcsRamRole)
+	case c.RoleArn != "":
+		return credentials.NewRamRoleArnCredential(c.AccessKeyID, c.AccessKeySecret, c.RoleArn, c.RoleSessionName, c.RoleSessionDuration)
+	default:
+		return credentials.NewAccessKeyCredential(c.AccessKeyID, c.AccessKeySecret)
+	}
+}
+
+// String describes the credentials without revealing secrets
+func (c Credentials) String() string {
+	switch {
+	case c.EcsRamRole != "":
+		return fmt.Sprintf("ECS RAM role %s", c.EcsRamRole)
+	case c.RoleArn != "":
+		return fmt.Sprintf("RAM role %s assumed with AccessKey %s", c.RoleArn, config.MaskSecret(c.AccessKeyID))
+	default:
+		return fmt.Sprintf("AccessKey %s", config.MaskSecret(c.AccessKeyID))
+	}
+}
@@ -0,0 +1,78 @@
+package aliyun
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/iliyian/aliyun-spot-manager/internal/tracing"
+)
+
+// CPUCredits is the latest CPU credit data of a burstable instance, in
+// credits: one credit is one vCPU at 100% for a minute
+type CPUCredits struct {
+	Balance        float64 // credits left
+	Usage          float64 // credits used in the last minute
+	AdvanceBalance float64 // credits borrowed ahead in unlimited mode
+	SurplusUsage   float64 // credits beyond the balance, billed in unlimited mode
+	Time           time.Time
+}
+
+// Burstable reports whether the instance is a burstable (t5/t6) instance earning CPU credits
+func (i *SpotInstance) Burstable() bool {
+	return i.CreditMode != "" ||
+		strings.HasPrefix(i.InstanceType, "ecs.t5-") || strings.HasPrefix(i.InstanceType, "ecs.t6-")
+}
+
+// Unlimited reports whether a burstable instance may exceed its credits, paying for the surplus
+func (i *SpotInstance) Unlimited() bool {
+	return i.CreditMode == "Unlimited"
+}
+
+// GetCPUCredits returns the latest CPU credit data of a burstable instance
+// from its CloudMonitor data of the last 10 minutes, nil when none is reported
+func (c *ECSClient) GetCPUCredits(ctx context.Context, regionID, instanceID string) (credits *CPUCredits, err error) {
+	ctx, span := tracing.Start(ctx, "ecs.GetCPUCredits", tracing.Instance(regionID, instanceID)...)
+	defer func() { tracing.End(span, err) }()
+
+	client, err := c.getClient(regionID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	request := ecs.CreateDescribeInstanceMonitorDataRequest()
+	request.Scheme = "https"
+	request.RegionId = regionID
+	request.InstanceId = instanceID
+	request.StartTime = now.Add(-10 * time.Minute).Format("2006-01-02T15:04:05Z")
+	request.EndTime = now.Format("2006-01-02T15:04:05Z")
+	request.Period = requests.NewInteger(60)
+
+	var response *ecs.DescribeInstanceMonitorDataResponse
+	err = c.resilience.call(ctx, regionID, "DescribeInstanceMonitorData", func() (err error) {
+		response, err = client.DescribeInstanceMonitorData(request)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get monitor data of instance %s: %w", instanceID, err)
+	}
+
+	for _, data := range response.MonitorData.InstanceMonitorData {
+		t, err := time.Parse(time.RFC3339, data.TimeStamp)
+		if err != nil || (credits != nil && !t.After(credits.Time)) {
+			continue
+		}
+		credits = &CPUCredits{
+			Balance:        data.CPUCreditBalance,
+			Usage:          data.CPUCreditUsage,
+			AdvanceBalance: data.CPUAdvanceCreditBalance,
+			SurplusUsage:   data.CPUNotpaidSurplusCreditUsage,
+			Time:           t,
+		}
+	}
+	return credits, nil
+}
@@ -0,0 +1,68 @@
+package aliyun
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/iliyian/aliyun-spot-manager/internal/tracing"
+)
+
+// Disk is a cloud disk attached to an instance
+type Disk struct {
+	DiskID    string
+	Name      string
+	Type      string // system or data
+	Category  string // e.g. cloud_essd, cloud_efficiency
+	Size      int    // GiB
+	Encrypted bool
+	Device    string // e.g. /dev/xvda
+	Status    string
+}
+
+// DescribeDisks returns the disks attached to an instance, the system disk first
+func (c *ECSClient) DescribeDisks(ctx context.Context, regionID, instanceID string) (disks []*Disk, err error) {
+	ctx, span := tracing.Start(ctx, "ecs.DescribeDisks", tracing.Instance(regionID, instanceID)...)
+	defer func() { tracing.End(span, err) }()
+
+	client, err := c.getClient(regionID)
+	if err != nil {
+		return nil, err
+	}
+
+	request := ecs.CreateDescribeDisksRequest()
+	request.Scheme = "https"
+	request.RegionId = regionID
+	request.InstanceId = instanceID
+	request.PageSize = requests.NewInteger(100)
+
+	var response *ecs.DescribeDisksResponse
+	err = c.resilience.call(ctx, regionID, "DescribeDisks", func() (err error) {
+		response, err = client.DescribeDisks(request)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get disks of instance %s: %w", instanceID, err)
+	}
+
+	var data []*Disk
+	for _, d := range response.Disks.Disk {
+		disk := &Disk{
+			DiskID:    d.DiskId,
+			Name:      d.DiskName,
+			Type:      d.Type,
+			Category:  d.Category,
+			Size:      d.Size,
+			Encrypted: d.Encrypted,
+			Device:    d.Device,
+			Status:    d.Status,
+		}
+		if disk.Type == "system" {
+			disks = append(disks, disk)
+		} else {
+			data = append(data, disk)
+		}
+	}
+	return append(disks, data...), nil
+}
@@ -1,44 +1,238 @@
 package aliyun
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk"
 	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/cms"
 	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/vpc"
+	"github.com/iliyian/aliyun-spot-manager/internal/tracing"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// ErrInstanceNotFound is returned for instances that don't exist, e.g. after being released
+var ErrInstanceNotFound = errors.New("instance not found")
+
 // SpotInstance represents a spot instance
 type SpotInstance struct {
 	InstanceID       string
 	InstanceName     string
 	RegionID         string
+	ZoneID           string
+	InstanceType     string
 	Status           string
 	PublicIPAddress  string
 	PrivateIPAddress string
+	IPv6Address      string
 	SpotStrategy     string
+	SpotPriceLimit   float64 // hourly bid of a SpotWithPriceLimit instance
+	SpotDuration     int     // protection period in hours after creation, 0 for none
+	CreationTime     time.Time
+	OSType           string // linux or windows
+	SecurityGroupIDs []string
+	StoppedMode      string   // KeepCharging or StopCharging once stopped
+	OperationLocks   []string // lock reasons, e.g. Recycling while a spot instance is reclaimed
+	CreditMode       string   // Standard or Unlimited for burstable instances
+	Tags             map[string]string
+}
+
+// newSpotInstance converts a DescribeInstances result to a SpotInstance
+func newSpotInstance(inst ecs.Instance, regionID string) *SpotInstance {
+	var publicIP, privateIP string
+	if len(inst.PublicIpAddress.IpAddress) > 0 {
+		publicIP = inst.PublicIpAddress.IpAddress[0]
+	}
+	// Check EIP
+	if publicIP == "" && inst.EipAddress.IpAddress != "" {
+		publicIP = inst.EipAddress.IpAddress
+	}
+	if len(inst.InnerIpAddress.IpAddress) > 0 {
+		privateIP = inst.InnerIpAddress.IpAddress[0]
+	}
+	if privateIP == "" && len(inst.VpcAttributes.PrivateIpAddress.IpAddress) > 0 {
+		privateIP = inst.VpcAttributes.PrivateIpAddress.IpAddress[0]
+	}
+
+	var ipv6 string
+	for _, eni := range inst.NetworkInterfaces.NetworkInterface {
+		if len(eni.Ipv6Sets.Ipv6Set) > 0 {
+			ipv6 = eni.Ipv6Sets.Ipv6Set[0].Ipv6Address
+			break
+		}
+	}
+
+	tags := make(map[string]string, len(inst.Tags.Tag))
+	for _, tag := range inst.Tags.Tag {
+		tags[tag.TagKey] = tag.TagValue
+	}
+
+	var locks []string
+	for _, lock := range inst.OperationLocks.LockReason {
+		locks = append(locks, lock.LockReason)
+	}
+
+	// CreationTime is like 2024-01-01T08:00Z
+	created, _ := time.Parse("2006-01-02T15:04Z", inst.CreationTime)
+
+	return &SpotInstance{
+		InstanceID:       inst.InstanceId,
+		InstanceName:     inst.InstanceName,
+		RegionID:         regionID,
+		ZoneID:           inst.ZoneId,
+		InstanceType:     inst.InstanceType,
+		Status:           inst.Status,
+		PublicIPAddress:  publicIP,
+		PrivateIPAddress: privateIP,
+		IPv6Address:      ipv6,
+		SpotStrategy:     inst.SpotStrategy,
+		SpotPriceLimit:   inst.SpotPriceLimit,
+		SpotDuration:     inst.SpotDuration,
+		CreationTime:     created,
+		OSType:           inst.OSType,
+		SecurityGroupIDs: inst.SecurityGroupIds.SecurityGroupId,
+		StoppedMode:      inst.StoppedMode,
+		OperationLocks:   locks,
+		CreditMode:       inst.CreditSpecification,
+		Tags:             tags,
+	}
+}
+
+// HealthCheckAddress returns the address used for health checks,
+// preferring the public IPv4 address and falling back to IPv6
+func (i *SpotInstance) HealthCheckAddress() string {
+	if i.PublicIPAddress != "" {
+		return i.PublicIPAddress
+	}
+	return i.IPv6Address
+}
+
+// ProtectedUntil returns when the spot protection period after creation ends,
+// zero when the instance has none
+func (i *SpotInstance) ProtectedUntil() time.Time {
+	if i.SpotDuration <= 0 || i.CreationTime.IsZero() {
+		return time.Time{}
+	}
+	return i.CreationTime.Add(time.Duration(i.SpotDuration) * time.Hour)
+}
+
+// PublicAddresses returns the public IPv4 and IPv6 addresses for display
+func (i *SpotInstance) PublicAddresses() string {
+	switch {
+	case i.PublicIPAddress != "" && i.IPv6Address != "":
+		return i.PublicIPAddress + ", " + i.IPv6Address
+	case i.PublicIPAddress != "":
+		return i.PublicIPAddress
+	default:
+		return i.IPv6Address
+	}
 }
 
 // ECSClient wraps the Aliyun ECS client
+// It is safe for concurrent use: regional clients are created once under
+// clientsMu, share the connection pool of transport, and are dropped by
+// UpdateCredentials so they are re-created with the rotated credentials.
 type ECSClient struct {
-	accessKeyID     string
-	accessKeySecret string
-	clients         map[string]*ecs.Client // region -> client
-	clientsMu       sync.RWMutex
+	creds     Credentials
+	transport *Transport
+	clients   map[string]*ecs.Client // region -> client, guarded by clientsMu
+	clientsMu sync.RWMutex
+
+	// VPC clients for EIPs, region -> client, guarded by clientsMu
+	vpcClients map[string]*vpc.Client
+
+	// CloudMonitor clients for metrics, region -> client, guarded by clientsMu
+	cmsClients map[string]*cms.Client
+
+	// Retries throttled calls and skips failing regions, nil calls once
+	resilience *Resilience
+
+	// Short-lived GetInstance results, invalidated by start/stop/reboot
+	cacheTTL time.Duration
+	cache    map[string]cachedInstance // instance ID -> result
+	cacheMu  sync.Mutex
+}
+
+// cachedInstance is a cached GetInstance result
+type cachedInstance struct {
+	inst    SpotInstance
+	expires time.Time
 }
 
 // NewECSClient creates a new ECS client
-func NewECSClient(accessKeyID, accessKeySecret string) *ECSClient {
+// All regional clients share the given transport
+func NewECSClient(creds Credentials, transport *Transport) *ECSClient {
 	return &ECSClient{
-		accessKeyID:     accessKeyID,
-		accessKeySecret: accessKeySecret,
-		clients:         make(map[string]*ecs.Client),
+		creds:      creds,
+		transport:  transport,
+		clients:    make(map[string]*ecs.Client),
+		vpcClients: make(map[string]*vpc.Client),
+		cmsClients: make(map[string]*cms.Client),
+		cache:      make(map[string]cachedInstance),
 	}
 }
 
+// SetResilience sets the retry and circuit breaker layer of API calls
+func (c *ECSClient) SetResilience(r *Resilience) {
+	c.resilience = r
+}
+
+// SetCacheTTL sets how long GetInstance results are reused, 0 disables caching
+func (c *ECSClient) SetCacheTTL(ttl time.Duration) {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+	c.cacheTTL = ttl
+	c.cache = make(map[string]cachedInstance)
+}
+
+// cachedInstance returns a copy of a fresh cached instance
+func (c *ECSClient) cachedInstance(instanceID string) (*SpotInstance, bool) {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+
+	entry, ok := c.cache[instanceID]
+	if !ok || time.Now().After(entry.expires) {
+		delete(c.cache, instanceID)
+		return nil, false
+	}
+	inst := entry.inst
+	return &inst, true
+}
+
+// cacheInstance stores a copy of an instance
+func (c *ECSClient) cacheInstance(inst *SpotInstance) {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+
+	if c.cacheTTL <= 0 {
+		return
+	}
+	c.cache[inst.InstanceID] = cachedInstance{inst: *inst, expires: time.Now().Add(c.cacheTTL)}
+}
+
+// invalidateOnStatusChange drops a cached instance whose status has changed
+func (c *ECSClient) invalidateOnStatusChange(instanceID, status string) {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+	if entry, ok := c.cache[instanceID]; ok && entry.inst.Status != status {
+		delete(c.cache, instanceID)
+	}
+}
+
+// InvalidateInstance drops the cached details of an instance, e.g. after its state changed
+func (c *ECSClient) InvalidateInstance(instanceID string) {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+	delete(c.cache, instanceID)
+}
+
 // getClient gets or creates an ECS client for the specified region
 func (c *ECSClient) getClient(regionID string) (*ecs.Client, error) {
 	// Try read lock first
@@ -58,17 +252,30 @@ func (c *ECSClient) getClient(regionID string) (*ecs.Client, error) {
 		return client, nil
 	}
 
-	client, err := ecs.NewClientWithAccessKey(regionID, c.accessKeyID, c.accessKeySecret)
+	client, err := ecs.NewClientWithOptions(regionID, sdk.NewConfig(), c.creds.credential())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create ECS client for region %s: %w", regionID, err)
 	}
+	c.transport.apply(&client.Client)
 
 	c.clients[regionID] = client
 	return client, nil
 }
 
+// UpdateCredentials replaces the credentials and drops all cached regional clients
+// so that they are re-created with the new credentials on next use
+func (c *ECSClient) UpdateCredentials(creds Credentials) {
+	c.clientsMu.Lock()
+	defer c.clientsMu.Unlock()
+
+	c.creds = creds
+	c.clients = make(map[string]*ecs.Client)
+	c.vpcClients = make(map[string]*vpc.Client)
+	c.cmsClients = make(map[string]*cms.Client)
+}
+
 // GetAllRegions returns all available regions
-func (c *ECSClient) GetAllRegions() ([]string, error) {
+func (c *ECSClient) GetAllRegions(ctx context.Context) ([]string, error) {
 	// Use cn-hangzhou as default region to query all regions
 	client, err := c.getClient("cn-hangzhou")
 	if err != nil {
@@ -78,7 +285,11 @@ func (c *ECSClient) GetAllRegions() ([]string, error) {
 	request := ecs.CreateDescribeRegionsRequest()
 	request.Scheme = "https"
 
-	response, err := client.DescribeRegions(request)
+	var response *ecs.DescribeRegionsResponse
+	err = c.resilience.call(ctx, "", "DescribeRegions", func() (err error) {
+		response, err = client.DescribeRegions(request)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to describe regions: %w", err)
 	}
@@ -92,7 +303,7 @@ func (c *ECSClient) GetAllRegions() ([]string, error) {
 }
 
 // GetSpotInstances returns all spot instances in the specified region
-func (c *ECSClient) GetSpotInstances(regionID string) ([]*SpotInstance, error) {
+func (c *ECSClient) GetSpotInstances(ctx context.Context, regionID string) ([]*SpotInstance, error) {
 	client, err := c.getClient(regionID)
 	if err != nil {
 		return nil, err
@@ -103,6 +314,10 @@ func (c *ECSClient) GetSpotInstances(regionID string) ([]*SpotInstance, error) {
 	pageSize := 100
 
 	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		request := ecs.CreateDescribeInstancesRequest()
 		request.Scheme = "https"
 		request.RegionId = regionID
@@ -111,7 +326,11 @@ func (c *ECSClient) GetSpotInstances(regionID string) ([]*SpotInstance, error) {
 		// Filter for pay-as-you-go instances (spot instances are a type of pay-as-you-go)
 		request.InstanceChargeType = "PostPaid"
 
-		response, err := client.DescribeInstances(request)
+		var response *ecs.DescribeInstancesResponse
+		err := c.resilience.call(ctx, regionID, "DescribeInstances", func() (err error) {
+			response, err = client.DescribeInstances(request)
+			return err
+		})
 		if err != nil {
 			return nil, fmt.Errorf("failed to describe instances in region %s: %w", regionID, err)
 		}
@@ -119,30 +338,7 @@ func (c *ECSClient) GetSpotInstances(regionID string) ([]*SpotInstance, error) {
 		for _, inst := range response.Instances.Instance {
 			// Filter for spot instances only
 			if inst.SpotStrategy != "NoSpot" && inst.SpotStrategy != "" {
-				var publicIP, privateIP string
-				if len(inst.PublicIpAddress.IpAddress) > 0 {
-					publicIP = inst.PublicIpAddress.IpAddress[0]
-				}
-				// Check EIP
-				if publicIP == "" && inst.EipAddress.IpAddress != "" {
-					publicIP = inst.EipAddress.IpAddress
-				}
-				if len(inst.InnerIpAddress.IpAddress) > 0 {
-					privateIP = inst.InnerIpAddress.IpAddress[0]
-				}
-				if privateIP == "" && len(inst.VpcAttributes.PrivateIpAddress.IpAddress) > 0 {
-					privateIP = inst.VpcAttributes.PrivateIpAddress.IpAddress[0]
-				}
-
-				instances = append(instances, &SpotInstance{
-					InstanceID:       inst.InstanceId,
-					InstanceName:     inst.InstanceName,
-					RegionID:         regionID,
-					Status:           inst.Status,
-					PublicIPAddress:  publicIP,
-					PrivateIPAddress: privateIP,
-					SpotStrategy:     inst.SpotStrategy,
-				})
+				instances = append(instances, newSpotInstance(inst, regionID))
 			}
 		}
 
@@ -157,7 +353,10 @@ func (c *ECSClient) GetSpotInstances(regionID string) ([]*SpotInstance, error) {
 }
 
 // GetInstanceStatus returns the current status of an instance
-func (c *ECSClient) GetInstanceStatus(regionID, instanceID string) (string, error) {
+func (c *ECSClient) GetInstanceStatus(ctx context.Context, regionID, instanceID string) (status string, err error) {
+	_, span := tracing.Start(ctx, "ecs.DescribeInstanceStatus", tracing.Instance(regionID, instanceID)...)
+	defer func() { tracing.End(span, err) }()
+
 	client, err := c.getClient(regionID)
 	if err != nil {
 		return "", err
@@ -168,20 +367,37 @@ func (c *ECSClient) GetInstanceStatus(regionID, instanceID string) (string, erro
 	request.RegionId = regionID
 	request.InstanceId = &[]string{instanceID}
 
-	response, err := client.DescribeInstanceStatus(request)
+	var response *ecs.DescribeInstanceStatusResponse
+	err = c.resilience.call(ctx, regionID, "DescribeInstanceStatus", func() (err error) {
+		response, err = client.DescribeInstanceStatus(request)
+		return err
+	})
 	if err != nil {
 		return "", fmt.Errorf("failed to get instance status: %w", err)
 	}
 
 	if len(response.InstanceStatuses.InstanceStatus) == 0 {
-		return "", fmt.Errorf("instance %s not found", instanceID)
+		return "", fmt.Errorf("instance %s: %w", instanceID, ErrInstanceNotFound)
 	}
 
-	return response.InstanceStatuses.InstanceStatus[0].Status, nil
+	status = response.InstanceStatuses.InstanceStatus[0].Status
+	span.SetAttributes(attribute.String("aliyun.instance_status", status))
+	c.invalidateOnStatusChange(instanceID, status)
+	return status, nil
 }
 
 // GetInstance returns detailed information about an instance
-func (c *ECSClient) GetInstance(regionID, instanceID string) (*SpotInstance, error) {
+// Results are cached briefly so repeated lookups within a cycle share one API call
+func (c *ECSClient) GetInstance(ctx context.Context, regionID, instanceID string) (inst *SpotInstance, err error) {
+	_, span := tracing.Start(ctx, "ecs.DescribeInstances", tracing.Instance(regionID, instanceID)...)
+	defer func() { tracing.End(span, err) }()
+
+	if inst, ok := c.cachedInstance(instanceID); ok {
+		log.Debugf("Using cached details of instance %s", instanceID)
+		span.SetAttributes(attribute.Bool("cache.hit", true))
+		return inst, nil
+	}
+
 	client, err := c.getClient(regionID)
 	if err != nil {
 		return nil, err
@@ -192,43 +408,34 @@ func (c *ECSClient) GetInstance(regionID, instanceID string) (*SpotInstance, err
 	request.RegionId = regionID
 	request.InstanceIds = fmt.Sprintf(`["%s"]`, instanceID)
 
-	response, err := client.DescribeInstances(request)
+	var response *ecs.DescribeInstancesResponse
+	err = c.resilience.call(ctx, regionID, "DescribeInstances", func() (err error) {
+		response, err = client.DescribeInstances(request)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get instance: %w", err)
 	}
 
 	if len(response.Instances.Instance) == 0 {
-		return nil, fmt.Errorf("instance %s not found", instanceID)
-	}
-
-	inst := response.Instances.Instance[0]
-	var publicIP, privateIP string
-	if len(inst.PublicIpAddress.IpAddress) > 0 {
-		publicIP = inst.PublicIpAddress.IpAddress[0]
-	}
-	if publicIP == "" && inst.EipAddress.IpAddress != "" {
-		publicIP = inst.EipAddress.IpAddress
-	}
-	if len(inst.InnerIpAddress.IpAddress) > 0 {
-		privateIP = inst.InnerIpAddress.IpAddress[0]
-	}
-	if privateIP == "" && len(inst.VpcAttributes.PrivateIpAddress.IpAddress) > 0 {
-		privateIP = inst.VpcAttributes.PrivateIpAddress.IpAddress[0]
+		return nil, fmt.Errorf("instance %s: %w", instanceID, ErrInstanceNotFound)
 	}
 
-	return &SpotInstance{
-		InstanceID:       inst.InstanceId,
-		InstanceName:     inst.InstanceName,
-		RegionID:         regionID,
-		Status:           inst.Status,
-		PublicIPAddress:  publicIP,
-		PrivateIPAddress: privateIP,
-		SpotStrategy:     inst.SpotStrategy,
-	}, nil
+	inst = newSpotInstance(response.Instances.Instance[0], regionID)
+	c.cacheInstance(inst)
+	return inst, nil
 }
 
 // StartInstance starts an instance
-func (c *ECSClient) StartInstance(regionID, instanceID string) error {
+func (c *ECSClient) StartInstance(ctx context.Context, regionID, instanceID string) (err error) {
+	_, span := tracing.Start(ctx, "ecs.StartInstance", tracing.Instance(regionID, instanceID)...)
+	defer func() { tracing.End(span, err) }()
+
+	// The SDK can't cancel a request, so don't send one once the caller gave up
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	client, err := c.getClient(regionID)
 	if err != nil {
 		return err
@@ -238,7 +445,11 @@ func (c *ECSClient) StartInstance(regionID, instanceID string) error {
 	request.Scheme = "https"
 	request.InstanceId = instanceID
 
-	_, err = client.StartInstance(request)
+	c.InvalidateInstance(instanceID)
+	err = c.resilience.call(ctx, regionID, "StartInstance", func() error {
+		_, err := client.StartInstance(request)
+		return err
+	})
 	if err != nil {
 		// Check if instance is already running or starting
 		if strings.Contains(err.Error(), "IncorrectInstanceStatus") {
@@ -251,21 +462,174 @@ func (c *ECSClient) StartInstance(regionID, instanceID string) error {
 	return nil
 }
 
-// DiscoverAllSpotInstances discovers all spot instances across all regions
-func (c *ECSClient) DiscoverAllSpotInstances() ([]*SpotInstance, error) {
-	log.Info("Fetching all regions...")
-	regions, err := c.GetAllRegions()
+// MaxStartBatch is the most instances a StartInstances call takes
+const MaxStartBatch = 100
+
+// StartInstances starts up to MaxStartBatch instances of a region in a single
+// call. Instances are started independently of each other: the result maps
+// each instance to the *InstanceError it failed with, nil when it started or
+// was already starting or running. err is set when the call itself failed.
+func (c *ECSClient) StartInstances(ctx context.Context, regionID string, instanceIDs []string) (results map[string]error, err error) {
+	_, span := tracing.Start(ctx, "ecs.StartInstances", attribute.String("aliyun.region", regionID), attribute.Int("instances", len(instanceIDs)))
+	defer func() { tracing.End(span, err) }()
+
+	if len(instanceIDs) > MaxStartBatch {
+		return nil, fmt.Errorf("cannot start more than %d instances at once", MaxStartBatch)
+	}
+	// The SDK can't cancel a request, so don't send one once the caller gave up
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	client, err := c.getClient(regionID)
 	if err != nil {
 		return nil, err
 	}
-	log.Infof("Found %d regions, scanning for spot instances...", len(regions))
+
+	request := ecs.CreateStartInstancesRequest()
+	request.Scheme = "https"
+	request.RegionId = regionID
+	request.InstanceId = &instanceIDs
+	request.BatchOptimization = "SuccessFirst"
+
+	for _, id := range instanceIDs {
+		c.InvalidateInstance(id)
+	}
+	var response *ecs.StartInstancesResponse
+	err = c.resilience.call(ctx, regionID, "StartInstances", func() (err error) {
+		response, err = client.StartInstances(request)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start instances in %s: %w", regionID, err)
+	}
+
+	results = make(map[string]error, len(instanceIDs))
+	for _, r := range response.InstanceResponses.InstanceResponse {
+		// Like StartInstance, an instance not in stopped state needs no start
+		if r.Code == "" || r.Code == "200" || strings.Contains(r.Code, "IncorrectInstanceStatus") {
+			log.Debugf("Instance %s: %s -> %s", r.InstanceId, r.PreviousStatus, r.CurrentStatus)
+			results[r.InstanceId] = nil
+			continue
+		}
+		results[r.InstanceId] = &InstanceError{InstanceID: r.InstanceId, Code: r.Code, Message: r.Message}
+	}
+	for _, id := range instanceIDs {
+		if _, ok := results[id]; !ok {
+			results[id] = &InstanceError{InstanceID: id, Code: "MissingResult", Message: "the instance is missing from the response"}
+		}
+	}
+	return results, nil
+}
+
+// StopInstance stops an instance
+// force stops it like a power cut, which can unwedge an instance stuck in a transitional state
+func (c *ECSClient) StopInstance(ctx context.Context, regionID, instanceID string, force bool) (err error) {
+	_, span := tracing.Start(ctx, "ecs.StopInstance", tracing.Instance(regionID, instanceID)...)
+	defer func() { tracing.End(span, err) }()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	client, err := c.getClient(regionID)
+	if err != nil {
+		return err
+	}
+
+	request := ecs.CreateStopInstanceRequest()
+	request.Scheme = "https"
+	request.InstanceId = instanceID
+	request.ForceStop = requests.NewBoolean(force)
+
+	c.InvalidateInstance(instanceID)
+	err = c.resilience.call(ctx, regionID, "StopInstance", func() error {
+		_, err := client.StopInstance(request)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to stop instance %s: %w", instanceID, err)
+	}
+
+	return nil
+}
+
+// RebootInstance reboots a running instance
+func (c *ECSClient) RebootInstance(ctx context.Context, regionID, instanceID string) (err error) {
+	_, span := tracing.Start(ctx, "ecs.RebootInstance", tracing.Instance(regionID, instanceID)...)
+	defer func() { tracing.End(span, err) }()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	client, err := c.getClient(regionID)
+	if err != nil {
+		return err
+	}
+
+	request := ecs.CreateRebootInstanceRequest()
+	request.Scheme = "https"
+	request.InstanceId = instanceID
+
+	c.InvalidateInstance(instanceID)
+	err = c.resilience.call(ctx, regionID, "RebootInstance", func() error {
+		_, err := client.RebootInstance(request)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reboot instance %s: %w", instanceID, err)
+	}
+
+	return nil
+}
+
+// DiscoverOptions controls a discovery scan across regions
+type DiscoverOptions struct {
+	Regions     []string                         // regions to scan, empty for all available regions
+	Concurrency int                              // regions scanned in parallel, defaults to 10
+	SkipRegion  func(regionID string) bool       // regions to leave out, e.g. blacklisted ones
+	OnRegion    func(regionID string, err error) // called with the result of each scanned region
+	Filter      *InstanceFilter                  // instances to manage, nil for all
+}
+
+// DiscoverAllSpotInstances discovers all spot instances across all regions
+// Regions not yet scanned when ctx is cancelled are skipped and the context error returned
+func (c *ECSClient) DiscoverAllSpotInstances(ctx context.Context, opts DiscoverOptions) ([]*SpotInstance, error) {
+	allRegions := opts.Regions
+	if len(allRegions) == 0 {
+		log.Info("Fetching all regions...")
+		var err error
+		allRegions, err = c.GetAllRegions(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	regions := allRegions
+	if opts.SkipRegion != nil {
+		regions = make([]string, 0, len(allRegions))
+		for _, region := range allRegions {
+			if opts.SkipRegion(region) {
+				log.Debugf("Region %s is skipped", region)
+				continue
+			}
+			regions = append(regions, region)
+		}
+	}
+	log.Infof("Found %d regions (%d skipped), scanning for spot instances...", len(allRegions), len(allRegions)-len(regions))
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 10
+	}
 
 	// Use concurrent scanning for faster discovery
 	var (
 		allInstances []*SpotInstance
 		mu           sync.Mutex
 		wg           sync.WaitGroup
-		semaphore    = make(chan struct{}, 10) // Limit concurrent requests
+		semaphore    = make(chan struct{}, concurrency) // Limit concurrent requests
 	)
 
 	startTime := time.Now()
@@ -278,8 +642,21 @@ func (c *ECSClient) DiscoverAllSpotInstances() ([]*SpotInstance, error) {
 			defer wg.Done()
 			semaphore <- struct{}{}        // Acquire
 			defer func() { <-semaphore }() // Release
+			if ctx.Err() != nil {
+				return
+			}
 
-			instances, err := c.GetSpotInstances(regionID)
+			instances, err := c.GetSpotInstances(ctx, regionID)
+			if ctx.Err() != nil {
+				return // cancelled, not a failure of the region
+			}
+			if errors.Is(err, ErrCircuitOpen) {
+				log.Debugf("Region %s skipped: %v", regionID, err)
+				return
+			}
+			if opts.OnRegion != nil {
+				opts.OnRegion(regionID, err)
+			}
 
 			scannedMu.Lock()
 			scannedCount++
@@ -291,6 +668,18 @@ func (c *ECSClient) DiscoverAllSpotInstances() ([]*SpotInstance, error) {
 				return
 			}
 
+			if !opts.Filter.IsEmpty() {
+				matched := instances[:0]
+				for _, inst := range instances {
+					if opts.Filter.Match(inst) {
+						matched = append(matched, inst)
+					} else {
+						log.Debugf("Region %s: instance %s (%s) excluded by filter", regionID, inst.InstanceName, inst.InstanceID)
+					}
+				}
+				instances = matched
+			}
+
 			if len(instances) > 0 {
 				mu.Lock()
 				allInstances = append(allInstances, instances...)
@@ -303,7 +692,38 @@ func (c *ECSClient) DiscoverAllSpotInstances() ([]*SpotInstance, error) {
 	}
 
 	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("discovery cancelled: %w", err)
+	}
 	log.Infof("Scan completed in %.1f seconds", time.Since(startTime).Seconds())
 
 	return allInstances, nil
-}
\ No newline at end of file
+}
+
+// SetInternetBandwidth changes the maximum outbound public bandwidth of an instance (Mbps)
+func (c *ECSClient) SetInternetBandwidth(ctx context.Context, regionID, instanceID string, mbps int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	client, err := c.getClient(regionID)
+	if err != nil {
+		return err
+	}
+
+	request := ecs.CreateModifyInstanceNetworkSpecRequest()
+	request.Scheme = "https"
+	request.InstanceId = instanceID
+	request.InternetMaxBandwidthOut = requests.NewInteger(mbps)
+
+	c.InvalidateInstance(instanceID)
+	err = c.resilience.call(ctx, regionID, "ModifyInstanceNetworkSpec", func() error {
+		_, err := client.ModifyInstanceNetworkSpec(request)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to modify bandwidth of instance %s: %w", instanceID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package aliyun
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/vpc"
+	"github.com/iliyian/aliyun-spot-manager/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// EIP is an elastic IP address
+type EIP struct {
+	AllocationID string
+	IPAddress    string
+	Status       string // Available, InUse, Associating or Unassociating
+	InstanceID   string // associated ECS instance, empty if none
+}
+
+// getVPCClient returns the VPC client of a region, creating it on first use
+func (c *ECSClient) getVPCClient(regionID string) (*vpc.Client, error) {
+	c.clientsMu.Lock()
+	defer c.clientsMu.Unlock()
+
+	if client, ok := c.vpcClients[regionID]; ok {
+		return client, nil
+	}
+	client, err := vpc.NewClientWithOptions(regionID, sdk.NewConfig(), c.creds.credential())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create VPC client for region %s: %w", regionID, err)
+	}
+	c.transport.apply(&client.Client)
+
+	c.vpcClients[regionID] = client
+	return client, nil
+}
+
+// DescribeEIP looks up an EIP by its allocation ID (eip-...) or its IP address
+func (c *ECSClient) DescribeEIP(ctx context.Context, regionID, eip string) (result *EIP, err error) {
+	ctx, span := tracing.Start(ctx, "vpc.DescribeEIP", attribute.String("aliyun.region", regionID), attribute.String("aliyun.eip", eip))
+	defer func() { tracing.End(span, err) }()
+
+	client, err := c.getVPCClient(regionID)
+	if err != nil {
+		return nil, err
+	}
+
+	request := vpc.CreateDescribeEipAddressesRequest()
+	request.Scheme = "https"
+	request.RegionId = regionID
+	if strings.HasPrefix(eip, "eip-") {
+		request.AllocationId = eip
+	} else {
+		request.EipAddress = eip
+	}
+
+	var response *vpc.DescribeEipAddressesResponse
+	err = c.resilience.call(ctx, regionID, "DescribeEipAddresses", func() (err error) {
+		response, err = client.DescribeEipAddresses(request)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get EIP %s: %w", eip, err)
+	}
+	if len(response.EipAddresses.EipAddress) == 0 {
+		return nil, fmt.Errorf("EIP %s not found in %s", eip, regionID)
+	}
+
+	address := response.EipAddresses.EipAddress[0]
+	result = &EIP{
+		AllocationID: address.AllocationId,
+		IPAddress:    address.IpAddress,
+		Status:       address.Status,
+	}
+	if address.InstanceType == "EcsInstance" {
+		result.InstanceID = address.InstanceId
+	}
+	return result, nil
+}
+
+// AssociateEIP associates an EIP with an instance, first unassociating it
+// from the instance holding it, if any. The instance must be running or stopped.
+func (c *ECSClient) AssociateEIP(ctx context.Context, regionID, allocationID, instanceID string) (err error) {
+	ctx, span := tracing.Start(ctx, "vpc.AssociateEIP", tracing.Instance(regionID, instanceID)...)
+	defer func() { tracing.End(span, err) }()
+
+	client, err := c.getVPCClient(regionID)
+	if err != nil {
+		return err
+	}
+	eip, err := c.DescribeEIP(ctx, regionID, allocationID)
+	if err != nil {
+		return err
+	}
+
+	if eip.InstanceID != "" {
+		unassociate := vpc.CreateUnassociateEipAddressRequest()
+		unassociate.Scheme = "https"
+		unassociate.RegionId = regionID
+		unassociate.AllocationId = allocationID
+		unassociate.InstanceType = "EcsInstance"
+		unassociate.InstanceId = eip.InstanceID
+		err = c.resilience.call(ctx, regionID, "UnassociateEipAddress", func() error {
+			_, err := client.UnassociateEipAddress(unassociate)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to unassociate EIP %s from %s: %w", eip.IPAddress, eip.InstanceID, err)
+		}
+		c.InvalidateInstance(eip.InstanceID)
+	}
+
+	// The EIP can only be associated once it is available
+	for i := 0; i < 30 && eip.Status != "Available"; i++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+		if eip, err = c.DescribeEIP(ctx, regionID, allocationID); err != nil {
+			return err
+		}
+	}
+
+	associate := vpc.CreateAssociateEipAddressRequest()
+	associate.Scheme = "https"
+	associate.RegionId = regionID
+	associate.AllocationId = allocationID
+	associate.InstanceType = "EcsInstance"
+	associate.InstanceId = instanceID
+	err = c.resilience.call(ctx, regionID, "AssociateEipAddress", func() error {
+		_, err := client.AssociateEipAddress(associate)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to associate EIP %s with %s: %w", eip.IPAddress, instanceID, err)
+	}
+	c.InvalidateInstance(instanceID)
+	return nil
+}
@@ -0,0 +1,87 @@
+package aliyun
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	sdkerrors "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
+)
+
+// authErrorCodes are error codes returned when the AccessKey is invalid,
+// disabled or has been rotated
+var authErrorCodes = []string{
+	"InvalidAccessKeyId",
+	"InvalidAccessKeySecret",
+	"SignatureDoesNotMatch",
+	"IncompleteSignature",
+	"Forbidden.AccessKeyDisabled",
+	"InvalidSecurityToken",
+}
+
+// capacityErrorCodes are error codes returned when the zone has no spot
+// capacity left for the instance type
+var capacityErrorCodes = []string{
+	"OperationDenied.NoStock",
+	"Zone.NotOnSale",
+	"InvalidResourceType.NotSupported",
+}
+
+// InstanceError is the failure of a single instance in a batch call
+type InstanceError struct {
+	InstanceID string
+	Code       string
+	Message    string
+}
+
+func (e *InstanceError) Error() string {
+	return fmt.Sprintf("failed to start instance %s: %s: %s", e.InstanceID, e.Code, e.Message)
+}
+
+// ErrorCode returns the Aliyun error code of an API error, or "" if unknown
+func ErrorCode(err error) string {
+	var instanceErr *InstanceError
+	if errors.As(err, &instanceErr) {
+		return instanceErr.Code
+	}
+	var serverErr *sdkerrors.ServerError
+	if errors.As(err, &serverErr) {
+		return serverErr.ErrorCode()
+	}
+	return ""
+}
+
+// IsAuthError checks if an error is caused by invalid or rotated credentials
+func IsAuthError(err error) bool {
+	if err == nil {
+		return false
+	}
+	code := ErrorCode(err)
+	if code == "" {
+		// Fall back to the error text for wrapped or client-side errors
+		code = err.Error()
+	}
+	for _, authCode := range authErrorCodes {
+		if strings.Contains(code, authCode) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsCapacityError checks if an error is caused by the zone being sold out
+func IsCapacityError(err error) bool {
+	if err == nil {
+		return false
+	}
+	code := ErrorCode(err)
+	if code == "" {
+		code = err.Error()
+	}
+	for _, capacityCode := range capacityErrorCodes {
+		if strings.Contains(code, capacityCode) {
+			return true
+		}
+	}
+	return false
+}
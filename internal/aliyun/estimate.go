@@ -0,0 +1,131 @@
+package aliyun
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Methods of calculating BillingSummary.MonthlyEstimate
+const (
+	EstimateHourly   = "hourly"   // per-instance hourly cost × 720 hours
+	EstimateDays     = "days"     // spend per elapsed day × 30 days
+	EstimateTrailing = "trailing" // month to date + the trailing 7-day daily average for the remaining days
+)
+
+// trailingEstimateDays is the window of the trailing estimate
+const trailingEstimateDays = 7
+
+// SetEstimateMethod sets how the monthly estimate is calculated
+func (c *BillingClient) SetEstimateMethod(method string) {
+	c.mu.Lock()
+	c.estimateMethod = method
+	c.mu.Unlock()
+}
+
+// getEstimateMethod returns how the monthly estimate is calculated
+func (c *BillingClient) getEstimateMethod() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.estimateMethod
+}
+
+// queryTrailing summarizes the full days before now for the trailing estimate
+func (c *BillingClient) queryTrailing(ctx context.Context, instances []InstanceInfo, now time.Time) (*BillingSummary, error) {
+	yesterday := now.AddDate(0, 0, -1)
+	_, batches, err := c.queryDays(ctx, yesterday.AddDate(0, 0, -(trailingEstimateDays-1)), yesterday)
+	if err != nil {
+		return nil, err
+	}
+	return summarizeBilling(instances, batches), nil
+}
+
+// applyMonthlyEstimate calculates the monthly estimate of a summary and each of its instances
+// Instances with an expected-hours hint and a known hourly cost are estimated as
+// hourly cost × hours per day × 30 regardless of method. trailing holds the
+// trailing window for EstimateTrailing, remainingDays the days left in the month.
+func applyMonthlyEstimate(result *BillingSummary, instances []InstanceInfo, method string, trailing *BillingSummary, remainingDays int) {
+	hints := make(map[string]float64)
+	for _, inst := range instances {
+		if inst.ExpectedHoursPerDay > 0 {
+			hints[inst.InstanceID] = inst.ExpectedHoursPerDay
+		}
+	}
+	if method == EstimateTrailing && trailing == nil {
+		method = EstimateDays
+	}
+
+	trailingAmounts := make(map[string]float64)
+	if trailing != nil {
+		for _, inst := range trailing.Instances {
+			trailingAmounts[inst.InstanceID] = inst.TotalAmount
+		}
+	}
+
+	// byDays extrapolates an amount spent over the elapsed days to 30 days
+	byDays := func(amount float64) float64 {
+		if result.ElapsedDays <= 0 {
+			return 0
+		}
+		return amount / float64(result.ElapsedDays) * 30
+	}
+	// byTrailing adds the trailing daily average for the rest of the month
+	byTrailing := func(amount, trailingAmount float64) float64 {
+		return amount + trailingAmount/trailingEstimateDays*float64(remainingDays)
+	}
+
+	var totalHourlyCost float64
+	hinted := 0
+	restByDays := false // hourly method fell back to days for some amounts
+	result.MonthlyEstimate = 0
+	for i := range result.Instances {
+		inst := &result.Instances[i]
+		hours, hasHint := hints[inst.InstanceID]
+		switch {
+		case hasHint && inst.HourlyCost > 0:
+			inst.MonthlyEstimate = inst.HourlyCost * hours * 30
+			hinted++
+		case method == EstimateHourly && inst.HourlyCost > 0:
+			inst.MonthlyEstimate = inst.HourlyCost * 30 * 24
+			totalHourlyCost += inst.HourlyCost
+		case method == EstimateTrailing:
+			inst.MonthlyEstimate = byTrailing(inst.TotalAmount, trailingAmounts[inst.InstanceID])
+		default:
+			inst.MonthlyEstimate = byDays(inst.TotalAmount)
+			restByDays = true
+		}
+		result.MonthlyEstimate += inst.MonthlyEstimate
+	}
+
+	// Other products have no running time, estimate them by days
+	if result.OtherAmount > 0 {
+		if method == EstimateTrailing {
+			result.MonthlyEstimate += byTrailing(result.OtherAmount, trailing.OtherAmount)
+		} else {
+			result.MonthlyEstimate += byDays(result.OtherAmount)
+			restByDays = true
+		}
+	}
+
+	var parts []string
+	switch method {
+	case EstimateHourly:
+		if totalHourlyCost > 0 {
+			parts = append(parts, fmt.Sprintf("按每小时费用总和: ¥%.4f/小时 × 720小时", totalHourlyCost))
+		}
+		if restByDays && result.ElapsedDays > 0 {
+			parts = append(parts, "其余按已过天数 × 30天")
+		}
+	case EstimateTrailing:
+		parts = append(parts, fmt.Sprintf("本月累计 + 最近%d天日均 × 剩余%d天", trailingEstimateDays, remainingDays))
+	default:
+		if result.ElapsedDays > 0 {
+			parts = append(parts, fmt.Sprintf("按已过天数: ¥%.4f/天 × 30天", result.TotalAmount/float64(result.ElapsedDays)))
+		}
+	}
+	if hinted > 0 {
+		parts = append(parts, fmt.Sprintf("%d 台实例按预计每日运行小时数", hinted))
+	}
+	result.EstimateMethod = strings.Join(parts, "，")
+}
@@ -0,0 +1,172 @@
+package aliyun
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+)
+
+// SystemEvent represents a scheduled system event (maintenance, redeploy, etc.) on an instance
+type SystemEvent struct {
+	EventID     string
+	InstanceID  string
+	RegionID    string
+	EventType   string // e.g. SystemMaintenance.Reboot
+	Status      string // Scheduled, Inquiring, Executing, ...
+	Reason      string
+	ImpactLevel string
+	NotBefore   time.Time // planned execution time
+	PublishTime time.Time
+}
+
+// EventSpotInterruption announces the reclaim of a spot instance about 5 minutes ahead
+const EventSpotInterruption = "Instance:PreemptibleInstanceInterruption"
+
+// interruptionEventTypes are the events that take an instance down
+var interruptionEventTypes = map[string]bool{
+	EventSpotInterruption:                         true,
+	"SystemMaintenance.Reboot":                    true,
+	"SystemMaintenance.Redeploy":                  true,
+	"SystemMaintenance.RebootAndIsolateErrorDisk": true,
+	"SystemMaintenance.RebootAndReInitErrorDisk":  true,
+	"SystemMaintenance.Stop":                      true,
+	"SystemFailure.Reboot":                        true,
+	"SystemFailure.Redeploy":                      true,
+}
+
+// IsInterruption reports whether the event stops, reboots or reclaims the instance
+func (e *SystemEvent) IsInterruption() bool {
+	return interruptionEventTypes[e.EventType]
+}
+
+// GetScheduledEvents returns scheduled (not yet executed) system events for the given instances
+func (c *ECSClient) GetScheduledEvents(ctx context.Context, regionID string, instanceIDs []string) ([]*SystemEvent, error) {
+	client, err := c.getClient(regionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*SystemEvent
+	// ResourceId accepts up to 100 instance IDs per request
+	for start := 0; start < len(instanceIDs); start += 100 {
+		end := start + 100
+		if end > len(instanceIDs) {
+			end = len(instanceIDs)
+		}
+		ids := instanceIDs[start:end]
+
+		pageNumber := 1
+		pageSize := 100
+		for {
+			request := ecs.CreateDescribeInstanceHistoryEventsRequest()
+			request.Scheme = "https"
+			request.RegionId = regionID
+			request.ResourceType = "instance"
+			request.ResourceId = &ids
+			request.InstanceEventCycleStatus = &[]string{"Scheduled", "Inquiring"}
+			request.PageNumber = requests.NewInteger(pageNumber)
+			request.PageSize = requests.NewInteger(pageSize)
+
+			var response *ecs.DescribeInstanceHistoryEventsResponse
+			err := c.resilience.call(ctx, regionID, "DescribeInstanceHistoryEvents", func() (err error) {
+				response, err = client.DescribeInstanceHistoryEvents(request)
+				return err
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to describe instance events in region %s: %w", regionID, err)
+			}
+
+			for _, e := range response.InstanceSystemEventSet.InstanceSystemEventType {
+				events = append(events, newSystemEvent(e, regionID))
+			}
+
+			if pageNumber*pageSize >= response.TotalCount {
+				break
+			}
+			pageNumber++
+		}
+	}
+
+	return events, nil
+}
+
+// GetInterruptionHistory returns the spot interruption events of an instance
+// due since a time, whether or not they were carried out yet
+func (c *ECSClient) GetInterruptionHistory(ctx context.Context, regionID, instanceID string, since time.Time) ([]*SystemEvent, error) {
+	client, err := c.getClient(regionID)
+	if err != nil {
+		return nil, err
+	}
+
+	request := ecs.CreateDescribeInstanceHistoryEventsRequest()
+	request.Scheme = "https"
+	request.RegionId = regionID
+	request.ResourceType = "instance"
+	request.ResourceId = &[]string{instanceID}
+	request.EventType = EventSpotInterruption
+	request.NotBeforeStart = since.UTC().Format("2006-01-02T15:04:05Z")
+	request.PageSize = requests.NewInteger(100)
+
+	var response *ecs.DescribeInstanceHistoryEventsResponse
+	err = c.resilience.call(ctx, regionID, "DescribeInstanceHistoryEvents", func() (err error) {
+		response, err = client.DescribeInstanceHistoryEvents(request)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe interruption events of instance %s: %w", instanceID, err)
+	}
+
+	var events []*SystemEvent
+	for _, e := range response.InstanceSystemEventSet.InstanceSystemEventType {
+		events = append(events, newSystemEvent(e, regionID))
+	}
+	return events, nil
+}
+
+// newSystemEvent converts a DescribeInstanceHistoryEvents result to a SystemEvent
+func newSystemEvent(e ecs.InstanceSystemEventType, regionID string) *SystemEvent {
+	event := &SystemEvent{
+		EventID:     e.EventId,
+		InstanceID:  e.InstanceId,
+		RegionID:    regionID,
+		EventType:   e.EventType.Name,
+		Status:      e.EventCycleStatus.Name,
+		Reason:      e.Reason,
+		ImpactLevel: e.ImpactLevel,
+	}
+	if t, err := time.Parse(time.RFC3339, e.NotBefore); err == nil {
+		event.NotBefore = t
+	}
+	if t, err := time.Parse(time.RFC3339, e.EventPublishTime); err == nil {
+		event.PublishTime = t
+	}
+	return event
+}
+
+// GetEventTypeDisplayName returns a friendly display name for a system event type
+func GetEventTypeDisplayName(eventType string) string {
+	names := map[string]string{
+		EventSpotInterruption:                         "抢占式实例回收",
+		"SystemMaintenance.Reboot":                    "系统维护重启",
+		"SystemMaintenance.Redeploy":                  "系统维护迁移",
+		"SystemMaintenance.RebootAndIsolateErrorDisk": "系统维护重启并隔离损坏磁盘",
+		"SystemMaintenance.RebootAndReInitErrorDisk":  "系统维护重启并重新初始化损坏磁盘",
+		"SystemMaintenance.Stop":                      "系统维护停止",
+		"SystemFailure.Reboot":                        "系统错误重启",
+		"SystemFailure.Redeploy":                      "系统错误迁移",
+		"SystemFailure.Delete":                        "系统错误释放",
+		"InstanceFailure.Reboot":                      "实例错误重启",
+		"InstanceExpiration.Stop":                     "到期停止",
+		"InstanceExpiration.Delete":                   "到期释放",
+		"AccountUnbalanced.Stop":                      "欠费停止",
+		"AccountUnbalanced.Delete":                    "欠费释放",
+	}
+
+	if name, ok := names[eventType]; ok {
+		return name
+	}
+	return eventType
+}
@@ -0,0 +1,83 @@
+package aliyun
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Billing export formats
+const (
+	ExportCSV  = "csv"
+	ExportJSON = "json"
+)
+
+// WriteCSV writes every billing item of the summary as a CSV row, instances
+// first and then other products
+func (s *BillingSummary) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"billing_cycle", "start", "end", "resource_type", "instance_id", "instance_name", "region",
+		"product_code", "product_detail", "instance_spec", "billing_item", "amount", "currency"})
+
+	start, end := s.StartTime.Format("2006-01-02 15:04"), s.EndTime.Format("2006-01-02 15:04")
+	row := func(kind string, item BillingItem) {
+		cw.Write([]string{
+			s.BillingCycle,
+			start,
+			end,
+			kind,
+			item.InstanceID,
+			item.InstanceName,
+			item.Region,
+			item.ProductCode,
+			item.ProductDetail,
+			item.InstanceSpec,
+			item.BillingItemName,
+			strconv.FormatFloat(item.PretaxAmount, 'f', 4, 64),
+			item.Currency,
+		})
+	}
+	for _, inst := range s.Instances {
+		for _, item := range inst.Items {
+			row("instance", item)
+		}
+	}
+	for _, product := range s.Products {
+		for _, item := range product.Items {
+			row("product", item)
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the whole summary as indented JSON
+func (s *BillingSummary) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
+
+// Export writes the summary in the given format
+func (s *BillingSummary) Export(w io.Writer, format string) error {
+	switch format {
+	case ExportCSV:
+		return s.WriteCSV(w)
+	case ExportJSON:
+		return s.WriteJSON(w)
+	default:
+		return fmt.Errorf("unsupported export format %q, expected csv or json", format)
+	}
+}
+
+// ExportFilename returns the download name of an exported summary, e.g. "billing-2024-11.csv"
+func (s *BillingSummary) ExportFilename(format string) string {
+	period := s.BillingCycle
+	if s.PeriodLabel != "" {
+		period = s.StartTime.Format("2006-01-02") + "_" + s.EndTime.Format("2006-01-02")
+	}
+	return fmt.Sprintf("billing-%s.%s", period, format)
+}
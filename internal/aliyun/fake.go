@@ -0,0 +1,1152 @@
+package aliyun
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	sdkerrors "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/bssopenapi"
+	log "github.com/sirupsen/logrus"
+)
+
+// Hourly pay-as-you-go prices of the instance types the fakes know, others cost fakeDefaultPrice
+var fakeOnDemandPrices = map[string]float64{
+	"ecs.t6-c1m1.large": 0.16,
+	"ecs.e-c1m2.large":  0.42,
+	"ecs.g7.xlarge":     1.38,
+}
+
+const (
+	fakeDefaultPrice    = 0.5
+	fakeSpotDiscount    = 0.2               // spot price as a share of the on-demand price
+	fakeTrafficPerHour  = 200 * 1024 * 1024 // internet traffic of an instance per running hour
+	fakeTrafficPriceGB  = 0.5               // traffic billing item price per GB
+	fakeDefaultDuration = 5 * time.Second
+)
+
+// fakeInstance is an instance of the FakeCloud with its pending status change
+type fakeInstance struct {
+	inst     SpotInstance
+	target   string // status reached at settleAt, empty when not transitioning
+	settleAt time.Time
+}
+
+// FakeCloud is an in-memory InstanceAPI for tests and the simulate mode.
+// Started and stopped instances reach their status after StartDelay, and
+// Reclaim stops an instance the way the spot market would.
+type FakeCloud struct {
+	StartDelay       time.Duration
+	StartFailureRate float64       // share of StartInstance calls failing for lack of stock, 0 to 1
+	ReclaimWarning   time.Duration // how long ReclaimRandomly announces a reclaim ahead, 0 for none
+
+	instances map[string]*fakeInstance
+	failures  map[string][]error // method name -> errors of its next calls
+	events    []*SystemEvent
+	bandwidth map[string]int
+	eips      map[string]*EIP // allocation ID -> EIP
+	snapshots []*Snapshot
+	snapshotN int // snapshots ever created, for their IDs
+	mu        sync.Mutex
+}
+
+// NewFakeCloud creates a fake holding copies of the given instances
+func NewFakeCloud(instances ...*SpotInstance) *FakeCloud {
+	f := &FakeCloud{
+		StartDelay: fakeDefaultDuration,
+		instances:  make(map[string]*fakeInstance),
+		failures:   make(map[string][]error),
+		bandwidth:  make(map[string]int),
+		eips:       make(map[string]*EIP),
+	}
+	for _, inst := range instances {
+		f.instances[inst.InstanceID] = &fakeInstance{inst: copyInstance(inst)}
+	}
+	return f
+}
+
+// demoTemplates are the instances DemoInstances cycles through
+var demoTemplates = []struct {
+	prefix, name, region, zone, instanceType, strategy string
+	priceLimit                                         float64
+	age                                                time.Duration
+}{
+	{"hk", "hk-proxy", "cn-hongkong", "cn-hongkong-b", "ecs.t6-c1m1.large", "SpotAsPriceGo", 0, 30 * 24 * time.Hour},
+	{"sg", "sg-web", "ap-southeast-1", "ap-southeast-1a", "ecs.e-c1m2.large", "SpotAsPriceGo", 0, 7 * 24 * time.Hour},
+	{"hz", "hz-build", "cn-hangzhou", "cn-hangzhou-h", "ecs.g7.xlarge", "SpotWithPriceLimit", 0.3, 20 * time.Minute},
+}
+
+// DemoInstances returns count running spot instances for the simulate mode,
+// spread over the regions of demoTemplates
+func DemoInstances(count int) []*SpotInstance {
+	instances := make([]*SpotInstance, 0, count)
+	for i := 0; i < count; i++ {
+		t := demoTemplates[i%len(demoTemplates)]
+		n := i/len(demoTemplates) + 1
+		name := t.name
+		if n > 1 {
+			name = fmt.Sprintf("%s-%d", t.name, n)
+		}
+		instances = append(instances, &SpotInstance{
+			InstanceID:       fmt.Sprintf("i-sim-%s%02d", t.prefix, n),
+			InstanceName:     name,
+			RegionID:         t.region,
+			ZoneID:           t.zone,
+			InstanceType:     t.instanceType,
+			Status:           "Running",
+			PublicIPAddress:  fmt.Sprintf("203.0.113.%d", 10+i),
+			PrivateIPAddress: fmt.Sprintf("172.16.0.%d", 10+i),
+			SpotStrategy:     t.strategy,
+			SpotPriceLimit:   t.priceLimit,
+			SpotDuration:     1,
+			CreationTime:     time.Now().Add(-t.age),
+			OSType:           "linux",
+			Tags:             map[string]string{"env": "demo"},
+		})
+	}
+	return instances
+}
+
+// FakeServerError returns an API error with an error code, like the SDK returns for a rejected request
+func FakeServerError(httpStatus int, code, message string) error {
+	content, _ := json.Marshal(map[string]string{"Code": code, "Message": message, "RequestId": "fake"})
+	return sdkerrors.NewServerError(httpStatus, string(content), "")
+}
+
+// Reclaim stops a running instance as if its spot capacity was reclaimed
+func (f *FakeCloud) Reclaim(instanceID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fi, err := f.instance(instanceID)
+	if err != nil {
+		return err
+	}
+	fi.inst.Status = "Stopped"
+	fi.target = ""
+	// Keep the carried out interruption in the history like Aliyun does
+	now := time.Now()
+	f.events = append(f.events, &SystemEvent{
+		EventID:     fmt.Sprintf("e-sim-%d", now.UnixNano()),
+		InstanceID:  instanceID,
+		RegionID:    fi.inst.RegionID,
+		EventType:   EventSpotInterruption,
+		Status:      "Executed",
+		NotBefore:   now,
+		PublishTime: now,
+	})
+	return nil
+}
+
+// ReclaimRandomly reclaims a random running instance about every interval until ctx is cancelled
+// Reclaims come at random, between half and one and a half intervals apart,
+// each announced by a spot interruption event ReclaimWarning ahead.
+func (f *FakeCloud) ReclaimRandomly(ctx context.Context, interval time.Duration) {
+	for {
+		delay := interval/2 + time.Duration(rand.Int63n(int64(interval)+1))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		var running []*SpotInstance
+		for _, inst := range f.Instances() {
+			if inst.Status == "Running" && !f.announced(inst.InstanceID) {
+				running = append(running, inst)
+			}
+		}
+		if len(running) == 0 {
+			continue
+		}
+		inst := running[rand.Intn(len(running))]
+		if f.ReclaimWarning <= 0 {
+			f.reclaim(inst.InstanceID)
+			continue
+		}
+
+		// Announce the reclaim like Aliyun does, then carry it out
+		now := time.Now()
+		f.AddEvent(&SystemEvent{
+			EventID:     fmt.Sprintf("e-sim-%d", now.UnixNano()),
+			InstanceID:  inst.InstanceID,
+			RegionID:    inst.RegionID,
+			EventType:   EventSpotInterruption,
+			Status:      "Scheduled",
+			NotBefore:   now.Add(f.ReclaimWarning),
+			PublishTime: now,
+		})
+		log.Infof("Simulated spot reclaim of instance %s announced, due in %s", inst.InstanceID, f.ReclaimWarning)
+		id := inst.InstanceID
+		time.AfterFunc(f.ReclaimWarning, func() { f.reclaim(id) })
+	}
+}
+
+// reclaim reclaims an instance for ReclaimRandomly
+func (f *FakeCloud) reclaim(instanceID string) {
+	if err := f.Reclaim(instanceID); err == nil {
+		log.Infof("Simulated spot reclaim of instance %s", instanceID)
+	}
+}
+
+// announced reports whether an instance has an event that isn't due yet
+func (f *FakeCloud) announced(instanceID string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, event := range f.events {
+		if event.InstanceID == instanceID && time.Now().Before(event.NotBefore) {
+			return true
+		}
+	}
+	return false
+}
+
+// FailNext makes the next call of a method (e.g. "StartInstance") return err
+// Errors queued for the same method are returned one per call.
+func (f *FakeCloud) FailNext(method string, err error) {
+	f.mu.Lock()
+	f.failures[method] = append(f.failures[method], err)
+	f.mu.Unlock()
+}
+
+// AddEvent adds a scheduled system event returned by GetScheduledEvents
+func (f *FakeCloud) AddEvent(event *SystemEvent) {
+	f.mu.Lock()
+	f.events = append(f.events, event)
+	f.mu.Unlock()
+}
+
+// Bandwidth returns the bandwidth last set for an instance, 0 if never set
+func (f *FakeCloud) Bandwidth(instanceID string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.bandwidth[instanceID]
+}
+
+// Instances returns copies of all instances, sorted by ID
+func (f *FakeCloud) Instances() []*SpotInstance {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	list := make([]*SpotInstance, 0, len(f.instances))
+	for _, fi := range f.instances {
+		f.settle(fi)
+		inst := copyInstance(&fi.inst)
+		list = append(list, &inst)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].InstanceID < list[j].InstanceID })
+	return list
+}
+
+// DiscoverAllSpotInstances returns the instances in the regions of opts
+func (f *FakeCloud) DiscoverAllSpotInstances(ctx context.Context, opts DiscoverOptions) ([]*SpotInstance, error) {
+	if err := f.fail("DiscoverAllSpotInstances"); err != nil {
+		return nil, err
+	}
+
+	all := f.Instances()
+	regions := opts.Regions
+	if len(regions) == 0 {
+		seen := make(map[string]bool)
+		for _, inst := range all {
+			if !seen[inst.RegionID] {
+				seen[inst.RegionID] = true
+				regions = append(regions, inst.RegionID)
+			}
+		}
+	}
+
+	var instances []*SpotInstance
+	for _, region := range regions {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("discovery cancelled: %w", err)
+		}
+		if opts.SkipRegion != nil && opts.SkipRegion(region) {
+			continue
+		}
+		if opts.OnRegion != nil {
+			opts.OnRegion(region, nil)
+		}
+		for _, inst := range all {
+			if inst.RegionID == region && opts.Filter.Match(inst) {
+				instances = append(instances, inst)
+			}
+		}
+	}
+	return instances, nil
+}
+
+// GetInstance returns a copy of an instance
+func (f *FakeCloud) GetInstance(ctx context.Context, regionID, instanceID string) (*SpotInstance, error) {
+	if err := f.fail("GetInstance"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fi, err := f.instance(instanceID)
+	if err != nil {
+		return nil, err
+	}
+	inst := copyInstance(&fi.inst)
+	return &inst, nil
+}
+
+// GetInstanceStatus returns the status of an instance
+func (f *FakeCloud) GetInstanceStatus(ctx context.Context, regionID, instanceID string) (string, error) {
+	if err := f.fail("GetInstanceStatus"); err != nil {
+		return "", err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fi, err := f.instance(instanceID)
+	if err != nil {
+		return "", err
+	}
+	return fi.inst.Status, nil
+}
+
+// StartInstance starts a stopped instance, which is Running after StartDelay
+// Like the real API, starting an instance that isn't stopped is skipped.
+func (f *FakeCloud) StartInstance(ctx context.Context, regionID, instanceID string) error {
+	if f.StartFailureRate > 0 && ctx.Err() == nil && rand.Float64() < f.StartFailureRate {
+		return fmt.Errorf("failed to start instance %s: %w", instanceID,
+			FakeServerError(403, "OperationDenied.NoStock", "The requested resource is sold out in the specified zone"))
+	}
+	return f.transition(ctx, "StartInstance", instanceID, "Stopped", "Starting", "Running")
+}
+
+// StartInstances starts each instance like StartInstance, reporting the failures per instance
+func (f *FakeCloud) StartInstances(ctx context.Context, regionID string, instanceIDs []string) (map[string]error, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := f.fail("StartInstances"); err != nil {
+		return nil, err
+	}
+	results := make(map[string]error, len(instanceIDs))
+	for _, id := range instanceIDs {
+		results[id] = nil
+		if err := f.StartInstance(ctx, regionID, id); err != nil {
+			results[id] = &InstanceError{InstanceID: id, Code: ErrorCode(err), Message: err.Error()}
+		}
+	}
+	return results, nil
+}
+
+// StopInstance stops a running instance, which is Stopped after StartDelay
+func (f *FakeCloud) StopInstance(ctx context.Context, regionID, instanceID string, force bool) error {
+	return f.transition(ctx, "StopInstance", instanceID, "Running", "Stopping", "Stopped")
+}
+
+// RebootInstance reboots a running instance, which is Running again after StartDelay
+func (f *FakeCloud) RebootInstance(ctx context.Context, regionID, instanceID string) error {
+	return f.transition(ctx, "RebootInstance", instanceID, "Running", "Starting", "Running")
+}
+
+// SetInternetBandwidth records the bandwidth of an instance
+func (f *FakeCloud) SetInternetBandwidth(ctx context.Context, regionID, instanceID string, mbps int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := f.fail("SetInternetBandwidth"); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, err := f.instance(instanceID); err != nil {
+		return err
+	}
+	f.bandwidth[instanceID] = mbps
+	return nil
+}
+
+// GetScheduledEvents returns the events added for the given instances
+func (f *FakeCloud) GetScheduledEvents(ctx context.Context, regionID string, instanceIDs []string) ([]*SystemEvent, error) {
+	if err := f.fail("GetScheduledEvents"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var events []*SystemEvent
+	now := time.Now()
+	for _, event := range f.events {
+		// Like the real API, executed events are no longer scheduled
+		if !event.NotBefore.IsZero() && !now.Before(event.NotBefore) {
+			continue
+		}
+		if event.RegionID == regionID && containsString(instanceIDs, event.InstanceID) {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// GetInterruptionHistory returns the spot interruption events of a fake instance due since a time
+func (f *FakeCloud) GetInterruptionHistory(ctx context.Context, regionID, instanceID string, since time.Time) ([]*SystemEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := f.fail("GetInterruptionHistory"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var events []*SystemEvent
+	for _, event := range f.events {
+		if event.InstanceID == instanceID && event.EventType == EventSpotInterruption && !event.NotBefore.Before(since) {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// GetOnDemandPrice returns the fake pay-as-you-go price of an instance type
+func (f *FakeCloud) GetOnDemandPrice(ctx context.Context, regionID, zoneID, instanceType string) (float64, error) {
+	if err := f.fail("GetOnDemandPrice"); err != nil {
+		return 0, err
+	}
+	return fakeOnDemandPrice(instanceType), nil
+}
+
+// GetSpotPriceHistory returns a made-up price every 6 hours in the zone of
+// the region's first instance and the two zones after it: the first volatile,
+// the second cheaper and the third stable
+func (f *FakeCloud) GetSpotPriceHistory(ctx context.Context, regionID, instanceType, osType string, start time.Time) ([]SpotPrice, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := f.fail("GetSpotPriceHistory"); err != nil {
+		return nil, err
+	}
+
+	zone := regionID + "-a"
+	f.mu.Lock()
+	for _, fi := range f.instances {
+		if fi.inst.RegionID == regionID && fi.inst.ZoneID != "" {
+			zone = fi.inst.ZoneID
+			break
+		}
+	}
+	f.mu.Unlock()
+
+	origin := fakeOnDemandPrice(instanceType)
+	discounts := []float64{fakeSpotDiscount, fakeSpotDiscount * 0.8, fakeSpotDiscount * 1.05}
+	swings := []float64{0.3, 0.15, 0.02}
+	var prices []SpotPrice
+	for i := range discounts {
+		// cn-hongkong-b, cn-hongkong-c, cn-hongkong-d
+		zoneID := zone[:len(zone)-1] + string(zone[len(zone)-1]+byte(i))
+		for t := start.Truncate(6 * time.Hour); t.Before(time.Now()); t = t.Add(6 * time.Hour) {
+			factor := 1 + swings[i]*math.Sin(float64(t.Unix()/21600+int64(i)))
+			prices = append(prices, SpotPrice{
+				ZoneID:       zoneID,
+				InstanceType: instanceType,
+				Time:         t,
+				Price:        math.Round(origin*discounts[i]*factor*10000) / 10000,
+				OriginPrice:  origin,
+			})
+		}
+	}
+	sort.SliceStable(prices, func(i, j int) bool { return prices[i].Time.Before(prices[j].Time) })
+	return prices, nil
+}
+
+// ReplaceInstance creates a running copy of an instance in the next zone of
+// its region, or the first of opts.Zones, after StartDelay
+func (f *FakeCloud) ReplaceInstance(ctx context.Context, regionID, instanceID string, opts ReplaceOptions) (*SpotInstance, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := f.fail("ReplaceInstance"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var inst SpotInstance
+	if fi, err := f.instance(instanceID); err == nil {
+		inst = copyInstance(&fi.inst)
+	} else if cfg := opts.Config; cfg != nil {
+		inst = SpotInstance{
+			InstanceName: cfg.InstanceName,
+			RegionID:     cfg.RegionID,
+			ZoneID:       cfg.ZoneID,
+			InstanceType: cfg.InstanceType,
+			SpotStrategy: cfg.SpotStrategy,
+			SpotDuration: 1,
+			Tags:         make(map[string]string),
+		}
+		if cfg.SpotStrategy == "SpotWithPriceLimit" {
+			inst.SpotPriceLimit = cfg.SpotPriceLimit
+		}
+		for key, value := range cfg.Tags {
+			inst.Tags[key] = value
+		}
+	} else {
+		return nil, err
+	}
+
+	if opts.SpotPriceLimit > 0 {
+		inst.SpotStrategy = "SpotWithPriceLimit"
+		inst.SpotPriceLimit = opts.SpotPriceLimit
+	}
+	zone := inst.ZoneID
+	switch {
+	case opts.SameZone:
+	case len(opts.Zones) > 0 && opts.Zones[0] != zone:
+		zone = opts.Zones[0]
+	default:
+		// cn-hongkong-b becomes cn-hongkong-c
+		zone = zone[:len(zone)-1] + string(zone[len(zone)-1]+1)
+	}
+	inst.InstanceID = fmt.Sprintf("%s-%d", instanceID, len(f.instances))
+	inst.ZoneID = zone
+	inst.Status = "Pending"
+	inst.CreationTime = time.Now()
+	inst.PublicIPAddress = fmt.Sprintf("198.51.100.%d", len(f.instances))
+	inst.Tags[TagReplaces] = instanceID
+	f.instances[inst.InstanceID] = &fakeInstance{inst: inst, target: "Running", settleAt: time.Now().Add(f.StartDelay)}
+
+	created := copyInstance(&inst)
+	return &created, nil
+}
+
+// MoveEIP moves the public IP of an instance to another one
+func (f *FakeCloud) MoveEIP(ctx context.Context, regionID, fromInstanceID, toInstanceID string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if err := f.fail("MoveEIP"); err != nil {
+		return "", err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	from, err := f.instance(fromInstanceID)
+	if err != nil {
+		return "", err
+	}
+	to, err := f.instance(toInstanceID)
+	if err != nil {
+		return "", err
+	}
+	address := from.inst.PublicIPAddress
+	to.inst.PublicIPAddress = address
+	from.inst.PublicIPAddress = ""
+	return address, nil
+}
+
+// AddEIP adds an EIP, associated with instanceID unless it is empty
+func (f *FakeCloud) AddEIP(allocationID, ipAddress, instanceID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	eip := &EIP{AllocationID: allocationID, IPAddress: ipAddress, Status: "Available"}
+	if fi, err := f.instance(instanceID); err == nil {
+		eip.Status = "InUse"
+		eip.InstanceID = instanceID
+		fi.inst.PublicIPAddress = ipAddress
+	}
+	f.eips[allocationID] = eip
+}
+
+// DescribeEIP looks up a fake EIP by its allocation ID or IP address
+func (f *FakeCloud) DescribeEIP(ctx context.Context, regionID, eip string) (*EIP, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := f.fail("DescribeEIP"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, e := range f.eips {
+		if e.AllocationID == eip || e.IPAddress == eip {
+			result := *e
+			return &result, nil
+		}
+	}
+	return nil, fmt.Errorf("EIP %s not found in %s", eip, regionID)
+}
+
+// AssociateEIP moves a fake EIP and its address to an instance
+func (f *FakeCloud) AssociateEIP(ctx context.Context, regionID, allocationID, instanceID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := f.fail("AssociateEIP"); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	eip, ok := f.eips[allocationID]
+	if !ok {
+		return fmt.Errorf("EIP %s not found in %s", allocationID, regionID)
+	}
+	to, err := f.instance(instanceID)
+	if err != nil {
+		return err
+	}
+	if from, err := f.instance(eip.InstanceID); err == nil {
+		from.inst.PublicIPAddress = ""
+	}
+	eip.Status = "InUse"
+	eip.InstanceID = instanceID
+	to.inst.PublicIPAddress = eip.IPAddress
+	return nil
+}
+
+// RunCommand pretends to run a script on a running fake instance, echoing it
+func (f *FakeCloud) RunCommand(ctx context.Context, regionID, instanceID, osType, script string, timeout time.Duration) (*CommandResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := f.fail("RunCommand"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fi, err := f.instance(instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if fi.inst.Status != "Running" {
+		return &CommandResult{InvokeID: "t-sim", Status: "Invalid", ErrorInfo: "the instance is not running"}, nil
+	}
+	return &CommandResult{InvokeID: "t-sim", Status: "Success", Output: "simulated run of:\n" + script, Duration: time.Second}, nil
+}
+
+// DescribeDisks returns the system disk of a fake instance
+func (f *FakeCloud) DescribeDisks(ctx context.Context, regionID, instanceID string) ([]*Disk, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := f.fail("DescribeDisks"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, err := f.instance(instanceID); err != nil {
+		return nil, err
+	}
+	return []*Disk{{
+		DiskID:   "d-" + instanceID,
+		Type:     "system",
+		Category: "cloud_essd",
+		Size:     40,
+		Device:   "/dev/xvda",
+		Status:   "In_use",
+	}}, nil
+}
+
+// CreateInstanceSnapshots snapshots the system disk of a fake instance, finished at once
+func (f *FakeCloud) CreateInstanceSnapshots(ctx context.Context, regionID, instanceID, reason string) ([]*Snapshot, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := f.fail("CreateInstanceSnapshots"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, err := f.instance(instanceID); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	f.snapshotN++
+	snapshot := &Snapshot{
+		SnapshotID:   fmt.Sprintf("s-sim%d", f.snapshotN),
+		Name:         fmt.Sprintf("spot-%s-system-%s", reason, now.Format("20060102-150405")),
+		DiskID:       "d-" + instanceID,
+		DiskType:     "system",
+		InstanceID:   instanceID,
+		Status:       "accomplished",
+		Progress:     "100%",
+		CreationTime: now,
+		Managed:      true,
+	}
+	f.snapshots = append(f.snapshots, snapshot)
+	result := *snapshot
+	return []*Snapshot{&result}, nil
+}
+
+// DescribeSnapshots returns the fake snapshots of an instance, newest first
+func (f *FakeCloud) DescribeSnapshots(ctx context.Context, regionID, instanceID string) ([]*Snapshot, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := f.fail("DescribeSnapshots"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var snapshots []*Snapshot
+	for i := len(f.snapshots) - 1; i >= 0; i-- {
+		if f.snapshots[i].InstanceID == instanceID {
+			snapshot := *f.snapshots[i]
+			snapshots = append(snapshots, &snapshot)
+		}
+	}
+	return snapshots, nil
+}
+
+// DeleteSnapshot deletes a fake snapshot
+func (f *FakeCloud) DeleteSnapshot(ctx context.Context, regionID, snapshotID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := f.fail("DeleteSnapshot"); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, snapshot := range f.snapshots {
+		if snapshot.SnapshotID == snapshotID {
+			f.snapshots = append(f.snapshots[:i], f.snapshots[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("snapshot %s not found in %s", snapshotID, regionID)
+}
+
+// DescribeSecurityGroupRules returns rules letting SSH, HTTP(S) and RDP in
+// from anywhere for every fake security group
+func (f *FakeCloud) DescribeSecurityGroupRules(ctx context.Context, regionID string, groupIDs []string) ([]SecurityGroupRule, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := f.fail("DescribeSecurityGroupRules"); err != nil {
+		return nil, err
+	}
+
+	var rules []SecurityGroupRule
+	for _, groupID := range groupIDs {
+		for _, port := range []int{22, 80, 443, 3389} {
+			rules = append(rules, SecurityGroupRule{
+				SecurityGroupID: groupID, Protocol: "tcp", PortFrom: port, PortTo: port,
+				Source: "0.0.0.0/0", Accept: true, Priority: 1,
+			})
+		}
+	}
+	return rules, nil
+}
+
+// GetCPUCredits returns CPU credits of a running fake burstable instance,
+// draining and refilling over the hour
+func (f *FakeCloud) GetCPUCredits(ctx context.Context, regionID, instanceID string) (*CPUCredits, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := f.fail("GetCPUCredits"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fi, err := f.instance(instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.inst.Burstable() || fi.inst.Status != "Running" {
+		return nil, nil
+	}
+	now := time.Now()
+	minute := float64(now.Minute())
+	return &CPUCredits{
+		Balance: math.Abs(30-minute) * 2,
+		Usage:   0.5,
+		Time:    now.Truncate(time.Minute),
+	}, nil
+}
+
+// GetInstanceMetrics returns utilization of a running fake instance, the CPU
+// swinging over the hour and memory and disk fixed per instance
+func (f *FakeCloud) GetInstanceMetrics(ctx context.Context, regionID, instanceID string) (*InstanceMetrics, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := f.fail("GetInstanceMetrics"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fi, err := f.instance(instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if fi.inst.Status != "Running" {
+		return nil, nil
+	}
+	h := fnv.New32a()
+	h.Write([]byte(instanceID))
+	seed := float64(h.Sum32() % 50)
+	now := time.Now()
+	return &InstanceMetrics{
+		CPU:    50 + 45*math.Sin(float64(now.Minute())/60*2*math.Pi),
+		Memory: 30 + seed,
+		Disk:   20 + seed*1.5,
+		Time:   now.Truncate(time.Minute),
+	}, nil
+}
+
+// CaptureLaunchConfig returns the configuration of a fake instance
+func (f *FakeCloud) CaptureLaunchConfig(ctx context.Context, regionID, instanceID string) (*LaunchConfig, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := f.fail("CaptureLaunchConfig"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fi, err := f.instance(instanceID)
+	if err != nil {
+		return nil, err
+	}
+	inst := copyInstance(&fi.inst)
+	delete(inst.Tags, TagReplaces)
+	return &LaunchConfig{
+		InstanceID:       inst.InstanceID,
+		CapturedAt:       time.Now(),
+		RegionID:         inst.RegionID,
+		ZoneID:           inst.ZoneID,
+		VpcID:            "vpc-sim",
+		VSwitchID:        "vsw-sim-" + inst.ZoneID,
+		InstanceName:     inst.InstanceName,
+		ImageID:          "ubuntu_22_04_x64_20G_alibase_20240101.vhd",
+		InstanceType:     inst.InstanceType,
+		SecurityGroupIDs: []string{"sg-sim"},
+		SpotStrategy:     inst.SpotStrategy,
+		SpotPriceLimit:   inst.SpotPriceLimit,
+		SystemDisk:       LaunchDisk{Category: "cloud_essd", Size: 40, DeleteWithInstance: true},
+		Tags:             inst.Tags,
+	}, nil
+}
+
+// SaveLaunchTemplate accepts the launch configuration without storing it
+func (f *FakeCloud) SaveLaunchTemplate(ctx context.Context, cfg *LaunchConfig) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return f.fail("SaveLaunchTemplate")
+}
+
+// UpdateCredentials does nothing, the fake needs no credentials
+func (f *FakeCloud) UpdateCredentials(creds Credentials) {}
+
+// transition moves an instance in status from through via to to
+func (f *FakeCloud) transition(ctx context.Context, method, instanceID, from, via, to string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := f.fail(method); err != nil {
+		return fmt.Errorf("failed to %s %s: %w", method, instanceID, err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fi, err := f.instance(instanceID)
+	if err != nil {
+		return err
+	}
+	if fi.inst.Status != from {
+		log.Debugf("Fake instance %s is %s, skipping %s", instanceID, fi.inst.Status, method)
+		return nil
+	}
+	fi.inst.Status = via
+	fi.target = to
+	fi.settleAt = time.Now().Add(f.StartDelay)
+	f.settle(fi)
+	return nil
+}
+
+// instance returns an instance with its status settled
+// The caller must hold f.mu.
+func (f *FakeCloud) instance(instanceID string) (*fakeInstance, error) {
+	fi, ok := f.instances[instanceID]
+	if !ok {
+		return nil, fmt.Errorf("instance %s: %w", instanceID, ErrInstanceNotFound)
+	}
+	f.settle(fi)
+	return fi, nil
+}
+
+// settle completes the pending status change of an instance once it is due
+// The caller must hold f.mu.
+func (f *FakeCloud) settle(fi *fakeInstance) {
+	if fi.target != "" && !time.Now().Before(fi.settleAt) {
+		fi.inst.Status = fi.target
+		fi.target = ""
+	}
+}
+
+// fail pops the next queued error of a method
+func (f *FakeCloud) fail(method string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	errs := f.failures[method]
+	if len(errs) == 0 {
+		return nil
+	}
+	f.failures[method] = errs[1:]
+	return errs[0]
+}
+
+// copyInstance returns a copy of an instance that shares no tags with it
+func copyInstance(inst *SpotInstance) SpotInstance {
+	c := *inst
+	c.SecurityGroupIDs = append([]string(nil), inst.SecurityGroupIDs...)
+	c.OperationLocks = append([]string(nil), inst.OperationLocks...)
+	c.Tags = make(map[string]string, len(inst.Tags))
+	for k, v := range inst.Tags {
+		c.Tags[k] = v
+	}
+	return c
+}
+
+// fakeOnDemandPrice returns the hourly on-demand price of an instance type
+func fakeOnDemandPrice(instanceType string) float64 {
+	if price, ok := fakeOnDemandPrices[instanceType]; ok {
+		return price
+	}
+	return fakeDefaultPrice
+}
+
+// fakeRunningHours returns the hours an instance ran on a day
+// Past days lose a few hours to reclaims, today counts up to now.
+func fakeRunningHours(instanceID string, day, now time.Time) float64 {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch {
+	case day.After(today):
+		return 0
+	case day.Equal(today):
+		return now.Sub(today).Hours()
+	}
+	h := fnv.New32a()
+	h.Write([]byte(instanceID + day.Format("2006-01-02")))
+	return float64(24 - h.Sum32()%5)
+}
+
+// FakeBilling is an in-memory BillingAPI charging the instances of a FakeCloud
+// their spot price and traffic for every hour they ran
+type FakeBilling struct {
+	cloud *FakeCloud
+}
+
+// NewFakeBilling creates fake billing for the instances of cloud
+func NewFakeBilling(cloud *FakeCloud) *FakeBilling {
+	return &FakeBilling{cloud: cloud}
+}
+
+// QueryBilling summarizes a billing cycle (YYYY-MM), the current month if empty
+func (b *FakeBilling) QueryBilling(ctx context.Context, instances []InstanceInfo, cycle string) (*BillingSummary, error) {
+	now := time.Now()
+	if cycle == "" {
+		cycle = now.Format("2006-01")
+	}
+	startTime, err := time.ParseInLocation("2006-01", cycle, now.Location())
+	if err != nil {
+		return nil, fmt.Errorf("invalid billing cycle %q, expected YYYY-MM", cycle)
+	}
+	endTime := startTime.AddDate(0, 1, 0)
+
+	last := endTime.AddDate(0, 0, -1)
+	if now.Before(last) {
+		last = now
+	}
+	_, batches := b.days(startTime, last)
+	result := summarizeBilling(instances, batches)
+	result.StartTime = startTime
+	result.BillingCycle = cycle
+	if now.Before(endTime) {
+		result.EndTime = now
+		result.ElapsedDays = now.Day()
+		applyMonthlyEstimate(result, instances, EstimateDays, nil, endTime.AddDate(0, 0, -1).Day()-now.Day())
+	} else {
+		result.EndTime = endTime
+		result.ElapsedDays = endTime.AddDate(0, 0, -1).Day()
+		result.Closed = true
+		result.MonthlyEstimate = result.TotalAmount
+		result.EstimateMethod = "账期已结束，为整月实际扣费"
+		for i := range result.Instances {
+			result.Instances[i].MonthlyEstimate = result.Instances[i].TotalAmount
+		}
+	}
+	return result, nil
+}
+
+// QueryBillingByDateRange summarizes every day from start to end (inclusive)
+func (b *FakeBilling) QueryBillingByDateRange(ctx context.Context, instances []InstanceInfo, start, end time.Time, label string) (*BillingSummary, error) {
+	if end.Before(start) {
+		return nil, fmt.Errorf("invalid date range: %s ~ %s", start.Format("2006-01-02"), end.Format("2006-01-02"))
+	}
+	_, batches := b.days(start, end)
+	result := summarizeBilling(instances, batches)
+	result.StartTime = start
+	result.EndTime = end
+	result.BillingCycle = start.Format("2006-01")
+	result.PeriodLabel = label
+	result.ElapsedDays = len(batches)
+	applyMonthlyEstimate(result, instances, EstimateDays, nil, 0)
+	return result, nil
+}
+
+// QueryDailyBilling returns the spend of each day from start to end (inclusive), oldest first
+func (b *FakeBilling) QueryDailyBilling(ctx context.Context, instances []InstanceInfo, start, end time.Time) ([]DailyBilling, error) {
+	if end.Before(start) {
+		return nil, fmt.Errorf("invalid date range: %s ~ %s", start.Format("2006-01-02"), end.Format("2006-01-02"))
+	}
+	days, batches := b.days(start, end)
+	return dailyBillings(instances, days, batches), nil
+}
+
+// QueryBillingHistory summarizes the current and previous billing cycles, newest first
+func (b *FakeBilling) QueryBillingHistory(ctx context.Context, instances []InstanceInfo, nMonths int) ([]*BillingSummary, error) {
+	if nMonths < 1 || nMonths > MaxBillingHistoryMonths {
+		return nil, fmt.Errorf("billing history must cover 1 to %d months, got %d", MaxBillingHistoryMonths, nMonths)
+	}
+	now := time.Now()
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	history := make([]*BillingSummary, 0, nMonths)
+	for i := 0; i < nMonths; i++ {
+		summary, err := b.QueryBilling(ctx, instances, thisMonth.AddDate(0, -i, 0).Format("2006-01"))
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, summary)
+	}
+	return history, nil
+}
+
+// QueryBandwidthReconciliation reconciles this month's traffic charges with the traffic summary
+func (b *FakeBilling) QueryBandwidthReconciliation(ctx context.Context, instances []InstanceInfo, traffic *TrafficSummary) (*BandwidthReconciliation, error) {
+	now := time.Now()
+	_, batches := b.days(time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()), now)
+	var items []bssopenapi.Item
+	for _, batch := range batches {
+		items = append(items, batch...)
+	}
+	result := reconcileBandwidth(instances, items, traffic)
+	result.BillingCycle = now.Format("2006-01")
+	result.EndTime = now
+	return result, nil
+}
+
+// UpdateCredentials does nothing, the fake needs no credentials
+func (b *FakeBilling) UpdateCredentials(creds Credentials) error {
+	return nil
+}
+
+// days returns the billing items of every day from start to end (inclusive)
+func (b *FakeBilling) days(start, end time.Time) ([]time.Time, [][]bssopenapi.Item) {
+	now := time.Now()
+	instances := b.cloud.Instances()
+	startDay := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	endDay := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, end.Location())
+
+	var days []time.Time
+	var batches [][]bssopenapi.Item
+	for day := startDay; !day.After(endDay); day = day.AddDate(0, 0, 1) {
+		var items []bssopenapi.Item
+		for _, inst := range instances {
+			hours := fakeRunningHours(inst.InstanceID, day, now)
+			if hours <= 0 {
+				continue
+			}
+			item := bssopenapi.Item{
+				ProductCode:       "ecs",
+				ProductName:       "云服务器 ECS",
+				InstanceID:        inst.InstanceID,
+				Region:            inst.RegionID,
+				InstanceSpec:      inst.InstanceType,
+				InternetIP:        inst.PublicIPAddress,
+				ServicePeriod:     strconv.Itoa(int(hours * 3600)),
+				ServicePeriodUnit: "秒",
+				Currency:          "CNY",
+			}
+			compute := item
+			compute.BillingItem = "云服务器配置"
+			compute.PretaxAmount = fakeOnDemandPrice(inst.InstanceType) * fakeSpotDiscount * hours
+			traffic := item
+			traffic.BillingItem = "流量"
+			traffic.PretaxAmount = hours * fakeTrafficPerHour / (1024 * 1024 * 1024) * fakeTrafficPriceGB
+			items = append(items, compute, traffic)
+		}
+		days = append(days, day)
+		batches = append(batches, items)
+	}
+	return days, batches
+}
+
+// FakeTraffic is an in-memory TrafficAPI counting fakeTrafficPerHour for
+// every instance of a FakeCloud
+type FakeTraffic struct {
+	cloud *FakeCloud
+}
+
+// NewFakeTraffic creates fake traffic for the instances of cloud
+func NewFakeTraffic(cloud *FakeCloud) *FakeTraffic {
+	return &FakeTraffic{cloud: cloud}
+}
+
+// QueryInternetTraffic returns the traffic of the current month
+func (t *FakeTraffic) QueryInternetTraffic(ctx context.Context) (*TrafficSummary, error) {
+	now := time.Now()
+	return t.QueryInternetTrafficByTimeRange(ctx, time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC), now)
+}
+
+// QueryInternetTrafficByTimeRange returns the traffic between startTime and endTime
+func (t *FakeTraffic) QueryInternetTrafficByTimeRange(ctx context.Context, startTime, endTime time.Time) (*TrafficSummary, error) {
+	hours := endTime.Sub(startTime).Hours()
+	if hours < 0 {
+		hours = 0
+	}
+	byRegion := make(map[string]int64)
+	var regions []string
+	for _, inst := range t.cloud.Instances() {
+		if _, ok := byRegion[inst.RegionID]; !ok {
+			regions = append(regions, inst.RegionID)
+		}
+		byRegion[inst.RegionID] += int64(hours * fakeTrafficPerHour)
+	}
+
+	details := make([]RegionTrafficDetail, 0, len(regions))
+	for _, region := range regions {
+		details = append(details, RegionTrafficDetail{
+			BusinessRegionId:      region,
+			ISPType:               "BGP",
+			Traffic:               byRegion[region],
+			ProductTrafficDetails: []ProductTrafficDetail{{Product: "ecs", Traffic: byRegion[region]}},
+		})
+	}
+	return summarizeTraffic(startTime, endTime, details), nil
+}
+
+// ApplyForecast projects the month-end traffic of a month-to-date summary
+func (t *FakeTraffic) ApplyForecast(ctx context.Context, summary *TrafficSummary, method string) error {
+	return applyForecast(ctx, summary, method, t.QueryInternetTrafficByTimeRange)
+}
+
+// UpdateCredentials does nothing, the fake needs no credentials
+func (t *FakeTraffic) UpdateCredentials(creds Credentials) error {
+	return nil
+}
+
+var (
+	_ InstanceAPI = (*FakeCloud)(nil)
+	_ BillingAPI  = (*FakeBilling)(nil)
+	_ TrafficAPI  = (*FakeTraffic)(nil)
+)
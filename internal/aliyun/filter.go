@@ -0,0 +1,59 @@
+package aliyun
+
+import (
+	"regexp"
+	"strings"
+)
+
+// InstanceFilter selects the spot instances to manage, leaving out those of
+// other projects in the same account. Empty fields match every instance.
+type InstanceFilter struct {
+	IncludeIDs  []string       // only these instance IDs
+	ExcludeIDs  []string       // never these instance IDs
+	NamePattern *regexp.Regexp // instance name must match
+	RequireTags []string       // "key=value" or "key" selectors, all must match
+	ExcludeTags []string       // "key=value" or "key" selectors, none may match
+}
+
+// IsEmpty reports whether the filter matches every instance
+func (f *InstanceFilter) IsEmpty() bool {
+	return f == nil || (len(f.IncludeIDs) == 0 && len(f.ExcludeIDs) == 0 && f.NamePattern == nil &&
+		len(f.RequireTags) == 0 && len(f.ExcludeTags) == 0)
+}
+
+// Match reports whether an instance passes the filter
+func (f *InstanceFilter) Match(inst *SpotInstance) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.IncludeIDs) > 0 && !containsString(f.IncludeIDs, inst.InstanceID) {
+		return false
+	}
+	if containsString(f.ExcludeIDs, inst.InstanceID) {
+		return false
+	}
+	if f.NamePattern != nil && !f.NamePattern.MatchString(inst.InstanceName) {
+		return false
+	}
+	for _, selector := range f.RequireTags {
+		if !matchTagSelector(selector, inst.Tags) {
+			return false
+		}
+	}
+	for _, selector := range f.ExcludeTags {
+		if matchTagSelector(selector, inst.Tags) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchTagSelector checks a "key=value" or "key" selector against instance tags
+func matchTagSelector(selector string, tags map[string]string) bool {
+	key, value, hasValue := strings.Cut(selector, "=")
+	tagValue, ok := tags[key]
+	if !ok {
+		return false
+	}
+	return !hasValue || tagValue == value
+}
@@ -0,0 +1,299 @@
+package aliyun
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/iliyian/aliyun-spot-manager/internal/tracing"
+)
+
+// LaunchTemplatePrefix names the ECS launch template of an instance, followed by its ID
+const LaunchTemplatePrefix = "spot-manager-"
+
+// LaunchConfig is the configuration of an instance captured to create a
+// copy of it, e.g. in another zone or after it was released
+type LaunchConfig struct {
+	InstanceID string    `json:"instance_id"`
+	CapturedAt time.Time `json:"captured_at"`
+
+	RegionID         string            `json:"region_id"`
+	ZoneID           string            `json:"zone_id"`
+	VpcID            string            `json:"vpc_id"`
+	VSwitchID        string            `json:"vswitch_id"`
+	InstanceName     string            `json:"instance_name"`
+	HostName         string            `json:"host_name,omitempty"`
+	ImageID          string            `json:"image_id"`
+	InstanceType     string            `json:"instance_type"`
+	SecurityGroupIDs []string          `json:"security_group_ids"`
+	KeyPairName      string            `json:"key_pair_name,omitempty"`
+	SpotStrategy     string            `json:"spot_strategy"`
+	SpotPriceLimit   float64           `json:"spot_price_limit,omitempty"`
+	BandwidthOut     int               `json:"internet_max_bandwidth_out,omitempty"`
+	InternetCharge   string            `json:"internet_charge_type,omitempty"`
+	SystemDisk       LaunchDisk        `json:"system_disk"`
+	DataDisks        []LaunchDisk      `json:"data_disks,omitempty"`
+	UserData         string            `json:"user_data,omitempty"` // base64 encoded
+	Tags             map[string]string `json:"tags,omitempty"`
+	EIPAllocationID  string            `json:"eip_allocation_id,omitempty"`
+}
+
+// LaunchDisk is a disk of a launch configuration; only its shape is kept, not its data
+type LaunchDisk struct {
+	Name               string `json:"name,omitempty"`
+	Category           string `json:"category"`
+	Size               int    `json:"size"`
+	PerformanceLevel   string `json:"performance_level,omitempty"`
+	Device             string `json:"device,omitempty"`
+	DeleteWithInstance bool   `json:"delete_with_instance"`
+}
+
+// CaptureLaunchConfig snapshots the configuration of an instance: image,
+// type, network, security groups, disks, user data and tags
+func (c *ECSClient) CaptureLaunchConfig(ctx context.Context, regionID, instanceID string) (cfg *LaunchConfig, err error) {
+	ctx, span := tracing.Start(ctx, "ecs.CaptureLaunchConfig", tracing.Instance(regionID, instanceID)...)
+	defer func() { tracing.End(span, err) }()
+
+	client, err := c.getClient(regionID)
+	if err != nil {
+		return nil, err
+	}
+	return c.captureLaunchConfig(ctx, client, regionID, instanceID)
+}
+
+// captureLaunchConfig reads the launch configuration of an instance
+func (c *ECSClient) captureLaunchConfig(ctx context.Context, client *ecs.Client, regionID, instanceID string) (*LaunchConfig, error) {
+	request := ecs.CreateDescribeInstancesRequest()
+	request.Scheme = "https"
+	request.RegionId = regionID
+	request.InstanceIds = fmt.Sprintf(`["%s"]`, instanceID)
+
+	var response *ecs.DescribeInstancesResponse
+	err := c.resilience.call(ctx, regionID, "DescribeInstances", func() (err error) {
+		response, err = client.DescribeInstances(request)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instance: %w", err)
+	}
+	if len(response.Instances.Instance) == 0 {
+		return nil, fmt.Errorf("instance %s: %w", instanceID, ErrInstanceNotFound)
+	}
+	inst := response.Instances.Instance[0]
+	if inst.VpcAttributes.VpcId == "" {
+		return nil, fmt.Errorf("instance %s is not in a VPC", instanceID)
+	}
+
+	cfg := &LaunchConfig{
+		InstanceID:       instanceID,
+		CapturedAt:       time.Now(),
+		RegionID:         regionID,
+		ZoneID:           inst.ZoneId,
+		VpcID:            inst.VpcAttributes.VpcId,
+		VSwitchID:        inst.VpcAttributes.VSwitchId,
+		InstanceName:     inst.InstanceName,
+		HostName:         inst.HostName,
+		ImageID:          inst.ImageId,
+		InstanceType:     inst.InstanceType,
+		SecurityGroupIDs: inst.SecurityGroupIds.SecurityGroupId,
+		KeyPairName:      inst.KeyPairName,
+		SpotStrategy:     inst.SpotStrategy,
+		SpotPriceLimit:   inst.SpotPriceLimit,
+		BandwidthOut:     inst.InternetMaxBandwidthOut,
+		InternetCharge:   inst.InternetChargeType,
+		Tags:             make(map[string]string),
+		EIPAllocationID:  inst.EipAddress.AllocationId,
+	}
+	for _, tag := range inst.Tags.Tag {
+		// A copy of a replacement replaces the instance it was copied from
+		if tag.TagKey != TagReplaces {
+			cfg.Tags[tag.TagKey] = tag.TagValue
+		}
+	}
+
+	disks := ecs.CreateDescribeDisksRequest()
+	disks.Scheme = "https"
+	disks.RegionId = regionID
+	disks.InstanceId = instanceID
+
+	var disksResponse *ecs.DescribeDisksResponse
+	err = c.resilience.call(ctx, regionID, "DescribeDisks", func() (err error) {
+		disksResponse, err = client.DescribeDisks(disks)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get disks of instance %s: %w", instanceID, err)
+	}
+	for _, disk := range disksResponse.Disks.Disk {
+		launchDisk := LaunchDisk{
+			Name:               disk.DiskName,
+			Category:           disk.Category,
+			Size:               disk.Size,
+			PerformanceLevel:   disk.PerformanceLevel,
+			Device:             disk.Device,
+			DeleteWithInstance: disk.DeleteWithInstance,
+		}
+		if disk.Type == "system" {
+			cfg.SystemDisk = launchDisk
+		} else {
+			cfg.DataDisks = append(cfg.DataDisks, launchDisk)
+		}
+	}
+
+	userData := ecs.CreateDescribeUserDataRequest()
+	userData.Scheme = "https"
+	userData.RegionId = regionID
+	userData.InstanceId = instanceID
+
+	var userDataResponse *ecs.DescribeUserDataResponse
+	err = c.resilience.call(ctx, regionID, "DescribeUserData", func() (err error) {
+		userDataResponse, err = client.DescribeUserData(userData)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user data of instance %s: %w", instanceID, err)
+	}
+	cfg.UserData = userDataResponse.UserData
+	return cfg, nil
+}
+
+// SaveLaunchTemplate stores a launch configuration as the default version of
+// the ECS launch template of its instance, creating the template the first time
+func (c *ECSClient) SaveLaunchTemplate(ctx context.Context, cfg *LaunchConfig) (err error) {
+	ctx, span := tracing.Start(ctx, "ecs.SaveLaunchTemplate", tracing.Instance(cfg.RegionID, cfg.InstanceID)...)
+	defer func() { tracing.End(span, err) }()
+
+	client, err := c.getClient(cfg.RegionID)
+	if err != nil {
+		return err
+	}
+	name := LaunchTemplatePrefix + cfg.InstanceID
+
+	request := ecs.CreateCreateLaunchTemplateVersionRequest()
+	request.Scheme = "https"
+	request.RegionId = cfg.RegionID
+	request.LaunchTemplateName = name
+	request.VersionDescription = "captured " + cfg.CapturedAt.Format(time.RFC3339)
+	request.ZoneId = cfg.ZoneID
+	request.VpcId = cfg.VpcID
+	request.VSwitchId = cfg.VSwitchID
+	request.InstanceName = cfg.InstanceName
+	request.HostName = cfg.HostName
+	request.ImageId = cfg.ImageID
+	request.InstanceType = cfg.InstanceType
+	request.SecurityGroupIds = &cfg.SecurityGroupIDs
+	request.KeyPairName = cfg.KeyPairName
+	request.InstanceChargeType = "PostPaid"
+	request.SpotStrategy = cfg.SpotStrategy
+	if cfg.SpotStrategy == "SpotWithPriceLimit" {
+		request.SpotPriceLimit = requests.NewFloat(cfg.SpotPriceLimit)
+	}
+	if cfg.BandwidthOut > 0 {
+		request.InternetMaxBandwidthOut = requests.NewInteger(cfg.BandwidthOut)
+		request.InternetChargeType = cfg.InternetCharge
+	}
+	if cfg.SystemDisk.Category != "" {
+		request.SystemDiskCategory = cfg.SystemDisk.Category
+		request.SystemDiskSize = requests.NewInteger(cfg.SystemDisk.Size)
+		request.SystemDiskPerformanceLevel = cfg.SystemDisk.PerformanceLevel
+	}
+	dataDisks := make([]ecs.CreateLaunchTemplateVersionDataDisk, 0, len(cfg.DataDisks))
+	for _, disk := range cfg.DataDisks {
+		dataDisks = append(dataDisks, ecs.CreateLaunchTemplateVersionDataDisk{
+			DiskName:           disk.Name,
+			Category:           disk.Category,
+			Size:               strconv.Itoa(disk.Size),
+			PerformanceLevel:   disk.PerformanceLevel,
+			DeleteWithInstance: strconv.FormatBool(disk.DeleteWithInstance),
+		})
+	}
+	request.DataDisk = &dataDisks
+	request.UserData = cfg.UserData
+	tags := make([]ecs.CreateLaunchTemplateVersionTag, 0, len(cfg.Tags))
+	for key, value := range cfg.Tags {
+		tags = append(tags, ecs.CreateLaunchTemplateVersionTag{Key: key, Value: value})
+	}
+	request.Tag = &tags
+
+	var response *ecs.CreateLaunchTemplateVersionResponse
+	err = c.resilience.call(ctx, cfg.RegionID, "CreateLaunchTemplateVersion", func() (err error) {
+		response, err = client.CreateLaunchTemplateVersion(request)
+		return err
+	})
+	if ErrorCode(err) == "InvalidLaunchTemplate.NotFound" {
+		return c.createLaunchTemplate(ctx, client, name, request)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save launch template %s: %w", name, err)
+	}
+
+	setDefault := ecs.CreateModifyLaunchTemplateDefaultVersionRequest()
+	setDefault.Scheme = "https"
+	setDefault.RegionId = cfg.RegionID
+	setDefault.LaunchTemplateId = response.LaunchTemplateId
+	setDefault.DefaultVersionNumber = requests.NewInteger64(response.LaunchTemplateVersionNumber)
+	err = c.resilience.call(ctx, cfg.RegionID, "ModifyLaunchTemplateDefaultVersion", func() error {
+		_, err := client.ModifyLaunchTemplateDefaultVersion(setDefault)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set default version of launch template %s: %w", name, err)
+	}
+	return nil
+}
+
+// createLaunchTemplate creates a launch template whose first version has the
+// settings of a version request
+func (c *ECSClient) createLaunchTemplate(ctx context.Context, client *ecs.Client, name string, version *ecs.CreateLaunchTemplateVersionRequest) error {
+	request := ecs.CreateCreateLaunchTemplateRequest()
+	request.Scheme = "https"
+	request.RegionId = version.RegionId
+	request.LaunchTemplateName = name
+	request.VersionDescription = version.VersionDescription
+	request.ZoneId = version.ZoneId
+	request.VpcId = version.VpcId
+	request.VSwitchId = version.VSwitchId
+	request.InstanceName = version.InstanceName
+	request.HostName = version.HostName
+	request.ImageId = version.ImageId
+	request.InstanceType = version.InstanceType
+	request.SecurityGroupIds = version.SecurityGroupIds
+	request.KeyPairName = version.KeyPairName
+	request.InstanceChargeType = version.InstanceChargeType
+	request.SpotStrategy = version.SpotStrategy
+	request.SpotPriceLimit = version.SpotPriceLimit
+	request.InternetMaxBandwidthOut = version.InternetMaxBandwidthOut
+	request.InternetChargeType = version.InternetChargeType
+	request.SystemDiskCategory = version.SystemDiskCategory
+	request.SystemDiskSize = version.SystemDiskSize
+	request.SystemDiskPerformanceLevel = version.SystemDiskPerformanceLevel
+	dataDisks := make([]ecs.CreateLaunchTemplateDataDisk, 0, len(*version.DataDisk))
+	for _, disk := range *version.DataDisk {
+		dataDisks = append(dataDisks, ecs.CreateLaunchTemplateDataDisk{
+			DiskName:           disk.DiskName,
+			Category:           disk.Category,
+			Size:               disk.Size,
+			PerformanceLevel:   disk.PerformanceLevel,
+			DeleteWithInstance: disk.DeleteWithInstance,
+		})
+	}
+	request.DataDisk = &dataDisks
+	request.UserData = version.UserData
+	tags := make([]ecs.CreateLaunchTemplateTag, 0, len(*version.Tag))
+	for _, tag := range *version.Tag {
+		tags = append(tags, ecs.CreateLaunchTemplateTag{Key: tag.Key, Value: tag.Value})
+	}
+	request.Tag = &tags
+
+	err := c.resilience.call(ctx, version.RegionId, "CreateLaunchTemplate", func() error {
+		_, err := client.CreateLaunchTemplate(request)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create launch template %s: %w", name, err)
+	}
+	return nil
+}
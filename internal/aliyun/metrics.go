@@ -0,0 +1,125 @@
+package aliyun
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/cms"
+	"github.com/iliyian/aliyun-spot-manager/internal/tracing"
+)
+
+// InstanceMetrics is the latest CloudMonitor utilization of an instance in percent,
+// negative when not reported. Memory and disk need the CloudMonitor agent.
+type InstanceMetrics struct {
+	CPU    float64
+	Memory float64
+	Disk   float64 // fullest mount point
+	Time   time.Time
+}
+
+// metricDatapoint is a datapoint of DescribeMetricLast
+type metricDatapoint struct {
+	Timestamp int64   `json:"timestamp"`
+	Average   float64 `json:"Average"`
+	Maximum   float64 `json:"Maximum"`
+}
+
+// getCMSClient returns the CloudMonitor client of a region, creating it on first use
+func (c *ECSClient) getCMSClient(regionID string) (*cms.Client, error) {
+	c.clientsMu.Lock()
+	defer c.clientsMu.Unlock()
+
+	if client, ok := c.cmsClients[regionID]; ok {
+		return client, nil
+	}
+	client, err := cms.NewClientWithOptions(regionID, sdk.NewConfig(), c.creds.credential())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CloudMonitor client for region %s: %w", regionID, err)
+	}
+	c.transport.apply(&client.Client)
+
+	c.cmsClients[regionID] = client
+	return client, nil
+}
+
+// GetInstanceMetrics returns the latest CPU, memory and disk utilization of an instance
+// from CloudMonitor, nil when none of them is reported
+func (c *ECSClient) GetInstanceMetrics(ctx context.Context, regionID, instanceID string) (metrics *InstanceMetrics, err error) {
+	ctx, span := tracing.Start(ctx, "cms.GetInstanceMetrics", tracing.Instance(regionID, instanceID)...)
+	defer func() { tracing.End(span, err) }()
+
+	client, err := c.getCMSClient(regionID)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics = &InstanceMetrics{CPU: -1, Memory: -1, Disk: -1}
+	reported := false
+	for _, metric := range []struct {
+		name  string
+		value *float64
+	}{
+		{"CPUUtilization", &metrics.CPU},
+		{"memory_usedutilization", &metrics.Memory},
+		{"diskusage_utilization", &metrics.Disk},
+	} {
+		points, err := c.describeMetricLast(ctx, client, regionID, instanceID, metric.name)
+		if err != nil {
+			return nil, err
+		}
+		// Disks report a datapoint per mount point, keep the fullest
+		for _, point := range points {
+			if point.Maximum > *metric.value {
+				*metric.value = point.Maximum
+			}
+			if t := time.UnixMilli(point.Timestamp); t.After(metrics.Time) {
+				metrics.Time = t
+			}
+			reported = true
+		}
+	}
+
+	if !reported {
+		return nil, nil
+	}
+	return metrics, nil
+}
+
+// describeMetricLast returns the latest datapoints of an ECS metric of an instance
+func (c *ECSClient) describeMetricLast(ctx context.Context, client *cms.Client, regionID, instanceID, metric string) ([]metricDatapoint, error) {
+	dimensions, err := json.Marshal([]map[string]string{{"instanceId": instanceID}})
+	if err != nil {
+		return nil, err
+	}
+
+	request := cms.CreateDescribeMetricLastRequest()
+	request.Scheme = "https"
+	request.Namespace = "acs_ecs_dashboard"
+	request.MetricName = metric
+	request.Dimensions = string(dimensions)
+	request.Period = "60"
+
+	var response *cms.DescribeMetricLastResponse
+	err = c.resilience.call(ctx, regionID, "DescribeMetricLast", func() (err error) {
+		response, err = client.DescribeMetricLast(request)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s of instance %s: %w", metric, instanceID, err)
+	}
+	if !response.Success {
+		return nil, fmt.Errorf("failed to get %s of instance %s: %s %s", metric, instanceID, response.Code, response.Message)
+	}
+	if response.Datapoints == "" {
+		return nil, nil
+	}
+
+	var points []metricDatapoint
+	if err := json.Unmarshal([]byte(response.Datapoints), &points); err != nil {
+		return nil, fmt.Errorf("failed to parse %s of instance %s: %w", metric, instanceID, err)
+	}
+	return points, nil
+}
@@ -0,0 +1,227 @@
+package aliyun
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/auth"
+)
+
+// ossTimeout bounds each OSS request
+const ossTimeout = 60 * time.Second
+
+// ErrObjectNotFound is returned for an OSS object that doesn't exist
+var ErrObjectNotFound = errors.New("object not found")
+
+// OSSClient reads and writes the objects of an OSS bucket. Requests are
+// signed with the monitor's credentials, STS tokens of role credentials
+// included, using the OSS header signature.
+type OSSClient struct {
+	endpoint  string // e.g. oss-cn-hangzhou.aliyuncs.com
+	bucket    string
+	transport *Transport
+	client    *http.Client
+
+	signer auth.Signer // replaced by UpdateCredentials, guarded by mu
+	mu     sync.RWMutex
+}
+
+// NewOSSClient creates a client of bucket at endpoint, the OSS domain of its
+// region like oss-cn-hangzhou.aliyuncs.com or its internal variant
+func NewOSSClient(creds Credentials, transport *Transport, endpoint, bucket string) (*OSSClient, error) {
+	signer, err := newOSSSigner(creds, transport)
+	if err != nil {
+		return nil, err
+	}
+
+	var rt http.RoundTripper = http.DefaultTransport
+	if transport != nil {
+		rt = transport
+	}
+	endpoint = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(endpoint, "https://"), "http://"), "/")
+	return &OSSClient{
+		endpoint:  endpoint,
+		bucket:    bucket,
+		transport: transport,
+		client:    &http.Client{Transport: rt, Timeout: ossTimeout},
+		signer:    signer,
+	}, nil
+}
+
+// newOSSSigner creates the signer of OSS requests. The SDK client only
+// provides it, refreshing STS tokens through the shared transport.
+func newOSSSigner(creds Credentials, transport *Transport) (auth.Signer, error) {
+	client, err := sdk.NewClientWithOptions("cn-hangzhou", sdk.NewConfig(), creds.credential())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OSS signer: %w", err)
+	}
+	transport.apply(client)
+	return client.GetSigner(), nil
+}
+
+// UpdateCredentials replaces the signer with one of the new credentials
+func (c *OSSClient) UpdateCredentials(creds Credentials) error {
+	signer, err := newOSSSigner(creds, c.transport)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.signer = signer
+	c.mu.Unlock()
+	return nil
+}
+
+// getSigner returns the current signer
+func (c *OSSClient) getSigner() auth.Signer {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.signer
+}
+
+// String returns the bucket as an oss:// URL
+func (c *OSSClient) String() string {
+	return fmt.Sprintf("oss://%s", c.bucket)
+}
+
+// PutObject uploads data as key, not cached by browsers or the CDN
+func (c *OSSClient) PutObject(ctx context.Context, key, contentType string, data []byte) error {
+	sum := md5.Sum(data)
+	headers := http.Header{}
+	headers.Set("Content-Type", contentType)
+	headers.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+	headers.Set("Cache-Control", "no-cache")
+
+	resp, err := c.do(ctx, http.MethodPut, key, "", headers, data)
+	if err != nil {
+		return fmt.Errorf("failed to upload %s to %s: %w", key, c, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// GetObject downloads key, the given version of it when versionID is set.
+// A missing object returns ErrObjectNotFound.
+func (c *OSSClient) GetObject(ctx context.Context, key, versionID string) ([]byte, error) {
+	var subresource string
+	if versionID != "" {
+		subresource = "versionId=" + versionID
+	}
+	resp, err := c.do(ctx, http.MethodGet, key, subresource, http.Header{}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s from %s: %w", key, c, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s from %s: %w", key, c, err)
+	}
+	return data, nil
+}
+
+// Versioning returns the versioning status of the bucket, Enabled or
+// Suspended, or empty when it was never enabled
+func (c *OSSClient) Versioning(ctx context.Context) (string, error) {
+	resp, err := c.do(ctx, http.MethodGet, "", "versioning", http.Header{}, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to get versioning of %s: %w", c, err)
+	}
+	defer resp.Body.Close()
+	var config struct {
+		Status string `xml:"Status"`
+	}
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&config); err != nil {
+		return "", fmt.Errorf("failed to decode versioning of %s: %w", c, err)
+	}
+	return config.Status, nil
+}
+
+// do sends a signed request for key with an optional subresource like
+// "versioning", returning the response of a 2xx status
+func (c *OSSClient) do(ctx context.Context, method, key, subresource string, headers http.Header, body []byte) (*http.Response, error) {
+	signer := c.getSigner()
+	accessKeyID, err := signer.GetAccessKeyId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credentials: %w", err)
+	}
+	if token := signer.GetExtraParam()["SecurityToken"]; token != "" {
+		headers.Set("x-oss-security-token", token)
+	}
+	headers.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	signature := signer.Sign(c.stringToSign(method, key, subresource, headers), "")
+	headers.Set("Authorization", fmt.Sprintf("OSS %s:%s", accessKeyID, signature))
+
+	target := url.URL{Scheme: "https", Host: c.bucket + "." + c.endpoint, Path: "/" + key, RawQuery: subresource}
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = headers
+	req.ContentLength = int64(len(body))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if resp.StatusCode == http.StatusNotFound && strings.Contains(string(data), "<Code>NoSuchKey</Code>") {
+			return nil, ErrObjectNotFound
+		}
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, ossErrorCode(data))
+	}
+	return resp, nil
+}
+
+// stringToSign builds the string signed for a request: the verb, content
+// headers, date, x-oss-* headers in order and the resource
+func (c *OSSClient) stringToSign(method, key, subresource string, headers http.Header) string {
+	var ossHeaders []string
+	for name := range headers {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-oss-") {
+			ossHeaders = append(ossHeaders, lower+":"+strings.TrimSpace(headers.Get(name))+"\n")
+		}
+	}
+	sort.Strings(ossHeaders)
+
+	resource := "/" + c.bucket + "/" + key
+	if subresource != "" {
+		resource += "?" + subresource
+	}
+	return strings.Join([]string{
+		method,
+		headers.Get("Content-MD5"),
+		headers.Get("Content-Type"),
+		headers.Get("Date"),
+		strings.Join(ossHeaders, "") + resource,
+	}, "\n")
+}
+
+// ossErrorCode extracts the error code of an OSS XML error response
+func ossErrorCode(data []byte) string {
+	text := string(data)
+	start := strings.Index(text, "<Code>")
+	end := strings.Index(text, "</Code>")
+	if start < 0 || end < start {
+		return strings.TrimSpace(text)
+	}
+	code := text[start+len("<Code>") : end]
+	if start, end := strings.Index(text, "<Message>"), strings.Index(text, "</Message>"); start >= 0 && end > start {
+		code += ": " + text[start+len("<Message>"):end]
+	}
+	return code
+}
@@ -0,0 +1,50 @@
+package aliyun
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	log "github.com/sirupsen/logrus"
+)
+
+// GetOnDemandPrice returns the pay-as-you-go hourly price of an instance type
+// Only the compute part is returned when the API breaks the price down, so it
+// compares with the "云服务器配置" billing item of a spot instance
+func (c *ECSClient) GetOnDemandPrice(ctx context.Context, regionID, zoneID, instanceType string) (float64, error) {
+	client, err := c.getClient(regionID)
+	if err != nil {
+		return 0, err
+	}
+
+	request := ecs.CreateDescribePriceRequest()
+	request.Scheme = "https"
+	request.RegionId = regionID
+	request.ZoneId = zoneID
+	request.ResourceType = "instance"
+	request.InstanceType = instanceType
+	request.PriceUnit = "Hour"
+	request.Period = requests.NewInteger(1)
+	request.SpotStrategy = "NoSpot"
+
+	var response *ecs.DescribePriceResponse
+	err = c.resilience.call(ctx, regionID, "DescribePrice", func() (err error) {
+		response, err = client.DescribePrice(request)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to describe price of %s in %s: %w", instanceType, regionID, err)
+	}
+
+	price := response.PriceInfo.Price.TradePrice
+	for _, detail := range response.PriceInfo.Price.DetailInfos.ResourcePriceModel {
+		if detail.Resource == "instanceType" {
+			price = detail.TradePrice
+			break
+		}
+	}
+
+	log.Debugf("On-demand price of %s in %s: %.4f/hour", instanceType, regionID, price)
+	return price, nil
+}
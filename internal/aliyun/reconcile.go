@@ -0,0 +1,170 @@
+package aliyun
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/bssopenapi"
+	log "github.com/sirupsen/logrus"
+)
+
+// bandwidthProducts are the products whose bills may contain bandwidth or traffic charges
+var bandwidthProducts = []string{"ecs", "eip", "cdt"}
+
+// BandwidthCharge is the bandwidth-related spend of a single resource
+type BandwidthCharge struct {
+	ResourceID   string // 实例ID / EIP ID，CDT 按区域汇总
+	InstanceName string // 监控中的实例名称，未监控为空
+	ProductCode  string // ecs / eip / cdt
+	Region       string
+	InternetIP   string
+	BillingItems []string // 计费项名称
+	Amount       float64
+	Tracked      bool // 是否为监控中的实例
+}
+
+// BandwidthReconciliation cross-checks CDT traffic against bandwidth billing items
+type BandwidthReconciliation struct {
+	BillingCycle    string
+	EndTime         time.Time
+	TrafficGB       float64 // CDT 统计的公网流量
+	Charges         []BandwidthCharge
+	TotalAmount     float64
+	TrackedAmount   float64 // 监控实例上的带宽费用
+	CDTAmount       float64 // 按 CDT 计费的流量费用
+	UntrackedAmount float64 // 未监控资源上的带宽费用
+	Discrepancies   []string
+}
+
+// isBandwidthItem reports whether a billing item is a bandwidth or traffic charge
+func isBandwidthItem(item bssopenapi.Item) bool {
+	if item.ProductCode == "cdt" {
+		return true
+	}
+	name := item.BillingItem
+	code := strings.ToLower(item.BillingItemCode)
+	return strings.Contains(name, "带宽") || strings.Contains(name, "流量") ||
+		strings.Contains(code, "bandwidth") || strings.Contains(code, "flow") || strings.Contains(code, "traffic")
+}
+
+// QueryBandwidthReconciliation collects this month's bandwidth charges for ECS, EIP and CDT
+// and reconciles them with the CDT traffic summary (which may be nil)
+func (c *BillingClient) QueryBandwidthReconciliation(ctx context.Context, instances []InstanceInfo, traffic *TrafficSummary) (*BandwidthReconciliation, error) {
+	now := time.Now()
+	cycle := now.Format("2006-01")
+
+	var items []bssopenapi.Item
+	for _, product := range bandwidthProducts {
+		productItems, err := c.queryInstanceBill(ctx, product, cycle, "")
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, productItems...)
+	}
+
+	result := reconcileBandwidth(instances, items, traffic)
+	result.BillingCycle = cycle
+	result.EndTime = now
+
+	log.Infof("Bandwidth reconciliation: %d charges, total: %.4f, untracked: %.4f, %d discrepancies",
+		len(result.Charges), result.TotalAmount, result.UntrackedAmount, len(result.Discrepancies))
+
+	return result, nil
+}
+
+// reconcileBandwidth groups bandwidth items by resource and flags discrepancies
+func reconcileBandwidth(instances []InstanceInfo, items []bssopenapi.Item, traffic *TrafficSummary) *BandwidthReconciliation {
+	instanceMap := make(map[string]InstanceInfo)
+	for _, inst := range instances {
+		instanceMap[inst.InstanceID] = inst
+	}
+
+	result := &BandwidthReconciliation{}
+	charges := make(map[string]*BandwidthCharge)
+
+	for _, item := range items {
+		if !isBandwidthItem(item) || item.PretaxAmount == 0 {
+			continue
+		}
+
+		resourceID := item.InstanceID
+		if item.ProductCode == "cdt" || resourceID == "" {
+			// CDT bills traffic per account and region, not per instance
+			resourceID = fmt.Sprintf("%s/%s", item.ProductCode, item.Region)
+		}
+
+		charge, ok := charges[resourceID]
+		if !ok {
+			charge = &BandwidthCharge{
+				ResourceID:  resourceID,
+				ProductCode: item.ProductCode,
+				Region:      item.Region,
+				InternetIP:  item.InternetIP,
+			}
+			if inst, tracked := instanceMap[item.InstanceID]; tracked {
+				charge.InstanceName = inst.InstanceName
+				charge.Tracked = true
+			}
+			charges[resourceID] = charge
+		}
+
+		name := formatBillingItemName(item.BillingItem, "")
+		if !containsString(charge.BillingItems, name) {
+			charge.BillingItems = append(charge.BillingItems, name)
+		}
+		charge.Amount += item.PretaxAmount
+	}
+
+	for _, charge := range charges {
+		result.Charges = append(result.Charges, *charge)
+		result.TotalAmount += charge.Amount
+		switch {
+		case charge.Tracked:
+			result.TrackedAmount += charge.Amount
+		case charge.ProductCode == "cdt":
+			result.CDTAmount += charge.Amount
+		default:
+			result.UntrackedAmount += charge.Amount
+		}
+	}
+	sort.Slice(result.Charges, func(i, j int) bool {
+		return result.Charges[i].Amount > result.Charges[j].Amount
+	})
+
+	if traffic != nil {
+		result.TrafficGB = traffic.TotalTrafficGB
+	}
+
+	// Flag anything that doesn't add up
+	for _, charge := range result.Charges {
+		if !charge.Tracked && charge.ProductCode != "cdt" {
+			result.Discrepancies = append(result.Discrepancies,
+				fmt.Sprintf("%s (%s) 不在监控列表中，产生带宽费用 ¥%.4f", charge.ResourceID, charge.ProductCode, charge.Amount))
+		}
+	}
+	if traffic != nil {
+		if result.TrafficGB > 0 && result.TotalAmount == 0 {
+			result.Discrepancies = append(result.Discrepancies,
+				fmt.Sprintf("CDT 统计流量 %.2f GB，但没有带宽/流量扣费（可能在免费额度内或账单尚未出账）", result.TrafficGB))
+		}
+		if result.TrafficGB == 0 && result.TrackedAmount+result.CDTAmount > 0 {
+			result.Discrepancies = append(result.Discrepancies,
+				fmt.Sprintf("CDT 未统计到流量，但有 ¥%.4f 带宽/流量扣费（可能为固定带宽或未开通 CDT）", result.TrackedAmount+result.CDTAmount))
+		}
+	}
+
+	return result
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
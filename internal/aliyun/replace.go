@@ -0,0 +1,355 @@
+package aliyun
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/iliyian/aliyun-spot-manager/internal/tracing"
+	log "github.com/sirupsen/logrus"
+)
+
+// TagReplaces is set on a replacement instance to the ID of the instance it replaces
+const TagReplaces = "spot-manager:replaces"
+
+// ReplaceOptions controls where a replacement instance is created
+type ReplaceOptions struct {
+	Zones []string // candidate zones in order of preference, empty for any zone with stock
+	// Configuration to copy, e.g. captured earlier; captured from the instance when nil
+	Config *LaunchConfig
+	// Create the copy in the instance's own zone instead of another one
+	SameZone bool
+	// Bid of the copy, making it SpotWithPriceLimit; 0 keeps the instance's strategy
+	SpotPriceLimit float64
+}
+
+// ReplaceInstance creates a copy of a spot instance in another zone of its
+// region from its launch configuration, trying the zones that report stock
+// in turn. The new instance starts on its own; the original is left
+// untouched. The disks are created empty, their data is not copied.
+func (c *ECSClient) ReplaceInstance(ctx context.Context, regionID, instanceID string, opts ReplaceOptions) (inst *SpotInstance, err error) {
+	ctx, span := tracing.Start(ctx, "ecs.ReplaceInstance", tracing.Instance(regionID, instanceID)...)
+	defer func() { tracing.End(span, err) }()
+
+	client, err := c.getClient(regionID)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := opts.Config
+	if spec == nil {
+		spec, err = c.captureLaunchConfig(ctx, client, regionID, instanceID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opts.SpotPriceLimit > 0 {
+		rebid := *spec
+		rebid.SpotStrategy = "SpotWithPriceLimit"
+		rebid.SpotPriceLimit = opts.SpotPriceLimit
+		spec = &rebid
+	}
+	zones, err := c.zonesWithStock(ctx, client, regionID, spec)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case opts.SameZone:
+		zones = preferredZones(zones, []string{spec.ZoneID})
+	case len(opts.Zones) > 0:
+		zones = preferredZones(zones, opts.Zones)
+	}
+
+	var lastErr error
+	for _, zoneID := range zones {
+		if zoneID == spec.ZoneID && !opts.SameZone {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		vswitchID, err := c.vswitchInZone(ctx, client, regionID, spec.VpcID, zoneID)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if vswitchID == "" {
+			log.Debugf("No vSwitch of VPC %s in zone %s, skipping", spec.VpcID, zoneID)
+			continue
+		}
+
+		newID, err := c.runInstance(ctx, client, regionID, instanceID, zoneID, vswitchID, spec)
+		if err != nil {
+			log.Warnf("Failed to create replacement of instance %s in %s: %v", instanceID, zoneID, err)
+			lastErr = err
+			continue
+		}
+		log.Infof("Created instance %s in %s to replace %s", newID, zoneID, instanceID)
+		tags := map[string]string{TagReplaces: instanceID}
+		for key, value := range spec.Tags {
+			tags[key] = value
+		}
+		replacement := &SpotInstance{
+			InstanceID:       newID,
+			InstanceName:     spec.InstanceName,
+			RegionID:         regionID,
+			ZoneID:           zoneID,
+			InstanceType:     spec.InstanceType,
+			Status:           "Pending",
+			SpotStrategy:     spec.SpotStrategy,
+			SecurityGroupIDs: spec.SecurityGroupIDs,
+			Tags:             tags,
+		}
+		if spec.SpotStrategy == "SpotWithPriceLimit" {
+			replacement.SpotPriceLimit = spec.SpotPriceLimit
+		}
+		return replacement, nil
+	}
+
+	if lastErr != nil {
+		return nil, fmt.Errorf("failed to create a replacement of instance %s: %w", instanceID, lastErr)
+	}
+	if opts.SameZone {
+		return nil, fmt.Errorf("zone %s has no stock of %s", spec.ZoneID, spec.InstanceType)
+	}
+	return nil, fmt.Errorf("no other zone of %s has stock of %s with a vSwitch of the instance's VPC", regionID, spec.InstanceType)
+}
+
+// zonesWithStock returns the zones of a region with spot stock of the instance type
+func (c *ECSClient) zonesWithStock(ctx context.Context, client *ecs.Client, regionID string, spec *LaunchConfig) ([]string, error) {
+	request := ecs.CreateDescribeAvailableResourceRequest()
+	request.Scheme = "https"
+	request.RegionId = regionID
+	request.DestinationResource = "InstanceType"
+	request.InstanceType = spec.InstanceType
+	request.InstanceChargeType = "PostPaid"
+	request.SpotStrategy = spec.SpotStrategy
+
+	var response *ecs.DescribeAvailableResourceResponse
+	err := c.resilience.call(ctx, regionID, "DescribeAvailableResource", func() (err error) {
+		response, err = client.DescribeAvailableResource(request)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query zones with stock in %s: %w", regionID, err)
+	}
+
+	var zones []string
+	for _, zone := range response.AvailableZones.AvailableZone {
+		if zone.Status == "Available" && zone.StatusCategory != "SoldOut" {
+			zones = append(zones, zone.ZoneId)
+		}
+	}
+	return zones, nil
+}
+
+// vswitchInZone returns an available vSwitch of a VPC in a zone, empty if there is none
+func (c *ECSClient) vswitchInZone(ctx context.Context, client *ecs.Client, regionID, vpcID, zoneID string) (string, error) {
+	request := ecs.CreateDescribeVSwitchesRequest()
+	request.Scheme = "https"
+	request.RegionId = regionID
+	request.VpcId = vpcID
+	request.ZoneId = zoneID
+
+	var response *ecs.DescribeVSwitchesResponse
+	err := c.resilience.call(ctx, regionID, "DescribeVSwitches", func() (err error) {
+		response, err = client.DescribeVSwitches(request)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list vSwitches in %s: %w", zoneID, err)
+	}
+	for _, vswitch := range response.VSwitches.VSwitch {
+		if vswitch.Status == "Available" {
+			return vswitch.VSwitchId, nil
+		}
+	}
+	return "", nil
+}
+
+// runInstance creates and starts one spot instance from a launch configuration
+func (c *ECSClient) runInstance(ctx context.Context, client *ecs.Client, regionID, replacedID, zoneID, vswitchID string, spec *LaunchConfig) (string, error) {
+	request := ecs.CreateRunInstancesRequest()
+	request.Scheme = "https"
+	request.RegionId = regionID
+	request.ZoneId = zoneID
+	request.VSwitchId = vswitchID
+	request.ImageId = spec.ImageID
+	request.InstanceType = spec.InstanceType
+	request.InstanceName = spec.InstanceName
+	request.HostName = spec.HostName
+	request.SecurityGroupIds = &spec.SecurityGroupIDs
+	request.KeyPairName = spec.KeyPairName
+	request.InstanceChargeType = "PostPaid"
+	request.SpotStrategy = spec.SpotStrategy
+	if spec.SpotStrategy == "SpotWithPriceLimit" {
+		request.SpotPriceLimit = requests.NewFloat(spec.SpotPriceLimit)
+	}
+	// An instance with an EIP gets it moved over instead of a new public IP
+	if spec.BandwidthOut > 0 && spec.EIPAllocationID == "" {
+		request.InternetMaxBandwidthOut = requests.NewInteger(spec.BandwidthOut)
+		request.InternetChargeType = spec.InternetCharge
+	}
+	if spec.SystemDisk.Category != "" {
+		request.SystemDiskCategory = spec.SystemDisk.Category
+		request.SystemDiskSize = strconv.Itoa(spec.SystemDisk.Size)
+		request.SystemDiskPerformanceLevel = spec.SystemDisk.PerformanceLevel
+	}
+	dataDisks := make([]ecs.RunInstancesDataDisk, 0, len(spec.DataDisks))
+	for _, disk := range spec.DataDisks {
+		dataDisks = append(dataDisks, ecs.RunInstancesDataDisk{
+			DiskName:           disk.Name,
+			Category:           disk.Category,
+			Size:               strconv.Itoa(disk.Size),
+			PerformanceLevel:   disk.PerformanceLevel,
+			DeleteWithInstance: strconv.FormatBool(disk.DeleteWithInstance),
+		})
+	}
+	request.DataDisk = &dataDisks
+	request.UserData = spec.UserData
+	tags := make([]ecs.RunInstancesTag, 0, len(spec.Tags)+1)
+	for key, value := range spec.Tags {
+		tags = append(tags, ecs.RunInstancesTag{Key: key, Value: value})
+	}
+	tags = append(tags, ecs.RunInstancesTag{Key: TagReplaces, Value: replacedID})
+	request.Tag = &tags
+	request.Amount = requests.NewInteger(1)
+	// Makes a retried call create the instance only once
+	request.ClientToken = fmt.Sprintf("replace-%s-%s", replacedID, zoneID)
+	if spec.SpotStrategy == "SpotWithPriceLimit" {
+		request.ClientToken += fmt.Sprintf("-%.0f", spec.SpotPriceLimit*10000)
+	}
+
+	var response *ecs.RunInstancesResponse
+	err := c.resilience.call(ctx, regionID, "RunInstances", func() (err error) {
+		response, err = client.RunInstances(request)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(response.InstanceIdSets.InstanceIdSet) == 0 {
+		return "", fmt.Errorf("RunInstances returned no instance")
+	}
+	return response.InstanceIdSets.InstanceIdSet[0], nil
+}
+
+// MoveEIP moves the EIP of an instance to another one, returning its address,
+// or "" if the instance has none. The target must be running or stopped.
+func (c *ECSClient) MoveEIP(ctx context.Context, regionID, fromInstanceID, toInstanceID string) (address string, err error) {
+	ctx, span := tracing.Start(ctx, "ecs.MoveEIP", tracing.Instance(regionID, fromInstanceID)...)
+	defer func() { tracing.End(span, err) }()
+
+	client, err := c.getClient(regionID)
+	if err != nil {
+		return "", err
+	}
+
+	eip, err := c.instanceEIP(ctx, client, regionID, fromInstanceID)
+	if err != nil || eip == nil {
+		return "", err
+	}
+
+	unassociate := ecs.CreateUnassociateEipAddressRequest()
+	unassociate.Scheme = "https"
+	unassociate.RegionId = regionID
+	unassociate.AllocationId = eip.AllocationId
+	unassociate.InstanceId = fromInstanceID
+	err = c.resilience.call(ctx, regionID, "UnassociateEipAddress", func() error {
+		_, err := client.UnassociateEipAddress(unassociate)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to unassociate EIP %s: %w", eip.IpAddress, err)
+	}
+	c.InvalidateInstance(fromInstanceID)
+
+	// The EIP can only be associated again once it is available
+	for i := 0; i < 30; i++ {
+		status, err := c.eipStatus(ctx, client, regionID, eip.AllocationId)
+		if err != nil {
+			return "", err
+		}
+		if status == "Available" {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+	}
+
+	associate := ecs.CreateAssociateEipAddressRequest()
+	associate.Scheme = "https"
+	associate.RegionId = regionID
+	associate.AllocationId = eip.AllocationId
+	associate.InstanceId = toInstanceID
+	err = c.resilience.call(ctx, regionID, "AssociateEipAddress", func() error {
+		_, err := client.AssociateEipAddress(associate)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to associate EIP %s with %s: %w", eip.IpAddress, toInstanceID, err)
+	}
+	c.InvalidateInstance(toInstanceID)
+	return eip.IpAddress, nil
+}
+
+// instanceEIP returns the EIP associated with an instance, nil if there is none
+func (c *ECSClient) instanceEIP(ctx context.Context, client *ecs.Client, regionID, instanceID string) (*ecs.EipAddress, error) {
+	request := ecs.CreateDescribeEipAddressesRequest()
+	request.Scheme = "https"
+	request.RegionId = regionID
+	request.AssociatedInstanceType = "EcsInstance"
+	request.AssociatedInstanceId = instanceID
+
+	var response *ecs.DescribeEipAddressesResponse
+	err := c.resilience.call(ctx, regionID, "DescribeEipAddresses", func() (err error) {
+		response, err = client.DescribeEipAddresses(request)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get EIP of instance %s: %w", instanceID, err)
+	}
+	if len(response.EipAddresses.EipAddress) == 0 {
+		return nil, nil
+	}
+	return &response.EipAddresses.EipAddress[0], nil
+}
+
+// eipStatus returns the status of an EIP, e.g. Available or InUse
+func (c *ECSClient) eipStatus(ctx context.Context, client *ecs.Client, regionID, allocationID string) (string, error) {
+	request := ecs.CreateDescribeEipAddressesRequest()
+	request.Scheme = "https"
+	request.RegionId = regionID
+	request.AllocationId = allocationID
+
+	var response *ecs.DescribeEipAddressesResponse
+	err := c.resilience.call(ctx, regionID, "DescribeEipAddresses", func() (err error) {
+		response, err = client.DescribeEipAddresses(request)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get EIP %s: %w", allocationID, err)
+	}
+	if len(response.EipAddresses.EipAddress) == 0 {
+		return "", fmt.Errorf("EIP %s not found", allocationID)
+	}
+	return response.EipAddresses.EipAddress[0].Status, nil
+}
+
+// preferredZones orders zones by preference, dropping those not preferred
+func preferredZones(zones, preferred []string) []string {
+	var ordered []string
+	for _, zone := range preferred {
+		if containsString(zones, zone) {
+			ordered = append(ordered, zone)
+		}
+	}
+	return ordered
+}
@@ -0,0 +1,254 @@
+package aliyun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	sdkerrors "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrCircuitOpen is returned without calling the API while a region's circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// retryableErrorCodes are error code prefixes of throttled or temporarily
+// unavailable API calls, worth retrying after a pause
+var retryableErrorCodes = []string{
+	"Throttling",
+	"ServiceUnavailable",
+}
+
+// IsRetryable checks if an error is caused by throttling or a temporarily unavailable service
+func IsRetryable(err error) bool {
+	var serverErr *sdkerrors.ServerError
+	if !errors.As(err, &serverErr) {
+		return false
+	}
+	if serverErr.HttpStatus() == http.StatusTooManyRequests || serverErr.HttpStatus() == http.StatusServiceUnavailable {
+		return true
+	}
+	for _, code := range retryableErrorCodes {
+		if strings.HasPrefix(serverErr.ErrorCode(), code) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTransient checks if an error says the endpoint is unhealthy rather than
+// the request wrong: throttling, unavailability, timeouts and network errors
+func isTransient(err error) bool {
+	if IsRetryable(err) {
+		return true
+	}
+	var clientErr *sdkerrors.ClientError
+	if errors.As(err, &clientErr) && clientErr.ErrorCode() == sdkerrors.TimeoutErrorCode {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// RetryPolicy controls retries of throttled API calls
+type RetryPolicy struct {
+	Retries   int           // retries after the first attempt, 0 disables
+	BaseDelay time.Duration // delay before the first retry, doubled for each next one
+	MaxDelay  time.Duration // upper bound of a single delay
+}
+
+// delay returns the jittered backoff before the given 1-based retry
+func (p RetryPolicy) delay(retry int) time.Duration {
+	d := p.BaseDelay << (retry - 1)
+	if d <= 0 || (p.MaxDelay > 0 && d > p.MaxDelay) {
+		d = p.MaxDelay
+	}
+	// Full jitter between half and the whole delay spreads out clients throttled together
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// BreakerState is the circuit breaker state of a region
+type BreakerState struct {
+	Region    string
+	Failures  int       // consecutive transient failures
+	Open      bool      // calls are being skipped
+	OpenUntil time.Time // when a trial call is let through
+	Trips     int       // times the breaker opened since startup
+	Retries   int       // throttled calls retried since startup
+}
+
+// Resilience retries throttled API calls with exponential backoff and trips
+// a per-region circuit breaker after repeated transient failures, so a
+// struggling region is skipped for a while instead of slowing every check
+type Resilience struct {
+	policy    RetryPolicy
+	threshold int           // consecutive failures that open a breaker, 0 disables
+	cooldown  time.Duration // how long an open breaker skips calls
+
+	regions map[string]*BreakerState
+	mu      sync.Mutex
+
+	// Latency and result codes of every call attempt
+	stats *apiStats
+}
+
+// NewResilience creates the retry and circuit breaker layer
+func NewResilience(policy RetryPolicy, threshold int, cooldown time.Duration) *Resilience {
+	return &Resilience{
+		policy:    policy,
+		threshold: threshold,
+		cooldown:  cooldown,
+		regions:   make(map[string]*BreakerState),
+		stats:     newAPIStats(),
+	}
+}
+
+// call runs an API call through the breaker of region with retries.
+// An empty region retries without circuit breaking, for global APIs.
+// A nil Resilience calls fn once.
+func (r *Resilience) call(ctx context.Context, region, api string, fn func() error) error {
+	if r == nil {
+		return fn()
+	}
+	if err := r.allow(region); err != nil {
+		return err
+	}
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+		err = fn()
+		latency := time.Since(start)
+		r.stats.record(api, region, latency, err)
+		log.Debugf("Aliyun API %s in %s: %s in %s", api, regionLabel(region), resultCode(err), latency.Round(time.Millisecond))
+
+		if err == nil || !IsRetryable(err) || attempt >= r.policy.Retries {
+			break
+		}
+
+		delay := r.policy.delay(attempt + 1)
+		log.Debugf("%s throttled in %s (%s), retry %d/%d in %s", api, regionLabel(region), ErrorCode(err), attempt+1, r.policy.Retries, delay.Round(time.Millisecond))
+		r.countRetry(region)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+
+	r.record(region, err)
+	return err
+}
+
+// allow returns ErrCircuitOpen while the breaker of region is open
+// Once the cooldown passes, calls are let through again as trials.
+func (r *Resilience) allow(region string) error {
+	if region == "" || r.threshold <= 0 {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state, ok := r.regions[region]
+	if !ok || !state.Open || time.Now().After(state.OpenUntil) {
+		return nil
+	}
+	return fmt.Errorf("%w for region %s until %s", ErrCircuitOpen, region, state.OpenUntil.Format("15:04:05"))
+}
+
+// record updates the breaker of region with the outcome of a call
+func (r *Resilience) record(region string, err error) {
+	if region == "" || r.threshold <= 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state := r.state(region)
+
+	if err == nil || !isTransient(err) {
+		if state.Open {
+			log.Infof("Circuit breaker for region %s closed, API calls succeed again", region)
+		}
+		state.Failures = 0
+		state.Open = false
+		return
+	}
+
+	state.Failures++
+	if state.Open {
+		// The trial call failed, keep skipping the region
+		state.OpenUntil = time.Now().Add(r.cooldown)
+		log.Warnf("Circuit breaker for region %s stays open until %s: %v", region, state.OpenUntil.Format("15:04:05"), err)
+		return
+	}
+	if state.Failures >= r.threshold {
+		state.Open = true
+		state.OpenUntil = time.Now().Add(r.cooldown)
+		state.Trips++
+		log.Warnf("Circuit breaker for region %s opened after %d consecutive failures, skipping it until %s: %v",
+			region, state.Failures, state.OpenUntil.Format("15:04:05"), err)
+	}
+}
+
+// countRetry counts a retried call of region
+func (r *Resilience) countRetry(region string) {
+	if region == "" {
+		return
+	}
+	r.mu.Lock()
+	r.state(region).Retries++
+	r.mu.Unlock()
+}
+
+// state returns the breaker of region, creating it if needed
+// The caller must hold r.mu.
+func (r *Resilience) state(region string) *BreakerState {
+	state, ok := r.regions[region]
+	if !ok {
+		state = &BreakerState{Region: region}
+		r.regions[region] = state
+	}
+	return state
+}
+
+// States returns the breakers of regions with failures, retries or trips, sorted by region
+func (r *Resilience) States() []BreakerState {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	states := make([]BreakerState, 0, len(r.regions))
+	for _, state := range r.regions {
+		if state.Failures > 0 || state.Retries > 0 || state.Trips > 0 {
+			states = append(states, *state)
+		}
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Region < states[j].Region })
+	return states
+}
+
+// APIStats returns the recorded SDK calls per API and region
+func (r *Resilience) APIStats() []APICallStats {
+	if r == nil {
+		return nil
+	}
+	return r.stats.snapshot()
+}
+
+// regionLabel returns the region for log messages of global APIs too
+func regionLabel(region string) string {
+	if region == "" {
+		return "global endpoint"
+	}
+	return region
+}
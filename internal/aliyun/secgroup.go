@@ -0,0 +1,105 @@
+package aliyun
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/iliyian/aliyun-spot-manager/internal/tracing"
+)
+
+// SecurityGroupRule is an inbound rule of a security group
+type SecurityGroupRule struct {
+	SecurityGroupID string
+	Protocol        string // tcp, udp, icmp, gre or all
+	PortFrom        int    // -1 for all ports
+	PortTo          int
+	Source          string // CIDR block, security group or prefix list
+	Accept          bool   // false for a drop rule
+	Priority        int    // 1 is the highest
+}
+
+// Covers reports whether the rule applies to traffic to a port over a protocol
+func (r SecurityGroupRule) Covers(protocol string, port int) bool {
+	if r.Protocol != "all" && r.Protocol != protocol {
+		return false
+	}
+	return r.PortFrom == -1 || (port >= r.PortFrom && port <= r.PortTo)
+}
+
+// FromAnywhere reports whether the rule applies to traffic from any address
+func (r SecurityGroupRule) FromAnywhere() bool {
+	return r.Source == "0.0.0.0/0" || r.Source == "::/0"
+}
+
+// DescribeSecurityGroupRules returns the inbound rules of security groups
+func (c *ECSClient) DescribeSecurityGroupRules(ctx context.Context, regionID string, groupIDs []string) (rules []SecurityGroupRule, err error) {
+	ctx, span := tracing.Start(ctx, "ecs.DescribeSecurityGroupRules")
+	defer func() { tracing.End(span, err) }()
+
+	client, err := c.getClient(regionID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, groupID := range groupIDs {
+		request := ecs.CreateDescribeSecurityGroupAttributeRequest()
+		request.Scheme = "https"
+		request.RegionId = regionID
+		request.SecurityGroupId = groupID
+		request.Direction = "ingress"
+
+		var response *ecs.DescribeSecurityGroupAttributeResponse
+		err = c.resilience.call(ctx, regionID, "DescribeSecurityGroupAttribute", func() (err error) {
+			response, err = client.DescribeSecurityGroupAttribute(request)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get rules of security group %s: %w", groupID, err)
+		}
+
+		for _, p := range response.Permissions.Permission {
+			if p.Direction != "" && p.Direction != "ingress" {
+				continue
+			}
+			rules = append(rules, newSecurityGroupRule(groupID, p))
+		}
+	}
+	return rules, nil
+}
+
+// newSecurityGroupRule converts a security group permission to a rule
+func newSecurityGroupRule(groupID string, p ecs.Permission) SecurityGroupRule {
+	rule := SecurityGroupRule{
+		SecurityGroupID: groupID,
+		Protocol:        strings.ToLower(p.IpProtocol),
+		PortFrom:        -1,
+		PortTo:          -1,
+		Accept:          !strings.EqualFold(p.Policy, "drop"),
+		Priority:        1,
+	}
+	// PortRange is like 22/22, 1/65535 or -1/-1
+	if from, to, ok := strings.Cut(p.PortRange, "/"); ok {
+		portFrom, errFrom := strconv.Atoi(from)
+		portTo, errTo := strconv.Atoi(to)
+		if errFrom == nil && errTo == nil && portFrom > 0 {
+			rule.PortFrom, rule.PortTo = portFrom, portTo
+		}
+	}
+	if priority, err := strconv.Atoi(p.Priority); err == nil {
+		rule.Priority = priority
+	}
+	switch {
+	case p.SourceCidrIp != "":
+		rule.Source = p.SourceCidrIp
+	case p.Ipv6SourceCidrIp != "":
+		rule.Source = p.Ipv6SourceCidrIp
+	case p.SourceGroupId != "":
+		rule.Source = p.SourceGroupId
+	default:
+		rule.Source = p.SourcePrefixListId
+	}
+	return rule
+}
@@ -0,0 +1,172 @@
+package aliyun
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/iliyian/aliyun-spot-manager/internal/tracing"
+)
+
+// TagSnapshotOf is set on the snapshots the manager creates to the ID of the instance they were taken from
+const TagSnapshotOf = "spot-manager:snapshot-of"
+
+// Snapshot is a disk snapshot of an instance
+type Snapshot struct {
+	SnapshotID   string
+	Name         string
+	DiskID       string
+	DiskType     string // system or data
+	InstanceID   string
+	Status       string // progressing, accomplished or failed
+	Progress     string // e.g. "42%"
+	CreationTime time.Time
+	Managed      bool // taken by the manager, tagged with TagSnapshotOf
+}
+
+// CreateInstanceSnapshots snapshots every disk of an instance, tagging the
+// snapshots with TagSnapshotOf. Snapshots finish in the background. The
+// snapshots of the other disks are returned along with the last disk error.
+func (c *ECSClient) CreateInstanceSnapshots(ctx context.Context, regionID, instanceID, reason string) (snapshots []*Snapshot, err error) {
+	ctx, span := tracing.Start(ctx, "ecs.CreateInstanceSnapshots", tracing.Instance(regionID, instanceID)...)
+	defer func() { tracing.End(span, err) }()
+
+	client, err := c.getClient(regionID)
+	if err != nil {
+		return nil, err
+	}
+
+	disks := ecs.CreateDescribeDisksRequest()
+	disks.Scheme = "https"
+	disks.RegionId = regionID
+	disks.InstanceId = instanceID
+
+	var disksResponse *ecs.DescribeDisksResponse
+	err = c.resilience.call(ctx, regionID, "DescribeDisks", func() (err error) {
+		disksResponse, err = client.DescribeDisks(disks)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get disks of instance %s: %w", instanceID, err)
+	}
+
+	now := time.Now()
+	var lastErr error
+	for _, disk := range disksResponse.Disks.Disk {
+		request := ecs.CreateCreateSnapshotRequest()
+		request.Scheme = "https"
+		request.RegionId = regionID
+		request.DiskId = disk.DiskId
+		request.SnapshotName = fmt.Sprintf("spot-%s-%s-%s", reason, disk.Type, now.Format("20060102-150405"))
+		request.Description = fmt.Sprintf("Created by aliyun-spot-manager (%s) from instance %s", reason, instanceID)
+		request.Tag = &[]ecs.CreateSnapshotTag{{Key: TagSnapshotOf, Value: instanceID}}
+
+		var response *ecs.CreateSnapshotResponse
+		err := c.resilience.call(ctx, regionID, "CreateSnapshot", func() (err error) {
+			response, err = client.CreateSnapshot(request)
+			return err
+		})
+		if err != nil {
+			lastErr = fmt.Errorf("failed to snapshot disk %s: %w", disk.DiskId, err)
+			continue
+		}
+		snapshots = append(snapshots, &Snapshot{
+			SnapshotID:   response.SnapshotId,
+			Name:         request.SnapshotName,
+			DiskID:       disk.DiskId,
+			DiskType:     disk.Type,
+			InstanceID:   instanceID,
+			Status:       "progressing",
+			Progress:     "0%",
+			CreationTime: now,
+			Managed:      true,
+		})
+	}
+	return snapshots, lastErr
+}
+
+// DescribeSnapshots returns the snapshots of the disks of an instance, newest
+// first, including those not taken by the manager
+func (c *ECSClient) DescribeSnapshots(ctx context.Context, regionID, instanceID string) (snapshots []*Snapshot, err error) {
+	ctx, span := tracing.Start(ctx, "ecs.DescribeSnapshots", tracing.Instance(regionID, instanceID)...)
+	defer func() { tracing.End(span, err) }()
+
+	client, err := c.getClient(regionID)
+	if err != nil {
+		return nil, err
+	}
+
+	for page := 1; ; page++ {
+		request := ecs.CreateDescribeSnapshotsRequest()
+		request.Scheme = "https"
+		request.RegionId = regionID
+		request.InstanceId = instanceID
+		request.PageNumber = requests.NewInteger(page)
+		request.PageSize = requests.NewInteger(100)
+
+		var response *ecs.DescribeSnapshotsResponse
+		err = c.resilience.call(ctx, regionID, "DescribeSnapshots", func() (err error) {
+			response, err = client.DescribeSnapshots(request)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list snapshots of instance %s: %w", instanceID, err)
+		}
+
+		for _, s := range response.Snapshots.Snapshot {
+			created, _ := time.Parse(time.RFC3339, s.CreationTime)
+			managed := false
+			for _, tag := range s.Tags.Tag {
+				if tag.TagKey == TagSnapshotOf {
+					managed = true
+				}
+			}
+			snapshots = append(snapshots, &Snapshot{
+				SnapshotID:   s.SnapshotId,
+				Name:         s.SnapshotName,
+				DiskID:       s.SourceDiskId,
+				DiskType:     s.SourceDiskType,
+				InstanceID:   instanceID,
+				Status:       s.Status,
+				Progress:     s.Progress,
+				CreationTime: created,
+				Managed:      managed,
+			})
+		}
+		if len(response.Snapshots.Snapshot) == 0 || page*100 >= response.TotalCount {
+			break
+		}
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreationTime.After(snapshots[j].CreationTime)
+	})
+	return snapshots, nil
+}
+
+// DeleteSnapshot deletes a snapshot
+func (c *ECSClient) DeleteSnapshot(ctx context.Context, regionID, snapshotID string) (err error) {
+	ctx, span := tracing.Start(ctx, "ecs.DeleteSnapshot")
+	defer func() { tracing.End(span, err) }()
+
+	client, err := c.getClient(regionID)
+	if err != nil {
+		return err
+	}
+
+	request := ecs.CreateDeleteSnapshotRequest()
+	request.Scheme = "https"
+	request.SnapshotId = snapshotID
+
+	err = c.resilience.call(ctx, regionID, "DeleteSnapshot", func() (err error) {
+		_, err = client.DeleteSnapshot(request)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete snapshot %s: %w", snapshotID, err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package aliyun
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/iliyian/aliyun-spot-manager/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// SpotPriceHistoryDays is how far back DescribeSpotPriceHistory reaches
+const SpotPriceHistoryDays = 30
+
+// maxSpotPricePages bounds the pages read of one price history
+const maxSpotPricePages = 20
+
+// SpotPrice is the hourly spot price of an instance type in a zone from a point in time
+type SpotPrice struct {
+	ZoneID       string
+	InstanceType string
+	Time         time.Time
+	Price        float64
+	OriginPrice  float64 // pay-as-you-go price
+}
+
+// GetSpotPriceHistory returns the spot price changes of an instance type in
+// all zones of a region since start, oldest first. osType is linux or windows.
+func (c *ECSClient) GetSpotPriceHistory(ctx context.Context, regionID, instanceType, osType string, start time.Time) (prices []SpotPrice, err error) {
+	ctx, span := tracing.Start(ctx, "ecs.GetSpotPriceHistory",
+		attribute.String("aliyun.region", regionID), attribute.String("aliyun.instance_type", instanceType))
+	defer func() { tracing.End(span, err) }()
+
+	client, err := c.getClient(regionID)
+	if err != nil {
+		return nil, err
+	}
+	if osType == "" {
+		osType = "linux"
+	}
+
+	offset := 0
+	for page := 0; page < maxSpotPricePages; page++ {
+		request := ecs.CreateDescribeSpotPriceHistoryRequest()
+		request.Scheme = "https"
+		request.RegionId = regionID
+		request.NetworkType = "vpc"
+		request.InstanceType = instanceType
+		request.OSType = osType
+		request.StartTime = start.UTC().Format("2006-01-02T15:04:05Z")
+		if offset > 0 {
+			request.Offset = requests.NewInteger(offset)
+		}
+
+		var response *ecs.DescribeSpotPriceHistoryResponse
+		err = c.resilience.call(ctx, regionID, "DescribeSpotPriceHistory", func() (err error) {
+			response, err = client.DescribeSpotPriceHistory(request)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query spot price history of %s in %s: %w", instanceType, regionID, err)
+		}
+
+		for _, point := range response.SpotPrices.SpotPriceType {
+			t, err := time.Parse(time.RFC3339, point.Timestamp)
+			if err != nil {
+				continue
+			}
+			prices = append(prices, SpotPrice{
+				ZoneID:       point.ZoneId,
+				InstanceType: point.InstanceType,
+				Time:         t,
+				Price:        point.SpotPrice,
+				OriginPrice:  point.OriginPrice,
+			})
+		}
+		if response.NextOffset <= offset || len(response.SpotPrices.SpotPriceType) == 0 {
+			break
+		}
+		offset = response.NextOffset
+	}
+
+	sort.SliceStable(prices, func(i, j int) bool { return prices[i].Time.Before(prices[j].Time) })
+	return prices, nil
+}
@@ -1,34 +1,70 @@
 package aliyun
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aliyun/alibaba-cloud-sdk-go/sdk"
 	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/responses"
 	log "github.com/sirupsen/logrus"
 )
 
 // TrafficClient wraps the Aliyun CDT client for traffic queries
 type TrafficClient struct {
-	client *sdk.Client
+	client    *sdk.Client
+	transport *Transport
+	mu        sync.RWMutex
+
+	// Retries throttled calls, nil calls once
+	resilience *Resilience
 }
 
 // NewTrafficClient creates a new CDT traffic client
-func NewTrafficClient(accessKeyID, accessKeySecret string) (*TrafficClient, error) {
+func NewTrafficClient(creds Credentials, transport *Transport) (*TrafficClient, error) {
 	// CDT API uses cn-hangzhou as the default region
-	client, err := sdk.NewClientWithAccessKey("cn-hangzhou", accessKeyID, accessKeySecret)
+	client, err := sdk.NewClientWithOptions("cn-hangzhou", sdk.NewConfig(), creds.credential())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create CDT client: %w", err)
 	}
+	transport.apply(client)
 
 	return &TrafficClient{
-		client: client,
+		client:    client,
+		transport: transport,
 	}, nil
 }
 
+// UpdateCredentials re-creates the CDT client with new credentials
+func (c *TrafficClient) UpdateCredentials(creds Credentials) error {
+	client, err := sdk.NewClientWithOptions("cn-hangzhou", sdk.NewConfig(), creds.credential())
+	if err != nil {
+		return fmt.Errorf("failed to create CDT client: %w", err)
+	}
+	c.transport.apply(client)
+
+	c.mu.Lock()
+	c.client = client
+	c.mu.Unlock()
+	return nil
+}
+
+// SetResilience sets the retry layer of API calls
+func (c *TrafficClient) SetResilience(r *Resilience) {
+	c.resilience = r
+}
+
+// getClient returns the current CDT client
+func (c *TrafficClient) getClient() *sdk.Client {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.client
+}
+
 // ProductTrafficDetail represents traffic detail for a specific product
 type ProductTrafficDetail struct {
 	Product string `json:"Product"` // eip, ipv6bandwidth, cbwp, etc.
@@ -57,11 +93,15 @@ type TrafficSummary struct {
 	StartTime          time.Time
 	EndTime            time.Time
 	BillingCycle       string // YYYY-MM
+	PeriodLabel        string // 非整月查询时的区间说明 (今天、昨天等)
 	ChinaMainland      TrafficRegionSummary
 	NonChinaMainland   TrafficRegionSummary
 	TotalTraffic       int64
 	TotalTrafficGB     float64
 	RegionDetails      []RegionTrafficDetail
+	ForecastMethod     string // 月末预测方式，空为未预测
+	Region             string // 只统计该区域时的区域 ID
+	Groups             []TrafficGroupSummary // 自定义区域分组，未配置时为空
 }
 
 // TrafficRegionSummary represents traffic summary for a region group
@@ -71,6 +111,9 @@ type TrafficRegionSummary struct {
 	Regions        []string
 	RegionCount    int
 	ProductDetails map[string]int64 // product -> traffic in bytes
+	QuotaGB        float64          // CDT 免费额度，0 为未设置
+	ForecastGB     float64          // 月末预计流量
+	PricePerGB     float64          // 超出免费额度后的单价 (元/GB)
 }
 
 // CDT API response structure
@@ -113,16 +156,16 @@ func IsChinaMainlandRegion(regionId string) bool {
 }
 
 // QueryInternetTraffic queries internet traffic for the current month
-func (c *TrafficClient) QueryInternetTraffic() (*TrafficSummary, error) {
+func (c *TrafficClient) QueryInternetTraffic(ctx context.Context) (*TrafficSummary, error) {
 	now := time.Now()
 	startTime := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
 	endTime := now
 
-	return c.QueryInternetTrafficByTimeRange(startTime, endTime)
+	return c.QueryInternetTrafficByTimeRange(ctx, startTime, endTime)
 }
 
 // QueryInternetTrafficByTimeRange queries internet traffic for a specific time range
-func (c *TrafficClient) QueryInternetTrafficByTimeRange(startTime, endTime time.Time) (*TrafficSummary, error) {
+func (c *TrafficClient) QueryInternetTrafficByTimeRange(ctx context.Context, startTime, endTime time.Time) (*TrafficSummary, error) {
 	request := requests.NewCommonRequest()
 	request.Method = "POST"
 	request.Scheme = "https"
@@ -130,12 +173,16 @@ func (c *TrafficClient) QueryInternetTrafficByTimeRange(startTime, endTime time.
 	request.Version = "2021-08-13"
 	request.ApiName = "ListCdtInternetTraffic"
 
-	request.QueryParams["StartTime"] = startTime.Format("2006-01-02T15:04:05Z")
-	request.QueryParams["EndTime"] = endTime.Format("2006-01-02T15:04:05Z")
+	request.QueryParams["StartTime"] = startTime.UTC().Format("2006-01-02T15:04:05Z")
+	request.QueryParams["EndTime"] = endTime.UTC().Format("2006-01-02T15:04:05Z")
 
 	log.Debugf("Querying CDT traffic from %s to %s", startTime.Format("2006-01-02"), endTime.Format("2006-01-02"))
 
-	response, err := c.client.ProcessCommonRequest(request)
+	var response *responses.CommonResponse
+	err := c.resilience.call(ctx, "", "ListCdtInternetTraffic", func() (err error) {
+		response, err = c.getClient().ProcessCommonRequest(request)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to query CDT traffic: %w", err)
 	}
@@ -149,12 +196,23 @@ func (c *TrafficClient) QueryInternetTrafficByTimeRange(startTime, endTime time.
 		return nil, fmt.Errorf("failed to parse CDT response: %w", err)
 	}
 
-	// Build summary
+	summary := summarizeTraffic(startTime, endTime, cdtResponse.TrafficDetails)
+
+	log.Infof("Traffic summary: Total=%.2f GB, China Mainland=%.2f GB (%d regions), Non-China=%.2f GB (%d regions)",
+		summary.TotalTrafficGB,
+		summary.ChinaMainland.TrafficGB, summary.ChinaMainland.RegionCount,
+		summary.NonChinaMainland.TrafficGB, summary.NonChinaMainland.RegionCount)
+
+	return summary, nil
+}
+
+// summarizeTraffic groups region traffic details into a summary
+func summarizeTraffic(startTime, endTime time.Time, details []RegionTrafficDetail) *TrafficSummary {
 	summary := &TrafficSummary{
 		StartTime:     startTime,
 		EndTime:       endTime,
 		BillingCycle:  startTime.Format("2006-01"),
-		RegionDetails: cdtResponse.TrafficDetails,ChinaMainland: TrafficRegionSummary{
+		RegionDetails: details,ChinaMainland: TrafficRegionSummary{
 			ProductDetails: make(map[string]int64),
 		},
 		NonChinaMainland: TrafficRegionSummary{
@@ -163,7 +221,7 @@ func (c *TrafficClient) QueryInternetTrafficByTimeRange(startTime, endTime time.
 	}
 
 	// Categorize traffic by region
-	for _, detail := range cdtResponse.TrafficDetails {
+	for _, detail := range details {
 		summary.TotalTraffic += detail.Traffic
 
 		if IsChinaMainlandRegion(detail.BusinessRegionId) {
@@ -188,12 +246,7 @@ func (c *TrafficClient) QueryInternetTrafficByTimeRange(startTime, endTime time.
 	summary.ChinaMainland.TrafficGB = float64(summary.ChinaMainland.Traffic) / (1024 * 1024 * 1024)
 	summary.NonChinaMainland.TrafficGB = float64(summary.NonChinaMainland.Traffic) / (1024 * 1024 * 1024)
 
-	log.Infof("Traffic summary: Total=%.2f GB, China Mainland=%.2f GB (%d regions), Non-China=%.2f GB (%d regions)",
-		summary.TotalTrafficGB,
-		summary.ChinaMainland.TrafficGB, summary.ChinaMainland.RegionCount,
-		summary.NonChinaMainland.TrafficGB, summary.NonChinaMainland.RegionCount)
-
-	return summary, nil
+	return summary
 }
 
 // FormatTrafficSize formats traffic size in human-readable format
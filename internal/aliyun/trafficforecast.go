@@ -0,0 +1,62 @@
+package aliyun
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Methods of calculating the month-end traffic forecast
+const (
+	TrafficForecastElapsed  = "elapsed"  // month-to-date traffic per elapsed day × days in month
+	TrafficForecastTrailing = "trailing" // month to date + the trailing 7-day daily average for the remaining days
+)
+
+// trailingForecastDays is the window of the trailing forecast
+const trailingForecastDays = 7
+
+// OverageGB returns the forecast traffic beyond the free quota
+func (r TrafficRegionSummary) OverageGB() float64 {
+	if r.ForecastGB <= r.QuotaGB {
+		return 0
+	}
+	return r.ForecastGB - r.QuotaGB
+}
+
+// OverageCost returns the projected cost of the forecast overage
+func (r TrafficRegionSummary) OverageCost() float64 {
+	return r.OverageGB() * r.PricePerGB
+}
+
+// ApplyForecast projects the month-end traffic of each region group of a
+// month-to-date summary
+func (c *TrafficClient) ApplyForecast(ctx context.Context, summary *TrafficSummary, method string) error {
+	return applyForecast(ctx, summary, method, c.QueryInternetTrafficByTimeRange)
+}
+
+// applyForecast projects the month-end traffic of a summary, querying the
+// trailing window with queryRange for the trailing method
+func applyForecast(ctx context.Context, summary *TrafficSummary, method string, queryRange func(ctx context.Context, startTime, endTime time.Time) (*TrafficSummary, error)) error {
+	monthEnd := summary.StartTime.AddDate(0, 1, 0)
+	elapsedDays := summary.EndTime.Sub(summary.StartTime).Hours() / 24
+	remainingDays := monthEnd.Sub(summary.EndTime).Hours() / 24
+	if elapsedDays <= 0 || remainingDays < 0 {
+		return fmt.Errorf("traffic summary %s is not a month-to-date summary", summary.BillingCycle)
+	}
+
+	switch method {
+	case TrafficForecastTrailing:
+		trailing, err := queryRange(ctx, summary.EndTime.Add(-trailingForecastDays*24*time.Hour), summary.EndTime)
+		if err != nil {
+			return err
+		}
+		summary.ChinaMainland.ForecastGB = summary.ChinaMainland.TrafficGB + trailing.ChinaMainland.TrafficGB/trailingForecastDays*remainingDays
+		summary.NonChinaMainland.ForecastGB = summary.NonChinaMainland.TrafficGB + trailing.NonChinaMainland.TrafficGB/trailingForecastDays*remainingDays
+	default:
+		days := elapsedDays + remainingDays
+		summary.ChinaMainland.ForecastGB = summary.ChinaMainland.TrafficGB / elapsedDays * days
+		summary.NonChinaMainland.ForecastGB = summary.NonChinaMainland.TrafficGB / elapsedDays * days
+	}
+	summary.ForecastMethod = method
+	return nil
+}
@@ -0,0 +1,56 @@
+package aliyun
+
+import "sort"
+
+// TrafficGroupSummary is the traffic of a user-defined region group
+type TrafficGroupSummary struct {
+	Name string
+	TrafficRegionSummary
+}
+
+// otherTrafficGroup collects regions not listed in any user-defined group
+const otherTrafficGroup = "其他"
+
+// GroupBy summarizes traffic per user-defined region group (name -> region IDs)
+// Regions not listed in any group are collected in "其他". Groups are sorted by
+// traffic, largest first.
+func (s *TrafficSummary) GroupBy(groups map[string][]string) {
+	groupOf := make(map[string]string)
+	for name, regions := range groups {
+		for _, region := range regions {
+			groupOf[region] = name
+		}
+	}
+
+	byName := make(map[string]*TrafficGroupSummary)
+	for _, detail := range s.RegionDetails {
+		name, ok := groupOf[detail.BusinessRegionId]
+		if !ok {
+			name = otherTrafficGroup
+		}
+		group, ok := byName[name]
+		if !ok {
+			group = &TrafficGroupSummary{Name: name}
+			group.ProductDetails = make(map[string]int64)
+			byName[name] = group
+		}
+		group.Traffic += detail.Traffic
+		group.Regions = append(group.Regions, detail.BusinessRegionId)
+		group.RegionCount++
+		for _, pd := range detail.ProductTrafficDetails {
+			group.ProductDetails[pd.Product] += pd.Traffic
+		}
+	}
+
+	s.Groups = make([]TrafficGroupSummary, 0, len(byName))
+	for _, group := range byName {
+		group.TrafficGB = float64(group.Traffic) / (1024 * 1024 * 1024)
+		s.Groups = append(s.Groups, *group)
+	}
+	sort.Slice(s.Groups, func(i, j int) bool {
+		if s.Groups[i].Traffic != s.Groups[j].Traffic {
+			return s.Groups[i].Traffic > s.Groups[j].Traffic
+		}
+		return s.Groups[i].Name < s.Groups[j].Name
+	})
+}
@@ -0,0 +1,28 @@
+package aliyun
+
+import "regexp"
+
+// regionIDPattern matches a region ID such as "cn-hongkong" or "ap-southeast-1"
+var regionIDPattern = regexp.MustCompile(`^[a-z]{2}-[a-z]+(-[a-z0-9]+)*$`)
+
+// IsRegionID reports whether s looks like a region ID
+func IsRegionID(s string) bool {
+	return regionIDPattern.MatchString(s)
+}
+
+// FilterRegion returns the summary of a single region
+// Free quotas and forecasts apply to whole region groups and are not carried over.
+func (s *TrafficSummary) FilterRegion(regionID string) *TrafficSummary {
+	var details []RegionTrafficDetail
+	for _, detail := range s.RegionDetails {
+		if detail.BusinessRegionId == regionID {
+			details = append(details, detail)
+		}
+	}
+
+	filtered := summarizeTraffic(s.StartTime, s.EndTime, details)
+	filtered.BillingCycle = s.BillingCycle
+	filtered.PeriodLabel = s.PeriodLabel
+	filtered.Region = regionID
+	return filtered
+}
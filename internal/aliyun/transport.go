@@ -0,0 +1,100 @@
+package aliyun
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk"
+)
+
+// HTTPOptions holds tuning options for the HTTP transport used by SDK clients
+type HTTPOptions struct {
+	ConnectTimeout      time.Duration
+	ReadTimeout         time.Duration
+	KeepAlive           time.Duration
+	IdleConnTimeout     time.Duration
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	TLSMinVersion       string // 1.0, 1.1, 1.2 or 1.3
+}
+
+// Transport is an HTTP transport shared by all SDK clients, so that
+// connections are reused across regional ECS clients, BSS and CDT
+type Transport struct {
+	opts HTTPOptions
+	rt   *http.Transport
+}
+
+// NewTransport creates a shared transport from the given options
+func NewTransport(opts HTTPOptions) (*Transport, error) {
+	tlsVersion, err := parseTLSVersion(opts.TLSMinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   opts.ConnectTimeout,
+		KeepAlive: opts.KeepAlive,
+	}
+
+	return &Transport{
+		opts: opts,
+		rt: &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			DialContext:         dialer.DialContext,
+			ForceAttemptHTTP2:   true,
+			MaxIdleConns:        opts.MaxIdleConns,
+			MaxIdleConnsPerHost: opts.MaxIdleConnsPerHost,
+			IdleConnTimeout:     opts.IdleConnTimeout,
+			TLSHandshakeTimeout: opts.ConnectTimeout,
+			TLSClientConfig: &tls.Config{
+				MinVersion: tlsVersion,
+			},
+		},
+	}, nil
+}
+
+// RoundTrip implements http.RoundTripper
+// The SDK mutates *http.Transport values it is given on every request, so the
+// underlying transport is hidden behind this wrapper to keep it safe to share
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.rt.RoundTrip(req)
+}
+
+// CloseIdleConnections closes all idle keep-alive connections
+func (t *Transport) CloseIdleConnections() {
+	t.rt.CloseIdleConnections()
+}
+
+// apply configures an SDK client to use the shared transport and timeouts
+func (t *Transport) apply(client *sdk.Client) {
+	if t == nil {
+		return
+	}
+	client.SetTransport(t)
+	if t.opts.ConnectTimeout > 0 {
+		client.SetConnectTimeout(t.opts.ConnectTimeout)
+	}
+	if t.opts.ReadTimeout > 0 {
+		client.SetReadTimeout(t.opts.ReadTimeout)
+	}
+}
+
+// parseTLSVersion converts a version string like "1.2" to a tls constant
+func parseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2", "":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS version: %s", version)
+	}
+}
@@ -0,0 +1,105 @@
+package aliyun
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// MaxBillingHistoryMonths caps the billing cycles fetched by QueryBillingHistory
+const MaxBillingHistoryMonths = 12
+
+// InstanceBillingTrend is the spend of one instance across billing cycles
+type InstanceBillingTrend struct {
+	InstanceID   string
+	InstanceName string
+	RunRate      float64   // 本月按当前速度的月度估算
+	Previous     []float64 // 之前各月实际扣费，与 BillingTrend.Previous 一一对应
+}
+
+// Change returns the run rate change against the previous month
+// ok is false when the instance had no spend last month
+func (t InstanceBillingTrend) Change() (amount, percent float64, ok bool) {
+	if len(t.Previous) == 0 || t.Previous[0] <= 0 {
+		return t.RunRate, 0, false
+	}
+	amount = t.RunRate - t.Previous[0]
+	return amount, amount / t.Previous[0] * 100, true
+}
+
+// BillingTrend compares this month's run rate against previous billing cycles
+type BillingTrend struct {
+	Current   *BillingSummary   // 本月
+	Previous  []*BillingSummary // 之前各月，最近的在前
+	Instances []InstanceBillingTrend
+}
+
+// Change returns the total run rate change against the previous month
+func (t *BillingTrend) Change() (amount, percent float64, ok bool) {
+	total := InstanceBillingTrend{RunRate: t.Current.MonthlyEstimate}
+	for _, summary := range t.Previous {
+		total.Previous = append(total.Previous, summary.TotalAmount)
+	}
+	return total.Change()
+}
+
+// QueryBillingHistory queries the current and previous billing cycles, nMonths in total,
+// newest first
+func (c *BillingClient) QueryBillingHistory(ctx context.Context, instances []InstanceInfo, nMonths int) ([]*BillingSummary, error) {
+	if nMonths < 1 || nMonths > MaxBillingHistoryMonths {
+		return nil, fmt.Errorf("billing history must cover 1 to %d months, got %d", MaxBillingHistoryMonths, nMonths)
+	}
+
+	now := time.Now()
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	history := make([]*BillingSummary, 0, nMonths)
+	for i := 0; i < nMonths; i++ {
+		summary, err := c.QueryBilling(ctx, instances, thisMonth.AddDate(0, -i, 0).Format("2006-01"))
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, summary)
+	}
+
+	log.Infof("Queried billing history for %d months", nMonths)
+	return history, nil
+}
+
+// NewBillingTrend builds the per-instance trend from QueryBillingHistory results
+func NewBillingTrend(history []*BillingSummary) *BillingTrend {
+	trend := &BillingTrend{Current: history[0], Previous: history[1:]}
+
+	byID := make(map[string]*InstanceBillingTrend)
+	get := func(id, name string) *InstanceBillingTrend {
+		t, ok := byID[id]
+		if !ok {
+			t = &InstanceBillingTrend{InstanceID: id, InstanceName: name, Previous: make([]float64, len(trend.Previous))}
+			byID[id] = t
+		}
+		return t
+	}
+
+	for _, inst := range trend.Current.Instances {
+		get(inst.InstanceID, inst.InstanceName).RunRate = inst.MonthlyEstimate
+	}
+	for i, summary := range trend.Previous {
+		for _, inst := range summary.Instances {
+			get(inst.InstanceID, inst.InstanceName).Previous[i] = inst.TotalAmount
+		}
+	}
+
+	for _, t := range byID {
+		trend.Instances = append(trend.Instances, *t)
+	}
+	sort.Slice(trend.Instances, func(i, j int) bool {
+		if trend.Instances[i].RunRate != trend.Instances[j].RunRate {
+			return trend.Instances[i].RunRate > trend.Instances[j].RunRate
+		}
+		return trend.Instances[i].InstanceName < trend.Instances[j].InstanceName
+	})
+	return trend
+}
@@ -0,0 +1,193 @@
+'use strict';
+
+const tokenKey = 'aliyun-spot-manager-token';
+
+const eventNames = {
+  reclaimed: '🔴 被回收',
+  start_attempt: '🔄 尝试启动',
+  started: '✅ 已启动',
+  start_failed: '❌ 启动失败',
+  ip_changed: '🌐 IP 变更',
+  unhealthy: '🩺 健康检查失败',
+  stuck: '⏳ 状态卡住',
+  manual_start: '▶️ 手动启动',
+  manual_stop: '⏹ 手动停止',
+  muted: '🔇 静音',
+  unmuted: '🔔 取消静音',
+  traffic_cap: '🚧 流量超限',
+  interruption: '⚠️ 即将中断',
+  no_capacity: '📦 库存不足',
+  recreated: '♻️ 已重建',
+  price_limit: '📈 接近出价上限',
+  rebid: '💹 已提高出价',
+};
+
+function $(id) {
+  return document.getElementById(id);
+}
+
+function escapeHTML(value) {
+  const div = document.createElement('div');
+  div.textContent = value == null ? '' : String(value);
+  return div.innerHTML;
+}
+
+async function api(method, path) {
+  const resp = await fetch(path, {
+    method,
+    headers: { Authorization: 'Bearer ' + localStorage.getItem(tokenKey) },
+  });
+  if (resp.status === 401) {
+    showLogin();
+    throw new Error('API Token 无效');
+  }
+  const body = await resp.json();
+  if (!resp.ok) {
+    throw new Error(body.error || resp.statusText);
+  }
+  return body;
+}
+
+function showError(err) {
+  $('error').textContent = err ? err.message : '';
+  $('error').hidden = !err;
+}
+
+function showLogin() {
+  localStorage.removeItem(tokenKey);
+  $('main').hidden = true;
+  $('login').hidden = false;
+}
+
+function renderInstances(instances) {
+  $('instances').innerHTML = instances.map((inst) => {
+    const addresses = [inst.PublicIPAddress, inst.IPv6Address].filter(Boolean).join(', ') || '-';
+    return `<tr>
+      <td>${escapeHTML(inst.InstanceName)}<br><code>${escapeHTML(inst.InstanceID)}</code></td>
+      <td>${escapeHTML(inst.RegionID)}</td>
+      <td>${escapeHTML(addresses)}</td>
+      <td class="status-${escapeHTML(inst.Status)}">${escapeHTML(inst.Status)}${inst.Muted ? ' 🔇' : ''}</td>
+      <td>
+        <button data-id="${escapeHTML(inst.InstanceID)}" data-action="start" ${inst.Status === 'Stopped' ? '' : 'disabled'}>启动</button>
+        <button data-id="${escapeHTML(inst.InstanceID)}" data-action="stop" ${inst.Status === 'Running' ? '' : 'disabled'}>停止</button>
+        <button data-id="${escapeHTML(inst.InstanceID)}" data-action="${inst.Muted ? 'unmute' : 'mute'}">${inst.Muted ? '取消静音' : '静音'}</button>
+      </td>
+    </tr>`;
+  }).join('') || '<tr><td colspan="5">暂无监控的实例</td></tr>';
+}
+
+function renderEvents(events) {
+  $('events').innerHTML = events.map((event) => `<tr>
+      <td>${escapeHTML(new Date(event.Time).toLocaleString())}</td>
+      <td>${escapeHTML(event.InstanceName)}</td>
+      <td>${escapeHTML(eventNames[event.Type] || event.Type)}</td>
+      <td>${escapeHTML(event.Message)}</td>
+    </tr>`).join('') || '<tr><td colspan="4">暂无事件</td></tr>';
+}
+
+async function loadInstances() {
+  renderInstances(await api('GET', '/api/instances?live=true'));
+}
+
+async function loadEvents() {
+  renderEvents(await api('GET', '/api/events?limit=20'));
+}
+
+async function loadBilling() {
+  try {
+    const summary = await api('GET', '/api/billing');
+    $('billing-total').textContent = '¥' + summary.TotalAmount.toFixed(2);
+    $('billing-estimate').textContent = '月度估算 ¥' + summary.MonthlyEstimate.toFixed(2);
+  } catch (err) {
+    $('billing-total').textContent = '-';
+    $('billing-estimate').textContent = err.message;
+  }
+}
+
+async function loadTraffic() {
+  try {
+    const summary = await api('GET', '/api/traffic');
+    $('traffic-total').textContent = summary.TotalTrafficGB.toFixed(2) + ' GB';
+    $('traffic-split').textContent = '中国大陆 ' + summary.ChinaMainland.TrafficGB.toFixed(2) +
+      ' GB | 非中国大陆 ' + summary.NonChinaMainland.TrafficGB.toFixed(2) + ' GB';
+  } catch (err) {
+    $('traffic-total').textContent = '-';
+    $('traffic-split').textContent = err.message;
+  }
+}
+
+async function refresh() {
+  showError(null);
+  try {
+    await Promise.all([loadInstances(), loadEvents()]);
+  } catch (err) {
+    showError(err);
+  }
+}
+
+function start() {
+  $('login').hidden = true;
+  $('main').hidden = false;
+  refresh();
+  loadBilling();
+  loadTraffic();
+}
+
+$('login-form').addEventListener('submit', (e) => {
+  e.preventDefault();
+  localStorage.setItem(tokenKey, $('token').value);
+  start();
+});
+
+$('refresh').addEventListener('click', () => {
+  refresh();
+  loadBilling();
+  loadTraffic();
+});
+
+$('discover').addEventListener('click', async (e) => {
+  e.target.disabled = true;
+  showError(null);
+  try {
+    await api('POST', '/api/discover');
+    await refresh();
+  } catch (err) {
+    showError(err);
+  } finally {
+    e.target.disabled = false;
+  }
+});
+
+$('logout').addEventListener('click', showLogin);
+
+$('instances').addEventListener('click', async (e) => {
+  const button = e.target.closest('button[data-action]');
+  if (!button) {
+    return;
+  }
+  const { id, action } = button.dataset;
+  if (action === 'stop' && !confirm('确定停止实例 ' + id + '？停止后将不会自动启动，直到再次手动启动。')) {
+    return;
+  }
+  button.disabled = true;
+  showError(null);
+  try {
+    await api('POST', `/api/instances/${encodeURIComponent(id)}/${action}`);
+    await refresh();
+  } catch (err) {
+    showError(err);
+    button.disabled = false;
+  }
+});
+
+if (localStorage.getItem(tokenKey)) {
+  start();
+} else {
+  showLogin();
+}
+
+setInterval(() => {
+  if (!$('main').hidden) {
+    refresh();
+  }
+}, 30000);
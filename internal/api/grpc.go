@@ -0,0 +1,267 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/iliyian/aliyun-spot-manager/api/spotmanagerpb"
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	"github.com/iliyian/aliyun-spot-manager/internal/monitor"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// GRPCServer is a token-authenticated gRPC server exposing the same control
+// operations as the HTTP API, see api/spotmanagerpb
+type GRPCServer struct {
+	spotmanagerpb.UnimplementedSpotManagerServer
+
+	backend Backend
+	token   string
+	addr    string
+	srv     *grpc.Server
+}
+
+// NewGRPCServer creates a gRPC server listening on addr
+func NewGRPCServer(addr, token string, backend Backend) *GRPCServer {
+	s := &GRPCServer{
+		backend: backend,
+		token:   token,
+		addr:    addr,
+	}
+	s.srv = grpc.NewServer(grpc.UnaryInterceptor(s.authenticate))
+	spotmanagerpb.RegisterSpotManagerServer(s.srv, s)
+	return s
+}
+
+// Start starts serving in a goroutine
+func (s *GRPCServer) Start() {
+	go func() {
+		log.Infof("Starting gRPC server on %s", s.addr)
+		listener, err := net.Listen("tcp", s.addr)
+		if err != nil {
+			log.Errorf("gRPC server failed: %v", err)
+			return
+		}
+		if err := s.srv.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			log.Errorf("gRPC server failed: %v", err)
+		}
+	}()
+}
+
+// Shutdown waits for running calls to finish, cancelling them once ctx is done
+func (s *GRPCServer) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.srv.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.srv.Stop()
+		return ctx.Err()
+	}
+}
+
+// authenticate requires "authorization: Bearer <token>" or "x-api-token: <token>" metadata
+func (s *GRPCServer) authenticate(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var token string
+	if values := md.Get("x-api-token"); len(values) > 0 {
+		token = values[0]
+	}
+	if values := md.Get("authorization"); len(values) > 0 && strings.HasPrefix(values[0], "Bearer ") {
+		token = strings.TrimPrefix(values[0], "Bearer ")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+		return nil, status.Error(codes.Unauthenticated, "invalid or missing API token")
+	}
+	return handler(ctx, req)
+}
+
+// ListInstances returns the tracked instances
+func (s *GRPCServer) ListInstances(ctx context.Context, req *spotmanagerpb.ListInstancesRequest) (*spotmanagerpb.ListInstancesResponse, error) {
+	return s.instanceList(s.backend.Instances(req.Live)), nil
+}
+
+// StartInstance starts a stopped instance in the background
+func (s *GRPCServer) StartInstance(ctx context.Context, req *spotmanagerpb.InstanceRequest) (*spotmanagerpb.InstanceActionResponse, error) {
+	if err := s.backend.StartInstanceByID(req.InstanceId); err != nil {
+		return nil, grpcError(err)
+	}
+	return &spotmanagerpb.InstanceActionResponse{InstanceId: req.InstanceId, Status: instanceActions["start"]}, nil
+}
+
+// StopInstance stops a running instance in the background
+func (s *GRPCServer) StopInstance(ctx context.Context, req *spotmanagerpb.InstanceRequest) (*spotmanagerpb.InstanceActionResponse, error) {
+	if err := s.backend.StopInstanceByID(req.InstanceId); err != nil {
+		return nil, grpcError(err)
+	}
+	return &spotmanagerpb.InstanceActionResponse{InstanceId: req.InstanceId, Status: instanceActions["stop"]}, nil
+}
+
+// SetMuted mutes or unmutes the notifications of an instance
+func (s *GRPCServer) SetMuted(ctx context.Context, req *spotmanagerpb.SetMutedRequest) (*spotmanagerpb.InstanceActionResponse, error) {
+	if err := s.backend.SetMuted(req.InstanceId, req.Muted); err != nil {
+		return nil, grpcError(err)
+	}
+	action := "unmute"
+	if req.Muted {
+		action = "mute"
+	}
+	return &spotmanagerpb.InstanceActionResponse{InstanceId: req.InstanceId, Status: instanceActions[action]}, nil
+}
+
+// DiscoverInstances re-discovers the spot instances and returns them
+func (s *GRPCServer) DiscoverInstances(ctx context.Context, req *spotmanagerpb.DiscoverInstancesRequest) (*spotmanagerpb.ListInstancesResponse, error) {
+	if err := s.backend.DiscoverInstances(); err != nil {
+		return nil, grpcError(err)
+	}
+	return s.instanceList(s.backend.Instances(false)), nil
+}
+
+// GetBilling returns the billing summary of a time range
+func (s *GRPCServer) GetBilling(ctx context.Context, req *spotmanagerpb.GetBillingRequest) (*spotmanagerpb.BillingSummary, error) {
+	summary, err := s.backend.QueryBilling(req.Range)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return billingMessage(summary), nil
+}
+
+// GetTraffic returns the CDT traffic summary of a time range
+func (s *GRPCServer) GetTraffic(ctx context.Context, req *spotmanagerpb.GetTrafficRequest) (*spotmanagerpb.TrafficSummary, error) {
+	summary, err := s.backend.QueryTraffic(req.Range)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return trafficMessage(summary), nil
+}
+
+// grpcError maps monitor errors to gRPC status codes, like writeBackendError
+func grpcError(err error) error {
+	switch {
+	case errors.Is(err, monitor.ErrInstanceNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, monitor.ErrInvalidTimeRange):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, monitor.ErrInstanceNotStopped), errors.Is(err, monitor.ErrInstanceNotRunning), errors.Is(err, monitor.ErrInstanceBusy):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, monitor.ErrShuttingDown):
+		return status.Error(codes.Unavailable, err.Error())
+	default:
+		log.Warnf("gRPC request failed: %v", err)
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// instanceList converts instances, adding the mute state
+func (s *GRPCServer) instanceList(instances []*aliyun.SpotInstance) *spotmanagerpb.ListInstancesResponse {
+	resp := &spotmanagerpb.ListInstancesResponse{Instances: make([]*spotmanagerpb.Instance, len(instances))}
+	for i, inst := range instances {
+		resp.Instances[i] = &spotmanagerpb.Instance{
+			InstanceId:       inst.InstanceID,
+			InstanceName:     inst.InstanceName,
+			RegionId:         inst.RegionID,
+			ZoneId:           inst.ZoneID,
+			InstanceType:     inst.InstanceType,
+			Status:           inst.Status,
+			PublicIpAddress:  inst.PublicIPAddress,
+			PrivateIpAddress: inst.PrivateIPAddress,
+			Ipv6Address:      inst.IPv6Address,
+			SpotStrategy:     inst.SpotStrategy,
+			SpotPriceLimit:   inst.SpotPriceLimit,
+			Tags:             inst.Tags,
+			Muted:            s.backend.IsMuted(inst.InstanceID),
+		}
+		if !inst.CreationTime.IsZero() {
+			resp.Instances[i].CreationTime = timestamppb.New(inst.CreationTime)
+		}
+	}
+	return resp
+}
+
+// billingMessage converts a billing summary
+func billingMessage(summary *aliyun.BillingSummary) *spotmanagerpb.BillingSummary {
+	msg := &spotmanagerpb.BillingSummary{
+		StartTime:         timestamppb.New(summary.StartTime),
+		EndTime:           timestamppb.New(summary.EndTime),
+		BillingCycle:      summary.BillingCycle,
+		PeriodLabel:       summary.PeriodLabel,
+		Closed:            summary.Closed,
+		TotalRunningHours: summary.TotalRunningHours,
+		InstanceAmount:    summary.InstanceAmount,
+		OtherAmount:       summary.OtherAmount,
+		TotalAmount:       summary.TotalAmount,
+		MonthlyEstimate:   summary.MonthlyEstimate,
+		OnDemandAmount:    summary.OnDemandAmount,
+		SavedAmount:       summary.SavedAmount,
+	}
+	for _, inst := range summary.Instances {
+		msg.Instances = append(msg.Instances, &spotmanagerpb.InstanceBilling{
+			InstanceId:      inst.InstanceID,
+			InstanceName:    inst.InstanceName,
+			Region:          inst.Region,
+			InstanceSpec:    inst.InstanceSpec,
+			Group:           inst.Group,
+			TotalAmount:     inst.TotalAmount,
+			RunningHours:    inst.RunningHours,
+			HourlyCost:      inst.HourlyCost,
+			MonthlyEstimate: inst.MonthlyEstimate,
+			ComputeAmount:   inst.ComputeAmount,
+			OnDemandAmount:  inst.OnDemandAmount,
+		})
+	}
+	for _, product := range summary.Products {
+		msg.Products = append(msg.Products, &spotmanagerpb.ProductBilling{
+			ProductCode: product.ProductCode,
+			ProductName: product.ProductName,
+			Resources:   int32(product.Resources),
+			TotalAmount: product.TotalAmount,
+		})
+	}
+	return msg
+}
+
+// trafficMessage converts a traffic summary
+func trafficMessage(summary *aliyun.TrafficSummary) *spotmanagerpb.TrafficSummary {
+	msg := &spotmanagerpb.TrafficSummary{
+		StartTime:         timestamppb.New(summary.StartTime),
+		EndTime:           timestamppb.New(summary.EndTime),
+		BillingCycle:      summary.BillingCycle,
+		PeriodLabel:       summary.PeriodLabel,
+		ChinaMainland:     trafficRegionMessage(&summary.ChinaMainland),
+		NonChinaMainland:  trafficRegionMessage(&summary.NonChinaMainland),
+		TotalTrafficBytes: summary.TotalTraffic,
+		TotalTrafficGb:    summary.TotalTrafficGB,
+	}
+	for i := range summary.Groups {
+		msg.Groups = append(msg.Groups, &spotmanagerpb.TrafficGroup{
+			Name:    summary.Groups[i].Name,
+			Traffic: trafficRegionMessage(&summary.Groups[i].TrafficRegionSummary),
+		})
+	}
+	return msg
+}
+
+// trafficRegionMessage converts the traffic of a region group
+func trafficRegionMessage(region *aliyun.TrafficRegionSummary) *spotmanagerpb.TrafficRegion {
+	return &spotmanagerpb.TrafficRegion{
+		TrafficBytes: region.Traffic,
+		TrafficGb:    region.TrafficGB,
+		Regions:      region.Regions,
+		QuotaGb:      region.QuotaGB,
+		ForecastGb:   region.ForecastGB,
+		PricePerGb:   region.PricePerGB,
+	}
+}
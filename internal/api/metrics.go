@@ -0,0 +1,67 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+)
+
+// handleMetrics handles GET /metrics in the Prometheus text format
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writeAPIMetrics(w, s.backend.APIStats())
+}
+
+// writeAPIMetrics writes the Aliyun API call counters and latency histograms
+func writeAPIMetrics(w io.Writer, stats []aliyun.APICallStats) {
+	fmt.Fprintln(w, "# HELP aliyun_api_calls_total Aliyun API call attempts by result code.")
+	fmt.Fprintln(w, "# TYPE aliyun_api_calls_total counter")
+	for _, st := range stats {
+		codes := make([]string, 0, len(st.Codes))
+		for code := range st.Codes {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		for _, code := range codes {
+			fmt.Fprintf(w, "aliyun_api_calls_total{%s,code=%q} %d\n", apiLabels(st), code, st.Codes[code])
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP aliyun_api_throttled_total Aliyun API call attempts rejected by throttling.")
+	fmt.Fprintln(w, "# TYPE aliyun_api_throttled_total counter")
+	for _, st := range stats {
+		fmt.Fprintf(w, "aliyun_api_throttled_total{%s} %d\n", apiLabels(st), st.Throttled)
+	}
+
+	fmt.Fprintln(w, "# HELP aliyun_api_latency_seconds Latency of Aliyun API call attempts.")
+	fmt.Fprintln(w, "# TYPE aliyun_api_latency_seconds histogram")
+	for _, st := range stats {
+		labels := apiLabels(st)
+		cumulative := 0
+		for i, bound := range aliyun.LatencyBuckets {
+			cumulative += st.Buckets[i]
+			fmt.Fprintf(w, "aliyun_api_latency_seconds_bucket{%s,le=\"%g\"} %d\n", labels, bound.Seconds(), cumulative)
+		}
+		fmt.Fprintf(w, "aliyun_api_latency_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, st.Calls)
+		fmt.Fprintf(w, "aliyun_api_latency_seconds_sum{%s} %g\n", labels, st.Total.Seconds())
+		fmt.Fprintf(w, "aliyun_api_latency_seconds_count{%s} %d\n", labels, st.Calls)
+	}
+}
+
+// apiLabels returns the api and region labels of a series
+func apiLabels(st aliyun.APICallStats) string {
+	region := st.Region
+	if region == "" {
+		region = "global"
+	}
+	return fmt.Sprintf("api=%q,region=%q", st.API, strings.ToLower(region))
+}
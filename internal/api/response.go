@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	"github.com/iliyian/aliyun-spot-manager/internal/monitor"
+	log "github.com/sirupsen/logrus"
+)
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Warnf("Failed to write API response: %v", err)
+	}
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// writeEventsCSV writes events as a CSV download
+func writeEventsCSV(w http.ResponseWriter, events []monitor.Event) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="events.csv"`)
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"time", "type", "instance_id", "instance_name", "region", "zone", "instance_type", "message"})
+	for _, event := range events {
+		cw.Write([]string{
+			event.Time.Format(time.RFC3339),
+			event.Type,
+			event.InstanceID,
+			event.InstanceName,
+			event.RegionID,
+			event.ZoneID,
+			event.InstanceType,
+			event.Message,
+		})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		log.Warnf("Failed to write CSV response: %v", err)
+	}
+}
+
+// writeBillingExport writes a billing summary as a CSV or JSON download
+func writeBillingExport(w http.ResponseWriter, summary *aliyun.BillingSummary, format string) {
+	contentType := "application/json"
+	if format == aliyun.ExportCSV {
+		contentType = "text/csv; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, summary.ExportFilename(format)))
+	w.WriteHeader(http.StatusOK)
+
+	if err := summary.Export(w, format); err != nil {
+		log.Warnf("Failed to write billing export: %v", err)
+	}
+}
+
+// writeBackendError maps monitor errors to HTTP status codes
+func writeBackendError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, monitor.ErrInstanceNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, monitor.ErrInvalidTimeRange):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, monitor.ErrInstanceNotStopped), errors.Is(err, monitor.ErrInstanceNotRunning), errors.Is(err, monitor.ErrInstanceBusy):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, monitor.ErrShuttingDown):
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+	default:
+		log.Warnf("API request failed: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,434 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/agent"
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	"github.com/iliyian/aliyun-spot-manager/internal/monitor"
+	log "github.com/sirupsen/logrus"
+)
+
+// Backend is the part of the monitor driven by the API
+type Backend interface {
+	Instances(live bool) []*aliyun.SpotInstance
+	StartInstanceByID(instanceID string) error
+	StopInstanceByID(instanceID string) error
+	SetMuted(instanceID string, muted bool) error
+	IsMuted(instanceID string) bool
+	EventHistory(instance string, limit int) []monitor.Event
+	DiscoverInstances() error
+	QueryBilling(rangeExpr string) (*aliyun.BillingSummary, error)
+	QueryTraffic(rangeExpr string) (*aliyun.TrafficSummary, error)
+	APIStats() []aliyun.APICallStats
+	Ready() error
+	AgentHeartbeat(instanceID string, terminationTime time.Time) error
+	AgentHeartbeats() map[string]monitor.AgentHeartbeat
+	HandleCloudEvent(event *aliyun.CloudEvent) error
+	LaunchConfig(instanceID string) (*aliyun.LaunchConfig, error)
+	MaintenanceMode() monitor.MaintenanceMode
+	SetMaintenanceMode(enabled bool, duration time.Duration) error
+}
+
+// instanceActions maps instance actions to the status reported on success
+var instanceActions = map[string]string{
+	"start":  "starting",
+	"stop":   "stopping",
+	"mute":   "muted",
+	"unmute": "unmuted",
+}
+
+// instanceView is a tracked instance as returned by the API
+type instanceView struct {
+	*aliyun.SpotInstance
+	Muted bool
+	Agent *monitor.AgentHeartbeat `json:",omitempty"`
+}
+
+// Server is a token-authenticated HTTP server exposing status and control endpoints
+type Server struct {
+	backend     Backend
+	token       string
+	agentSecret string
+	srv         *http.Server
+}
+
+// NewServer creates an API server listening on addr
+// With dashboard set, the web dashboard is served at "/"; with cloudEvents
+// set, ECS events pushed by EventBridge or CloudMonitor are accepted. Agent
+// heartbeats are accepted with per-instance tokens derived from agentSecret,
+// not with the API token, and not at all without a secret.
+func NewServer(addr, token, agentSecret string, dashboard, cloudEvents bool, backend Backend) *Server {
+	s := &Server{
+		backend:     backend,
+		token:       token,
+		agentSecret: agentSecret,
+	}
+
+	api := http.NewServeMux()
+	api.HandleFunc("/api/instances", s.handleInstances)
+	api.HandleFunc("/api/instances/", s.handleInstanceAction)
+	api.HandleFunc("/api/events", s.handleEvents)
+	api.HandleFunc("/api/discover", s.handleDiscover)
+	api.HandleFunc("/api/billing", s.handleBilling)
+	api.HandleFunc("/api/traffic", s.handleTraffic)
+	api.HandleFunc("/api/maintenance", s.handleMaintenance)
+
+	// The dashboard assets are public, the page asks for the token itself
+	// Probes are public too so container orchestrators can reach them
+	mux := http.NewServeMux()
+	mux.Handle("/api/", s.authenticate(api))
+	mux.HandleFunc("/api/agent/heartbeat", s.handleAgentHeartbeat)
+	mux.Handle("/metrics", s.authenticate(http.HandlerFunc(s.handleMetrics)))
+	if cloudEvents {
+		mux.Handle("/api/cloud-events", allowQueryToken(s.authenticate(http.HandlerFunc(s.handleCloudEvent))))
+	}
+	mux.HandleFunc("/healthz", s.handleHealthz)
+	mux.HandleFunc("/readyz", s.handleReadyz)
+	if dashboard {
+		mux.Handle("/", dashboardHandler())
+	}
+
+	s.srv = &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s
+}
+
+// Start starts serving in a goroutine
+func (s *Server) Start() {
+	go func() {
+		log.Infof("Starting API server on %s", s.srv.Addr)
+		if err := s.srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("API server failed: %v", err)
+		}
+	}()
+}
+
+// Shutdown gracefully stops the server
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.srv.Shutdown(ctx)
+}
+
+// authenticate requires "Authorization: Bearer <token>" or "X-API-Token: <token>"
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("X-API-Token")
+		if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			token = strings.TrimPrefix(auth, "Bearer ")
+		}
+
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "invalid or missing API token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowQueryToken accepts the token as "?token=" for senders that can't set
+// headers, like CloudMonitor webhooks
+func allowQueryToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token := r.URL.Query().Get("token"); token != "" && r.Header.Get("Authorization") == "" {
+			r.Header.Set("X-API-Token", token)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleHealthz handles GET /healthz, answering as long as the process serves requests
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleReadyz handles GET /readyz, answering 503 until the monitor is ready
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if err := s.backend.Ready(); err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{
+			"status": "unavailable",
+			"reason": err.Error(),
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleInstances handles GET /api/instances[?live=true]
+func (s *Server) handleInstances(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	live := r.URL.Query().Get("live") == "true" || r.URL.Query().Get("live") == "1"
+	writeJSON(w, http.StatusOK, s.instanceViews(s.backend.Instances(live)))
+}
+
+// instanceViews adds the mute state to instances
+func (s *Server) instanceViews(instances []*aliyun.SpotInstance) []instanceView {
+	heartbeats := s.backend.AgentHeartbeats()
+	views := make([]instanceView, len(instances))
+	for i, inst := range instances {
+		views[i] = instanceView{SpotInstance: inst, Muted: s.backend.IsMuted(inst.InstanceID)}
+		if heartbeat, ok := heartbeats[inst.InstanceID]; ok {
+			views[i].Agent = &heartbeat
+		}
+	}
+	return views
+}
+
+// handleInstanceAction handles POST /api/instances/{id}/{start,stop,mute,unmute}
+func (s *Server) handleInstanceAction(w http.ResponseWriter, r *http.Request) {
+	instanceID, action, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/instances/"), "/")
+	if ok && instanceID != "" && action == "launch-config" {
+		s.handleLaunchConfig(w, r, instanceID)
+		return
+	}
+	status, known := instanceActions[action]
+	if !ok || instanceID == "" || !known {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var err error
+	switch action {
+	case "start":
+		err = s.backend.StartInstanceByID(instanceID)
+	case "stop":
+		err = s.backend.StopInstanceByID(instanceID)
+	case "mute", "unmute":
+		err = s.backend.SetMuted(instanceID, action == "mute")
+	}
+	if err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{
+		"instance_id": instanceID,
+		"status":      status,
+	})
+}
+
+// handleLaunchConfig returns the captured launch configuration of an instance
+func (s *Server) handleLaunchConfig(w http.ResponseWriter, r *http.Request, instanceID string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	cfg, err := s.backend.LaunchConfig(instanceID)
+	if err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	if cfg == nil {
+		writeError(w, http.StatusNotFound, "no launch configuration captured")
+		return
+	}
+	writeJSON(w, http.StatusOK, cfg)
+}
+
+// handleEvents handles GET /api/events[?instance=i-xxx&limit=50&format=csv]
+// A limit of 0 returns the whole history
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	limit := 50
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	events := s.backend.EventHistory(query.Get("instance"), limit)
+	switch query.Get("format") {
+	case "", "json":
+		writeJSON(w, http.StatusOK, events)
+	case "csv":
+		writeEventsCSV(w, events)
+	default:
+		writeError(w, http.StatusBadRequest, "format must be json or csv")
+	}
+}
+
+// handleDiscover handles POST /api/discover
+func (s *Server) handleDiscover(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if err := s.backend.DiscoverInstances(); err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.instanceViews(s.backend.Instances(false)))
+}
+
+// handleBilling handles GET /api/billing[?range=today&format=csv]
+// format=csv or format=json downloads the summary as a file
+func (s *Server) handleBilling(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format != "" && format != aliyun.ExportCSV && format != aliyun.ExportJSON {
+		writeError(w, http.StatusBadRequest, "format must be json or csv")
+		return
+	}
+
+	summary, err := s.backend.QueryBilling(query.Get("range"))
+	if err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	if format == "" {
+		writeJSON(w, http.StatusOK, summary)
+		return
+	}
+	writeBillingExport(w, summary, format)
+}
+
+// handleTraffic handles GET /api/traffic[?range=today]
+func (s *Server) handleTraffic(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	summary, err := s.backend.QueryTraffic(r.URL.Query().Get("range"))
+	if err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, summary)
+}
+
+// maintenanceRequest is the body of a maintenance mode change
+type maintenanceRequest struct {
+	Enabled  bool   `json:"enabled"`
+	Duration string `json:"duration"` // e.g. "2h", empty until turned off
+}
+
+// handleMaintenance handles GET and POST /api/maintenance
+func (s *Server) handleMaintenance(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.backend.MaintenanceMode())
+		return
+	case http.MethodPost:
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req maintenanceRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<16)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid maintenance request")
+		return
+	}
+	var duration time.Duration
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid duration")
+			return
+		}
+		duration = d
+	}
+	if err := s.backend.SetMaintenanceMode(req.Enabled, duration); err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.backend.MaintenanceMode())
+}
+
+// agentHeartbeatRequest is the body of an agent heartbeat
+type agentHeartbeatRequest struct {
+	InstanceID      string    `json:"instance_id"`
+	TerminationTime time.Time `json:"termination_time"` // zero unless a reclaim is announced
+}
+
+// handleAgentHeartbeat handles POST /api/agent/heartbeat from the agent running on an instance
+// The token must be the one of the reported instance, see agent.Token.
+func (s *Server) handleAgentHeartbeat(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.agentSecret == "" {
+		writeError(w, http.StatusForbidden, "agent heartbeats are disabled, AGENT_SECRET is not set")
+		return
+	}
+
+	var req agentHeartbeatRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<16)).Decode(&req); err != nil || req.InstanceID == "" {
+		writeError(w, http.StatusBadRequest, "invalid heartbeat")
+		return
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(agent.Token(s.agentSecret, req.InstanceID))) != 1 {
+		writeError(w, http.StatusUnauthorized, "invalid or missing agent token for this instance")
+		return
+	}
+	if err := s.backend.AgentHeartbeat(req.InstanceID, req.TerminationTime); err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{
+		"instance_id": req.InstanceID,
+		"status":      "ok",
+	})
+}
+
+// handleCloudEvent handles POST /api/cloud-events from an EventBridge HTTP
+// target or a CloudMonitor event subscription webhook
+func (s *Server) handleCloudEvent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read event")
+		return
+	}
+	event, err := aliyun.ParseCloudEvent(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.backend.HandleCloudEvent(event); err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{
+		"instance_id": event.InstanceID,
+		"status":      "ok",
+	})
+}
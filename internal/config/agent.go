@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// AgentConfig holds the settings of the agent subcommand, which runs on a
+// spot instance itself and needs no Aliyun credentials
+type AgentConfig struct {
+	// Monitor API base URL heartbeats are sent to, empty to only run the script
+	ControllerURL string
+	// Heartbeat token of this instance, printed by the monitor's agent-token command
+	Token string
+	// Instance ID reported, read from the metadata service when empty
+	InstanceID string
+	// ECS metadata service base URL
+	MetadataURL string
+	// Seconds between termination-time polls
+	PollInterval int
+	// Seconds between heartbeats
+	HeartbeatInterval int
+	// Shell command run once when a reclaim is announced
+	ShutdownScript string
+	// Seconds the shutdown script may run
+	ShutdownTimeout int
+
+	// Logging, the same settings as the monitor's
+	LogLevel      string
+	LogFormat     string
+	LogFile       string
+	LogFileLevel  string
+	LogFileFormat string
+}
+
+// LoadAgent loads the agent configuration from environment variables
+func LoadAgent() (*AgentConfig, error) {
+	cfg := &AgentConfig{
+		ControllerURL:     strings.TrimRight(os.Getenv("AGENT_CONTROLLER_URL"), "/"),
+		Token:             os.Getenv("AGENT_TOKEN"),
+		InstanceID:        os.Getenv("AGENT_INSTANCE_ID"),
+		MetadataURL:       strings.TrimRight(getEnvString("AGENT_METADATA_URL", "http://100.100.100.200/latest/meta-data"), "/"),
+		PollInterval:      getEnvInt("AGENT_POLL_INTERVAL", 5),
+		HeartbeatInterval: getEnvInt("AGENT_HEARTBEAT_INTERVAL", 30),
+		ShutdownScript:    os.Getenv("AGENT_SHUTDOWN_SCRIPT"),
+		ShutdownTimeout:   getEnvInt("AGENT_SHUTDOWN_TIMEOUT", 240),
+
+		LogLevel:      getEnvString("LOG_LEVEL", "info"),
+		LogFormat:     getEnvString("LOG_FORMAT", "text"),
+		LogFile:       os.Getenv("LOG_FILE"),
+		LogFileLevel:  os.Getenv("LOG_FILE_LEVEL"),
+		LogFileFormat: getEnvString("LOG_FILE_FORMAT", "json"),
+	}
+
+	if cfg.ControllerURL == "" && cfg.ShutdownScript == "" {
+		return nil, fmt.Errorf("AGENT_CONTROLLER_URL or AGENT_SHUTDOWN_SCRIPT is required")
+	}
+	if cfg.ControllerURL != "" && !strings.HasPrefix(cfg.ControllerURL, "http://") && !strings.HasPrefix(cfg.ControllerURL, "https://") {
+		return nil, fmt.Errorf("AGENT_CONTROLLER_URL must be an http:// or https:// URL")
+	}
+	if cfg.PollInterval < 1 {
+		cfg.PollInterval = 1
+	}
+	if cfg.HeartbeatInterval < cfg.PollInterval {
+		cfg.HeartbeatInterval = cfg.PollInterval
+	}
+	if cfg.ShutdownTimeout < 1 {
+		cfg.ShutdownTimeout = 240
+	}
+
+	return cfg, nil
+}
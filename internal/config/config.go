@@ -3,7 +3,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"regexp"
 	"strconv"
+	"time"
 )
 
 // Config holds all configuration for the application
@@ -12,30 +14,292 @@ type Config struct {
 	AliyunAccessKeyID     string
 	AliyunAccessKeySecret string
 
+	// Role credentials instead of using the AccessKey directly
+	AliyunRoleArn             string // RAM role assumed with the AccessKey via STS
+	AliyunRoleSessionName     string
+	AliyunRoleSessionDuration int    // seconds
+	AliyunEcsRamRole          string // RAM role of the ECS instance running the monitor, no AccessKey needed
+
+	// Credential rotation
+	CredentialsFile          string // file re-read for rotated AccessKeys
+	CredentialsWatchInterval int    // seconds, 0 disables watching
+
+	// Simulate mode, in-memory fake instances instead of the Aliyun API
+	Simulate                bool
+	SimulateReclaimInterval int     // average seconds between simulated reclaims, 0 disables
+	SimulateInstances       int     // demo instances
+	SimulateStartFailure    float64 // share of starts failing for lack of stock, 0 to 1
+	SimulateReclaimWarning  int     // seconds a simulated reclaim is announced ahead, 0 for none
+
 	// Telegram settings
 	TelegramEnabled  bool
 	TelegramBotToken string
 	TelegramChatID   string
 
 	// Check settings
-	CheckInterval int    // seconds
-	CronSchedule  string // cron expression
+	CheckInterval             int    // seconds
+	CronSchedule              string // cron expression
+	CheckConcurrency          int    // instances checked in parallel
+	CheckConcurrencyPerRegion int    // instances of one region checked in parallel, 0 for no limit
+
+	// Alert when no check completed within WatchdogMultiplier check intervals,
+	// 0 disables. WatchdogRestart cancels the stalled check and exits the
+	// process if that doesn't help.
+	WatchdogMultiplier int
+	WatchdogRestart    bool
+
+	// Region discovery
+	Regions                string // comma-separated region allowlist, empty scans all regions
+	RediscoveryInterval    int    // seconds between re-discoveries, 0 disables
+	DiscoveryConcurrency   int    // regions scanned in parallel
+	RegionFailureThreshold int    // consecutive failures before blacklisting, 0 disables
+	RegionBlacklistHours   int
+
+	// Instance selection, empty values match every instance
+	InstanceIDs         string // comma-separated allowlist
+	ExcludeInstanceIDs  string // comma-separated denylist
+	InstanceNamePattern string // regular expression the instance name must match
+	InstanceTags        string // comma-separated "key=value" or "key", all required
+	ExcludeInstanceTags string // comma-separated "key=value" or "key", any excludes
+
+	// ECS tag whose value names the group of an instance in reports, e.g. "env"
+	// The "group" field of the config file takes precedence.
+	GroupTag string
+
+	// Maintenance event polling
+	MaintenanceCheckInterval  int // seconds, 0 disables
+	InterruptionCheckInterval int // seconds between polls for imminent reclaims and reboots, 0 disables
+
+	// How long instance details are reused between API calls
+	InstanceCacheTTL int // seconds, 0 disables
+
+	// Compare the public address of running instances every check and notify changes
+	IPChangeCheckEnabled bool
 
 	// Retry settings
 	RetryCount    int
 	RetryInterval int // seconds
 
+	// How long a started instance may take to reach Running, and how often it is polled meanwhile
+	StartTimeout      int // seconds
+	StartPollInterval int // seconds
+
+	// Seconds to collect starts of a region into one StartInstances call, 0 starts each on its own
+	StartBatchWindow int
+
+	// Seconds a start waits for the instances it depends on (depends_on in the config file)
+	DependencyTimeout int
+
+	// Checks an instance whose start keeps failing are skipped, doubling from
+	// StartBackoffBase up to StartBackoffMax, until giving up for StartGiveUpHours
+	StartBackoffBase int // seconds, 0 retries every check
+	StartBackoffMax  int // seconds
+	StartGiveUpAfter int // consecutive failed checks, 0 never gives up
+	StartGiveUpHours int
+
+	// Seconds between start attempts while the zone has no capacity, 0 retries as usual
+	CapacityRetryInterval int
+
+	// Re-create an instance in another zone after this many start attempts
+	// without capacity, 0 disables
+	RecreateAfter   int
+	RecreateZones   string // comma-separated zones to re-create in, in order of preference
+	RecreateMoveEIP bool   // move the EIP of the old instance to the new one
+
+	// Capture the launch configuration of each instance into the state store,
+	// re-creation uses it when the instance can no longer be described
+	LaunchConfigCapture bool
+	LaunchTemplateSync  bool // also save it as an ECS launch template
+
+	// Spot price advisor
+	SpotAdvisorSchedule string // cron schedule of the /advisor report, empty disables
+	SpotAdvisorTypes    string // comma-separated instance types compared besides the ones in use
+
+	// Market price checks of SpotWithPriceLimit instances
+	PriceLimitCheckInterval int     // seconds, 0 disables
+	PriceLimitAlertRatio    float64 // alert when the market price reaches this share of the limit
+
+	// CPU credit checks of burstable (t5/t6) instances
+	CPUCreditCheckInterval  int     // seconds, 0 disables
+	CPUCreditAlertThreshold float64 // alert when the balance drops to this many credits
+
+	// Show CloudMonitor CPU, memory and disk utilization in /status
+	StatusMetrics bool
+
+	// Aliyun API throttling retries and per-region circuit breaker
+	APIRetryCount           int // retries of a throttled call, 0 disables
+	APIRetryMaxDelay        int // seconds, upper bound of the exponential backoff
+	CircuitBreakerThreshold int // consecutive failures that skip a region, 0 disables
+	CircuitBreakerCooldown  int // seconds a region is skipped
+
+	// How long shutdown waits for in-flight instance starts before cancelling them
+	ShutdownTimeout int // seconds
+
 	// Notification settings
 	NotifyCooldown int // seconds
 
+	// Comma-separated transitions notified on top of the regular notifications:
+	// stopped, started, ip_changed, released or all
+	StateChangeNotify string
+
+	// Monthly reclaim statistics report
+	ReclaimStatsMonthly bool
+
+	// Daily "monitor alive" summary
+	HeartbeatEnabled  bool
+	HeartbeatTime     string // HH:MM in local time
+	HeartbeatSchedule string // cron expression generated from HeartbeatTime
+
+	// Dead man's switch pinged after every successful check cycle
+	HealthchecksPingURL string
+
+	// Billing summaries
+	BillingProducts string // product codes besides ecs, e.g. "eip,cdt,snapshot"
+	BillingEstimate string // monthly estimate method: hourly, days or trailing
+
+	// CDT free traffic quota alerts
+	TrafficQuotaChinaGB  float64 // China mainland quota, 0 disables
+	TrafficQuotaGlobalGB float64 // non-mainland quota, 0 disables
+	TrafficCheckInterval int     // seconds, 0 disables polling
+
+	// Month-end traffic forecast
+	TrafficForecast    string  // elapsed or trailing
+	TrafficPriceChina  float64 // CNY per GB beyond the China mainland quota
+	TrafficPriceGlobal float64 // CNY per GB beyond the non-mainland quota
+
+	// Daily traffic delta report
+	TrafficDailyReport         bool
+	TrafficDailyReportTime     string // HH:MM in local time
+	TrafficDailyReportSchedule string // cron expression generated from TrafficDailyReportTime
+
+	// Traffic hard cap enforcement
+	TrafficHardCapGB    float64 // month-to-date total traffic, 0 disables
+	TrafficCapAction    string  // stop or bandwidth
+	TrafficCapBandwidth int     // Mbps for the bandwidth action
+	TrafficCapInstances string  // comma-separated instance IDs, empty for all tracked
+
+	// Monthly budget alerts
+	MonthlyBudget       float64 // CNY, 0 disables
+	BudgetCheckInterval int     // seconds
+
+	// Daily cost anomaly alerts
+	CostAnomalyMultiplier float64 // alert when a day exceeds this multiple of the 7-day average, 0 disables
+	CostAnomalyMinAmount  float64 // CNY the day must exceed the average by
+
+	// Stuck state detection
+	StuckStateTimeout   int  // seconds in Starting/Stopping before alerting, 0 disables
+	StuckStateRemediate bool // force stop and restart stuck instances
+
+	// Start instances stopped from the console or API too, not only reclaimed ones
+	AutoStartManualStops bool
+
 	// Health check settings
-	HealthCheckEnabled  bool
-	HealthCheckTimeout  int // seconds
-	HealthCheckInterval int // seconds
+	HealthCheckEnabled      bool
+	HealthCheckTimeout      int    // seconds
+	HealthCheckInterval     int    // seconds
+	HealthChecks            string // e.g. "ping,tcp:443,http:/healthz"
+	HealthCheckPolicy       string // all, any, or number of checks required
+	HealthCheckProbeTimeout int    // seconds, per single probe
+	HealthCheckCommand      string // local command for the "cmd" check
+	HealthChecksWindows     string // checks for Windows instances, empty uses HealthChecks
+	HealthCheckRDPGrace     int    // seconds RDP must answer after a start before "rdp" passes
+
+	// Background health monitoring of running instances
+	HealthMonitorEnabled  bool
+	HealthMonitorInterval int    // seconds
+	HealthMonitorFailures int    // consecutive failures before acting
+	HealthMonitorAction   string // notify or reboot
+
+	// SSH health check settings
+	HealthCheckSSHPort       int
+	HealthCheckSSHUser       string
+	HealthCheckSSHPassword   string
+	HealthCheckSSHKey        string // private key path
+	HealthCheckSSHKnownHosts string // known_hosts path, empty skips host key verification
+	HealthCheckSSHCommand    string
+
+	// Hook concurrency (SSH/command checks, playbook commands and webhooks)
+	HookConcurrency        int // across all instances, 0 is unlimited
+	HookConcurrencyPerHost int // per target host, 0 is unlimited
+
+	// Lifecycle event hooks for reclaims, starts and failures
+	EventHookCommand string // shell command receiving the event JSON on stdin
+	EventHookURLs    string // comma-separated URLs the event JSON is POSTed to
+	EventHookEvents  string // comma-separated events to run hooks for, empty for all
+	EventHookTimeout int    // seconds per command or request
+
+	// Disk snapshots of the instances opted in through the config file
+	SnapshotSchedule  string // cron schedule of the periodic snapshots, empty disables
+	SnapshotRetention int    // snapshots kept per disk
+
+	// HTTP transport settings for Aliyun SDK clients
+	HTTPConnectTimeout      int // seconds
+	HTTPReadTimeout         int // seconds
+	HTTPKeepAlive           int // seconds
+	HTTPIdleConnTimeout     int // seconds
+	HTTPMaxIdleConns        int
+	HTTPMaxIdleConnsPerHost int
+	HTTPTLSMinVersion       string
+
+	// HTTP API
+	APIEnabled         bool
+	APIListen          string
+	APIToken           string
+	DashboardEnabled   bool   // serve the web dashboard on the API listener
+	CloudEventsEnabled bool   // accept pushed ECS events from EventBridge/CloudMonitor
+	AgentSecret        string // derives the per-instance heartbeat tokens of agents, empty to refuse heartbeats
+
+	// gRPC API, authenticated with APIToken
+	GRPCEnabled bool
+	GRPCListen  string
+
+	// Cloudflare DNS records of instances, see InstanceConfig.CloudflareRecord
+	CloudflareAPIToken string
 
 	// Logging
-	LogLevel string
-	LogFile  string
+	LogLevel      string // stdout level
+	LogFormat     string // stdout format: text or json
+	LogFile       string
+	LogFileLevel  string // defaults to LogLevel
+	LogFileFormat string // log file format: text or json
+
+	// OpenTelemetry tracing, exported via OTLP as set by OTEL_EXPORTER_OTLP_* variables
+	TracingEnabled bool
+
+	// Leader election through a Kubernetes Lease, so of several replicas
+	// only the leader checks, starts and notifies
+	LeaderElection          bool
+	LeaderElectionLease     string
+	LeaderElectionNamespace string // empty for the namespace of the pod
+	LeaderElectionDuration  int    // seconds a lease is valid without renewal
+
+	// Directory for persisted state (cooldowns, mutes, start backoffs, events)
+	DataDir string
+
+	// Redis server keeping the state instead of DataDir, shared by replicas
+	RedisURL       string // redis://[user:password@]host:port/db, rediss:// for TLS
+	RedisKeyPrefix string
+
+	// Static status page regenerated after each check, written to a local
+	// directory and/or uploaded to an OSS bucket for public hosting
+	StatusPagePath        string
+	StatusPageOSSBucket   string
+	StatusPageOSSEndpoint string // e.g. oss-cn-hangzhou.aliyuncs.com
+	StatusPageOSSPrefix   string // object key prefix, e.g. status/
+	StatusPageShowIP      bool   // include public IP addresses
+
+	// Backups of the state store and event history to an OSS bucket with
+	// versioning, restored at startup into an empty store
+	BackupOSSBucket      string
+	BackupOSSEndpoint    string // e.g. oss-cn-hangzhou-internal.aliyuncs.com
+	BackupOSSPrefix      string // object key prefix
+	BackupSchedule       string // cron schedule of the backups
+	BackupRestore        bool
+	BackupRestoreVersion string // OSS version ID to restore, empty for the latest
+
+	// Optional JSON config file for per-instance settings
+	ConfigFile string
+	File       *FileConfig
 }
 
 // Load loads configuration from environment variables
@@ -45,40 +309,415 @@ func Load() (*Config, error) {
 		AliyunAccessKeyID:     os.Getenv("ALIYUN_ACCESS_KEY_ID"),
 		AliyunAccessKeySecret: os.Getenv("ALIYUN_ACCESS_KEY_SECRET"),
 
+		// Role credentials
+		AliyunRoleArn:             os.Getenv("ALIYUN_ROLE_ARN"),
+		AliyunRoleSessionName:     getEnvString("ALIYUN_ROLE_SESSION_NAME", "aliyun-spot-manager"),
+		AliyunRoleSessionDuration: getEnvInt("ALIYUN_ROLE_SESSION_DURATION", 3600),
+		AliyunEcsRamRole:          os.Getenv("ALIYUN_ECS_RAM_ROLE"),
+
+		// Credential rotation
+		CredentialsFile:          getEnvString("CREDENTIALS_FILE", ".env"),
+		CredentialsWatchInterval: getEnvInt("CREDENTIALS_WATCH_INTERVAL", 60),
+
+		// Simulate mode
+		Simulate:                getEnvBool("SIMULATE", false),
+		SimulateReclaimInterval: getEnvInt("SIMULATE_RECLAIM_INTERVAL", 300),
+		SimulateInstances:       getEnvInt("SIMULATE_INSTANCES", 3),
+		SimulateStartFailure:    getEnvFloat("SIMULATE_START_FAILURE_RATE", 0),
+		SimulateReclaimWarning:  getEnvInt("SIMULATE_RECLAIM_WARNING", 60),
+
 		// Telegram
 		TelegramEnabled:  getEnvBool("TELEGRAM_ENABLED", true),
 		TelegramBotToken: os.Getenv("TELEGRAM_BOT_TOKEN"),
 		TelegramChatID:   os.Getenv("TELEGRAM_CHAT_ID"),
 
 		// Check settings
-		CheckInterval: getEnvInt("CHECK_INTERVAL", 60),
+		CheckInterval:             getEnvInt("CHECK_INTERVAL", 60),
+		CheckConcurrency:          getEnvInt("CHECK_CONCURRENCY", 5),
+		CheckConcurrencyPerRegion: getEnvInt("CHECK_CONCURRENCY_PER_REGION", 0),
+
+		WatchdogMultiplier: getEnvInt("WATCHDOG_MULTIPLIER", 10),
+		WatchdogRestart:    getEnvBool("WATCHDOG_RESTART", false),
+
+		// Region discovery
+		Regions:                os.Getenv("REGIONS"),
+		RediscoveryInterval:    getEnvInt("REDISCOVERY_INTERVAL", 3600),
+		DiscoveryConcurrency:   getEnvInt("DISCOVERY_CONCURRENCY", 10),
+		RegionFailureThreshold: getEnvInt("REGION_FAILURE_THRESHOLD", 3),
+		RegionBlacklistHours:   getEnvInt("REGION_BLACKLIST_HOURS", 24),
+
+		// Instance selection
+		InstanceIDs:         os.Getenv("INSTANCE_IDS"),
+		ExcludeInstanceIDs:  os.Getenv("EXCLUDE_INSTANCE_IDS"),
+		InstanceNamePattern: os.Getenv("INSTANCE_NAME_PATTERN"),
+		InstanceTags:        os.Getenv("INSTANCE_TAGS"),
+		ExcludeInstanceTags: os.Getenv("EXCLUDE_INSTANCE_TAGS"),
+		GroupTag:            os.Getenv("GROUP_TAG"),
+
+		// Maintenance event polling
+		MaintenanceCheckInterval:  getEnvInt("MAINTENANCE_CHECK_INTERVAL", 600),
+		InterruptionCheckInterval: getEnvInt("INTERRUPTION_CHECK_INTERVAL", 30),
+
+		// Instance details cache
+		InstanceCacheTTL: getEnvInt("INSTANCE_CACHE_TTL", 30),
+
+		IPChangeCheckEnabled: getEnvBool("IP_CHANGE_CHECK_ENABLED", true),
 
 		// Retry settings
 		RetryCount:    getEnvInt("RETRY_COUNT", 3),
 		RetryInterval: getEnvInt("RETRY_INTERVAL", 30),
 
+		StartTimeout:          getEnvInt("START_TIMEOUT", 120),
+		StartPollInterval:     getEnvInt("START_POLL_INTERVAL", 5),
+		StartBatchWindow:      getEnvInt("START_BATCH_WINDOW", 2),
+		DependencyTimeout:     getEnvInt("DEPENDENCY_TIMEOUT", 600),
+		StartBackoffBase:      getEnvInt("START_BACKOFF_BASE", 300),
+		StartBackoffMax:       getEnvInt("START_BACKOFF_MAX", 3600),
+		StartGiveUpAfter:      getEnvInt("START_GIVE_UP_AFTER", 6),
+		StartGiveUpHours:      getEnvInt("START_GIVE_UP_HOURS", 6),
+		CapacityRetryInterval: getEnvInt("CAPACITY_RETRY_INTERVAL", 600),
+		RecreateAfter:         getEnvInt("RECREATE_AFTER", 0),
+		RecreateZones:         os.Getenv("RECREATE_ZONES"),
+		RecreateMoveEIP:       getEnvBool("RECREATE_MOVE_EIP", true),
+		LaunchConfigCapture:   getEnvBool("LAUNCH_CONFIG_CAPTURE", false),
+		LaunchTemplateSync:    getEnvBool("LAUNCH_TEMPLATE_SYNC", false),
+		SpotAdvisorSchedule:   os.Getenv("SPOT_ADVISOR_SCHEDULE"),
+		SpotAdvisorTypes:      os.Getenv("SPOT_ADVISOR_TYPES"),
+
+		PriceLimitCheckInterval: getEnvInt("PRICE_LIMIT_CHECK_INTERVAL", 1800),
+		PriceLimitAlertRatio:    getEnvFloat("PRICE_LIMIT_ALERT_RATIO", 0.9),
+
+		CPUCreditCheckInterval:  getEnvInt("CPU_CREDIT_CHECK_INTERVAL", 600),
+		CPUCreditAlertThreshold: getEnvFloat("CPU_CREDIT_ALERT_THRESHOLD", 1),
+
+		StatusMetrics: getEnvBool("STATUS_METRICS", true),
+
+		ShutdownTimeout: getEnvInt("SHUTDOWN_TIMEOUT", 60),
+
+		APIRetryCount:           getEnvInt("API_RETRY_COUNT", 3),
+		APIRetryMaxDelay:        getEnvInt("API_RETRY_MAX_DELAY", 20),
+		CircuitBreakerThreshold: getEnvInt("CIRCUIT_BREAKER_THRESHOLD", 5),
+		CircuitBreakerCooldown:  getEnvInt("CIRCUIT_BREAKER_COOLDOWN", 300),
+
 		// Notification settings
-		NotifyCooldown: getEnvInt("NOTIFY_COOLDOWN", 300),
+		NotifyCooldown:    getEnvInt("NOTIFY_COOLDOWN", 300),
+		StateChangeNotify: os.Getenv("STATE_CHANGE_NOTIFY"),
+
+		// Reports
+		ReclaimStatsMonthly: getEnvBool("RECLAIM_STATS_MONTHLY", true),
+		HeartbeatEnabled:    getEnvBool("HEARTBEAT_ENABLED", false),
+		HeartbeatTime:       getEnvString("HEARTBEAT_TIME", "09:00"),
+		HealthchecksPingURL: os.Getenv("HEALTHCHECKS_PING_URL"),
+
+		// Billing summaries
+		BillingProducts: getEnvString("BILLING_PRODUCTS", "eip,cdt,snapshot"),
+		BillingEstimate: getEnvString("BILLING_ESTIMATE", "hourly"),
+
+		// CDT free traffic quota alerts
+		TrafficQuotaChinaGB:  getEnvFloat("TRAFFIC_QUOTA_CN_GB", 20),
+		TrafficQuotaGlobalGB: getEnvFloat("TRAFFIC_QUOTA_GLOBAL_GB", 200),
+		TrafficCheckInterval: getEnvInt("TRAFFIC_CHECK_INTERVAL", 3600),
+
+		// Month-end traffic forecast
+		TrafficForecast:    getEnvString("TRAFFIC_FORECAST", "elapsed"),
+		TrafficPriceChina:  getEnvFloat("TRAFFIC_PRICE_CN", 0.8),
+		TrafficPriceGlobal: getEnvFloat("TRAFFIC_PRICE_GLOBAL", 1.0),
+
+		// Daily traffic delta report
+		TrafficDailyReport:     getEnvBool("TRAFFIC_DAILY_REPORT", false),
+		TrafficDailyReportTime: getEnvString("TRAFFIC_DAILY_REPORT_TIME", "09:00"),
+
+		// Traffic hard cap enforcement
+		TrafficHardCapGB:    getEnvFloat("TRAFFIC_HARD_CAP_GB", 0),
+		TrafficCapAction:    getEnvString("TRAFFIC_CAP_ACTION", "stop"),
+		TrafficCapBandwidth: getEnvInt("TRAFFIC_CAP_BANDWIDTH", 1),
+		TrafficCapInstances: os.Getenv("TRAFFIC_CAP_INSTANCES"),
+
+		// Monthly budget alerts
+		MonthlyBudget:       getEnvFloat("MONTHLY_BUDGET", 0),
+		BudgetCheckInterval: getEnvInt("BUDGET_CHECK_INTERVAL", 3600),
+
+		// Daily cost anomaly alerts
+		CostAnomalyMultiplier: getEnvFloat("COST_ANOMALY_MULTIPLIER", 0),
+		CostAnomalyMinAmount:  getEnvFloat("COST_ANOMALY_MIN_AMOUNT", 1),
+
+		// Stuck state detection
+		StuckStateTimeout:   getEnvInt("STUCK_STATE_TIMEOUT", 600),
+		StuckStateRemediate: getEnvBool("STUCK_STATE_REMEDIATE", false),
+
+		AutoStartManualStops: getEnvBool("AUTO_START_MANUAL_STOPS", false),
 
 		// Health check settings
-		HealthCheckEnabled:  getEnvBool("HEALTH_CHECK_ENABLED", true),
-		HealthCheckTimeout:  getEnvInt("HEALTH_CHECK_TIMEOUT", 300),
-		HealthCheckInterval: getEnvInt("HEALTH_CHECK_INTERVAL", 10),
+		HealthCheckEnabled:      getEnvBool("HEALTH_CHECK_ENABLED", true),
+		HealthCheckTimeout:      getEnvInt("HEALTH_CHECK_TIMEOUT", 300),
+		HealthCheckInterval:     getEnvInt("HEALTH_CHECK_INTERVAL", 10),
+		HealthChecks:            getEnvString("HEALTH_CHECKS", "ping"),
+		HealthCheckPolicy:       getEnvString("HEALTH_CHECK_POLICY", "all"),
+		HealthCheckProbeTimeout: getEnvInt("HEALTH_CHECK_PROBE_TIMEOUT", 5),
+		HealthCheckCommand:      os.Getenv("HEALTH_CHECK_COMMAND"),
+		HealthChecksWindows:     getEnvString("HEALTH_CHECKS_WINDOWS", "rdp"),
+		HealthCheckRDPGrace:     getEnvInt("HEALTH_CHECK_RDP_GRACE", 60),
+
+		// Background health monitoring
+		HealthMonitorEnabled:  getEnvBool("HEALTH_MONITOR_ENABLED", false),
+		HealthMonitorInterval: getEnvInt("HEALTH_MONITOR_INTERVAL", 300),
+		HealthMonitorFailures: getEnvInt("HEALTH_MONITOR_FAILURES", 3),
+		HealthMonitorAction:   getEnvString("HEALTH_MONITOR_ACTION", "notify"),
+
+		// SSH health check settings
+		HealthCheckSSHPort:       getEnvInt("HEALTH_CHECK_SSH_PORT", 22),
+		HealthCheckSSHUser:       getEnvString("HEALTH_CHECK_SSH_USER", "root"),
+		HealthCheckSSHPassword:   os.Getenv("HEALTH_CHECK_SSH_PASSWORD"),
+		HealthCheckSSHKey:        os.Getenv("HEALTH_CHECK_SSH_KEY"),
+		HealthCheckSSHKnownHosts: os.Getenv("HEALTH_CHECK_SSH_KNOWN_HOSTS"),
+		HealthCheckSSHCommand:    getEnvString("HEALTH_CHECK_SSH_COMMAND", "uptime"),
+
+		// Hook concurrency
+		HookConcurrency:        getEnvInt("HOOK_CONCURRENCY", 4),
+		HookConcurrencyPerHost: getEnvInt("HOOK_CONCURRENCY_PER_HOST", 1),
+
+		// Lifecycle event hooks
+		EventHookCommand: os.Getenv("EVENT_HOOK_COMMAND"),
+		EventHookURLs:    os.Getenv("EVENT_HOOK_URLS"),
+		EventHookEvents:  os.Getenv("EVENT_HOOK_EVENTS"),
+		EventHookTimeout: getEnvInt("EVENT_HOOK_TIMEOUT", 30),
+
+		// Disk snapshots
+		SnapshotSchedule:  os.Getenv("SNAPSHOT_SCHEDULE"),
+		SnapshotRetention: getEnvInt("SNAPSHOT_RETENTION", 3),
+
+		// HTTP transport settings
+		HTTPConnectTimeout:      getEnvInt("HTTP_CONNECT_TIMEOUT", 5),
+		HTTPReadTimeout:         getEnvInt("HTTP_READ_TIMEOUT", 10),
+		HTTPKeepAlive:           getEnvInt("HTTP_KEEP_ALIVE", 30),
+		HTTPIdleConnTimeout:     getEnvInt("HTTP_IDLE_CONN_TIMEOUT", 90),
+		HTTPMaxIdleConns:        getEnvInt("HTTP_MAX_IDLE_CONNS", 100),
+		HTTPMaxIdleConnsPerHost: getEnvInt("HTTP_MAX_IDLE_CONNS_PER_HOST", 10),
+		HTTPTLSMinVersion:       getEnvString("HTTP_TLS_MIN_VERSION", "1.2"),
+
+		// HTTP API
+		APIEnabled: getEnvBool("API_ENABLED", false),
+		APIListen:  getEnvString("API_LISTEN", ":8080"),
+		APIToken:   os.Getenv("API_TOKEN"),
+
+		DashboardEnabled:   getEnvBool("DASHBOARD_ENABLED", true),
+		CloudEventsEnabled: getEnvBool("CLOUD_EVENTS_ENABLED", false),
+		AgentSecret:        os.Getenv("AGENT_SECRET"),
+
+		GRPCEnabled: getEnvBool("GRPC_ENABLED", false),
+		GRPCListen:  getEnvString("GRPC_LISTEN", ":9090"),
+
+		CloudflareAPIToken: os.Getenv("CLOUDFLARE_API_TOKEN"),
 
 		// Logging
-		LogLevel: getEnvString("LOG_LEVEL", "info"),
-		LogFile:  os.Getenv("LOG_FILE"),
+		LogLevel:      getEnvString("LOG_LEVEL", "info"),
+		LogFormat:     getEnvString("LOG_FORMAT", "text"),
+		LogFile:       os.Getenv("LOG_FILE"),
+		LogFileLevel:  os.Getenv("LOG_FILE_LEVEL"),
+		LogFileFormat: getEnvString("LOG_FILE_FORMAT", "json"),
+
+		// Tracing
+		TracingEnabled: getEnvBool("TRACING_ENABLED", false),
+
+		// Leader election
+		LeaderElection:          getEnvBool("LEADER_ELECTION", false),
+		LeaderElectionLease:     getEnvString("LEADER_ELECTION_LEASE", "aliyun-spot-manager"),
+		LeaderElectionNamespace: os.Getenv("LEADER_ELECTION_NAMESPACE"),
+		LeaderElectionDuration:  getEnvInt("LEADER_ELECTION_DURATION", 15),
+
+		// State persistence
+		DataDir:        getEnvString("DATA_DIR", "data"),
+		RedisURL:       os.Getenv("REDIS_URL"),
+		RedisKeyPrefix: getEnvString("REDIS_KEY_PREFIX", "aliyun-spot:"),
+
+		// Status page
+		StatusPagePath:        os.Getenv("STATUS_PAGE_PATH"),
+		StatusPageOSSBucket:   os.Getenv("STATUS_PAGE_OSS_BUCKET"),
+		StatusPageOSSEndpoint: os.Getenv("STATUS_PAGE_OSS_ENDPOINT"),
+		StatusPageOSSPrefix:   os.Getenv("STATUS_PAGE_OSS_PREFIX"),
+		StatusPageShowIP:      getEnvBool("STATUS_PAGE_SHOW_IP", false),
+
+		// State backups
+		BackupOSSBucket:      os.Getenv("BACKUP_OSS_BUCKET"),
+		BackupOSSEndpoint:    os.Getenv("BACKUP_OSS_ENDPOINT"),
+		BackupOSSPrefix:      getEnvString("BACKUP_OSS_PREFIX", "aliyun-spot-manager/"),
+		BackupSchedule:       getEnvString("BACKUP_SCHEDULE", "0 * * * *"),
+		BackupRestore:        getEnvBool("BACKUP_RESTORE", true),
+		BackupRestoreVersion: os.Getenv("BACKUP_RESTORE_VERSION"),
+
+		// Config file
+		ConfigFile: getEnvString("CONFIG_FILE", "config.json"),
+	}
+
+	fileCfg, err := LoadFile(cfg.ConfigFile)
+	if err != nil {
+		return nil, err
 	}
+	cfg.File = fileCfg
 
 	// Generate cron schedule from check interval
 	cfg.CronSchedule = fmt.Sprintf("@every %ds", cfg.CheckInterval)
 
-	// Validate required fields
-	if cfg.AliyunAccessKeyID == "" {
-		return nil, fmt.Errorf("ALIYUN_ACCESS_KEY_ID is required")
+	// Generate the heartbeat schedule from its time of day
+	heartbeat, err := time.Parse("15:04", cfg.HeartbeatTime)
+	if err != nil {
+		return nil, fmt.Errorf("HEARTBEAT_TIME must be HH:MM: %w", err)
 	}
-	if cfg.AliyunAccessKeySecret == "" {
-		return nil, fmt.Errorf("ALIYUN_ACCESS_KEY_SECRET is required")
+	cfg.HeartbeatSchedule = fmt.Sprintf("%d %d * * *", heartbeat.Minute(), heartbeat.Hour())
+
+	trafficReport, err := time.Parse("15:04", cfg.TrafficDailyReportTime)
+	if err != nil {
+		return nil, fmt.Errorf("TRAFFIC_DAILY_REPORT_TIME must be HH:MM: %w", err)
+	}
+	cfg.TrafficDailyReportSchedule = fmt.Sprintf("%d %d * * *", trafficReport.Minute(), trafficReport.Hour())
+
+	// Validate required fields, the ECS RAM role and the simulate mode need no AccessKey
+	if cfg.AliyunEcsRamRole != "" && cfg.AliyunRoleArn != "" {
+		return nil, fmt.Errorf("ALIYUN_ECS_RAM_ROLE and ALIYUN_ROLE_ARN cannot both be set")
+	}
+	if cfg.AliyunEcsRamRole == "" && !cfg.Simulate {
+		if cfg.AliyunAccessKeyID == "" {
+			return nil, fmt.Errorf("ALIYUN_ACCESS_KEY_ID is required")
+		}
+		if cfg.AliyunAccessKeySecret == "" {
+			return nil, fmt.Errorf("ALIYUN_ACCESS_KEY_SECRET is required")
+		}
+	}
+	if cfg.AliyunRoleArn != "" && (cfg.AliyunRoleSessionDuration < 900 || cfg.AliyunRoleSessionDuration > 43200) {
+		return nil, fmt.Errorf("ALIYUN_ROLE_SESSION_DURATION must be between 900 and 43200 seconds")
+	}
+
+	if cfg.HealthMonitorAction != "notify" && cfg.HealthMonitorAction != "reboot" {
+		return nil, fmt.Errorf("HEALTH_MONITOR_ACTION must be notify or reboot")
+	}
+	if cfg.HealthMonitorFailures < 1 {
+		cfg.HealthMonitorFailures = 1
+	}
+	if cfg.BillingEstimate != "hourly" && cfg.BillingEstimate != "days" && cfg.BillingEstimate != "trailing" {
+		return nil, fmt.Errorf("BILLING_ESTIMATE must be hourly, days or trailing")
+	}
+	if _, err := regexp.Compile(cfg.InstanceNamePattern); err != nil {
+		return nil, fmt.Errorf("INSTANCE_NAME_PATTERN is not a valid regular expression: %w", err)
+	}
+	if cfg.TrafficForecast != "elapsed" && cfg.TrafficForecast != "trailing" {
+		return nil, fmt.Errorf("TRAFFIC_FORECAST must be elapsed or trailing")
+	}
+	if cfg.TrafficCapAction != "stop" && cfg.TrafficCapAction != "bandwidth" {
+		return nil, fmt.Errorf("TRAFFIC_CAP_ACTION must be stop or bandwidth")
+	}
+	if cfg.TrafficCapAction == "bandwidth" && cfg.TrafficCapBandwidth < 0 {
+		return nil, fmt.Errorf("TRAFFIC_CAP_BANDWIDTH must not be negative")
+	}
+	if cfg.APIRetryMaxDelay < 1 {
+		cfg.APIRetryMaxDelay = 20
+	}
+	if cfg.CircuitBreakerCooldown < 1 {
+		cfg.CircuitBreakerCooldown = 300
+	}
+	if cfg.ShutdownTimeout < 0 {
+		return nil, fmt.Errorf("SHUTDOWN_TIMEOUT must not be negative")
+	}
+	if cfg.StartPollInterval < 1 {
+		cfg.StartPollInterval = 1
+	}
+	if cfg.StartTimeout < cfg.StartPollInterval {
+		return nil, fmt.Errorf("START_TIMEOUT must be at least START_POLL_INTERVAL")
+	}
+	if cfg.StartBackoffBase < 0 {
+		cfg.StartBackoffBase = 0
+	}
+	if cfg.StartBackoffMax < cfg.StartBackoffBase {
+		cfg.StartBackoffMax = cfg.StartBackoffBase
+	}
+	if cfg.StartGiveUpAfter < 0 {
+		cfg.StartGiveUpAfter = 0
+	}
+	if cfg.StartGiveUpAfter > 0 && cfg.StartGiveUpHours < 1 {
+		return nil, fmt.Errorf("START_GIVE_UP_HOURS must be at least 1")
+	}
+	if cfg.StartBatchWindow < 0 {
+		cfg.StartBatchWindow = 0
+	}
+	if cfg.DependencyTimeout < 0 {
+		cfg.DependencyTimeout = 0
+	}
+	if cfg.LeaderElection && cfg.LeaderElectionDuration < 5 {
+		return nil, fmt.Errorf("LEADER_ELECTION_DURATION must be at least 5")
+	}
+	if cfg.StatusPageOSSBucket != "" && cfg.StatusPageOSSEndpoint == "" {
+		return nil, fmt.Errorf("STATUS_PAGE_OSS_ENDPOINT is required when STATUS_PAGE_OSS_BUCKET is set")
+	}
+	if cfg.BackupOSSBucket != "" && cfg.BackupOSSEndpoint == "" {
+		return nil, fmt.Errorf("BACKUP_OSS_ENDPOINT is required when BACKUP_OSS_BUCKET is set")
+	}
+	if cfg.BackupOSSBucket != "" && cfg.DataDir == "" && cfg.RedisURL == "" {
+		return nil, fmt.Errorf("BACKUP_OSS_BUCKET requires DATA_DIR or REDIS_URL")
+	}
+	if cfg.WatchdogMultiplier < 0 {
+		cfg.WatchdogMultiplier = 0
+	} else if cfg.WatchdogMultiplier > 0 && cfg.WatchdogMultiplier < 3 {
+		return nil, fmt.Errorf("WATCHDOG_MULTIPLIER must be at least 3")
+	}
+	if cfg.CapacityRetryInterval < 0 {
+		cfg.CapacityRetryInterval = 0
+	} else if cfg.CapacityRetryInterval > 0 && cfg.CapacityRetryInterval < 60 {
+		cfg.CapacityRetryInterval = 60
+	}
+	if cfg.RecreateAfter < 0 {
+		return nil, fmt.Errorf("RECREATE_AFTER must not be negative")
+	}
+	if cfg.RecreateAfter > 0 && cfg.CapacityRetryInterval == 0 {
+		return nil, fmt.Errorf("RECREATE_AFTER requires CAPACITY_RETRY_INTERVAL")
+	}
+	if cfg.PriceLimitCheckInterval < 0 {
+		cfg.PriceLimitCheckInterval = 0
+	}
+	if cfg.CPUCreditCheckInterval < 0 {
+		cfg.CPUCreditCheckInterval = 0
+	}
+	if cfg.PriceLimitAlertRatio <= 0 || cfg.PriceLimitAlertRatio > 1 {
+		return nil, fmt.Errorf("PRICE_LIMIT_ALERT_RATIO must be between 0 and 1")
+	}
+	if cfg.LaunchTemplateSync && !cfg.LaunchConfigCapture {
+		return nil, fmt.Errorf("LAUNCH_TEMPLATE_SYNC requires LAUNCH_CONFIG_CAPTURE")
+	}
+	if cfg.BudgetCheckInterval < 1 {
+		cfg.BudgetCheckInterval = 3600
+	}
+	if cfg.CheckConcurrency < 1 {
+		cfg.CheckConcurrency = 1
+	}
+	if cfg.EventHookTimeout < 1 {
+		cfg.EventHookTimeout = 30
+	}
+	if cfg.SnapshotRetention < 1 {
+		cfg.SnapshotRetention = 1
+	}
+	if cfg.SimulateInstances < 1 || cfg.SimulateInstances > 50 {
+		return nil, fmt.Errorf("SIMULATE_INSTANCES must be between 1 and 50")
+	}
+	if cfg.SimulateStartFailure < 0 || cfg.SimulateStartFailure > 1 {
+		return nil, fmt.Errorf("SIMULATE_START_FAILURE_RATE must be between 0 and 1")
+	}
+	if cfg.Simulate {
+		// The demo instances' IPs are documentation addresses, unreachable for health checks
+		cfg.HealthCheckEnabled = false
+	}
+
+	for key, format := range map[string]string{"LOG_FORMAT": cfg.LogFormat, "LOG_FILE_FORMAT": cfg.LogFileFormat} {
+		if format != "text" && format != "json" {
+			return nil, fmt.Errorf("%s must be text or json", key)
+		}
+	}
+
+	if cfg.APIEnabled && cfg.APIToken == "" {
+		return nil, fmt.Errorf("API_TOKEN is required when the API is enabled")
+	}
+	if cfg.GRPCEnabled && cfg.APIToken == "" {
+		return nil, fmt.Errorf("API_TOKEN is required when the gRPC API is enabled")
+	}
+	if cfg.CloudEventsEnabled && !cfg.APIEnabled {
+		return nil, fmt.Errorf("API_ENABLED is required when CLOUD_EVENTS_ENABLED is set")
 	}
 
 	if cfg.TelegramEnabled {
@@ -109,6 +748,15 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
 func getEnvBool(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
 		if boolValue, err := strconv.ParseBool(value); err == nil {
@@ -116,4 +764,4 @@ func getEnvBool(key string, defaultValue bool) bool {
 		}
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
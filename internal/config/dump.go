@@ -0,0 +1,64 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// secretFields are masked when the config is dumped; those set to true keep
+// their start and end visible to tell values apart
+var secretFields = map[string]bool{
+	"AliyunAccessKeyID":      true,
+	"AliyunAccessKeySecret":  false,
+	"TelegramBotToken":       false,
+	"HealthchecksPingURL":    false,
+	"HealthCheckSSHPassword": false,
+	"APIToken":               false,
+	"AgentSecret":            false,
+	"CloudflareAPIToken":     false,
+	"RedisURL":               false,
+}
+
+// Dump returns the effective configuration, one "Field = value" line per
+// setting followed by the config file, with secrets masked
+func (c *Config) Dump() string {
+	var sb strings.Builder
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Name == "File" {
+			continue
+		}
+		value := fmt.Sprint(v.Field(i).Interface())
+		partial, secret := secretFields[field.Name]
+		switch {
+		case value == "":
+			value = `""`
+		case secret && partial:
+			value = MaskSecret(value)
+		case secret:
+			value = "****"
+		}
+		sb.WriteString(fmt.Sprintf("%s = %s\n", field.Name, value))
+	}
+
+	if c.File != nil && (len(c.File.Instances) > 0 || len(c.File.TrafficGroups) > 0) {
+		data, err := json.MarshalIndent(c.File, "", "  ")
+		if err != nil {
+			data = []byte(err.Error())
+		}
+		sb.WriteString(fmt.Sprintf("\n# %s\n%s\n", c.ConfigFile, data))
+	}
+	return sb.String()
+}
+
+// MaskSecret masks all but the first and last 4 characters of a secret
+func MaskSecret(s string) string {
+	if len(s) <= 8 {
+		return "****"
+	}
+	return s[:4] + "****" + s[len(s)-4:]
+}
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// FileConfig holds settings loaded from the optional JSON config file
+// Settings that don't fit in environment variables (per-instance overrides etc.) live here
+type FileConfig struct {
+	// Instances maps an instance ID, "tag:key=value" or "tag:key" to its overrides
+	Instances map[string]*InstanceConfig `json:"instances"`
+
+	// TrafficGroups maps a group name to region IDs for the traffic summary,
+	// replacing the China mainland / non-mainland split
+	TrafficGroups map[string][]string `json:"traffic_groups"`
+}
+
+// InstanceConfig holds per-instance overrides; zero values fall back to global settings
+type InstanceConfig struct {
+	HealthChecks            string `json:"health_checks"`              // e.g. "tcp:443,http:/healthz"
+	HealthCheckPolicy       string `json:"health_check_policy"`        // all, any or a number
+	HealthCheckTimeout      int    `json:"health_check_timeout"`       // seconds
+	HealthCheckInterval     int    `json:"health_check_interval"`      // seconds
+	HealthCheckProbeTimeout int    `json:"health_check_probe_timeout"` // seconds
+	HealthCheckCommand      string `json:"health_check_command"`       // command for the "cmd" check
+	HealthCheckRDPGrace     int    `json:"health_check_rdp_grace"`     // seconds RDP must answer after a start before "rdp" passes
+
+	// How long the instance may take to reach Running after a start, and how often it is polled
+	StartTimeout      int `json:"start_timeout"`       // seconds
+	StartPollInterval int `json:"start_poll_interval"` // seconds
+
+	// Group names the group the instance is reported under, e.g. "prod"
+	Group string `json:"group"`
+
+	// DependsOn lists instances (IDs or names) that must be running and healthy
+	// before this instance is started, waiting at most DependsOnTimeout
+	DependsOn        []string `json:"depends_on"`
+	DependsOnTimeout int      `json:"depends_on_timeout"` // seconds, 0 uses DEPENDENCY_TIMEOUT
+
+	// ExpectedHoursPerDay feeds the monthly estimate for instances not running around the clock
+	ExpectedHoursPerDay float64 `json:"expected_hours_per_day"`
+
+	// Playbook runs after the instance is running, replacing the default health check wait
+	Playbook []PlaybookStep `json:"playbook"`
+
+	// PreShutdown runs when a reclaim or maintenance reboot is announced, before the instance goes down
+	PreShutdown []PlaybookStep `json:"pre_shutdown"`
+
+	// BootstrapScript runs on the instance through Cloud Assistant once it is healthy after a start
+	BootstrapScript  string `json:"bootstrap_script"`
+	BootstrapTimeout int    `json:"bootstrap_timeout"` // seconds, default 300
+
+	// Disk snapshots, taken on SNAPSHOT_SCHEDULE and/or when a reclaim is announced
+	SnapshotOnSchedule bool `json:"snapshot_on_schedule"`
+	SnapshotOnReclaim  bool `json:"snapshot_on_reclaim"`
+	SnapshotRetention  int  `json:"snapshot_retention"` // snapshots kept per disk, 0 uses SNAPSHOT_RETENTION
+
+	// RequiredPorts must stay open in the security groups, checked after a start, e.g. "22,443,udp:51820"
+	RequiredPorts string `json:"required_ports"`
+
+	// EIP is the allocation ID or address of the EIP associated again when the instance starts without it
+	EIP string `json:"eip"`
+
+	// Cloudflare DNS record pointed at the public addresses of the instance
+	CloudflareZone   string `json:"cloudflare_zone"`   // zone ID or name, e.g. example.com
+	CloudflareRecord string `json:"cloudflare_record"` // full record name, e.g. web.example.com
+}
+
+// PlaybookStep is one step of a recovery playbook
+type PlaybookStep struct {
+	Name            string `json:"name"`
+	Type            string `json:"type"`              // wait_healthy, eip, dns, cloud_assistant, http, command, sleep
+	Timeout         int    `json:"timeout"`           // seconds per attempt, 0 uses the step default
+	Retries         int    `json:"retries"`           // extra attempts after a failure
+	RetryInterval   int    `json:"retry_interval"`    // seconds between attempts, default 10
+	ContinueOnError bool   `json:"continue_on_error"` // keep going if the step fails
+
+	// Type-specific settings; {id}, {name}, {region} and {ip} are expanded
+	URL          string `json:"url"`           // http: URL to request
+	ExpectStatus int    `json:"expect_status"` // http: expected status, default any 2xx
+	ExpectBody   string `json:"expect_body"`   // http: substring the body must contain
+	Command      string `json:"command"`       // command: local shell command; cloud_assistant: script run on the instance
+	Seconds      int    `json:"seconds"`       // sleep: seconds to wait
+}
+
+// DisplayName returns the step name, falling back to its type
+func (s PlaybookStep) DisplayName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.Type
+}
+
+// LoadFile loads the JSON config file; a missing file yields an empty config
+func LoadFile(path string) (*FileConfig, error) {
+	fc := &FileConfig{}
+	if path == "" {
+		return fc, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fc, nil
+		}
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(data, fc); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	seen := make(map[string]string)
+	for name, regions := range fc.TrafficGroups {
+		for _, region := range regions {
+			if other, ok := seen[region]; ok && other != name {
+				return nil, fmt.Errorf("region %s is in traffic groups %s and %s", region, other, name)
+			}
+			seen[region] = name
+		}
+	}
+
+	return fc, nil
+}
+
+// InstanceConfig returns the overrides for an instance, matching by instance ID
+// first and then by tag selectors in sorted order. Returns nil if none match.
+func (c *Config) InstanceConfig(instanceID string, tags map[string]string) *InstanceConfig {
+	if c.File == nil || len(c.File.Instances) == 0 {
+		return nil
+	}
+
+	if ic, ok := c.File.Instances[instanceID]; ok {
+		return ic
+	}
+
+	keys := make([]string, 0, len(c.File.Instances))
+	for key := range c.File.Instances {
+		if strings.HasPrefix(key, "tag:") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if matchTag(strings.TrimPrefix(key, "tag:"), tags) {
+			return c.File.Instances[key]
+		}
+	}
+
+	return nil
+}
+
+// matchTag checks a "key=value" or "key" selector against instance tags
+func matchTag(selector string, tags map[string]string) bool {
+	key, value, hasValue := strings.Cut(selector, "=")
+	tagValue, ok := tags[key]
+	if !ok {
+		return false
+	}
+	return !hasValue || tagValue == value
+}
@@ -0,0 +1,168 @@
+// Package dns points DNS records at the public addresses of spot instances,
+// which change when an instance without an EIP is restarted or re-created.
+package dns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cloudflareAPI is the base URL of the Cloudflare v4 API
+const cloudflareAPI = "https://api.cloudflare.com/client/v4"
+
+// requestTimeout bounds each Cloudflare API request
+const requestTimeout = 10 * time.Second
+
+// zoneIDPattern matches a Cloudflare zone ID, as opposed to a zone name
+var zoneIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// Cloudflare updates DNS records through the Cloudflare API with an API token
+// that has the Zone:Read and DNS:Edit permissions
+type Cloudflare struct {
+	token  string
+	client *http.Client
+
+	zones   map[string]string // zone name -> zone ID, guarded by zonesMu
+	zonesMu sync.Mutex
+}
+
+// NewCloudflare creates a Cloudflare client
+func NewCloudflare(token string) *Cloudflare {
+	return &Cloudflare{
+		token:  token,
+		client: &http.Client{Timeout: requestTimeout},
+		zones:  make(map[string]string),
+	}
+}
+
+// cloudflareRecord is a DNS record as returned by the API
+type cloudflareRecord struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// cloudflareResponse is the envelope of every API response
+type cloudflareResponse struct {
+	Success bool `json:"success"`
+	Errors  []struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+	Result json.RawMessage `json:"result"`
+}
+
+// UpdateRecord points an A or AAAA record at address, creating the record
+// when it doesn't exist. zone is a zone ID or name. It reports whether the
+// record was changed; an existing record keeps its TTL and proxy setting.
+func (c *Cloudflare) UpdateRecord(ctx context.Context, zone, name, recordType, address string) (bool, error) {
+	zoneID, err := c.zoneID(ctx, zone)
+	if err != nil {
+		return false, err
+	}
+
+	query := url.Values{"type": {recordType}, "name": {name}}
+	var records []cloudflareRecord
+	if err := c.do(ctx, http.MethodGet, "/zones/"+zoneID+"/dns_records?"+query.Encode(), nil, &records); err != nil {
+		return false, fmt.Errorf("failed to get %s record %s: %w", recordType, name, err)
+	}
+
+	if len(records) == 0 {
+		record := map[string]interface{}{"type": recordType, "name": name, "content": address, "ttl": 1, "proxied": false}
+		if err := c.do(ctx, http.MethodPost, "/zones/"+zoneID+"/dns_records", record, nil); err != nil {
+			return false, fmt.Errorf("failed to create %s record %s: %w", recordType, name, err)
+		}
+		return true, nil
+	}
+
+	if records[0].Content == address {
+		return false, nil
+	}
+	update := map[string]string{"content": address}
+	if err := c.do(ctx, http.MethodPatch, "/zones/"+zoneID+"/dns_records/"+records[0].ID, update, nil); err != nil {
+		return false, fmt.Errorf("failed to update %s record %s: %w", recordType, name, err)
+	}
+	return true, nil
+}
+
+// zoneID resolves a zone name to its ID, caching the result
+func (c *Cloudflare) zoneID(ctx context.Context, zone string) (string, error) {
+	if zoneIDPattern.MatchString(zone) {
+		return zone, nil
+	}
+
+	c.zonesMu.Lock()
+	id, ok := c.zones[zone]
+	c.zonesMu.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	var zones []struct {
+		ID string `json:"id"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/zones?"+url.Values{"name": {zone}}.Encode(), nil, &zones); err != nil {
+		return "", fmt.Errorf("failed to look up zone %s: %w", zone, err)
+	}
+	if len(zones) == 0 {
+		return "", fmt.Errorf("zone %s not found", zone)
+	}
+
+	c.zonesMu.Lock()
+	c.zones[zone] = zones[0].ID
+	c.zonesMu.Unlock()
+	return zones[0].ID, nil
+}
+
+// do sends an API request and decodes the result into out unless it is nil
+func (c *Cloudflare) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, cloudflareAPI+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result cloudflareResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil {
+		return fmt.Errorf("status %d: invalid response: %w", resp.StatusCode, err)
+	}
+	if !result.Success {
+		messages := make([]string, 0, len(result.Errors))
+		for _, e := range result.Errors {
+			messages = append(messages, fmt.Sprintf("%d %s", e.Code, e.Message))
+		}
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.Join(messages, "; "))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(result.Result, out)
+}
@@ -0,0 +1,52 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// CommandChecker runs a local command and treats exit code 0 as healthy
+// The instance IP is passed as the first argument and as $SPOT_INSTANCE_IP
+type CommandChecker struct {
+	Command string
+	Timeout time.Duration
+}
+
+// Name returns the checker name
+func (c *CommandChecker) Name() string {
+	name := c.Command
+	if len(name) > 30 {
+		name = name[:27] + "..."
+	}
+	return "cmd:" + name
+}
+
+// Check runs the command against the host
+func (c *CommandChecker) Check(ctx context.Context, host string) error {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", c.Command, host)
+	} else {
+		// "sh" becomes $0 so that the IP is available as $1
+		cmd = exec.CommandContext(ctx, "sh", "-c", c.Command, "sh", host)
+	}
+	cmd.Env = append(os.Environ(), "SPOT_INSTANCE_IP="+host)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("command failed: %w (output: %s)", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
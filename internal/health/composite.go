@@ -0,0 +1,112 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Composite combines several checkers and passes when at least Required of them pass
+// Required equal to the number of checkers gives AND semantics, 1 gives OR semantics
+type Composite struct {
+	Checkers []Checker
+	Required int
+}
+
+// NewComposite creates a composite checker with the given policy
+// policy is "all", "any" or the number of checks that must pass
+func NewComposite(checkers []Checker, policy string) (*Composite, error) {
+	if len(checkers) == 0 {
+		return nil, fmt.Errorf("composite health check requires at least one check")
+	}
+
+	required, err := parsePolicy(policy, len(checkers))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Composite{
+		Checkers: checkers,
+		Required: required,
+	}, nil
+}
+
+// Name returns a description like "ping & tcp:443" or "any(ping | tcp:443)"
+func (c *Composite) Name() string {
+	names := make([]string, len(c.Checkers))
+	for i, checker := range c.Checkers {
+		names[i] = checker.Name()
+	}
+
+	switch c.Required {
+	case len(c.Checkers):
+		return strings.Join(names, " & ")
+	case 1:
+		return strings.Join(names, " | ")
+	default:
+		return fmt.Sprintf("%d/%d(%s)", c.Required, len(c.Checkers), strings.Join(names, ", "))
+	}
+}
+
+// Check runs all checks concurrently and evaluates the policy
+func (c *Composite) Check(ctx context.Context, host string) error {
+	errs := make([]error, len(c.Checkers))
+
+	var wg sync.WaitGroup
+	for i, checker := range c.Checkers {
+		wg.Add(1)
+		go func(i int, checker Checker) {
+			defer wg.Done()
+			errs[i] = checker.Check(ctx, host)
+		}(i, checker)
+	}
+	wg.Wait()
+
+	passed := 0
+	var failures []string
+	for i, err := range errs {
+		if err == nil {
+			passed++
+		} else {
+			failures = append(failures, fmt.Sprintf("%s: %v", c.Checkers[i].Name(), err))
+		}
+	}
+
+	if passed >= c.Required {
+		return nil
+	}
+	return fmt.Errorf("%d/%d checks passed, %d required (%s)",
+		passed, len(c.Checkers), c.Required, strings.Join(failures, "; "))
+}
+
+// GracePeriod returns the longest grace period of the combined checkers
+func (c *Composite) GracePeriod() time.Duration {
+	var grace time.Duration
+	for _, checker := range c.Checkers {
+		if g := gracePeriod(checker); g > grace {
+			grace = g
+		}
+	}
+	return grace
+}
+
+// parsePolicy converts a policy string to the number of checks required to pass
+func parsePolicy(policy string, total int) (int, error) {
+	switch strings.ToLower(strings.TrimSpace(policy)) {
+	case "all", "and", "":
+		return total, nil
+	case "any", "or":
+		return 1, nil
+	}
+
+	var required int
+	if _, err := fmt.Sscanf(policy, "%d", &required); err != nil {
+		return 0, fmt.Errorf("invalid health check policy: %s", policy)
+	}
+	if required < 1 || required > total {
+		return 0, fmt.Errorf("health check policy %d out of range (1-%d)", required, total)
+	}
+	return required, nil
+}
@@ -0,0 +1,68 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Checker checks whether a host is healthy
+type Checker interface {
+	// Name returns a short description of the check for logs and notifications
+	Name() string
+	// Check performs a single check against the host
+	Check(ctx context.Context, host string) error
+}
+
+// graceful is implemented by checkers that only count as passed after a
+// start once the host kept passing for a while, like RDP on Windows
+type graceful interface {
+	// GracePeriod is how long the checks must pass in a row after a start
+	GracePeriod() time.Duration
+}
+
+// gracePeriod returns the grace period of a checker, 0 for none
+func gracePeriod(checker Checker) time.Duration {
+	if g, ok := checker.(graceful); ok {
+		return g.GracePeriod()
+	}
+	return 0
+}
+
+// WaitForHealth runs the checker repeatedly until it passes or the timeout
+// expires. After a start the checker must keep passing for its grace period;
+// a single Check never waits for it, so checks of a running host pass at once.
+func WaitForHealth(ctx context.Context, checker Checker, host string, timeout, interval time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	grace := gracePeriod(checker)
+	var passingSince time.Time
+	var lastErr error
+	for {
+		if lastErr = checker.Check(ctx, host); lastErr == nil {
+			if passingSince.IsZero() {
+				passingSince = time.Now()
+			}
+			elapsed := time.Since(passingSince)
+			if elapsed >= grace {
+				return nil
+			}
+			lastErr = fmt.Errorf("%s passes, waiting for grace period (%s/%s)", checker.Name(), elapsed.Truncate(time.Second), grace)
+		} else {
+			passingSince = time.Time{}
+		}
+		log.Debugf("Health check %s for %s failed: %v", checker.Name(), host, lastErr)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("health check %s timed out after %s: %w", checker.Name(), timeout, lastErr)
+		case <-ticker.C:
+		}
+	}
+}
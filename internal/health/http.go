@@ -0,0 +1,80 @@
+package health
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// HTTPChecker checks that an HTTP endpoint on the host returns a 2xx/3xx status
+type HTTPChecker struct {
+	Scheme  string // http or https
+	Port    int    // 0 uses the scheme default
+	Path    string
+	Timeout time.Duration
+
+	clientOnce sync.Once
+	client     *http.Client // built on the first check, reusing connections
+}
+
+// Name returns the checker name
+func (c *HTTPChecker) Name() string {
+	if c.Port != 0 {
+		return fmt.Sprintf("%s:%d%s", c.Scheme, c.Port, c.Path)
+	}
+	return fmt.Sprintf("%s:%s", c.Scheme, c.Path)
+}
+
+// Check sends a GET request to the endpoint
+func (c *HTTPChecker) Check(ctx context.Context, host string) error {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	hostPort := host
+	if c.Port != 0 {
+		hostPort = net.JoinHostPort(host, strconv.Itoa(c.Port))
+	} else if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+		hostPort = "[" + host + "]"
+	}
+	url := fmt.Sprintf("%s://%s%s", c.Scheme, hostPort, c.Path)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("GET %s failed: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("GET %s returned status %d", url, resp.StatusCode)
+	}
+	return nil
+}
+
+// httpClient returns the client shared by the checks of this checker, so
+// repeated probes don't each leave a transport with idle connections behind
+func (c *HTTPChecker) httpClient() *http.Client {
+	c.clientOnce.Do(func() {
+		// Instances are addressed by IP, so certificates can't be verified
+		c.client = &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+				IdleConnTimeout: 90 * time.Second,
+			},
+		}
+	})
+	return c.client
+}
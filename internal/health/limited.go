@@ -0,0 +1,39 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/limit"
+)
+
+// limitedChecker runs a checker only when the limiter has a free slot for the host,
+// so mass recoveries don't open dozens of SSH sessions or commands at once
+type limitedChecker struct {
+	Checker
+	limiter *limit.Limiter
+}
+
+// withLimiter wraps a checker with a limiter; a nil limiter returns it unchanged
+func withLimiter(checker Checker, limiter *limit.Limiter) Checker {
+	if limiter == nil {
+		return checker
+	}
+	return &limitedChecker{Checker: checker, limiter: limiter}
+}
+
+// Check waits for a slot and then runs the wrapped checker
+func (c *limitedChecker) Check(ctx context.Context, host string) error {
+	release, err := c.limiter.Acquire(ctx, host)
+	if err != nil {
+		return fmt.Errorf("waiting for a free hook slot: %w", err)
+	}
+	defer release()
+	return c.Checker.Check(ctx, host)
+}
+
+// GracePeriod returns the grace period of the wrapped checker
+func (c *limitedChecker) GracePeriod() time.Duration {
+	return gracePeriod(c.Checker)
+}
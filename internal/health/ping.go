@@ -0,0 +1,64 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// PingChecker checks that a host answers ICMP echo requests
+// It shells out to the system ping binary so no raw socket privileges are needed
+type PingChecker struct {
+	Timeout time.Duration
+}
+
+// Name returns the checker name
+func (c *PingChecker) Name() string {
+	return "ping"
+}
+
+// Check sends a single ping to the host
+func (c *PingChecker) Check(ctx context.Context, host string) error {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout+time.Second)
+	defer cancel()
+
+	ipv6 := isIPv6(host)
+	name := "ping"
+	var args []string
+	switch {
+	case runtime.GOOS == "windows":
+		args = []string{"-n", "1", "-w", strconv.Itoa(int(timeout.Milliseconds()))}
+		if ipv6 {
+			args = append(args, "-6")
+		}
+	case ipv6 && runtime.GOOS == "darwin":
+		// macOS ping only speaks IPv4
+		name = "ping6"
+		args = []string{"-c", "1"}
+	default:
+		args = []string{"-c", "1", "-W", strconv.Itoa(int(timeout.Seconds()))}
+		if ipv6 {
+			args = append(args, "-6")
+		}
+	}
+	args = append(args, host)
+
+	if output, err := exec.CommandContext(ctx, name, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("ping %s failed: %w (output: %s)", host, err, output)
+	}
+	return nil
+}
+
+// isIPv6 reports whether host is a literal IPv6 address
+func isIPv6(host string) bool {
+	ip := net.ParseIP(host)
+	return ip != nil && ip.To4() == nil
+}
@@ -0,0 +1,80 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// x224ConnectionRequest is a TPKT-wrapped X.224 Connection Request carrying an
+// RDP Negotiation Request for TLS, as sent by mstsc when opening a session
+var x224ConnectionRequest = []byte{
+	0x03, 0x00, 0x00, 0x13, // TPKT header, length 19
+	0x0e, 0xe0, 0x00, 0x00, 0x00, 0x00, 0x00, // X.224 Connection Request
+	0x01, 0x00, 0x08, 0x00, 0x01, 0x00, 0x00, 0x00, // RDP_NEG_REQ, PROTOCOL_SSL
+}
+
+// RDPChecker checks that Remote Desktop is accepting sessions
+// Windows opens port 3389 well before the desktop is usable, so after a
+// start WaitForHealth only reports success once RDP answered for Grace
+type RDPChecker struct {
+	Port    int
+	Grace   time.Duration
+	Timeout time.Duration
+}
+
+// Name returns the checker name
+func (c *RDPChecker) Name() string {
+	return fmt.Sprintf("rdp:%d", c.port())
+}
+
+// GracePeriod returns how long RDP must answer after a start
+func (c *RDPChecker) GracePeriod() time.Duration {
+	return c.Grace
+}
+
+// Check sends an X.224 Connection Request and expects a Connection Confirm
+func (c *RDPChecker) Check(ctx context.Context, host string) error {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	addr := net.JoinHostPort(host, strconv.Itoa(c.port()))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write(x224ConnectionRequest); err != nil {
+		return fmt.Errorf("failed to send RDP connection request to %s: %w", addr, err)
+	}
+
+	// TPKT header (version 3) followed by X.224 Connection Confirm (0xd0)
+	resp := make([]byte, 6)
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return fmt.Errorf("no RDP response from %s: %w", addr, err)
+	}
+	if resp[0] != 0x03 || resp[5]&0xf0 != 0xd0 {
+		return fmt.Errorf("unexpected RDP response from %s: % x", addr, resp)
+	}
+	return nil
+}
+
+func (c *RDPChecker) port() int {
+	if c.Port == 0 {
+		return 3389
+	}
+	return c.Port
+}
@@ -0,0 +1,127 @@
+package health
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/limit"
+)
+
+// Options holds settings shared by checkers built from a spec
+type Options struct {
+	Timeout time.Duration // per-probe timeout
+	SSH     SSHConfig
+	Command string // command used by a bare "cmd" item
+	// RDPGrace is how long RDP must answer before the "rdp" check passes
+	RDPGrace time.Duration
+	// Limiter bounds concurrent SSH and command checks, nil means unlimited
+	Limiter *limit.Limiter
+}
+
+// Parse builds a checker from a comma separated spec such as
+// "ping,tcp:443,http:/healthz,https:8443/status,ssh,rdp,cmd" and a policy
+// ("all", "any" or a number). A single check is returned as-is.
+func Parse(spec, policy string, opts Options) (Checker, error) {
+	var checkers []Checker
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		checker, err := parseItem(item, opts)
+		if err != nil {
+			return nil, err
+		}
+		checkers = append(checkers, checker)
+	}
+
+	if len(checkers) == 0 {
+		return nil, fmt.Errorf("no health checks in spec %q", spec)
+	}
+	if len(checkers) == 1 {
+		return checkers[0], nil
+	}
+	return NewComposite(checkers, policy)
+}
+
+// parseItem builds a single checker from a spec item like "tcp:443"
+func parseItem(item string, opts Options) (Checker, error) {
+	kind, arg, _ := strings.Cut(item, ":")
+
+	switch strings.ToLower(kind) {
+	case "ping":
+		return &PingChecker{Timeout: opts.Timeout}, nil
+
+	case "tcp":
+		port, err := strconv.Atoi(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port in health check %q", item)
+		}
+		return &TCPChecker{Port: port, Timeout: opts.Timeout}, nil
+
+	case "http", "https":
+		// Accepts "http:/path", "http:8080" and "http:8080/path"
+		checker := &HTTPChecker{Scheme: strings.ToLower(kind), Path: "/", Timeout: opts.Timeout}
+		portStr, path := arg, ""
+		if idx := strings.Index(arg, "/"); idx >= 0 {
+			portStr, path = arg[:idx], arg[idx:]
+		}
+		if portStr != "" {
+			port, err := strconv.Atoi(portStr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid port in health check %q", item)
+			}
+			checker.Port = port
+		}
+		if path != "" {
+			checker.Path = path
+		}
+		return checker, nil
+
+	case "ssh":
+		sshCfg := opts.SSH
+		if arg != "" {
+			port, err := strconv.Atoi(arg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid port in health check %q", item)
+			}
+			sshCfg.Port = port
+		}
+		if sshCfg.Timeout == 0 {
+			sshCfg.Timeout = opts.Timeout
+		}
+		checker, err := NewSSHChecker(sshCfg)
+		if err != nil {
+			return nil, err
+		}
+		return withLimiter(checker, opts.Limiter), nil
+
+	case "rdp":
+		checker := &RDPChecker{Grace: opts.RDPGrace, Timeout: opts.Timeout}
+		if arg != "" {
+			port, err := strconv.Atoi(arg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid port in health check %q", item)
+			}
+			checker.Port = port
+		}
+		return checker, nil
+
+	case "cmd":
+		// Inline commands can't contain commas; use the "cmd" item with a
+		// configured command for anything more complex
+		command := arg
+		if command == "" {
+			command = opts.Command
+		}
+		if command == "" {
+			return nil, fmt.Errorf("health check %q requires a command", item)
+		}
+		return withLimiter(&CommandChecker{Command: command, Timeout: opts.Timeout}, opts.Limiter), nil
+
+	default:
+		return nil, fmt.Errorf("unknown health check type %q", kind)
+	}
+}
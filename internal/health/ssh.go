@@ -0,0 +1,126 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SSHConfig holds settings for the SSH health check
+type SSHConfig struct {
+	Port           int
+	User           string
+	Password       string
+	PrivateKeyPath string
+	KnownHostsPath string // empty disables host key verification
+	Command        string // optional command to run after connecting, e.g. uptime
+	Timeout        time.Duration
+}
+
+// SSHChecker verifies that an instance accepts SSH logins
+// A successful login (and command) means the OS has fully booted
+type SSHChecker struct {
+	cfg          SSHConfig
+	clientConfig *ssh.ClientConfig
+}
+
+// NewSSHChecker creates a new SSH checker
+func NewSSHChecker(cfg SSHConfig) (*SSHChecker, error) {
+	var auths []ssh.AuthMethod
+	if cfg.PrivateKeyPath != "" {
+		key, err := os.ReadFile(cfg.PrivateKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SSH private key: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse SSH private key: %w", err)
+		}
+		auths = append(auths, ssh.PublicKeys(signer))
+	}
+	if cfg.Password != "" {
+		auths = append(auths, ssh.Password(cfg.Password))
+	}
+	if len(auths) == 0 {
+		return nil, fmt.Errorf("SSH health check requires a private key or password")
+	}
+
+	// Spot instances may be re-created with new host keys, so verification is opt-in
+	hostKeyCallback := ssh.InsecureIgnoreHostKey()
+	if cfg.KnownHostsPath != "" {
+		callback, err := knownhosts.New(cfg.KnownHostsPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load known_hosts: %w", err)
+		}
+		hostKeyCallback = callback
+	}
+
+	if cfg.Port == 0 {
+		cfg.Port = 22
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	return &SSHChecker{
+		cfg: cfg,
+		clientConfig: &ssh.ClientConfig{
+			User:            cfg.User,
+			Auth:            auths,
+			HostKeyCallback: hostKeyCallback,
+			Timeout:         cfg.Timeout,
+		},
+	}, nil
+}
+
+// Name returns the checker name
+func (c *SSHChecker) Name() string {
+	return fmt.Sprintf("ssh:%d", c.cfg.Port)
+}
+
+// Check connects to the host via SSH and optionally runs the configured command
+func (c *SSHChecker) Check(ctx context.Context, host string) error {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	addr := net.JoinHostPort(host, strconv.Itoa(c.cfg.Port))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	// Bound the handshake and command by the same deadline
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, c.clientConfig)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("SSH handshake with %s failed: %w", addr, err)
+	}
+	client := ssh.NewClient(sshConn, chans, reqs)
+	defer client.Close()
+
+	if c.cfg.Command == "" {
+		return nil
+	}
+
+	session, err := client.NewSession()
+	if err != nil {
+		return fmt.Errorf("failed to open SSH session: %w", err)
+	}
+	defer session.Close()
+
+	if output, err := session.CombinedOutput(c.cfg.Command); err != nil {
+		return fmt.Errorf("command %q failed: %w (output: %s)", c.cfg.Command, err, output)
+	}
+
+	return nil
+}
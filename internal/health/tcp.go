@@ -0,0 +1,38 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// TCPChecker checks that a TCP port on the host accepts connections
+type TCPChecker struct {
+	Port    int
+	Timeout time.Duration
+}
+
+// Name returns the checker name
+func (c *TCPChecker) Name() string {
+	return fmt.Sprintf("tcp:%d", c.Port)
+}
+
+// Check opens and immediately closes a TCP connection
+func (c *TCPChecker) Check(ctx context.Context, host string) error {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	addr := net.JoinHostPort(host, strconv.Itoa(c.Port))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	return conn.Close()
+}
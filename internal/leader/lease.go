@@ -0,0 +1,352 @@
+// Package leader elects one of several monitor replicas with a Kubernetes
+// Lease, so only the leader starts instances and sends notifications while
+// the others stand by to take over.
+package leader
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// serviceAccountDir holds the credentials Kubernetes mounts into pods
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// requestTimeout bounds each API server request
+const requestTimeout = 10 * time.Second
+
+// microTime is the layout of the MicroTime fields of a Lease
+const microTime = "2006-01-02T15:04:05.000000Z07:00"
+
+// Errors of API server requests
+var (
+	errNotFound = errors.New("lease not found")
+	errConflict = errors.New("lease was updated concurrently")
+)
+
+// Options configure a Lease election
+type Options struct {
+	Name      string        // Lease name
+	Namespace string        // empty for the namespace of the pod
+	Identity  string        // holder identity, e.g. the pod name
+	Duration  time.Duration // how long a lease is valid without renewal
+}
+
+// Lease elects a leader through a coordination.k8s.io/v1 Lease, talking to
+// the API server with the service account of the pod
+type Lease struct {
+	opts    Options
+	baseURL string
+	client  *http.Client
+
+	stopRenewing context.CancelFunc // set once acquired, stops the renewal
+	renewing     chan struct{}      // closed when the renewal stopped
+}
+
+// lease is the subset of a Lease object the election uses
+type lease struct {
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Metadata   leaseMetadata `json:"metadata"`
+	Spec       leaseSpec     `json:"spec"`
+}
+
+// leaseMetadata is the object metadata of a Lease
+type leaseMetadata struct {
+	Name            string `json:"name"`
+	Namespace       string `json:"namespace,omitempty"`
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+// leaseSpec is the spec of a Lease, times in the MicroTime layout
+type leaseSpec struct {
+	HolderIdentity       string `json:"holderIdentity,omitempty"`
+	LeaseDurationSeconds int    `json:"leaseDurationSeconds,omitempty"`
+	AcquireTime          string `json:"acquireTime,omitempty"`
+	RenewTime            string `json:"renewTime,omitempty"`
+	LeaseTransitions     int    `json:"leaseTransitions"`
+}
+
+// NewLease creates a Lease election from the in-cluster configuration
+func NewLease(opts Options) (*Lease, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("not running in Kubernetes, KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT are unset")
+	}
+	ca, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the service account CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificate in the service account CA")
+	}
+	if opts.Namespace == "" {
+		namespace, err := os.ReadFile(serviceAccountDir + "/namespace")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the pod namespace: %w", err)
+		}
+		opts.Namespace = strings.TrimSpace(string(namespace))
+	}
+	if opts.Identity == "" {
+		if opts.Identity, err = os.Hostname(); err != nil {
+			return nil, fmt.Errorf("failed to get the hostname for the lease identity: %w", err)
+		}
+	}
+
+	return &Lease{
+		opts:    opts,
+		baseURL: "https://" + net.JoinHostPort(host, port),
+		client: &http.Client{
+			Timeout:   requestTimeout,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}},
+		},
+	}, nil
+}
+
+// Identity returns the holder identity of this replica
+func (l *Lease) Identity() string {
+	return l.opts.Identity
+}
+
+// Acquire blocks until this replica holds the Lease or ctx is cancelled. It
+// returns a context that is cancelled when the leadership is lost, i.e. the
+// Lease couldn't be renewed within its duration, or when ctx is. The Lease
+// stays renewed until Release, so shutting down doesn't hand it over while
+// instance starts are still in flight.
+func (l *Lease) Acquire(ctx context.Context) (context.Context, error) {
+	retry := l.opts.Duration / 5
+	logged := false
+	for {
+		held, holder, err := l.tryAcquire(ctx)
+		if errors.Is(err, errConflict) {
+			log.Debugf("Lost the race for lease %s/%s, retrying", l.opts.Namespace, l.opts.Name)
+		} else if err != nil {
+			log.Warnf("Failed to acquire lease %s/%s: %v", l.opts.Namespace, l.opts.Name, err)
+		} else if held {
+			break
+		} else if !logged {
+			log.Infof("Lease %s/%s is held by %s, standing by", l.opts.Namespace, l.opts.Name, holder)
+			logged = true
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(retry):
+		}
+	}
+	log.Infof("Acquired lease %s/%s as %s", l.opts.Namespace, l.opts.Name, l.opts.Identity)
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+	renewCtx, stopRenewing := context.WithCancel(context.Background())
+	l.stopRenewing = stopRenewing
+	l.renewing = make(chan struct{})
+	go func() {
+		defer close(l.renewing)
+		defer cancel()
+		renewed := time.Now()
+		ticker := time.NewTicker(retry)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-renewCtx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			held, holder, err := l.tryAcquire(renewCtx)
+			switch {
+			case renewCtx.Err() != nil:
+				return
+			case err == nil && held:
+				renewed = time.Now()
+				continue
+			case err == nil:
+				log.Errorf("Lease %s/%s was taken over by %s, stepping down", l.opts.Namespace, l.opts.Name, holder)
+				return
+			}
+			log.Warnf("Failed to renew lease %s/%s: %v", l.opts.Namespace, l.opts.Name, err)
+			// Step down before the others may consider the lease expired
+			if time.Since(renewed) > l.opts.Duration*2/3 {
+				log.Errorf("Lease %s/%s not renewed for %s, stepping down", l.opts.Namespace, l.opts.Name, time.Since(renewed).Round(time.Second))
+				return
+			}
+		}
+	}()
+	return leaderCtx, nil
+}
+
+// Release stops renewing the Lease and gives it up if this replica still
+// holds it, so a standby takes over right away. Call it once the work the
+// leadership guards has stopped; it does nothing if the Lease was never
+// acquired.
+func (l *Lease) Release() {
+	if l.stopRenewing == nil {
+		return
+	}
+	l.stopRenewing()
+	<-l.renewing
+	l.release()
+}
+
+// tryAcquire creates, renews or takes over an expired Lease, reporting
+// whether this replica holds it and who does otherwise. Losing a race with
+// another replica returns errConflict.
+func (l *Lease) tryAcquire(ctx context.Context) (bool, string, error) {
+	now := time.Now()
+	current, err := l.get(ctx)
+	if err != nil {
+		return false, "", err
+	}
+	if current == nil {
+		created := l.newLease(now)
+		if err := l.do(ctx, http.MethodPost, l.collectionPath(), created, nil); err != nil {
+			return false, "", fmt.Errorf("failed to create lease: %w", err)
+		}
+		return true, l.opts.Identity, nil
+	}
+
+	spec := &current.Spec
+	if spec.HolderIdentity != "" && spec.HolderIdentity != l.opts.Identity && !expired(spec, now) {
+		return false, spec.HolderIdentity, nil
+	}
+	if spec.HolderIdentity != l.opts.Identity {
+		spec.LeaseTransitions++
+		spec.AcquireTime = now.UTC().Format(microTime)
+	}
+	spec.HolderIdentity = l.opts.Identity
+	spec.LeaseDurationSeconds = int(l.opts.Duration.Seconds())
+	spec.RenewTime = now.UTC().Format(microTime)
+	if err := l.do(ctx, http.MethodPut, l.objectPath(), current, nil); err != nil {
+		return false, "", fmt.Errorf("failed to update lease: %w", err)
+	}
+	return true, l.opts.Identity, nil
+}
+
+// release gives the Lease up if this replica still holds it
+func (l *Lease) release() {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	current, err := l.get(ctx)
+	if err != nil || current == nil || current.Spec.HolderIdentity != l.opts.Identity {
+		return
+	}
+	current.Spec.HolderIdentity = ""
+	current.Spec.RenewTime = ""
+	if err := l.do(ctx, http.MethodPut, l.objectPath(), current, nil); err != nil {
+		log.Warnf("Failed to release lease %s/%s: %v", l.opts.Namespace, l.opts.Name, err)
+		return
+	}
+	log.Infof("Released lease %s/%s", l.opts.Namespace, l.opts.Name)
+}
+
+// expired reports whether the holder of a Lease missed its renewal
+func expired(spec *leaseSpec, now time.Time) bool {
+	renewed, err := time.Parse(microTime, spec.RenewTime)
+	if err != nil {
+		return true
+	}
+	return now.After(renewed.Add(time.Duration(spec.LeaseDurationSeconds) * time.Second))
+}
+
+// newLease returns a Lease held by this replica
+func (l *Lease) newLease(now time.Time) *lease {
+	stamp := now.UTC().Format(microTime)
+	return &lease{
+		APIVersion: "coordination.k8s.io/v1",
+		Kind:       "Lease",
+		Metadata:   leaseMetadata{Name: l.opts.Name, Namespace: l.opts.Namespace},
+		Spec: leaseSpec{
+			HolderIdentity:       l.opts.Identity,
+			LeaseDurationSeconds: int(l.opts.Duration.Seconds()),
+			AcquireTime:          stamp,
+			RenewTime:            stamp,
+		},
+	}
+}
+
+// get returns the Lease, nil if it doesn't exist yet
+func (l *Lease) get(ctx context.Context) (*lease, error) {
+	var current lease
+	err := l.do(ctx, http.MethodGet, l.objectPath(), nil, &current)
+	if errors.Is(err, errNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lease: %w", err)
+	}
+	return &current, nil
+}
+
+// collectionPath returns the API path of the Leases of the namespace
+func (l *Lease) collectionPath() string {
+	return fmt.Sprintf("/apis/coordination.k8s.io/v1/namespaces/%s/leases", l.opts.Namespace)
+}
+
+// objectPath returns the API path of the Lease
+func (l *Lease) objectPath() string {
+	return l.collectionPath() + "/" + l.opts.Name
+}
+
+// do sends a request to the API server, decoding the response into result
+// if set. The token is read for every request, as projected tokens rotate.
+func (l *Lease) do(ctx context.Context, method, path string, body, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	token, err := os.ReadFile(serviceAccountDir + "/token")
+	if err != nil {
+		return fmt.Errorf("failed to read the service account token: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, l.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return errNotFound
+	case resp.StatusCode == http.StatusConflict:
+		return errConflict
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if result != nil {
+		return json.Unmarshal(data, result)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package limit
+
+import (
+	"context"
+	"sync"
+)
+
+// Limiter bounds how many hooks (SSH sessions, commands, webhooks) run at once,
+// both in total and against any single host
+type Limiter struct {
+	global  chan struct{} // nil means unlimited
+	perHost int           // 0 means unlimited
+	hosts   map[string]*hostSlots
+	mu      sync.Mutex
+}
+
+// hostSlots is the semaphore of one host, dropped when nobody uses it
+type hostSlots struct {
+	sem  chan struct{}
+	refs int
+}
+
+// New creates a limiter; a limit of 0 or less disables that limit
+func New(global, perHost int) *Limiter {
+	l := &Limiter{
+		perHost: perHost,
+		hosts:   make(map[string]*hostSlots),
+	}
+	if global > 0 {
+		l.global = make(chan struct{}, global)
+	}
+	if perHost < 0 {
+		l.perHost = 0
+	}
+	return l
+}
+
+// Acquire blocks until both a global slot and a slot for host are free,
+// returning a function that releases them. A nil Limiter never blocks.
+func (l *Limiter) Acquire(ctx context.Context, host string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	// Wait for the host first so a busy host doesn't hold a global slot
+	releaseHost, err := l.acquireHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	if l.global == nil {
+		return releaseHost, nil
+	}
+	select {
+	case l.global <- struct{}{}:
+	case <-ctx.Done():
+		releaseHost()
+		return nil, ctx.Err()
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-l.global
+			releaseHost()
+		})
+	}, nil
+}
+
+// acquireHost takes a slot of the host's semaphore
+func (l *Limiter) acquireHost(ctx context.Context, host string) (func(), error) {
+	if l.perHost == 0 || host == "" {
+		return func() {}, nil
+	}
+
+	l.mu.Lock()
+	slots, ok := l.hosts[host]
+	if !ok {
+		slots = &hostSlots{sem: make(chan struct{}, l.perHost)}
+		l.hosts[host] = slots
+	}
+	slots.refs++
+	l.mu.Unlock()
+
+	unref := func() {
+		l.mu.Lock()
+		slots.refs--
+		if slots.refs == 0 {
+			delete(l.hosts, host)
+		}
+		l.mu.Unlock()
+	}
+
+	select {
+	case slots.sem <- struct{}{}:
+	case <-ctx.Done():
+		unref()
+		return nil, ctx.Err()
+	}
+
+	return func() {
+		<-slots.sem
+		unref()
+	}, nil
+}
@@ -0,0 +1,33 @@
+package monitor
+
+import (
+	"context"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	log "github.com/sirupsen/logrus"
+)
+
+// checkAddress compares the public address of a running instance with the
+// tracked one, publishing a change and tracking the fresh details. The
+// address of a running instance changes when its EIP or public IP is
+// unassociated or replaced, or it was restarted outside the monitor.
+func (m *Monitor) checkAddress(ctx context.Context, inst *aliyun.SpotInstance) {
+	current, err := m.ecsClient.GetInstance(ctx, inst.RegionID, inst.InstanceID)
+	if err != nil {
+		log.Debugf("Failed to get details of instance %s: %v", inst.InstanceID, err)
+		return
+	}
+	m.observeAddress(inst, current)
+}
+
+// observeAddress tracks fresh details of a running instance, publishing an
+// unexpected address change
+func (m *Monitor) observeAddress(old, current *aliyun.SpotInstance) {
+	if old.PublicAddresses() == current.PublicAddresses() {
+		return
+	}
+	log.Warnf("Public IP of running instance %s (%s) changed from %q to %q",
+		current.InstanceName, current.InstanceID, old.PublicAddresses(), current.PublicAddresses())
+	m.setTrackedInstance(current)
+	m.bus.publish(busMessage{Topic: topicAddressChanged, Instance: current, PrevAddress: old.PublicAddresses(), Unexpected: true})
+}
@@ -0,0 +1,298 @@
+package monitor
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	log "github.com/sirupsen/logrus"
+)
+
+// spotPriceTTL is how long a queried spot price history is reused
+const spotPriceTTL = 6 * time.Hour
+
+// Thresholds for recommending another zone or instance type
+const (
+	advisorCheaperRatio   = 0.9 // average price at most this share of the current one
+	advisorStablerRatio   = 0.5 // volatility at most this share of the current one
+	advisorStablerPremium = 1.1 // a stabler option may cost up to this share of the current one
+	advisorMinVolatility  = 0.05
+)
+
+// spotPriceHistory is a cached spot price history of an instance type in a region
+type spotPriceHistory struct {
+	prices    []aliyun.SpotPrice
+	fetchedAt time.Time
+}
+
+// zonePriceStats summarizes the spot price of an instance type in a zone
+type zonePriceStats struct {
+	zoneID       string
+	instanceType string
+	current      float64
+	average      float64
+	min, max     float64
+	origin       float64   // pay-as-you-go price
+	volatility   float64   // standard deviation as a share of the average
+	trend        float64   // last week's average against the first week's, as a change
+	daily        []float64 // daily averages, oldest first
+}
+
+// computeZonePriceStats summarizes price histories per zone and instance type
+// by sampling the price every hour of [start, end)
+func computeZonePriceStats(prices []aliyun.SpotPrice, start, end time.Time) []*zonePriceStats {
+	series := make(map[string][]aliyun.SpotPrice)
+	var keys []string
+	for _, price := range prices {
+		key := price.ZoneID + "/" + price.InstanceType
+		if _, ok := series[key]; !ok {
+			keys = append(keys, key)
+		}
+		series[key] = append(series[key], price)
+	}
+	sort.Strings(keys)
+
+	var all []*zonePriceStats
+	for _, key := range keys {
+		points := series[key]
+		var samples []float64
+		next := 0
+		current := -1.0
+		for t := start; t.Before(end); t = t.Add(time.Hour) {
+			for next < len(points) && !points[next].Time.After(t) {
+				current = points[next].Price
+				next++
+			}
+			if current >= 0 {
+				samples = append(samples, current)
+			}
+		}
+		if len(samples) == 0 {
+			continue
+		}
+
+		last := points[len(points)-1]
+		stats := &zonePriceStats{
+			zoneID:       last.ZoneID,
+			instanceType: last.InstanceType,
+			current:      last.Price,
+			origin:       last.OriginPrice,
+			min:          samples[0],
+			max:          samples[0],
+		}
+		var sum float64
+		for _, sample := range samples {
+			sum += sample
+			stats.min = math.Min(stats.min, sample)
+			stats.max = math.Max(stats.max, sample)
+		}
+		stats.average = sum / float64(len(samples))
+		var variance float64
+		for _, sample := range samples {
+			variance += (sample - stats.average) * (sample - stats.average)
+		}
+		if stats.average > 0 {
+			stats.volatility = math.Sqrt(variance/float64(len(samples))) / stats.average
+		}
+
+		week := 7 * 24
+		if len(samples) >= 2*week {
+			first, recent := mean(samples[:week]), mean(samples[len(samples)-week:])
+			if first > 0 {
+				stats.trend = recent/first - 1
+			}
+		}
+		for i := 0; i < len(samples); i += 24 {
+			stats.daily = append(stats.daily, mean(samples[i:min(i+24, len(samples))]))
+		}
+		all = append(all, stats)
+	}
+	return all
+}
+
+// mean returns the average of values
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// priceSparkline draws values scaled between their minimum and maximum
+func priceSparkline(values []float64) string {
+	blocks := []rune("▁▂▃▄▅▆▇█")
+	low, high := values[0], values[0]
+	for _, v := range values {
+		low, high = math.Min(low, v), math.Max(high, v)
+	}
+	spark := make([]rune, len(values))
+	for i, v := range values {
+		level := 0
+		if high > low {
+			level = int((v-low)/(high-low)*float64(len(blocks)-1) + 0.5)
+		}
+		spark[i] = blocks[level]
+	}
+	return string(spark)
+}
+
+// cachedSpotPrices returns the spot price history of an instance type in a region
+// over the last 30 days, querying it when missing or expired
+func (m *Monitor) cachedSpotPrices(regionID, instanceType, osType string) ([]aliyun.SpotPrice, error) {
+	key := regionID + "/" + instanceType + "/" + osType
+
+	m.spotPricesMu.Lock()
+	cached, ok := m.spotPrices[key]
+	m.spotPricesMu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < spotPriceTTL {
+		return cached.prices, nil
+	}
+
+	start := time.Now().AddDate(0, 0, -aliyun.SpotPriceHistoryDays)
+	prices, err := m.ecsClient.GetSpotPriceHistory(m.ctx, regionID, instanceType, osType, start)
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("Queried %d spot prices of %s in %s", len(prices), instanceType, regionID)
+
+	m.spotPricesMu.Lock()
+	m.spotPrices[key] = spotPriceHistory{prices: prices, fetchedAt: time.Now()}
+	m.spotPricesMu.Unlock()
+	return prices, nil
+}
+
+// advisorStats returns the price statistics of the instance type of an
+// instance and of the extra candidate types in every zone of its region
+func (m *Monitor) advisorStats(inst *aliyun.SpotInstance, start, end time.Time) ([]*zonePriceStats, error) {
+	types := []string{inst.InstanceType}
+	seen := map[string]bool{inst.InstanceType: true}
+	for _, instanceType := range splitList(m.cfg.SpotAdvisorTypes) {
+		if !seen[instanceType] {
+			seen[instanceType] = true
+			types = append(types, instanceType)
+		}
+	}
+
+	var prices []aliyun.SpotPrice
+	for _, instanceType := range types {
+		history, err := m.cachedSpotPrices(inst.RegionID, instanceType, inst.OSType)
+		if err != nil {
+			if instanceType == inst.InstanceType {
+				return nil, err
+			}
+			log.Warnf("Failed to query spot prices of %s in %s: %v", instanceType, inst.RegionID, err)
+			continue
+		}
+		prices = append(prices, history...)
+	}
+	return computeZonePriceStats(prices, start, end), nil
+}
+
+// recommendZones picks a clearly cheaper and a clearly stabler option than current
+func recommendZones(current *zonePriceStats, candidates []*zonePriceStats) (cheaper, stabler *zonePriceStats) {
+	for _, c := range candidates {
+		if c == current {
+			continue
+		}
+		if c.average <= current.average*advisorCheaperRatio && (cheaper == nil || c.average < cheaper.average) {
+			cheaper = c
+		}
+		if current.volatility >= advisorMinVolatility &&
+			c.volatility <= current.volatility*advisorStablerRatio &&
+			c.average <= current.average*advisorStablerPremium &&
+			(stabler == nil || c.volatility < stabler.volatility) {
+			stabler = c
+		}
+	}
+	return cheaper, stabler
+}
+
+// formatAdvice formats the price statistics and recommendations of an instance
+func formatAdvice(inst *aliyun.SpotInstance, all []*zonePriceStats) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("<b>%s</b> (%s, %s)\n", inst.InstanceName, inst.InstanceType, inst.ZoneID))
+
+	var current *zonePriceStats
+	for _, stats := range all {
+		if stats.zoneID == inst.ZoneID && stats.instanceType == inst.InstanceType {
+			current = stats
+			break
+		}
+	}
+	if current == nil {
+		sb.WriteString("   暂无当前可用区的价格记录\n")
+		return sb.String()
+	}
+
+	line := fmt.Sprintf("   当前 ¥%.4f/时，均价 ¥%.4f", current.current, current.average)
+	if current.origin > 0 {
+		line += fmt.Sprintf(" (按量价的 %.1f 折)", current.average/current.origin*10)
+	}
+	sb.WriteString(line + "\n")
+	sb.WriteString(fmt.Sprintf("   区间 ¥%.4f ~ ¥%.4f，波动 %.1f%%\n", current.min, current.max, current.volatility*100))
+	trend := "→"
+	if current.trend >= 0.02 {
+		trend = "↑"
+	} else if current.trend <= -0.02 {
+		trend = "↓"
+	}
+	sb.WriteString(fmt.Sprintf("   趋势 %s %+.1f%% <code>%s</code>\n", trend, current.trend*100, priceSparkline(current.daily)))
+
+	// Only options of another instance type name it
+	label := func(stats *zonePriceStats) string {
+		if stats.instanceType == current.instanceType {
+			return stats.zoneID
+		}
+		return stats.zoneID + " " + stats.instanceType
+	}
+	cheaper, stabler := recommendZones(current, all)
+	if cheaper != nil {
+		sb.WriteString(fmt.Sprintf("   💰 更便宜: %s 均价 ¥%.4f (%+.1f%%)\n",
+			label(cheaper), cheaper.average, (cheaper.average/current.average-1)*100))
+	}
+	if stabler != nil && stabler != cheaper {
+		sb.WriteString(fmt.Sprintf("   🛡 更稳定: %s 波动 %.1f%%，均价 ¥%.4f (%+.1f%%)\n",
+			label(stabler), stabler.volatility*100, stabler.average, (stabler.average/current.average-1)*100))
+	}
+	if cheaper == nil && stabler == nil {
+		sb.WriteString("   ✅ 当前可用区已是较优选择\n")
+	}
+	return sb.String()
+}
+
+// SendAdvisorReport sends the spot price statistics of each tracked instance
+// over the last 30 days with cheaper or more stable zones and types
+func (m *Monitor) SendAdvisorReport() error {
+	if m.notifier == nil {
+		return fmt.Errorf("telegram notifier not initialized")
+	}
+
+	end := time.Now()
+	start := end.AddDate(0, 0, -aliyun.SpotPriceHistoryDays)
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("💡 <b>抢占式价格顾问</b> (最近 %d 天)\n", aliyun.SpotPriceHistoryDays))
+	sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━\n")
+
+	instances := m.Instances(false)
+	if len(instances) == 0 {
+		sb.WriteString("\n暂无监控中的实例")
+		return m.notifier.Send(sb.String())
+	}
+	for _, inst := range instances {
+		sb.WriteString("\n")
+		all, err := m.advisorStats(inst, start, end)
+		if err != nil {
+			log.Warnf("Failed to query spot prices for instance %s: %v", inst.InstanceID, err)
+			sb.WriteString(fmt.Sprintf("<b>%s</b>\n   ❌ 价格查询失败: %v\n", inst.InstanceName, err))
+			continue
+		}
+		sb.WriteString(formatAdvice(inst, all))
+	}
+
+	sb.WriteString("\n<i>波动为小时价格的标准差与均价之比，价格波动大的可用区通常回收更频繁；更换规格前请确认配置满足需要</i>")
+	return m.notifier.Send(sb.String())
+}
@@ -0,0 +1,87 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	log "github.com/sirupsen/logrus"
+)
+
+// interruptionDedupWindow is how close two announced interruption times of
+// an instance must be to count as the same reclaim, since the agent and the
+// ECS event report it with slightly different times
+const interruptionDedupWindow = 10 * time.Minute
+
+// AgentHeartbeat is the last report of the agent running on an instance
+type AgentHeartbeat struct {
+	Time            time.Time  `json:"time"`
+	TerminationTime *time.Time `json:"termination_time,omitempty"`
+}
+
+// AgentHeartbeat records a heartbeat of the agent running on an instance
+// A termination time announced by the instance metadata is handled like an
+// interruption event: notified and the pre-shutdown playbook run.
+func (m *Monitor) AgentHeartbeat(instanceID string, terminationTime time.Time) error {
+	inst := m.findInstance(instanceID)
+	if inst == nil {
+		return ErrInstanceNotFound
+	}
+
+	heartbeat := AgentHeartbeat{Time: time.Now()}
+	if !terminationTime.IsZero() {
+		heartbeat.TerminationTime = &terminationTime
+	}
+	m.agentsMu.Lock()
+	m.agents[instanceID] = heartbeat
+	m.agentsMu.Unlock()
+
+	if terminationTime.IsZero() {
+		return nil
+	}
+
+	event := &aliyun.SystemEvent{
+		EventID:     fmt.Sprintf("agent-%s-%d", instanceID, terminationTime.Unix()),
+		InstanceID:  instanceID,
+		RegionID:    inst.RegionID,
+		EventType:   aliyun.EventSpotInterruption,
+		Status:      "Scheduled",
+		Reason:      "reported by the on-instance agent",
+		NotBefore:   terminationTime,
+		PublishTime: heartbeat.Time,
+	}
+	if m.announceInterruption(inst, event) {
+		log.Warnf("Agent on instance %s (%s) reports a reclaim at %s, in %s",
+			inst.InstanceName, inst.InstanceID, terminationTime.Local().Format("15:04:05"),
+			time.Until(terminationTime).Round(time.Second))
+	}
+	return nil
+}
+
+// AgentHeartbeats returns the last agent heartbeat of each instance that has one
+func (m *Monitor) AgentHeartbeats() map[string]AgentHeartbeat {
+	m.agentsMu.Lock()
+	defer m.agentsMu.Unlock()
+
+	heartbeats := make(map[string]AgentHeartbeat, len(m.agents))
+	for id, heartbeat := range m.agents {
+		heartbeats[id] = heartbeat
+	}
+	return heartbeats
+}
+
+// markInterruption records an announced interruption of an instance and
+// reports whether it is new, so a reclaim seen by the agent, a pushed event
+// and the scheduled events is only handled once
+func (m *Monitor) markInterruption(instanceID string, notBefore time.Time) bool {
+	m.agentsMu.Lock()
+	defer m.agentsMu.Unlock()
+
+	if last, ok := m.interruptions[instanceID]; ok {
+		if diff := notBefore.Sub(last); diff > -interruptionDedupWindow && diff < interruptionDedupWindow {
+			return false
+		}
+	}
+	m.interruptions[instanceID] = notBefore
+	return true
+}
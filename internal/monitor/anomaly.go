@@ -0,0 +1,111 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	log "github.com/sirupsen/logrus"
+)
+
+// anomalyBaselineDays is the trailing window a day's spend is compared with
+const anomalyBaselineDays = 7
+
+// maxAnomalyDrivers caps the billing items listed as the cause of an anomaly
+const maxAnomalyDrivers = 3
+
+// costDriver is a billing item whose spend rose against its baseline
+type costDriver struct {
+	name     string
+	amount   float64
+	baseline float64
+}
+
+// CheckCostAnomaly compares yesterday's spend with the trailing 7-day average
+// and alerts when it exceeds COST_ANOMALY_MULTIPLIER times the average
+func (m *Monitor) CheckCostAnomaly() error {
+	if m.billingClient == nil {
+		return fmt.Errorf("billing client not initialized")
+	}
+	if m.notifier == nil {
+		return fmt.Errorf("telegram notifier not initialized")
+	}
+	multiplier := m.cfg.CostAnomalyMultiplier
+	if multiplier <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	yesterday := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, -1)
+	days, err := m.billingClient.QueryDailyBilling(m.ctx, m.instanceInfos(), yesterday.AddDate(0, 0, -anomalyBaselineDays), yesterday)
+	if err != nil {
+		if aliyun.IsAuthError(err) {
+			m.handleAuthError(err)
+		}
+		return fmt.Errorf("failed to query daily billing: %w", err)
+	}
+	if len(days) != anomalyBaselineDays+1 {
+		return fmt.Errorf("expected %d days of billing, got %d", anomalyBaselineDays+1, len(days))
+	}
+
+	day := days[len(days)-1]
+	baselineDays := days[:len(days)-1]
+	var baseline float64
+	baselineItems := make(map[string]float64)
+	for _, d := range baselineDays {
+		baseline += d.Amount
+		for name, amount := range d.ByItem {
+			baselineItems[name] += amount
+		}
+	}
+	baseline /= float64(len(baselineDays))
+	for name := range baselineItems {
+		baselineItems[name] /= float64(len(baselineDays))
+	}
+
+	if day.Amount < baseline*multiplier || day.Amount-baseline < m.cfg.CostAnomalyMinAmount {
+		log.Debugf("Cost anomaly check: %s spend ¥%.2f, 7-day average ¥%.2f", day.Date.Format("2006-01-02"), day.Amount, baseline)
+		return nil
+	}
+
+	// Billing items that grew the most explain the anomaly
+	var drivers []costDriver
+	for name, amount := range day.ByItem {
+		if amount > baselineItems[name] {
+			drivers = append(drivers, costDriver{name: name, amount: amount, baseline: baselineItems[name]})
+		}
+	}
+	sort.Slice(drivers, func(i, j int) bool {
+		return drivers[i].amount-drivers[i].baseline > drivers[j].amount-drivers[j].baseline
+	})
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🚨 <b>费用异常</b> (%s)\n", day.Date.Format("2006-01-02")))
+	sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+	sb.WriteString(fmt.Sprintf("当日扣费: ¥%.2f\n", day.Amount))
+	sb.WriteString(fmt.Sprintf("前 %d 天日均: ¥%.2f\n", anomalyBaselineDays, baseline))
+	if baseline > 0 {
+		sb.WriteString(fmt.Sprintf("增幅: +¥%.2f (日均的 %.1f 倍)\n", day.Amount-baseline, day.Amount/baseline))
+	} else {
+		sb.WriteString(fmt.Sprintf("增幅: +¥%.2f (前 %d 天无扣费)\n", day.Amount, anomalyBaselineDays))
+	}
+
+	if len(drivers) > 0 {
+		sb.WriteString("\n<b>主要增长计费项</b>\n")
+		for i, d := range drivers {
+			if i == maxAnomalyDrivers {
+				break
+			}
+			sb.WriteString(fmt.Sprintf("   • %s: ¥%.2f (日均 ¥%.2f, +¥%.2f)\n", d.name, d.amount, d.baseline, d.amount-d.baseline))
+		}
+	}
+	sb.WriteString("\n使用 /daily 查看每日扣费")
+
+	if err := m.notifier.Send(sb.String()); err != nil {
+		return fmt.Errorf("failed to send cost anomaly alert: %w", err)
+	}
+	log.Infof("Cost anomaly alert sent (%s: ¥%.2f vs 7-day average ¥%.2f)", day.Date.Format("2006-01-02"), day.Amount, baseline)
+	return nil
+}
@@ -0,0 +1,118 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+)
+
+// APIStats returns the recorded Aliyun SDK calls per API and region
+func (m *Monitor) APIStats() []aliyun.APICallStats {
+	return m.resilience.APIStats()
+}
+
+// sendAPIStats sends latency, errors and throttling of the Aliyun API calls
+// since startup, per API and for the slowest regions
+func (m *Monitor) sendAPIStats() error {
+	if m.notifier == nil {
+		return fmt.Errorf("telegram notifier not initialized")
+	}
+
+	stats := m.APIStats()
+	if len(stats) == 0 {
+		return m.notifier.Send("📡 <b>API 调用统计</b>\n\n启动以来还没有 API 调用")
+	}
+
+	// Aggregate the regions of each API
+	byAPI := make(map[string]*aliyun.APICallStats)
+	var apis []string
+	for _, s := range stats {
+		agg, ok := byAPI[s.API]
+		if !ok {
+			agg = &aliyun.APICallStats{API: s.API, Codes: make(map[string]int)}
+			byAPI[s.API] = agg
+			apis = append(apis, s.API)
+		}
+		agg.Calls += s.Calls
+		agg.Errors += s.Errors
+		agg.Throttled += s.Throttled
+		agg.Total += s.Total
+		if s.Max > agg.Max {
+			agg.Max = s.Max
+		}
+		for code, n := range s.Codes {
+			agg.Codes[code] += n
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("📡 <b>API 调用统计</b>\n")
+	sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	sb.WriteString(fmt.Sprintf("统计自 %s 启动以来\n\n", m.startedAt.Format("01-02 15:04")))
+
+	for _, api := range apis {
+		agg := byAPI[api]
+		sb.WriteString(fmt.Sprintf("<b>%s</b>\n", api))
+		sb.WriteString(fmt.Sprintf("   调用 %d 次 | 平均 %s | 最长 %s\n", agg.Calls, formatLatency(agg.Average()), formatLatency(agg.Max)))
+		if agg.Errors > 0 {
+			sb.WriteString(fmt.Sprintf("   失败 %d 次 (限流 %d): %s\n", agg.Errors, agg.Throttled, formatErrorCodes(agg.Codes)))
+		}
+	}
+
+	// The slowest regions point at where recoveries lose time
+	regional := make([]aliyun.APICallStats, 0, len(stats))
+	for _, s := range stats {
+		if s.Region != "" {
+			regional = append(regional, s)
+		}
+	}
+	sort.Slice(regional, func(i, j int) bool { return regional[i].Average() > regional[j].Average() })
+	if len(regional) > 5 {
+		regional = regional[:5]
+	}
+	if len(regional) > 0 {
+		sb.WriteString("\n<b>最慢的区域调用</b>\n")
+		for _, s := range regional {
+			sb.WriteString(fmt.Sprintf("   %s %s: 平均 %s | %d 次\n", s.Region, s.API, formatLatency(s.Average()), s.Calls))
+		}
+	}
+
+	return m.notifier.Send(sb.String())
+}
+
+// formatErrorCodes lists the error codes of calls, most frequent first
+func formatErrorCodes(codes map[string]int) string {
+	type codeCount struct {
+		code  string
+		count int
+	}
+	var list []codeCount
+	for code, n := range codes {
+		if code != "OK" {
+			list = append(list, codeCount{code, n})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].count != list[j].count {
+			return list[i].count > list[j].count
+		}
+		return list[i].code < list[j].code
+	})
+
+	parts := make([]string, len(list))
+	for i, c := range list {
+		parts[i] = fmt.Sprintf("%s ×%d", c.code, c.count)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatLatency formats an API latency in milliseconds or seconds
+func formatLatency(d time.Duration) string {
+	if d < time.Second {
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+	return fmt.Sprintf("%.1fs", d.Seconds())
+}
@@ -0,0 +1,98 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	log "github.com/sirupsen/logrus"
+)
+
+// startBackoff is an instance whose start failed in consecutive checks
+type startBackoff struct {
+	since    time.Time // the first failed check
+	failures int
+	retryAt  time.Time
+	gaveUp   bool // paused for START_GIVE_UP_HOURS rather than backing off
+}
+
+// startBackoffDelay returns how long to wait after the given number of
+// consecutive failed checks, doubling from START_BACKOFF_BASE up to START_BACKOFF_MAX
+func (m *Monitor) startBackoffDelay(failures int) time.Duration {
+	delay := time.Duration(m.cfg.StartBackoffBase) * time.Second
+	limit := time.Duration(m.cfg.StartBackoffMax) * time.Second
+	for i := 1; i < failures && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		delay = limit
+	}
+	return delay
+}
+
+// backOffStart pushes the next start of an instance whose start failed back,
+// further with every failed check. After START_GIVE_UP_AFTER failed checks it
+// gives up for START_GIVE_UP_HOURS and sends a summary instead.
+func (m *Monitor) backOffStart(inst *aliyun.SpotInstance, err error) {
+	now := time.Now()
+	m.startBackoffsMu.Lock()
+	backoff, ok := m.startBackoffs[inst.InstanceID]
+	if !ok {
+		backoff = &startBackoff{since: now}
+		m.startBackoffs[inst.InstanceID] = backoff
+	}
+	backoff.failures++
+	backoff.gaveUp = m.cfg.StartGiveUpAfter > 0 && backoff.failures >= m.cfg.StartGiveUpAfter
+	if backoff.gaveUp {
+		backoff.retryAt = now.Add(time.Duration(m.cfg.StartGiveUpHours) * time.Hour)
+	} else {
+		backoff.retryAt = now.Add(m.startBackoffDelay(backoff.failures))
+	}
+	state := *backoff
+	m.startBackoffsMu.Unlock()
+	m.saveInstanceState(inst.InstanceID)
+
+	if !state.gaveUp {
+		log.Warnf("Start of instance %s failed in %d consecutive checks, next attempt at %s",
+			inst.InstanceID, state.failures, state.retryAt.Format("15:04:05"))
+		return
+	}
+	log.Errorf("Giving up starting instance %s (%s) after %d failed checks, next attempt at %s",
+		inst.InstanceName, inst.InstanceID, state.failures, state.retryAt.Format("01-02 15:04"))
+	m.bus.publish(busMessage{Topic: topicStartGaveUp, Instance: inst, Attempts: state.failures, Err: err,
+		RequestedAt: state.since, Until: state.retryAt})
+}
+
+// endStartBackoff forgets the failed starts of an instance once it is running
+// or started by hand
+func (m *Monitor) endStartBackoff(inst *aliyun.SpotInstance) {
+	m.startBackoffsMu.Lock()
+	backoff, ok := m.startBackoffs[inst.InstanceID]
+	delete(m.startBackoffs, inst.InstanceID)
+	m.startBackoffsMu.Unlock()
+
+	if ok {
+		log.Infof("Instance %s is running again after %d failed checks", inst.InstanceID, backoff.failures)
+		m.saveInstanceState(inst.InstanceID)
+	}
+}
+
+// startBackoffOf returns the backoff state of an instance whose starts keep failing
+func (m *Monitor) startBackoffOf(instanceID string) (startBackoff, bool) {
+	m.startBackoffsMu.Lock()
+	defer m.startBackoffsMu.Unlock()
+
+	backoff, ok := m.startBackoffs[instanceID]
+	if !ok {
+		return startBackoff{}, false
+	}
+	return *backoff, true
+}
+
+// formatStartBackoff describes the backoff of an instance for /status
+func formatStartBackoff(backoff startBackoff) string {
+	if backoff.gaveUp {
+		return fmt.Sprintf("连续 %d 次检查启动失败，已暂停至 %s", backoff.failures, backoff.retryAt.Local().Format("01-02 15:04"))
+	}
+	return fmt.Sprintf("连续 %d 次检查启动失败，%s 再次尝试", backoff.failures, backoff.retryAt.Local().Format("15:04"))
+}
@@ -0,0 +1,181 @@
+package monitor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	log "github.com/sirupsen/logrus"
+)
+
+// backupObject is the name of the state backup in the OSS bucket, after the prefix
+const backupObject = "state.json.gz"
+
+// backupTimeout bounds a backup or restore
+const backupTimeout = 2 * time.Minute
+
+// backupBuckets are the keyed buckets of the state store that are backed up
+var backupBuckets = []string{instancesBucket, trackedBucket, reclaimsBucket, budgetBucket, trafficBucket, launchConfigsBucket, maintenanceModeBucket}
+
+// backupLogs are the log buckets that are backed up, with the entries they keep
+var backupLogs = map[string]int{
+	eventsBucket: maxStoredEvents,
+	statusBucket: maxStoredStatusChanges,
+}
+
+// stateBackup is the portable form of the state store, independent of
+// whether it is kept in BoltDB or Redis
+type stateBackup struct {
+	CreatedAt time.Time                             `json:"created_at"`
+	Buckets   map[string]map[string]json.RawMessage `json:"buckets"`
+	Logs      map[string][]json.RawMessage          `json:"logs"`
+}
+
+// backupKey returns the object key of the state backup
+func (m *Monitor) backupKey() string {
+	return m.cfg.BackupOSSPrefix + backupObject
+}
+
+// BackupState uploads the state store, event history included, to the OSS
+// bucket. With versioning enabled on the bucket every backup is kept as a
+// version of the same object.
+func (m *Monitor) BackupState() error {
+	if m.backupOSS == nil || m.store == nil {
+		return nil
+	}
+
+	backup := stateBackup{
+		CreatedAt: time.Now(),
+		Buckets:   make(map[string]map[string]json.RawMessage),
+		Logs:      make(map[string][]json.RawMessage),
+	}
+	for _, bucket := range backupBuckets {
+		entries := make(map[string]json.RawMessage)
+		err := m.store.ForEach(bucket, func(key string, data []byte) error {
+			entries[key] = append(json.RawMessage(nil), data...)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", bucket, err)
+		}
+		if len(entries) > 0 {
+			backup.Buckets[bucket] = entries
+		}
+	}
+	for bucket := range backupLogs {
+		var entries []json.RawMessage
+		err := m.store.Tail(bucket, 0, func(data []byte) error {
+			entries = append(entries, append(json.RawMessage(nil), data...))
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", bucket, err)
+		}
+		if len(entries) > 0 {
+			backup.Logs[bucket] = entries
+		}
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(backup); err != nil {
+		return fmt.Errorf("failed to encode state backup: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to compress state backup: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), backupTimeout)
+	defer cancel()
+	if err := m.backupOSS.PutObject(ctx, m.backupKey(), "application/gzip", buf.Bytes()); err != nil {
+		return err
+	}
+	log.Infof("Backed up state to %s/%s (%d events, %d KB)", m.backupOSS, m.backupKey(), len(backup.Logs[eventsBucket]), buf.Len()/1024)
+	return nil
+}
+
+// restoreBackup fills an empty state store from the OSS backup, so a monitor
+// on a fresh machine carries on with the history of the previous one. A store
+// that already holds state is left alone.
+func (m *Monitor) restoreBackup() error {
+	if m.backupOSS == nil || m.store == nil || !m.cfg.BackupRestore {
+		return nil
+	}
+
+	empty, err := m.storeEmpty()
+	if err != nil {
+		return err
+	}
+	if !empty {
+		log.Debug("State store is not empty, not restoring the OSS backup")
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), backupTimeout)
+	defer cancel()
+	if status, err := m.backupOSS.Versioning(ctx); err != nil {
+		log.Warnf("Failed to check versioning of the backup bucket: %v", err)
+	} else if status != "Enabled" {
+		log.Warnf("Versioning is not enabled on %s, each backup replaces the previous one", m.backupOSS)
+	}
+
+	data, err := m.backupOSS.GetObject(ctx, m.backupKey(), m.cfg.BackupRestoreVersion)
+	if errors.Is(err, aliyun.ErrObjectNotFound) && m.cfg.BackupRestoreVersion == "" {
+		log.Infof("No state backup in %s yet, starting with an empty state", m.backupOSS)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to decompress state backup: %w", err)
+	}
+	var backup stateBackup
+	if err := json.NewDecoder(io.LimitReader(zr, 256<<20)).Decode(&backup); err != nil {
+		return fmt.Errorf("failed to decode state backup: %w", err)
+	}
+
+	for bucket, entries := range backup.Buckets {
+		for key, data := range entries {
+			if err := m.store.Put(bucket, key, data); err != nil {
+				return fmt.Errorf("failed to restore %s/%s: %w", bucket, key, err)
+			}
+		}
+	}
+	for bucket, entries := range backup.Logs {
+		keep, known := backupLogs[bucket]
+		if !known {
+			continue
+		}
+		for _, data := range entries {
+			if err := m.store.Append(bucket, data, keep); err != nil {
+				return fmt.Errorf("failed to restore %s: %w", bucket, err)
+			}
+		}
+	}
+	log.Infof("Restored state backup of %s from %s (%d events)",
+		backup.CreatedAt.Local().Format("2006-01-02 15:04"), m.backupOSS, len(backup.Logs[eventsBucket]))
+	return nil
+}
+
+// storeEmpty reports whether the state store holds neither instance state nor events
+func (m *Monitor) storeEmpty() (bool, error) {
+	// Stop at the first entry found
+	errFound := errors.New("found")
+	err := m.store.ForEach(instancesBucket, func(string, []byte) error { return errFound })
+	if err == nil {
+		err = m.store.Tail(eventsBucket, 1, func([]byte) error { return errFound })
+	}
+	if errors.Is(err, errFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
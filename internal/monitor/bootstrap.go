@@ -0,0 +1,111 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	"github.com/iliyian/aliyun-spot-manager/internal/config"
+	"github.com/iliyian/aliyun-spot-manager/internal/playbook"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultBootstrapTimeout bounds a bootstrap script without bootstrap_timeout
+const defaultBootstrapTimeout = 5 * time.Minute
+
+// Only the end of the bootstrap output goes into the notification
+const (
+	bootstrapOutputLines = 10
+	bootstrapOutputChars = 800
+)
+
+// bootstrapResult summarizes a bootstrap script run for the started notification
+type bootstrapResult struct {
+	status string // e.g. "✅ 成功 (12 秒)"
+	output string // the last lines of the output
+	ok     bool
+}
+
+// runBootstrap runs the configured bootstrap script of a healthy instance
+// through Cloud Assistant, returning nil when none is configured. A failed
+// script doesn't fail the start, it is reported in the started notification.
+func (m *Monitor) runBootstrap(ctx context.Context, inst *aliyun.SpotInstance) *bootstrapResult {
+	ic := m.cfg.InstanceConfig(inst.InstanceID, inst.Tags)
+	if ic == nil || ic.BootstrapScript == "" {
+		return nil
+	}
+	timeout := defaultBootstrapTimeout
+	if ic.BootstrapTimeout > 0 {
+		timeout = time.Duration(ic.BootstrapTimeout) * time.Second
+	}
+
+	return m.runCloudAssistant(ctx, inst, ic.BootstrapScript, timeout)
+}
+
+// cloudAssistantStep is the cloud_assistant playbook step, running its
+// command, or the configured bootstrap script without one, on the instance
+func (m *Monitor) cloudAssistantStep(ctx context.Context, step config.PlaybookStep, inst *aliyun.SpotInstance) error {
+	script := playbook.Expand(step.Command, inst)
+	timeout := defaultBootstrapTimeout
+	if script == "" {
+		ic := m.cfg.InstanceConfig(inst.InstanceID, inst.Tags)
+		if ic == nil || ic.BootstrapScript == "" {
+			return fmt.Errorf("cloud_assistant step requires a command or bootstrap_script")
+		}
+		script = ic.BootstrapScript
+		if ic.BootstrapTimeout > 0 {
+			timeout = time.Duration(ic.BootstrapTimeout) * time.Second
+		}
+	}
+	if step.Timeout > 0 {
+		timeout = time.Duration(step.Timeout) * time.Second
+	}
+
+	result := m.runCloudAssistant(ctx, inst, script, timeout)
+	if !result.ok {
+		if result.output != "" {
+			return fmt.Errorf("%s\n%s", result.status, result.output)
+		}
+		return fmt.Errorf("%s", result.status)
+	}
+	return nil
+}
+
+// runCloudAssistant runs a script on an instance through Cloud Assistant
+func (m *Monitor) runCloudAssistant(ctx context.Context, inst *aliyun.SpotInstance, script string, timeout time.Duration) *bootstrapResult {
+	log.Infof("Running bootstrap script on instance %s via Cloud Assistant", inst.InstanceID)
+	result, err := m.ecsClient.RunCommand(ctx, inst.RegionID, inst.InstanceID, inst.OSType, script, timeout)
+	if err != nil {
+		log.Warnf("Bootstrap script on instance %s failed: %v", inst.InstanceID, err)
+		return &bootstrapResult{status: fmt.Sprintf("❌ 无法执行: %v", err)}
+	}
+
+	summary := &bootstrapResult{output: outputTail(result.Output), ok: result.Succeeded()}
+	switch {
+	case summary.ok:
+		summary.status = fmt.Sprintf("✅ 成功 (%.0f 秒)", result.Duration.Seconds())
+	case result.Status == "Success" || result.Status == "Failed":
+		summary.status = fmt.Sprintf("❌ 退出码 %d (%.0f 秒)", result.ExitCode, result.Duration.Seconds())
+	case result.ErrorInfo != "":
+		summary.status = fmt.Sprintf("❌ %s: %s", result.Status, result.ErrorInfo)
+	default:
+		summary.status = "❌ " + result.Status
+	}
+	log.Infof("Bootstrap script on instance %s finished: %s, exit code %d", inst.InstanceID, result.Status, result.ExitCode)
+	return summary
+}
+
+// outputTail returns the last lines of a command output, bounded in length
+func outputTail(output string) string {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	if len(lines) > bootstrapOutputLines {
+		lines = lines[len(lines)-bootstrapOutputLines:]
+	}
+	tail := strings.Join(lines, "\n")
+	if runes := []rune(tail); len(runes) > bootstrapOutputChars {
+		tail = "…" + string(runes[len(runes)-bootstrapOutputChars:])
+	}
+	return strings.TrimSpace(tail)
+}
@@ -0,0 +1,139 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// budgetBucket holds the budget thresholds already alerted in the state store
+const budgetBucket = "budget"
+
+// budgetKey is the key of the alert state in budgetBucket
+const budgetKey = "alerts"
+
+// budgetThresholds are the budget percentages alerted on, in ascending order
+var budgetThresholds = []int{50, 80, 100}
+
+// budgetAlertState records the highest threshold alerted per billing cycle,
+// so each threshold is alerted once a month, also across restarts
+type budgetAlertState struct {
+	Cycle    string `json:"cycle"`    // billing cycle (YYYY-MM) the thresholds belong to
+	Spent    int    `json:"spent"`    // highest threshold crossed by month-to-date spend
+	Estimate int    `json:"estimate"` // highest threshold crossed by the monthly estimate
+}
+
+// crossedThreshold returns the highest of thresholds (percentages of limit,
+// ascending) reached by amount, 0 if none
+func crossedThreshold(amount, limit float64, thresholds []int) int {
+	crossed := 0
+	for _, threshold := range thresholds {
+		if amount >= limit*float64(threshold)/100 {
+			crossed = threshold
+		}
+	}
+	return crossed
+}
+
+// CheckBudget queries this month's billing and alerts when month-to-date
+// spend or the monthly estimate crosses a new budget threshold
+func (m *Monitor) CheckBudget() error {
+	if m.billingClient == nil {
+		return fmt.Errorf("billing client not initialized")
+	}
+	if m.notifier == nil {
+		return fmt.Errorf("telegram notifier not initialized")
+	}
+	budget := m.cfg.MonthlyBudget
+	if budget <= 0 {
+		return nil
+	}
+
+	summary, err := m.queryBilling(nil)
+	if err != nil {
+		return err
+	}
+	spent := crossedThreshold(summary.TotalAmount, budget, budgetThresholds)
+	estimate := crossedThreshold(summary.MonthlyEstimate, budget, budgetThresholds)
+
+	m.budgetAlertsMu.Lock()
+	defer m.budgetAlertsMu.Unlock()
+
+	state := m.budgetAlerts
+	if state.Cycle != summary.BillingCycle {
+		state = budgetAlertState{Cycle: summary.BillingCycle}
+	}
+	if spent <= state.Spent && estimate <= state.Estimate {
+		log.Debugf("Budget check: spent ¥%.2f, estimate ¥%.2f of ¥%.2f, no new threshold",
+			summary.TotalAmount, summary.MonthlyEstimate, budget)
+		return nil
+	}
+
+	level := spent
+	if estimate > level {
+		level = estimate
+	}
+	icon := "ℹ️"
+	switch {
+	case level >= 100:
+		icon = "🚨"
+	case level >= 80:
+		icon = "⚠️"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s <b>预算告警 (%s)</b>\n", icon, summary.BillingCycle))
+	sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+	sb.WriteString(fmt.Sprintf("月度预算: ¥%.2f\n", budget))
+	sb.WriteString(fmt.Sprintf("本月已扣费: ¥%.2f (%.0f%%)\n", summary.TotalAmount, summary.TotalAmount/budget*100))
+	sb.WriteString(fmt.Sprintf("月度估算: ¥%.2f (%.0f%%)\n\n", summary.MonthlyEstimate, summary.MonthlyEstimate/budget*100))
+	if spent > state.Spent {
+		if spent >= 100 {
+			sb.WriteString("❗ 本月扣费已超出预算\n")
+		} else {
+			sb.WriteString(fmt.Sprintf("本月扣费已达预算的 %d%%\n", spent))
+		}
+	}
+	if estimate > state.Estimate {
+		if estimate >= 100 {
+			sb.WriteString("❗ 按当前用量，本月费用预计超出预算\n")
+		} else {
+			sb.WriteString(fmt.Sprintf("按当前用量，本月费用预计达到预算的 %d%%\n", estimate))
+		}
+	}
+
+	if err := m.notifier.Send(sb.String()); err != nil {
+		return fmt.Errorf("failed to send budget alert: %w", err)
+	}
+	log.Infof("Budget alert sent (spent ¥%.2f, estimate ¥%.2f, budget ¥%.2f)",
+		summary.TotalAmount, summary.MonthlyEstimate, budget)
+
+	if spent > state.Spent {
+		state.Spent = spent
+	}
+	if estimate > state.Estimate {
+		state.Estimate = estimate
+	}
+	m.budgetAlerts = state
+	if m.store != nil {
+		if err := m.store.Put(budgetBucket, budgetKey, state); err != nil {
+			log.Warnf("Failed to save budget alert state: %v", err)
+		}
+	}
+	return nil
+}
+
+// loadBudgetAlerts restores the budget thresholds already alerted
+func (m *Monitor) loadBudgetAlerts() error {
+	return m.store.ForEach(budgetBucket, func(key string, data []byte) error {
+		if key != budgetKey {
+			return nil
+		}
+		if err := json.Unmarshal(data, &m.budgetAlerts); err != nil {
+			log.Warnf("Ignoring corrupt budget alert state: %v", err)
+		}
+		return nil
+	})
+}
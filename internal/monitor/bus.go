@@ -0,0 +1,117 @@
+package monitor
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	log "github.com/sirupsen/logrus"
+)
+
+// Topics published by the check and start workflow
+const (
+	topicStatusChanged    = "status_changed"
+	topicReclaimDetected  = "reclaim_detected"
+	topicStartRequested   = "start_requested"
+	topicStartSucceeded   = "start_succeeded"
+	topicStartFailed      = "start_failed"
+	topicStartGaveUp      = "start_gave_up"
+	topicAddressChanged   = "address_changed"
+	topicHealthPassed     = "health_passed"
+	topicHealthFailed     = "health_failed"
+	topicInterruptionDue  = "interruption_due"
+	topicNoCapacity       = "no_capacity"
+	topicRecreated        = "instance_recreated"
+	topicPriceLimitNear   = "price_limit_near"
+	topicRebid            = "instance_rebid"
+	topicNoCredits        = "cpu_credits_exhausted"
+	topicInstanceReleased = "instance_released"
+)
+
+// topicAll subscribes a handler to every topic
+const topicAll = "*"
+
+// busMessage describes something that happened to an instance
+// Only the fields relevant to the topic are set
+type busMessage struct {
+	Topic    string
+	Time     time.Time
+	Ctx      context.Context // trace context of the publisher
+	Instance *aliyun.SpotInstance
+
+	Status      string               // status_changed: the new status
+	PrevStatus  string               // status_changed: the last seen status, empty on the first poll
+	PrevAddress string               // address_changed: the public address before the change
+	Unexpected  bool                 // address_changed: seen on a running instance rather than after a start
+	Attempt     int                  // start_requested: the 1-based attempt number
+	Attempts    int                  // start_requested, start_failed: attempts allowed; start_gave_up: failed checks
+	RequestedAt time.Time            // start_succeeded: when the first start attempt was made; start_gave_up: the first failed check
+	Until       time.Time            // start_gave_up: when starts are attempted again
+	Duration    time.Duration        // health_passed: time from the first attempt to healthy
+	Check       string               // health_passed: result summary; health_failed: check name
+	Timeout     time.Duration        // health_failed: how long the check waited
+	Playbook    bool                 // health_*: the outcome comes from a recovery playbook; address_changed: changed by a playbook step
+	Err         error                // start_failed, start_gave_up, health_failed, no_capacity
+	Event       *aliyun.SystemEvent  // interruption_due: the announced reclaim or maintenance
+	Replacement *aliyun.SpotInstance // instance_recreated, instance_rebid: the instance created
+	Address     string               // instance_recreated, instance_rebid: the EIP moved over, empty if none
+	Price       float64              // price_limit_near: the market price
+	Credits     *aliyun.CPUCredits   // cpu_credits_exhausted: the latest credit data
+	EIP         string               // health_passed: outcome of associating the configured EIP, empty if not needed
+	Bootstrap   *bootstrapResult     // health_passed: outcome of the bootstrap script, nil if none
+}
+
+// busHandler handles a published message
+type busHandler func(msg busMessage)
+
+// bus is a synchronous in-process publish/subscribe bus. Handlers run on the
+// publisher's goroutine in subscription order, so notifications, hooks and
+// persistence observe an instance's messages in the order they happened.
+type bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]busHandler
+}
+
+// newBus creates an empty bus
+func newBus() *bus {
+	return &bus{handlers: make(map[string][]busHandler)}
+}
+
+// subscribe registers a handler for a topic, or every topic with topicAll
+func (b *bus) subscribe(topic string, handler busHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[topic] = append(b.handlers[topic], handler)
+}
+
+// publish delivers a message to the handlers of its topic, then to the
+// handlers of every topic
+func (b *bus) publish(msg busMessage) {
+	if msg.Time.IsZero() {
+		msg.Time = time.Now()
+	}
+	if msg.Ctx == nil {
+		msg.Ctx = context.Background()
+	}
+
+	b.mu.RLock()
+	handlers := make([]busHandler, 0, len(b.handlers[msg.Topic])+len(b.handlers[topicAll]))
+	handlers = append(handlers, b.handlers[msg.Topic]...)
+	handlers = append(handlers, b.handlers[topicAll]...)
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		b.dispatch(handler, msg)
+	}
+}
+
+// dispatch runs a handler, keeping a panicking subscriber from taking down the workflow
+func (b *bus) dispatch(handler busHandler, msg busMessage) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("Subscriber of %s for instance %s panicked: %v", msg.Topic, msg.Instance.InstanceID, r)
+		}
+	}()
+	handler(msg)
+}
@@ -0,0 +1,157 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrNoCapacity is returned when a start fails because the zone is sold out
+var ErrNoCapacity = errors.New("no spot capacity in the zone")
+
+// capacityWait is an instance that failed to start for lack of capacity
+type capacityWait struct {
+	since    time.Time
+	retryAt  time.Time
+	attempts int
+}
+
+// capacityRetryInterval returns the slow retry interval, 0 when disabled
+func (m *Monitor) capacityRetryInterval() time.Duration {
+	return time.Duration(m.cfg.CapacityRetryInterval) * time.Second
+}
+
+// waitForCapacity moves an instance to the slow retry schedule, notifying
+// only when it starts waiting
+func (m *Monitor) waitForCapacity(inst *aliyun.SpotInstance, err error) {
+	now := time.Now()
+	m.capacityWaitsMu.Lock()
+	wait, waiting := m.capacityWaits[inst.InstanceID]
+	if !waiting {
+		wait = &capacityWait{since: now}
+		m.capacityWaits[inst.InstanceID] = wait
+	}
+	wait.attempts++
+	wait.retryAt = now.Add(m.capacityRetryInterval())
+	attempts := wait.attempts
+	m.capacityWaitsMu.Unlock()
+	m.saveInstanceState(inst.InstanceID)
+
+	if waiting {
+		log.Infof("Still no capacity for instance %s after %d attempts, retrying at %s",
+			inst.InstanceID, attempts, wait.retryAt.Format("15:04:05"))
+		return
+	}
+	log.Warnf("No capacity for instance %s (%s) in %s, retrying every %s",
+		inst.InstanceName, inst.InstanceID, inst.ZoneID, m.capacityRetryInterval())
+	m.bus.publish(busMessage{Topic: topicNoCapacity, Instance: inst, Err: err})
+}
+
+// endCapacityWait takes an instance off the slow retry schedule once a start went through
+func (m *Monitor) endCapacityWait(inst *aliyun.SpotInstance) {
+	m.capacityWaitsMu.Lock()
+	wait, waiting := m.capacityWaits[inst.InstanceID]
+	delete(m.capacityWaits, inst.InstanceID)
+	m.capacityWaitsMu.Unlock()
+
+	if waiting {
+		log.Infof("Capacity is back for instance %s after %s", inst.InstanceID, time.Since(wait.since).Round(time.Second))
+		m.saveInstanceState(inst.InstanceID)
+	}
+}
+
+// capacityRetryAt returns when an instance waiting for capacity is retried
+func (m *Monitor) capacityRetryAt(instanceID string) (time.Time, bool) {
+	m.capacityWaitsMu.Lock()
+	defer m.capacityWaitsMu.Unlock()
+
+	wait, ok := m.capacityWaits[instanceID]
+	if !ok {
+		return time.Time{}, false
+	}
+	return wait.retryAt, true
+}
+
+// recreateDue reports whether an instance has waited for capacity long enough to be re-created
+func (m *Monitor) recreateDue(instanceID string) bool {
+	if m.cfg.RecreateAfter <= 0 {
+		return false
+	}
+	m.capacityWaitsMu.Lock()
+	defer m.capacityWaitsMu.Unlock()
+	wait, ok := m.capacityWaits[instanceID]
+	return ok && wait.attempts >= m.cfg.RecreateAfter
+}
+
+// recreateInstance replaces an instance stuck without capacity by a copy in
+// another zone, moves its EIP over and tracks the copy instead. The old
+// instance is kept stopped and left alone by auto-start, so its disks can be
+// recovered or released by hand.
+func (m *Monitor) recreateInstance(ctx context.Context, inst *aliyun.SpotInstance) error {
+	log.Warnf("Instance %s (%s) had no capacity for %d attempts, re-creating it in another zone",
+		inst.InstanceName, inst.InstanceID, m.cfg.RecreateAfter)
+
+	requestedAt := time.Now()
+	replacement, address, err := m.replaceTracked(ctx, inst, aliyun.ReplaceOptions{Zones: splitList(m.cfg.RecreateZones)})
+	if err != nil {
+		return fmt.Errorf("failed to re-create instance: %w", err)
+	}
+
+	m.bus.publish(busMessage{Topic: topicRecreated, Instance: inst, Replacement: replacement, Address: address})
+	m.bus.publish(busMessage{Topic: topicStartSucceeded, Ctx: ctx, Instance: replacement, RequestedAt: requestedAt})
+	return nil
+}
+
+// replaceTracked creates a copy of an instance, waits for it to run, moves
+// the EIP over if enabled and tracks the copy instead, returning it and the
+// moved address. The old instance is marked manually stopped.
+func (m *Monitor) replaceTracked(ctx context.Context, inst *aliyun.SpotInstance, opts aliyun.ReplaceOptions) (*aliyun.SpotInstance, string, error) {
+	if m.cfg.LaunchConfigCapture {
+		// The stored configuration covers an instance that can no longer be described
+		if cfg, err := m.captureLaunchConfig(ctx, inst); err == nil {
+			opts.Config = cfg
+		} else if stored := m.launchConfig(inst.InstanceID); stored != nil {
+			log.Warnf("Failed to capture launch configuration of instance %s, using the one captured at %s: %v",
+				inst.InstanceID, stored.CapturedAt.Local().Format("2006-01-02 15:04"), err)
+			opts.Config = stored
+		}
+	}
+
+	replacement, err := m.ecsClient.ReplaceInstance(ctx, inst.RegionID, inst.InstanceID, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := m.waitForStatus(ctx, replacement.RegionID, replacement.InstanceID, "Running", 5*time.Minute, m.pollInterval()); err != nil {
+		return nil, "", fmt.Errorf("replacement %s did not start: %w", replacement.InstanceID, err)
+	}
+
+	var address string
+	if m.cfg.RecreateMoveEIP {
+		address, err = m.ecsClient.MoveEIP(ctx, inst.RegionID, inst.InstanceID, replacement.InstanceID)
+		if err != nil {
+			log.Warnf("Failed to move the EIP of instance %s to %s: %v", inst.InstanceID, replacement.InstanceID, err)
+		} else if address != "" {
+			log.Infof("Moved EIP %s from instance %s to %s", address, inst.InstanceID, replacement.InstanceID)
+		}
+	}
+	if updated, err := m.ecsClient.GetInstance(ctx, replacement.RegionID, replacement.InstanceID); err != nil {
+		log.Warnf("Failed to get details of instance %s: %v", replacement.InstanceID, err)
+	} else {
+		replacement = updated
+	}
+
+	// Track the replacement; the old instance stays stopped
+	m.capacityWaitsMu.Lock()
+	delete(m.capacityWaits, inst.InstanceID)
+	m.capacityWaitsMu.Unlock()
+	m.setManuallyStopped(inst.InstanceID, true)
+	m.mu.Lock()
+	m.instances = append(m.instances, replacement)
+	m.mu.Unlock()
+	m.saveTrackedInstances()
+	return replacement, address, nil
+}
@@ -0,0 +1,67 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	log "github.com/sirupsen/logrus"
+)
+
+// HandleCloudEvent reacts to an ECS system event pushed by EventBridge or
+// CloudMonitor: a stopped instance is checked and started right away instead
+// of at the next check, an announced reclaim is handled like an interruption
+// event. Events of untracked instances are ignored.
+func (m *Monitor) HandleCloudEvent(event *aliyun.CloudEvent) error {
+	inst := m.findInstance(event.InstanceID)
+	if inst == nil {
+		log.Debugf("Ignoring %s event of untracked instance %s", event.Name, event.InstanceID)
+		return nil
+	}
+
+	switch event.Name {
+	case aliyun.EventSpotInterruption:
+		notBefore := event.Time.Add(aliyun.SpotInterruptionLead)
+		systemEvent := &aliyun.SystemEvent{
+			EventID:     fmt.Sprintf("pushed-%s-%d", inst.InstanceID, event.Time.Unix()),
+			InstanceID:  inst.InstanceID,
+			RegionID:    inst.RegionID,
+			EventType:   aliyun.EventSpotInterruption,
+			Status:      "Scheduled",
+			Reason:      "pushed by an event subscription",
+			NotBefore:   notBefore,
+			PublishTime: event.Time,
+		}
+		if m.announceInterruption(inst, systemEvent) {
+			log.Warnf("Pushed event: instance %s (%s) will be reclaimed at %s, in %s",
+				inst.InstanceName, inst.InstanceID, notBefore.Local().Format("15:04:05"),
+				time.Until(notBefore).Round(time.Second))
+		}
+	case aliyun.EventStateChange:
+		log.Infof("Pushed event: instance %s (%s) is now %s", inst.InstanceName, inst.InstanceID, event.State)
+		if event.State == "Stopped" {
+			go m.checkNow(inst)
+		} else if event.State != "" {
+			m.observeStatus(inst, event.State)
+		}
+	default:
+		log.Debugf("Ignoring %s event of instance %s", event.Name, inst.InstanceID)
+	}
+	return nil
+}
+
+// checkNow checks a single instance outside the regular check, starting it if stopped
+func (m *Monitor) checkNow(inst *aliyun.SpotInstance) {
+	if m.isClosing() {
+		return
+	}
+	if !m.claimInstance(inst.InstanceID) {
+		log.Debugf("Instance %s is already being handled, skipping", inst.InstanceID)
+		return
+	}
+	defer m.releaseInstance(inst.InstanceID)
+
+	if err := m.checkInstance(m.ctx, inst); err != nil {
+		log.Errorf("Failed to check instance %s: %v", inst.InstanceID, err)
+	}
+}
@@ -0,0 +1,256 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	"github.com/iliyian/aliyun-spot-manager/internal/notify"
+	"github.com/iliyian/aliyun-spot-manager/internal/tracing"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrInstanceNotFound is returned for instance IDs that are not tracked
+	ErrInstanceNotFound = errors.New("instance not tracked")
+	// ErrInstanceNotStopped is returned when starting an instance that isn't stopped
+	ErrInstanceNotStopped = errors.New("instance is not stopped")
+	// ErrInstanceNotRunning is returned when stopping an instance that isn't running
+	ErrInstanceNotRunning = errors.New("instance is not running")
+	// ErrInvalidTimeRange is returned for unsupported relative time ranges
+	ErrInvalidTimeRange = errors.New("unsupported time range")
+	// ErrShuttingDown is returned when starting an instance during shutdown
+	ErrShuttingDown = errors.New("monitor is shutting down")
+	// ErrInstanceBusy is returned when the instance is already being checked or started
+	ErrInstanceBusy = errors.New("instance is being handled")
+)
+
+// Instances returns a copy of the tracked instances
+// With live set, the current status of each instance is fetched from the API
+func (m *Monitor) Instances(live bool) []*aliyun.SpotInstance {
+	m.mu.RLock()
+	instances := make([]*aliyun.SpotInstance, len(m.instances))
+	for i, inst := range m.instances {
+		copied := *inst
+		instances[i] = &copied
+	}
+	m.mu.RUnlock()
+
+	if live {
+		for _, inst := range instances {
+			status, err := m.ecsClient.GetInstanceStatus(m.ctx, inst.RegionID, inst.InstanceID)
+			if err != nil {
+				log.Warnf("Failed to get status of instance %s: %v", inst.InstanceID, err)
+				status = "Unknown"
+			}
+			inst.Status = status
+		}
+	}
+	return instances
+}
+
+// findInstance returns the tracked instance with the given ID
+func (m *Monitor) findInstance(instanceID string) *aliyun.SpotInstance {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, inst := range m.instances {
+		if inst.InstanceID == instanceID {
+			return inst
+		}
+	}
+	return nil
+}
+
+// StartInstanceByID starts a stopped tracked instance in the background
+// The outcome is reported through the usual notifications
+func (m *Monitor) StartInstanceByID(instanceID string) error {
+	inst := m.findInstance(instanceID)
+	if inst == nil {
+		return ErrInstanceNotFound
+	}
+
+	status, err := m.ecsClient.GetInstanceStatus(m.ctx, inst.RegionID, inst.InstanceID)
+	if err != nil {
+		return fmt.Errorf("failed to get status: %w", err)
+	}
+	if status != "Stopped" {
+		return fmt.Errorf("%w (status: %s)", ErrInstanceNotStopped, status)
+	}
+
+	if m.isClosing() {
+		return ErrShuttingDown
+	}
+	if !m.claimInstance(inst.InstanceID) {
+		return ErrInstanceBusy
+	}
+
+	log.Infof("Manual start requested for instance %s (%s)", inst.InstanceName, inst.InstanceID)
+	m.setManuallyStopped(inst.InstanceID, false)
+	m.endStartBackoff(inst)
+	m.recordEvent(inst, EventManualStart, "手动启动")
+	go func() {
+		defer m.releaseInstance(inst.InstanceID)
+		ctx, span := tracing.Start(m.ctx, "monitor.StartInstanceByID", tracing.Instance(inst.RegionID, inst.InstanceID)...)
+		err := m.startInstance(ctx, inst)
+		tracing.End(span, err)
+		if err != nil {
+			log.Errorf("Manual start of instance %s failed: %v", inst.InstanceID, err)
+		}
+	}()
+	return nil
+}
+
+// StartInstanceAndWait starts a stopped tracked instance and waits until it
+// is running and healthy, for one-shot use from the command line
+func (m *Monitor) StartInstanceAndWait(ctx context.Context, instanceID string) error {
+	inst := m.findInstance(instanceID)
+	if inst == nil {
+		return ErrInstanceNotFound
+	}
+
+	status, err := m.ecsClient.GetInstanceStatus(ctx, inst.RegionID, inst.InstanceID)
+	if err != nil {
+		return fmt.Errorf("failed to get status: %w", err)
+	}
+	if status != "Stopped" {
+		return fmt.Errorf("%w (status: %s)", ErrInstanceNotStopped, status)
+	}
+
+	if !m.claimInstance(inst.InstanceID) {
+		return ErrInstanceBusy
+	}
+	defer m.releaseInstance(inst.InstanceID)
+
+	log.Infof("Manual start requested for instance %s (%s)", inst.InstanceName, inst.InstanceID)
+	m.setManuallyStopped(inst.InstanceID, false)
+	m.recordEvent(inst, EventManualStart, "手动启动")
+	return m.startInstance(ctx, inst)
+}
+
+// StopInstanceByID stops a running tracked instance
+// Auto-start leaves the instance alone until it is started again
+func (m *Monitor) StopInstanceByID(instanceID string) error {
+	inst := m.findInstance(instanceID)
+	if inst == nil {
+		return ErrInstanceNotFound
+	}
+
+	status, err := m.ecsClient.GetInstanceStatus(m.ctx, inst.RegionID, inst.InstanceID)
+	if err != nil {
+		return fmt.Errorf("failed to get status: %w", err)
+	}
+	if status != "Running" {
+		return fmt.Errorf("%w (status: %s)", ErrInstanceNotRunning, status)
+	}
+
+	log.Infof("Manual stop requested for instance %s (%s)", inst.InstanceName, inst.InstanceID)
+	m.setManuallyStopped(inst.InstanceID, true)
+	if err := m.ecsClient.StopInstance(m.ctx, inst.RegionID, inst.InstanceID, false); err != nil {
+		m.setManuallyStopped(inst.InstanceID, false)
+		return err
+	}
+	m.recordEvent(inst, EventManualStop, "手动停止，暂停自动启动")
+	return nil
+}
+
+// setManuallyStopped marks or clears an instance as stopped on request
+func (m *Monitor) setManuallyStopped(instanceID string, stopped bool) {
+	m.manualStopsMu.Lock()
+	if stopped {
+		m.manualStops[instanceID] = true
+	} else {
+		delete(m.manualStops, instanceID)
+	}
+	m.manualStopsMu.Unlock()
+
+	m.saveInstanceState(instanceID)
+}
+
+// isManuallyStopped reports whether an instance was stopped on request
+func (m *Monitor) isManuallyStopped(instanceID string) bool {
+	m.manualStopsMu.Lock()
+	defer m.manualStopsMu.Unlock()
+	return m.manualStops[instanceID]
+}
+
+// SetMuted mutes or unmutes the notifications of a tracked instance
+func (m *Monitor) SetMuted(instanceID string, muted bool) error {
+	inst := m.findInstance(instanceID)
+	if inst == nil {
+		return ErrInstanceNotFound
+	}
+
+	m.mutedMu.Lock()
+	if muted {
+		m.muted[instanceID] = true
+	} else {
+		delete(m.muted, instanceID)
+	}
+	m.mutedMu.Unlock()
+	m.saveInstanceState(instanceID)
+
+	if muted {
+		log.Infof("Notifications muted for instance %s", instanceID)
+		m.recordEvent(inst, EventMuted, "已静音通知")
+	} else {
+		log.Infof("Notifications unmuted for instance %s", instanceID)
+		m.recordEvent(inst, EventUnmuted, "已恢复通知")
+	}
+	return nil
+}
+
+// IsMuted reports whether notifications of an instance are muted
+func (m *Monitor) IsMuted(instanceID string) bool {
+	m.mutedMu.RLock()
+	defer m.mutedMu.RUnlock()
+	return m.muted[instanceID]
+}
+
+// notifierFor returns the notifier for an instance, or nil if it is muted
+// or the maintenance mode is on
+func (m *Monitor) notifierFor(instanceID string) *notify.TelegramNotifier {
+	if m.IsMuted(instanceID) || m.inMaintenance() {
+		return nil
+	}
+	return m.notifier
+}
+
+// QueryBilling returns billing for a relative range such as "today" or "last week"
+// An empty range means the current month
+func (m *Monitor) QueryBilling(rangeExpr string) (*aliyun.BillingSummary, error) {
+	if m.billingClient == nil {
+		return nil, fmt.Errorf("billing client not initialized")
+	}
+
+	period, err := parseTimeRange(strings.Fields(rangeExpr), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return m.queryBilling(period)
+}
+
+// QueryTraffic returns CDT traffic for a relative range such as "today" or "last week",
+// optionally followed by a region ID. An empty range means the current month
+func (m *Monitor) QueryTraffic(rangeExpr string) (*aliyun.TrafficSummary, error) {
+	if m.trafficClient == nil {
+		return nil, fmt.Errorf("traffic client not initialized")
+	}
+
+	region, rest := splitRegionArg(strings.Fields(rangeExpr))
+	period, err := parseTimeRange(rest, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	summary, err := m.queryTraffic(period)
+	if err != nil {
+		return nil, err
+	}
+	if region != "" {
+		summary = summary.FilterRegion(region)
+	}
+	return summary, nil
+}
@@ -0,0 +1,138 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	"github.com/iliyian/aliyun-spot-manager/internal/config"
+	"github.com/joho/godotenv"
+	log "github.com/sirupsen/logrus"
+)
+
+// aliyunCredentials returns the Aliyun API credentials from the config
+func aliyunCredentials(cfg *config.Config) aliyun.Credentials {
+	return aliyun.Credentials{
+		AccessKeyID:         cfg.AliyunAccessKeyID,
+		AccessKeySecret:     cfg.AliyunAccessKeySecret,
+		RoleArn:             cfg.AliyunRoleArn,
+		RoleSessionName:     cfg.AliyunRoleSessionName,
+		RoleSessionDuration: cfg.AliyunRoleSessionDuration,
+		EcsRamRole:          cfg.AliyunEcsRamRole,
+	}
+}
+
+// StartCredentialWatcher watches the credentials file and reloads the
+// AccessKey when it changes, so key rotation doesn't require a restart
+// It stops when ctx is cancelled.
+func (m *Monitor) StartCredentialWatcher(ctx context.Context) {
+	// The ECS RAM role has no AccessKey to rotate, the SDK refreshes its token
+	if m.cfg.AliyunEcsRamRole != "" || m.cfg.CredentialsFile == "" || m.cfg.CredentialsWatchInterval <= 0 {
+		return
+	}
+
+	go func() {
+		log.Infof("Watching %s for credential changes", m.cfg.CredentialsFile)
+		var lastModTime time.Time
+		if info, err := os.Stat(m.cfg.CredentialsFile); err == nil {
+			lastModTime = info.ModTime()
+		}
+
+		ticker := time.NewTicker(time.Duration(m.cfg.CredentialsWatchInterval) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			info, err := os.Stat(m.cfg.CredentialsFile)
+			if err != nil {
+				log.Debugf("Failed to stat credentials file: %v", err)
+				continue
+			}
+			if !info.ModTime().After(lastModTime) {
+				continue
+			}
+			lastModTime = info.ModTime()
+
+			if err := m.reloadCredentials("配置文件变更"); err != nil {
+				log.Warnf("Failed to reload credentials: %v", err)
+			}
+		}
+	}()
+}
+
+// handleAuthError reloads credentials after an API call failed with an auth error
+func (m *Monitor) handleAuthError(err error) {
+	log.Warnf("Aliyun API authentication failed, reloading credentials: %v", err)
+	m.setCredentialsRejected(true)
+	if reloadErr := m.reloadCredentials("鉴权失败"); reloadErr != nil {
+		log.Warnf("Failed to reload credentials: %v", reloadErr)
+	}
+}
+
+// reloadCredentials reads the AccessKey from the credentials file and
+// re-creates all Aliyun clients if it differs from the one in use
+// The credential fields of m.cfg are only read or written under credsMu
+// once the monitor is running.
+func (m *Monitor) reloadCredentials(reason string) error {
+	if m.cfg.CredentialsFile == "" {
+		return fmt.Errorf("no credentials file configured")
+	}
+
+	env, err := godotenv.Read(m.cfg.CredentialsFile)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", m.cfg.CredentialsFile, err)
+	}
+	accessKeyID := env["ALIYUN_ACCESS_KEY_ID"]
+	accessKeySecret := env["ALIYUN_ACCESS_KEY_SECRET"]
+	if accessKeyID == "" || accessKeySecret == "" {
+		return fmt.Errorf("credentials file %s has no AccessKey", m.cfg.CredentialsFile)
+	}
+
+	m.credsMu.Lock()
+	defer m.credsMu.Unlock()
+
+	if accessKeyID == m.cfg.AliyunAccessKeyID && accessKeySecret == m.cfg.AliyunAccessKeySecret {
+		log.Debug("Credentials unchanged, skipping reload")
+		return nil
+	}
+
+	creds := aliyunCredentials(m.cfg)
+	creds.AccessKeyID = accessKeyID
+	creds.AccessKeySecret = accessKeySecret
+	m.ecsClient.UpdateCredentials(creds)
+	if m.billingClient != nil {
+		if err := m.billingClient.UpdateCredentials(creds); err != nil {
+			return err
+		}
+	}
+	if m.trafficClient != nil {
+		if err := m.trafficClient.UpdateCredentials(creds); err != nil {
+			return err
+		}
+	}
+	for _, ossClient := range []*aliyun.OSSClient{m.statusPageOSS, m.backupOSS} {
+		if ossClient != nil {
+			if err := ossClient.UpdateCredentials(creds); err != nil {
+				return err
+			}
+		}
+	}
+	m.cfg.AliyunAccessKeyID = accessKeyID
+	m.cfg.AliyunAccessKeySecret = accessKeySecret
+
+	log.Infof("Aliyun credentials refreshed (%s), AccessKey ID: %s", reason, config.MaskSecret(accessKeyID))
+
+	if m.notifier != nil {
+		if err := m.notifier.NotifyCredentialsRefreshed(config.MaskSecret(accessKeyID), reason); err != nil {
+			log.Warnf("Failed to send credentials refreshed notification: %v", err)
+		}
+	}
+
+	return nil
+}
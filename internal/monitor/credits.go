@@ -0,0 +1,67 @@
+package monitor
+
+import (
+	"fmt"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	log "github.com/sirupsen/logrus"
+)
+
+// lastStatus returns the status of an instance seen by the last check, empty before the first
+func (m *Monitor) lastStatus(instanceID string) string {
+	m.statusesMu.Lock()
+	defer m.statusesMu.Unlock()
+	return m.statuses[instanceID]
+}
+
+// CheckCPUCredits alerts once when a running burstable instance in standard
+// mode runs out of CPU credits. It is then held to its baseline performance,
+// which looks like an outage but isn't a reclaim.
+func (m *Monitor) CheckCPUCredits() error {
+	var lastErr error
+	for _, inst := range m.Instances(false) {
+		if !inst.Burstable() || inst.Unlimited() || m.lastStatus(inst.InstanceID) != "Running" || m.isClosing() {
+			continue
+		}
+		credits, err := m.ecsClient.GetCPUCredits(m.ctx, inst.RegionID, inst.InstanceID)
+		if err != nil {
+			log.Warnf("Failed to get CPU credits of instance %s: %v", inst.InstanceID, err)
+			lastErr = err
+			continue
+		}
+		if credits == nil {
+			log.Debugf("No CPU credit data of instance %s yet", inst.InstanceID)
+			continue
+		}
+
+		exhausted := credits.Balance <= m.cfg.CPUCreditAlertThreshold
+		m.creditAlertsMu.Lock()
+		alerted := m.creditAlerts[inst.InstanceID]
+		m.creditAlerts[inst.InstanceID] = exhausted
+		m.creditAlertsMu.Unlock()
+
+		log.Debugf("Instance %s has %.1f CPU credits, using %.1f a minute", inst.InstanceID, credits.Balance, credits.Usage)
+		if exhausted && !alerted {
+			log.Warnf("Instance %s (%s) ran out of CPU credits (%.1f left), its CPU is held to the baseline",
+				inst.InstanceName, inst.InstanceID, credits.Balance)
+			m.bus.publish(busMessage{Topic: topicNoCredits, Instance: inst, Credits: credits})
+		}
+	}
+
+	if lastErr != nil {
+		return fmt.Errorf("failed to get CPU credits of some instances: %w", lastErr)
+	}
+	return nil
+}
+
+// formatCredits describes the CPU credits of a burstable instance for /status
+func formatCredits(inst *aliyun.SpotInstance, credits *aliyun.CPUCredits, threshold float64) string {
+	text := fmt.Sprintf("%.1f（每分钟消耗 %.1f）", credits.Balance, credits.Usage)
+	switch {
+	case inst.Unlimited():
+		text += fmt.Sprintf("，无限模式，超额 %.1f", credits.SurplusUsage)
+	case credits.Balance <= threshold:
+		text += " ⚠️ 已耗尽，性能受限"
+	}
+	return text
+}
@@ -0,0 +1,181 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	log "github.com/sirupsen/logrus"
+)
+
+// dependenciesOf returns the tracked instances an instance depends on,
+// matched by instance ID or name
+func (m *Monitor) dependenciesOf(inst *aliyun.SpotInstance) []*aliyun.SpotInstance {
+	ic := m.cfg.InstanceConfig(inst.InstanceID, inst.Tags)
+	if ic == nil || len(ic.DependsOn) == 0 {
+		return nil
+	}
+
+	var deps []*aliyun.SpotInstance
+	for _, name := range ic.DependsOn {
+		matched := m.matchInstances(name)
+		if name == "" || len(matched) == 0 {
+			log.Warnf("Dependency %q of instance %s is not a tracked instance, ignoring it", name, inst.InstanceID)
+			continue
+		}
+		for _, dep := range matched {
+			if dep.InstanceID != inst.InstanceID {
+				deps = append(deps, dep)
+			}
+		}
+	}
+	return deps
+}
+
+// dependencyTimeout returns how long the start of an instance waits for its
+// dependencies, per-instance overrides taking precedence
+func (m *Monitor) dependencyTimeout(inst *aliyun.SpotInstance) time.Duration {
+	if ic := m.cfg.InstanceConfig(inst.InstanceID, inst.Tags); ic != nil && ic.DependsOnTimeout > 0 {
+		return time.Duration(ic.DependsOnTimeout) * time.Second
+	}
+	return time.Duration(m.cfg.DependencyTimeout) * time.Second
+}
+
+// sortByDependencies orders instances so dependencies come before the
+// instances depending on them, keeping the order otherwise. A check then
+// starts databases before the apps waiting for them even with a low
+// CHECK_CONCURRENCY.
+func (m *Monitor) sortByDependencies(instances []*aliyun.SpotInstance) {
+	depths := make(map[string]int, len(instances))
+	visiting := make(map[string]bool)
+
+	var depth func(inst *aliyun.SpotInstance) int
+	depth = func(inst *aliyun.SpotInstance) int {
+		if d, ok := depths[inst.InstanceID]; ok {
+			return d
+		}
+		// A cycle is cut where it's found; the wait timeout resolves it at start
+		if visiting[inst.InstanceID] {
+			return 0
+		}
+		visiting[inst.InstanceID] = true
+		d := 0
+		for _, dep := range m.dependenciesOf(inst) {
+			if dd := depth(dep) + 1; dd > d {
+				d = dd
+			}
+		}
+		delete(visiting, inst.InstanceID)
+		depths[inst.InstanceID] = d
+		return d
+	}
+
+	for _, inst := range instances {
+		depth(inst)
+	}
+	sort.SliceStable(instances, func(i, j int) bool {
+		return depths[instances[i].InstanceID] < depths[instances[j].InstanceID]
+	})
+}
+
+// waitForDependencies waits until every dependency of an instance is running
+// and healthy. Dependencies that won't come back on their own, i.e. stopped
+// on request or given up on, aren't waited for. When the timeout passes the
+// instance is started anyway and the wait is recorded in the event history.
+func (m *Monitor) waitForDependencies(ctx context.Context, inst *aliyun.SpotInstance) error {
+	deps := m.dependenciesOf(inst)
+	if len(deps) == 0 {
+		return nil
+	}
+
+	timeout := m.dependencyTimeout(inst)
+	deadline := time.Now().Add(timeout)
+	interval := m.pollInterval()
+	for _, dep := range deps {
+		logged := false
+		for {
+			reason := m.dependencyNotReady(ctx, dep)
+			if reason == "" {
+				break
+			}
+			if !logged {
+				log.Infof("Instance %s waits for dependency %s (%s): %s", inst.InstanceID, dep.InstanceName, dep.InstanceID, reason)
+				logged = true
+			}
+			if time.Now().After(deadline) {
+				log.Warnf("Dependency %s of instance %s is still not ready after %s (%s), starting anyway",
+					dep.InstanceID, inst.InstanceID, timeout, reason)
+				m.recordEvent(inst, EventDependency, fmt.Sprintf("依赖 %s 等待 %.0f 分钟仍未就绪（%s），仍然启动", dep.InstanceName, timeout.Minutes(), reason))
+				return nil
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(interval):
+			}
+		}
+		if logged {
+			log.Infof("Dependency %s of instance %s is ready", dep.InstanceID, inst.InstanceID)
+		}
+	}
+	return nil
+}
+
+// dependencyNotReady returns why a dependency isn't ready yet, or an empty string if it is
+func (m *Monitor) dependencyNotReady(ctx context.Context, dep *aliyun.SpotInstance) string {
+	if m.isManuallyStopped(dep.InstanceID) {
+		return ""
+	}
+	// A dependency being started stays claimed until its health check passed
+	if m.isBusy(dep.InstanceID) {
+		return "正在处理"
+	}
+	status, err := m.ecsClient.GetInstanceStatus(ctx, dep.RegionID, dep.InstanceID)
+	if err != nil {
+		return fmt.Sprintf("查询状态失败: %v", err)
+	}
+	if status == "Stopped" && !m.comesBack(dep) {
+		return ""
+	}
+	if status != "Running" {
+		return status
+	}
+
+	host := dep.HealthCheckAddress()
+	if !m.cfg.HealthCheckEnabled || host == "" {
+		return ""
+	}
+	checker, _, err := m.healthCheckerFor(dep)
+	if err != nil {
+		// A broken health check config is reported elsewhere, Running is all we know
+		return ""
+	}
+	if err := checker.Check(ctx, host); err != nil {
+		return fmt.Sprintf("健康检查 %s 未通过", checker.Name())
+	}
+	return ""
+}
+
+// comesBack reports whether a stopped dependency is going to be started by
+// the monitor, going by what its own checks found out so far
+func (m *Monitor) comesBack(dep *aliyun.SpotInstance) bool {
+	if backoff, failing := m.startBackoffOf(dep.InstanceID); failing && backoff.gaveUp {
+		return false
+	}
+	if m.cfg.AutoStartManualStops {
+		return true
+	}
+	m.stopCausesMu.Lock()
+	cause := m.stopCauses[dep.InstanceID]
+	m.stopCausesMu.Unlock()
+	return cause != stopManual
+}
+
+// isBusy reports whether an instance is being handled
+func (m *Monitor) isBusy(instanceID string) bool {
+	m.busyMu.Lock()
+	defer m.busyMu.Unlock()
+	return m.busy[instanceID]
+}
@@ -0,0 +1,154 @@
+package monitor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	log "github.com/sirupsen/logrus"
+)
+
+// diskUsageTimeout bounds the Cloud Assistant command reading filesystem usage
+const diskUsageTimeout = 30 * time.Second
+
+// diskUsageWarning is the filesystem usage in percent flagged by /disk
+const diskUsageWarning = 90
+
+// diskUsageScripts print "<mount point> <size KiB> <used KiB>" per local filesystem
+var diskUsageScripts = map[string]string{
+	"linux": `df -P -k -x tmpfs -x devtmpfs -x overlay -x squashfs | awk 'NR > 1 { print $6, $2, $3 }'`,
+	"windows": `Get-CimInstance Win32_LogicalDisk -Filter "DriveType=3" | ForEach-Object {
+  "{0} {1} {2}" -f $_.DeviceID, [math]::Floor($_.Size / 1KB), [math]::Floor(($_.Size - $_.FreeSpace) / 1KB)
+}`,
+}
+
+// filesystemUsage is the usage of a mounted filesystem
+type filesystemUsage struct {
+	mount string
+	size  int64 // KiB
+	used  int64 // KiB
+}
+
+// percent returns the used share of the filesystem
+func (u filesystemUsage) percent() float64 {
+	if u.size == 0 {
+		return 0
+	}
+	return float64(u.used) / float64(u.size) * 100
+}
+
+// handleDiskCommand handles /disk [instance], listing the disks of each
+// instance and, for running ones, their filesystem usage via Cloud Assistant
+func (m *Monitor) handleDiskCommand(args []string) error {
+	if m.notifier == nil {
+		return fmt.Errorf("telegram notifier not initialized")
+	}
+	instance := ""
+	if len(args) > 0 {
+		instance = args[0]
+	}
+	instances := m.matchInstances(instance)
+	if len(instances) == 0 {
+		return m.notifier.Send("💽 <b>磁盘</b>\n\n暂无匹配的实例")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("💽 <b>磁盘</b>\n")
+	sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+
+	for _, inst := range instances {
+		sb.WriteString(fmt.Sprintf("<b>%s</b> (<code>%s</code>)\n", inst.InstanceName, inst.InstanceID))
+		disks, err := m.ecsClient.DescribeDisks(m.ctx, inst.RegionID, inst.InstanceID)
+		if err != nil {
+			log.Warnf("Failed to list disks of instance %s: %v", inst.InstanceID, err)
+			sb.WriteString("   查询失败\n\n")
+			continue
+		}
+		for _, disk := range disks {
+			sb.WriteString(fmt.Sprintf("   %s <code>%s</code> %s %d GiB", diskTypeName(disk.Type), disk.DiskID, disk.Category, disk.Size))
+			if disk.Encrypted {
+				sb.WriteString(" 🔒")
+			}
+			sb.WriteString("\n")
+		}
+
+		if m.lastStatus(inst.InstanceID) != "Running" {
+			sb.WriteString("   <i>实例未运行，无法读取文件系统用量</i>\n\n")
+			continue
+		}
+		usage, err := m.filesystemUsage(inst)
+		if err != nil {
+			log.Warnf("Failed to read filesystem usage of instance %s: %v", inst.InstanceID, err)
+			sb.WriteString(fmt.Sprintf("   <i>无法读取文件系统用量: %v</i>\n\n", err))
+			continue
+		}
+		for _, u := range usage {
+			line := fmt.Sprintf("   📂 %s %s / %s (%.0f%%)", u.mount, formatKiB(u.used), formatKiB(u.size), u.percent())
+			if u.percent() >= diskUsageWarning {
+				line += " ⚠️"
+			}
+			sb.WriteString(line + "\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("<i>文件系统用量通过云助手读取，需实例运行云助手客户端</i>")
+	return m.notifier.Send(sb.String())
+}
+
+// filesystemUsage reads the usage of the local filesystems of a running
+// instance through Cloud Assistant
+func (m *Monitor) filesystemUsage(inst *aliyun.SpotInstance) ([]filesystemUsage, error) {
+	osType := inst.OSType
+	if osType != "windows" {
+		osType = "linux"
+	}
+	result, err := m.ecsClient.RunCommand(m.ctx, inst.RegionID, inst.InstanceID, osType, diskUsageScripts[osType], diskUsageTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Succeeded() {
+		if result.ErrorInfo != "" {
+			return nil, fmt.Errorf("%s: %s", result.Status, result.ErrorInfo)
+		}
+		return nil, fmt.Errorf("%s, exit code %d", result.Status, result.ExitCode)
+	}
+
+	usage := parseFilesystemUsage(result.Output)
+	if len(usage) == 0 {
+		return nil, fmt.Errorf("no filesystems reported")
+	}
+	return usage, nil
+}
+
+// parseFilesystemUsage parses the output of diskUsageScripts, skipping lines it doesn't understand
+func parseFilesystemUsage(output string) []filesystemUsage {
+	var usage []filesystemUsage
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		size, err1 := strconv.ParseInt(fields[1], 10, 64)
+		used, err2 := strconv.ParseInt(fields[2], 10, 64)
+		if err1 != nil || err2 != nil || size <= 0 {
+			continue
+		}
+		usage = append(usage, filesystemUsage{mount: fields[0], size: size, used: used})
+	}
+	return usage
+}
+
+// formatKiB formats a size in KiB for display
+func formatKiB(kib int64) string {
+	switch {
+	case kib >= 1024*1024:
+		return fmt.Sprintf("%.1fG", float64(kib)/(1024*1024))
+	case kib >= 1024:
+		return fmt.Sprintf("%.0fM", float64(kib)/1024)
+	default:
+		return fmt.Sprintf("%dK", kib)
+	}
+}
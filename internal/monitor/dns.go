@@ -0,0 +1,89 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	"github.com/iliyian/aliyun-spot-manager/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// dnsConfigFor returns the overrides holding the Cloudflare record of an instance
+func (m *Monitor) dnsConfigFor(inst *aliyun.SpotInstance) *config.InstanceConfig {
+	return m.carriedInstanceConfig(inst, func(ic *config.InstanceConfig) bool { return ic.CloudflareRecord != "" })
+}
+
+// updateDNS points the Cloudflare record configured for an instance at its
+// public addresses once it started or its address changed. Instances with a
+// playbook leave this to its dns step while starting.
+func (m *Monitor) updateDNS(msg busMessage) {
+	if m.cloudflare == nil {
+		return
+	}
+	inst := msg.Instance
+	if msg.Playbook || (msg.Topic == topicStartSucceeded && len(m.playbookFor(inst)) > 0) {
+		return
+	}
+	ic := m.dnsConfigFor(inst)
+	if ic == nil {
+		return
+	}
+
+	ctx := msg.Ctx
+	if ctx == nil {
+		ctx = m.ctx
+	}
+	m.pointDNS(ctx, inst, ic, func(recordType, address string, err error) {
+		if notifier := m.notifierFor(inst.InstanceID); notifier != nil {
+			if err := notifier.Send(fmt.Sprintf("⚠️ 实例 %s 的 Cloudflare 解析 %s 更新失败\n%s 记录应指向 %s\n错误: %v",
+				inst.InstanceName, ic.CloudflareRecord, recordType, address, err)); err != nil {
+				log.Warnf("Failed to send DNS failure notification: %v", err)
+			}
+		}
+	})
+}
+
+// dnsStep is the dns playbook step, pointing the Cloudflare record at the instance
+func (m *Monitor) dnsStep(ctx context.Context, step config.PlaybookStep, inst *aliyun.SpotInstance) error {
+	if m.cloudflare == nil {
+		return fmt.Errorf("cloudflare is not configured")
+	}
+	ic := m.dnsConfigFor(inst)
+	if ic == nil {
+		return fmt.Errorf("no cloudflare_record configured for the instance")
+	}
+	if inst.PublicAddresses() == "" {
+		return fmt.Errorf("instance has no public IP")
+	}
+
+	var failed error
+	m.pointDNS(ctx, inst, ic, func(recordType, address string, err error) {
+		if failed == nil {
+			failed = fmt.Errorf("failed to point %s record %s at %s: %w", recordType, ic.CloudflareRecord, address, err)
+		}
+	})
+	return failed
+}
+
+// pointDNS updates the A and AAAA records of an instance, calling onError for
+// each record that failed to update
+func (m *Monitor) pointDNS(ctx context.Context, inst *aliyun.SpotInstance, ic *config.InstanceConfig, onError func(recordType, address string, err error)) {
+	for _, record := range []struct{ recordType, address string }{
+		{"A", inst.PublicIPAddress},
+		{"AAAA", inst.IPv6Address},
+	} {
+		if record.address == "" {
+			continue
+		}
+		changed, err := m.cloudflare.UpdateRecord(ctx, ic.CloudflareZone, ic.CloudflareRecord, record.recordType, record.address)
+		if err != nil {
+			log.Warnf("Failed to update DNS of instance %s: %v", inst.InstanceID, err)
+			onError(record.recordType, record.address, err)
+			continue
+		}
+		if changed {
+			log.Infof("Pointed %s record %s of instance %s at %s", record.recordType, ic.CloudflareRecord, inst.InstanceID, record.address)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	"github.com/iliyian/aliyun-spot-manager/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// carriedInstanceConfig returns the overrides of an instance for a setting
+// that follows it when re-created: its own when they have the setting,
+// otherwise those of the instance it was re-created from
+func (m *Monitor) carriedInstanceConfig(inst *aliyun.SpotInstance, has func(*config.InstanceConfig) bool) *config.InstanceConfig {
+	if ic := m.cfg.InstanceConfig(inst.InstanceID, inst.Tags); ic != nil && has(ic) {
+		return ic
+	}
+	if replaced := inst.Tags[aliyun.TagReplaces]; replaced != "" {
+		if ic := m.cfg.InstanceConfig(replaced, inst.Tags); ic != nil && has(ic) {
+			return ic
+		}
+	}
+	return nil
+}
+
+// eipFor returns the configured EIP of an instance
+func (m *Monitor) eipFor(inst *aliyun.SpotInstance) string {
+	ic := m.carriedInstanceConfig(inst, func(ic *config.InstanceConfig) bool { return ic.EIP != "" })
+	if ic == nil {
+		return ""
+	}
+	return ic.EIP
+}
+
+// ensureEIP associates the configured EIP with a freshly started instance
+// that came up without it. It returns the refreshed instance and the outcome
+// for the started notification, empty when there was nothing to do.
+func (m *Monitor) ensureEIP(ctx context.Context, inst *aliyun.SpotInstance) (*aliyun.SpotInstance, string) {
+	updated, outcome, _ := m.attachEIP(ctx, inst, false)
+	return updated, outcome
+}
+
+// eipStep is the eip playbook step, associating the configured EIP with the instance
+func (m *Monitor) eipStep(ctx context.Context, step config.PlaybookStep, inst *aliyun.SpotInstance) error {
+	if m.eipFor(inst) == "" {
+		return fmt.Errorf("no eip configured for the instance")
+	}
+	_, _, err := m.attachEIP(ctx, inst, true)
+	return err
+}
+
+// attachEIP associates the configured EIP with an instance unless it already
+// has it, returning the refreshed instance, the outcome for the started
+// notification and the error, if any. A playbook step passes fromPlaybook so
+// the address change leaves DNS to the playbook's own dns step.
+func (m *Monitor) attachEIP(ctx context.Context, inst *aliyun.SpotInstance, fromPlaybook bool) (*aliyun.SpotInstance, string, error) {
+	configured := m.eipFor(inst)
+	if configured == "" {
+		return inst, "", nil
+	}
+
+	eip, err := m.ecsClient.DescribeEIP(ctx, inst.RegionID, configured)
+	if err != nil {
+		log.Warnf("Failed to get EIP %s of instance %s: %v", configured, inst.InstanceID, err)
+		return inst, fmt.Sprintf("查询 %s 失败: %v", configured, err), fmt.Errorf("failed to get eip %s: %w", configured, err)
+	}
+	if eip.InstanceID == inst.InstanceID {
+		return inst, "", nil
+	}
+	// Only take the EIP over from the instance this one replaced
+	if eip.InstanceID != "" && eip.InstanceID != inst.Tags[aliyun.TagReplaces] {
+		log.Warnf("EIP %s of instance %s is associated with %s, not taking it over", eip.IPAddress, inst.InstanceID, eip.InstanceID)
+		return inst, fmt.Sprintf("%s 已绑定到 %s，未自动绑定", eip.IPAddress, eip.InstanceID),
+			fmt.Errorf("eip %s is associated with %s", eip.IPAddress, eip.InstanceID)
+	}
+
+	log.Infof("Instance %s came up without EIP %s, associating it", inst.InstanceID, eip.IPAddress)
+	if err := m.ecsClient.AssociateEIP(ctx, inst.RegionID, eip.AllocationID, inst.InstanceID); err != nil {
+		log.Errorf("Failed to associate EIP %s with instance %s: %v", eip.IPAddress, inst.InstanceID, err)
+		return inst, fmt.Sprintf("%s 绑定失败: %v", eip.IPAddress, err), fmt.Errorf("failed to associate eip %s: %w", eip.IPAddress, err)
+	}
+
+	outcome := fmt.Sprintf("已自动绑定 %s", eip.IPAddress)
+	updated, err := m.ecsClient.GetInstance(ctx, inst.RegionID, inst.InstanceID)
+	if err != nil {
+		log.Warnf("Failed to get updated instance info: %v", err)
+		return inst, outcome, nil
+	}
+	if fromPlaybook && inst.PublicAddresses() != updated.PublicAddresses() {
+		log.Infof("Public IP of instance %s changed from %q to %q", updated.InstanceID, inst.PublicAddresses(), updated.PublicAddresses())
+		m.bus.publish(busMessage{Topic: topicAddressChanged, Instance: updated, PrevAddress: inst.PublicAddresses(), Playbook: true})
+		m.setTrackedInstance(updated)
+	} else {
+		m.updateTrackedInstance(inst, updated)
+	}
+	return updated, outcome, nil
+}
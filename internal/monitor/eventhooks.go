@@ -0,0 +1,198 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// hookEvents maps the bus topics lifecycle hooks run for to their event names
+var hookEvents = map[string]string{
+	topicReclaimDetected: "reclaimed",
+	topicHealthPassed:    "started",
+	topicStartFailed:     "start_failed",
+	topicHealthFailed:    "unhealthy",
+	topicAddressChanged:  "ip_changed",
+}
+
+// hookQueueSize bounds the events waiting for their hooks
+const hookQueueSize = 100
+
+// hookPayload is the event JSON a lifecycle hook receives
+type hookPayload struct {
+	Event      string       `json:"event"`
+	Time       time.Time    `json:"time"`
+	Instance   hookInstance `json:"instance"`
+	PreviousIP string       `json:"previous_ip,omitempty"` // ip_changed
+	Error      string       `json:"error,omitempty"`       // start_failed, unhealthy
+	Duration   float64      `json:"duration_seconds,omitempty"`
+}
+
+// hookInstance describes the instance of a hook event
+type hookInstance struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Region    string `json:"region"`
+	Zone      string `json:"zone"`
+	Type      string `json:"type"`
+	PublicIP  string `json:"public_ip"`
+	IPv6      string `json:"ipv6,omitempty"`
+	PrivateIP string `json:"private_ip"`
+}
+
+// newEventHooks validates EVENT_HOOK_EVENTS and returns the queue of the
+// hook runner, nil when no hook is configured
+func newEventHooks(command, urls, events string) (chan hookPayload, map[string]bool, error) {
+	if command == "" && urls == "" {
+		return nil, nil, nil
+	}
+
+	known := make(map[string]bool, len(hookEvents))
+	names := make([]string, 0, len(hookEvents))
+	for _, name := range hookEvents {
+		known[name] = true
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	enabled := known
+	if events != "" {
+		enabled = make(map[string]bool)
+		for _, name := range splitList(events) {
+			if !known[name] {
+				return nil, nil, fmt.Errorf("unknown event %q in EVENT_HOOK_EVENTS, must be one of %s", name, strings.Join(names, ", "))
+			}
+			enabled[name] = true
+		}
+	}
+	return make(chan hookPayload, hookQueueSize), enabled, nil
+}
+
+// queueEventHook queues the lifecycle hooks of a message without waiting for them
+func (m *Monitor) queueEventHook(msg busMessage) {
+	event := hookEvents[msg.Topic]
+	if m.eventHooks == nil || !m.eventHookEvents[event] {
+		return
+	}
+
+	inst := msg.Instance
+	payload := hookPayload{
+		Event: event,
+		Time:  msg.Time,
+		Instance: hookInstance{
+			ID:        inst.InstanceID,
+			Name:      inst.InstanceName,
+			Region:    inst.RegionID,
+			Zone:      inst.ZoneID,
+			Type:      inst.InstanceType,
+			PublicIP:  inst.PublicIPAddress,
+			IPv6:      inst.IPv6Address,
+			PrivateIP: inst.PrivateIPAddress,
+		},
+		PreviousIP: msg.PrevAddress,
+		Duration:   msg.Duration.Seconds(),
+	}
+	if msg.Err != nil {
+		payload.Error = msg.Err.Error()
+	}
+
+	select {
+	case m.eventHooks <- payload:
+	default:
+		log.Warnf("Event hook queue is full, dropping %s hook of instance %s", event, inst.InstanceID)
+	}
+}
+
+// runEventHooks runs the queued lifecycle hooks one event at a time, so
+// they see the events of an instance in order, until the monitor stops
+func (m *Monitor) runEventHooks() {
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case payload := <-m.eventHooks:
+			m.runEventHook(payload)
+		}
+	}
+}
+
+// runEventHook runs EVENT_HOOK_COMMAND and posts to EVENT_HOOK_URLS for an event
+func (m *Monitor) runEventHook(payload hookPayload) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Warnf("Failed to encode %s event: %v", payload.Event, err)
+		return
+	}
+	timeout := time.Duration(m.cfg.EventHookTimeout) * time.Second
+
+	if m.cfg.EventHookCommand != "" {
+		if err := runHookCommand(m.ctx, m.cfg.EventHookCommand, payload, body, timeout); err != nil {
+			log.Warnf("Event hook command for %s of instance %s failed: %v", payload.Event, payload.Instance.ID, err)
+		} else {
+			log.Debugf("Event hook command for %s of instance %s done", payload.Event, payload.Instance.ID)
+		}
+	}
+	for _, url := range splitList(m.cfg.EventHookURLs) {
+		if err := postHook(m.ctx, url, body, timeout); err != nil {
+			log.Warnf("Event hook %s for %s of instance %s failed: %v", url, payload.Event, payload.Instance.ID, err)
+		} else {
+			log.Debugf("Event hook %s for %s of instance %s done", url, payload.Event, payload.Instance.ID)
+		}
+	}
+}
+
+// runHookCommand runs a shell command with the event JSON on stdin and the
+// event and instance details in its environment
+func runHookCommand(ctx context.Context, command string, payload hookPayload, body []byte, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(),
+		"SPOT_EVENT="+payload.Event,
+		"SPOT_INSTANCE_ID="+payload.Instance.ID,
+		"SPOT_INSTANCE_NAME="+payload.Instance.Name,
+		"SPOT_INSTANCE_REGION="+payload.Instance.Region,
+		"SPOT_INSTANCE_IP="+payload.Instance.PublicIP,
+		"SPOT_PREVIOUS_IP="+payload.PreviousIP,
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w (output: %s)", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// postHook posts the event JSON to a URL, expecting a 2xx status
+func postHook(ctx context.Context, url string, body []byte, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
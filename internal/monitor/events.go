@@ -0,0 +1,107 @@
+package monitor
+
+import (
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxRecentEvents is how many events are kept in memory
+const maxRecentEvents = 100
+
+// Event types
+const (
+	EventReclaimed     = "reclaimed"
+	EventStarted       = "started"
+	EventStartAttempt  = "start_attempt"
+	EventStartFailed   = "start_failed"
+	EventStartGaveUp   = "start_gave_up"
+	EventIPChanged     = "ip_changed"
+	EventUnhealthy     = "unhealthy"
+	EventStuck         = "stuck"
+	EventManualStart   = "manual_start"
+	EventManualStop    = "manual_stop"
+	EventMuted         = "muted"
+	EventUnmuted       = "unmuted"
+	EventTrafficCap    = "traffic_cap"
+	EventInterruption  = "interruption"
+	EventNoCapacity    = "no_capacity"
+	EventRecreated     = "recreated"
+	EventPriceLimit    = "price_limit"
+	EventRebid         = "rebid"
+	EventSnapshot      = "snapshot"
+	EventSecurityGroup = "security_group"
+	EventCPUCredits    = "cpu_credits"
+	EventDependency    = "dependency"
+	EventReleased      = "released"
+)
+
+// Event is a notable lifecycle event of a tracked instance
+type Event struct {
+	Time         time.Time
+	Type         string
+	InstanceID   string
+	InstanceName string
+	RegionID     string
+	ZoneID       string
+	InstanceType string
+	Message      string
+}
+
+// recordEvent appends an event for an instance, dropping the oldest beyond the limit
+func (m *Monitor) recordEvent(inst *aliyun.SpotInstance, eventType, message string) {
+	event := Event{
+		Time:         time.Now(),
+		Type:         eventType,
+		InstanceID:   inst.InstanceID,
+		InstanceName: inst.InstanceName,
+		RegionID:     inst.RegionID,
+		ZoneID:       inst.ZoneID,
+		InstanceType: inst.InstanceType,
+		Message:      message,
+	}
+
+	m.eventsMu.Lock()
+	m.events = append(m.events, event)
+	if n := len(m.events); n > maxRecentEvents {
+		m.events = m.events[n-maxRecentEvents:]
+	}
+	m.eventsMu.Unlock()
+
+	m.saveEvent(event)
+}
+
+// EventHistory returns up to limit events, newest first, optionally only those
+// of one instance (matched by ID or name). A limit of 0 or less returns all.
+// Events beyond the in-memory window come from the state store.
+func (m *Monitor) EventHistory(instance string, limit int) []Event {
+	var all []Event
+	if m.store != nil {
+		stored, err := m.storedEvents()
+		if err != nil {
+			log.Warnf("Failed to read event history, showing recent events only: %v", err)
+		} else {
+			all = stored
+		}
+	}
+	if all == nil {
+		m.eventsMu.RLock()
+		all = make([]Event, len(m.events))
+		copy(all, m.events)
+		m.eventsMu.RUnlock()
+	}
+
+	events := make([]Event, 0)
+	for i := len(all) - 1; i >= 0; i-- {
+		if limit > 0 && len(events) >= limit {
+			break
+		}
+		event := all[i]
+		if instance != "" && event.InstanceID != instance && event.InstanceName != instance {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events
+}
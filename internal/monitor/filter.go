@@ -0,0 +1,34 @@
+package monitor
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+)
+
+// instanceFilter builds the instance selection from the config
+func (m *Monitor) instanceFilter() *aliyun.InstanceFilter {
+	filter := &aliyun.InstanceFilter{
+		IncludeIDs:  splitList(m.cfg.InstanceIDs),
+		ExcludeIDs:  splitList(m.cfg.ExcludeInstanceIDs),
+		RequireTags: splitList(m.cfg.InstanceTags),
+		ExcludeTags: splitList(m.cfg.ExcludeInstanceTags),
+	}
+	if m.cfg.InstanceNamePattern != "" {
+		// Validated when the config is loaded
+		filter.NamePattern = regexp.MustCompile(m.cfg.InstanceNamePattern)
+	}
+	return filter
+}
+
+// splitList splits a comma-separated setting, dropping blank items
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
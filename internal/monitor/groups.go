@@ -0,0 +1,110 @@
+package monitor
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+)
+
+// instanceGroup is a named group of tracked instances
+type instanceGroup struct {
+	name      string
+	instances []*aliyun.SpotInstance
+}
+
+// groupOf returns the group of an instance: the "group" field of its config,
+// else the value of its GROUP_TAG tag. Empty if it has none.
+func (m *Monitor) groupOf(inst *aliyun.SpotInstance) string {
+	if ic := m.cfg.InstanceConfig(inst.InstanceID, inst.Tags); ic != nil && ic.Group != "" {
+		return ic.Group
+	}
+	if m.cfg.GroupTag != "" {
+		return inst.Tags[m.cfg.GroupTag]
+	}
+	return ""
+}
+
+// groupInstances sections instances by group, sorted by name with ungrouped
+// instances last. Returns nil if no instance has a group.
+func (m *Monitor) groupInstances(instances []*aliyun.SpotInstance) []instanceGroup {
+	byName := make(map[string][]*aliyun.SpotInstance)
+	for _, inst := range instances {
+		name := m.groupOf(inst)
+		byName[name] = append(byName[name], inst)
+	}
+	if _, ungrouped := byName[""]; ungrouped && len(byName) == 1 {
+		return nil
+	}
+
+	groups := make([]instanceGroup, 0, len(byName))
+	for name, members := range byName {
+		if name != "" {
+			groups = append(groups, instanceGroup{name: name, instances: members})
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].name < groups[j].name })
+	if members, ok := byName[""]; ok {
+		groups = append(groups, instanceGroup{name: aliyun.UngroupedName, instances: members})
+	}
+	return groups
+}
+
+// findGroup returns the group name matching s case-insensitively, if any
+// tracked instance is in it
+func (m *Monitor) findGroup(s string) (string, bool) {
+	if s == "" {
+		return "", false
+	}
+	for _, inst := range m.Instances(false) {
+		if group := m.groupOf(inst); group != "" && strings.EqualFold(group, s) {
+			return group, true
+		}
+	}
+	return "", false
+}
+
+// splitGroupArg separates a group name from the other command arguments
+func (m *Monitor) splitGroupArg(args []string) (group string, rest []string) {
+	for _, arg := range args {
+		if group == "" {
+			if name, ok := m.findGroup(arg); ok {
+				group = name
+				continue
+			}
+		}
+		rest = append(rest, arg)
+	}
+	return group, rest
+}
+
+// trafficGroups returns the region groups of the traffic summary: the
+// traffic_groups of the config file, else the regions of each instance group.
+// A region hosting instances of several groups is reported under all their
+// names joined, since CDT traffic can't be split by instance.
+func (m *Monitor) trafficGroups() map[string][]string {
+	if m.cfg.File != nil && len(m.cfg.File.TrafficGroups) > 0 {
+		return m.cfg.File.TrafficGroups
+	}
+	groups := m.groupInstances(m.Instances(false))
+	if groups == nil {
+		return nil
+	}
+
+	regionGroups := make(map[string][]string)
+	for _, group := range groups {
+		for _, inst := range group.instances {
+			names := regionGroups[inst.RegionID]
+			if len(names) == 0 || names[len(names)-1] != group.name {
+				regionGroups[inst.RegionID] = append(names, group.name)
+			}
+		}
+	}
+
+	byName := make(map[string][]string)
+	for region, names := range regionGroups {
+		name := strings.Join(names, " + ")
+		byName[name] = append(byName[name], region)
+	}
+	return byName
+}
@@ -0,0 +1,157 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	"github.com/iliyian/aliyun-spot-manager/internal/config"
+	"github.com/iliyian/aliyun-spot-manager/internal/health"
+	"github.com/iliyian/aliyun-spot-manager/internal/limit"
+	"github.com/iliyian/aliyun-spot-manager/internal/tracing"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// healthSettings holds the effective health check settings for an instance
+type healthSettings struct {
+	checks       string
+	policy       string
+	timeout      time.Duration
+	interval     time.Duration
+	probeTimeout time.Duration
+	command      string
+	rdpGrace     time.Duration
+}
+
+// newHealthChecker builds a health checker from the given settings
+// SSH and command checks share the hook limiter's slots
+func newHealthChecker(cfg *config.Config, hooks *limit.Limiter, settings healthSettings) (health.Checker, error) {
+	return health.Parse(settings.checks, settings.policy, health.Options{
+		Timeout:  settings.probeTimeout,
+		Command:  settings.command,
+		RDPGrace: settings.rdpGrace,
+		Limiter:  hooks,
+		SSH: health.SSHConfig{
+			Port:           cfg.HealthCheckSSHPort,
+			User:           cfg.HealthCheckSSHUser,
+			Password:       cfg.HealthCheckSSHPassword,
+			PrivateKeyPath: cfg.HealthCheckSSHKey,
+			KnownHostsPath: cfg.HealthCheckSSHKnownHosts,
+			Command:        cfg.HealthCheckSSHCommand,
+		},
+	})
+}
+
+// healthSettingsFor returns the global health settings merged with per-instance overrides
+func (m *Monitor) healthSettingsFor(inst *aliyun.SpotInstance) healthSettings {
+	settings := healthSettings{
+		checks:       m.cfg.HealthChecks,
+		policy:       m.cfg.HealthCheckPolicy,
+		timeout:      time.Duration(m.cfg.HealthCheckTimeout) * time.Second,
+		interval:     time.Duration(m.cfg.HealthCheckInterval) * time.Second,
+		probeTimeout: time.Duration(m.cfg.HealthCheckProbeTimeout) * time.Second,
+		command:      m.cfg.HealthCheckCommand,
+		rdpGrace:     time.Duration(m.cfg.HealthCheckRDPGrace) * time.Second,
+	}
+	// Windows keeps booting long after the network is up, so it gets its own checks
+	if inst.OSType == "windows" && m.cfg.HealthChecksWindows != "" {
+		settings.checks = m.cfg.HealthChecksWindows
+	}
+
+	ic := m.cfg.InstanceConfig(inst.InstanceID, inst.Tags)
+	if ic == nil {
+		return settings
+	}
+	if ic.HealthChecks != "" {
+		settings.checks = ic.HealthChecks
+	}
+	if ic.HealthCheckPolicy != "" {
+		settings.policy = ic.HealthCheckPolicy
+	}
+	if ic.HealthCheckTimeout > 0 {
+		settings.timeout = time.Duration(ic.HealthCheckTimeout) * time.Second
+	}
+	if ic.HealthCheckInterval > 0 {
+		settings.interval = time.Duration(ic.HealthCheckInterval) * time.Second
+	}
+	if ic.HealthCheckProbeTimeout > 0 {
+		settings.probeTimeout = time.Duration(ic.HealthCheckProbeTimeout) * time.Second
+	}
+	if ic.HealthCheckCommand != "" {
+		settings.command = ic.HealthCheckCommand
+	}
+	if ic.HealthCheckRDPGrace > 0 {
+		settings.rdpGrace = time.Duration(ic.HealthCheckRDPGrace) * time.Second
+	}
+	return settings
+}
+
+// healthCheckerFor returns the (cached) health checker for an instance
+func (m *Monitor) healthCheckerFor(inst *aliyun.SpotInstance) (health.Checker, healthSettings, error) {
+	settings := m.healthSettingsFor(inst)
+	key := fmt.Sprintf("%s|%s|%s|%s|%s", settings.checks, settings.policy, settings.probeTimeout, settings.command, settings.rdpGrace)
+
+	m.healthCheckersMu.Lock()
+	defer m.healthCheckersMu.Unlock()
+
+	if checker, ok := m.healthCheckers[key]; ok {
+		return checker, settings, nil
+	}
+
+	checker, err := newHealthChecker(m.cfg, m.hooks, settings)
+	if err != nil {
+		return nil, settings, err
+	}
+	m.healthCheckers[key] = checker
+	return checker, settings, nil
+}
+
+// healthResult describes the outcome of the post-start health check
+type healthResult struct {
+	checked bool   // false when health checks are disabled or skipped
+	name    string // checker description
+	timeout time.Duration
+	err     error
+}
+
+// summary returns a short description for notifications
+func (r healthResult) summary() string {
+	switch {
+	case !r.checked:
+		return "未检查"
+	case r.err != nil:
+		return fmt.Sprintf("失败 (%s)", r.name)
+	default:
+		return fmt.Sprintf("通过 ✓ (%s)", r.name)
+	}
+}
+
+// waitForHealthy waits until the instance passes its health checks
+func (m *Monitor) waitForHealthy(ctx context.Context, inst *aliyun.SpotInstance) healthResult {
+	if !m.cfg.HealthCheckEnabled {
+		return healthResult{}
+	}
+	host := inst.HealthCheckAddress()
+	if host == "" {
+		log.Debugf("Instance %s has no public IP, skipping health check", inst.InstanceID)
+		return healthResult{}
+	}
+
+	checker, settings, err := m.healthCheckerFor(inst)
+	if err != nil {
+		return healthResult{checked: true, name: "config", err: fmt.Errorf("invalid health check config: %w", err)}
+	}
+
+	log.Infof("Waiting for instance %s to pass health check %s", inst.InstanceID, checker.Name())
+	ctx, span := tracing.Start(ctx, "monitor.waitForHealthy", attribute.String("health.check", checker.Name()))
+	err = health.WaitForHealth(ctx, checker, host, settings.timeout, settings.interval)
+	tracing.End(span, err)
+	return healthResult{
+		checked: true,
+		name:    checker.Name(),
+		timeout: settings.timeout,
+		err:     err,
+	}
+}
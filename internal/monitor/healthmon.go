@@ -0,0 +1,110 @@
+package monitor
+
+import (
+	"fmt"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	log "github.com/sirupsen/logrus"
+)
+
+// CheckRunningHealth health-checks all Running instances and alerts (or reboots)
+// instances that are up in ECS but unresponsive at the network level
+func (m *Monitor) CheckRunningHealth() error {
+	m.mu.RLock()
+	instances := make([]*aliyun.SpotInstance, len(m.instances))
+	copy(instances, m.instances)
+	m.mu.RUnlock()
+
+	for _, inst := range instances {
+		// An instance being started is health-checked by the start workflow
+		if !m.claimInstance(inst.InstanceID) {
+			log.Debugf("Instance %s is being handled, skipping background health check", inst.InstanceID)
+			continue
+		}
+		err := m.checkRunningInstanceHealth(inst)
+		m.releaseInstance(inst.InstanceID)
+		if err != nil {
+			log.Warnf("Background health check for %s failed: %v", inst.InstanceID, err)
+		}
+	}
+	return nil
+}
+
+// checkRunningInstanceHealth runs a single health probe against a running instance
+func (m *Monitor) checkRunningInstanceHealth(inst *aliyun.SpotInstance) error {
+	ctx := m.ctx
+	status, err := m.ecsClient.GetInstanceStatus(ctx, inst.RegionID, inst.InstanceID)
+	if err != nil {
+		return fmt.Errorf("failed to get status: %w", err)
+	}
+	if status != "Running" {
+		// Stopped instances are handled by the regular check
+		m.resetHealthFailures(inst.InstanceID)
+		return nil
+	}
+	host := inst.HealthCheckAddress()
+	if host == "" {
+		return nil
+	}
+
+	checker, _, err := m.healthCheckerFor(inst)
+	if err != nil {
+		return fmt.Errorf("invalid health check config: %w", err)
+	}
+
+	checkErr := checker.Check(ctx, host)
+	if checkErr == nil {
+		if m.resetHealthFailures(inst.InstanceID) >= m.cfg.HealthMonitorFailures {
+			log.Infof("Instance %s is healthy again", inst.InstanceID)
+			if notifier := m.notifierFor(inst.InstanceID); notifier != nil {
+				if err := notifier.NotifyInstanceRecovered(inst.InstanceID, inst.InstanceName, inst.RegionID, checker.Name()); err != nil {
+					log.Warnf("Failed to send recovered notification: %v", err)
+				}
+			}
+		}
+		return nil
+	}
+
+	failures := m.incrementHealthFailures(inst.InstanceID)
+	log.Warnf("Instance %s failed health check %s (%d/%d): %v",
+		inst.InstanceID, checker.Name(), failures, m.cfg.HealthMonitorFailures, checkErr)
+
+	// Act exactly once when the threshold is reached
+	if failures != m.cfg.HealthMonitorFailures {
+		return nil
+	}
+
+	reboot := m.cfg.HealthMonitorAction == "reboot" && !m.inMaintenance()
+	m.recordEvent(inst, EventUnhealthy, fmt.Sprintf("健康检查 %s 连续失败 %d 次", checker.Name(), failures))
+	if notifier := m.notifierFor(inst.InstanceID); notifier != nil {
+		if err := notifier.NotifyInstanceUnhealthy(inst.InstanceID, inst.InstanceName, inst.RegionID, inst.PublicAddresses(), checker.Name(), failures, checkErr, reboot); err != nil {
+			log.Warnf("Failed to send unhealthy notification: %v", err)
+		}
+	}
+
+	if reboot {
+		log.Warnf("Rebooting unresponsive instance %s", inst.InstanceID)
+		if err := m.ecsClient.RebootInstance(ctx, inst.RegionID, inst.InstanceID); err != nil {
+			return fmt.Errorf("failed to reboot: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// incrementHealthFailures increments and returns the consecutive failure count
+func (m *Monitor) incrementHealthFailures(instanceID string) int {
+	m.healthFailuresMu.Lock()
+	defer m.healthFailuresMu.Unlock()
+	m.healthFailures[instanceID]++
+	return m.healthFailures[instanceID]
+}
+
+// resetHealthFailures resets the failure count, returning the previous value
+func (m *Monitor) resetHealthFailures(instanceID string) int {
+	m.healthFailuresMu.Lock()
+	defer m.healthFailuresMu.Unlock()
+	previous := m.healthFailures[instanceID]
+	delete(m.healthFailures, instanceID)
+	return previous
+}
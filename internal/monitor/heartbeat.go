@@ -0,0 +1,97 @@
+package monitor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxHeartbeatEvents caps the events listed in the heartbeat
+const maxHeartbeatEvents = 10
+
+// SendHeartbeat sends the daily "monitor alive" summary: tracked instances and
+// their statuses, events of the last 24 hours, and month-to-date spend and traffic
+func (m *Monitor) SendHeartbeat() error {
+	if m.notifier == nil {
+		return fmt.Errorf("telegram notifier not initialized")
+	}
+
+	now := time.Now()
+	var sb strings.Builder
+	sb.WriteString("💓 <b>监控运行中</b>\n")
+	sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+	sb.WriteString(fmt.Sprintf("已运行: %s\n", humanDuration(now.Sub(m.startedAt))))
+
+	// Instances and their live statuses
+	instances := m.Instances(true)
+	counts := make(map[string]int)
+	for _, inst := range instances {
+		counts[inst.Status]++
+	}
+	sb.WriteString(fmt.Sprintf("监控实例: %d 台", len(instances)))
+	if len(instances) > 0 {
+		parts := make([]string, 0, len(counts))
+		for _, status := range []string{"Running", "Stopped", "Starting", "Stopping", "Unknown"} {
+			if counts[status] > 0 {
+				parts = append(parts, fmt.Sprintf("%s %d", status, counts[status]))
+				delete(counts, status)
+			}
+		}
+		for status, n := range counts {
+			parts = append(parts, fmt.Sprintf("%s %d", status, n))
+		}
+		sb.WriteString(fmt.Sprintf(" (%s)", strings.Join(parts, ", ")))
+	}
+	sb.WriteString("\n")
+	for _, inst := range instances {
+		if inst.Status != "Running" {
+			sb.WriteString(fmt.Sprintf("   ⚠️ %s: %s\n", inst.InstanceName, inst.Status))
+		}
+	}
+
+	// Events of the last 24 hours, newest first
+	since := now.Add(-24 * time.Hour)
+	var recent []Event
+	for _, event := range m.EventHistory("", 0) {
+		if event.Time.Before(since) {
+			break
+		}
+		recent = append(recent, event)
+	}
+	sb.WriteString(fmt.Sprintf("\n<b>24 小时事件</b>: %d 条\n", len(recent)))
+	for i, event := range recent {
+		if i == maxHeartbeatEvents {
+			sb.WriteString(fmt.Sprintf("   ... 另有 %d 条，/history 查看\n", len(recent)-maxHeartbeatEvents))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("   %s %s %s\n", event.Time.Local().Format("15:04"), eventDisplayName(event.Type), event.InstanceName))
+	}
+
+	// Month-to-date spend and traffic
+	sb.WriteString("\n<b>本月</b>\n")
+	if m.billingClient != nil {
+		if summary, err := m.queryBilling(nil); err != nil {
+			log.Warnf("Failed to query billing for heartbeat: %v", err)
+			sb.WriteString("   扣费: 查询失败\n")
+		} else {
+			sb.WriteString(fmt.Sprintf("   扣费: ¥%.2f (月度估算 ¥%.2f)\n", summary.TotalAmount, summary.MonthlyEstimate))
+		}
+	}
+	if m.trafficClient != nil {
+		if summary, err := m.queryTraffic(nil); err != nil {
+			log.Warnf("Failed to query traffic for heartbeat: %v", err)
+			sb.WriteString("   流量: 查询失败\n")
+		} else {
+			sb.WriteString(fmt.Sprintf("   流量: %.2f GB\n", summary.TotalTrafficGB))
+		}
+	}
+
+	if err := m.notifier.Send(sb.String()); err != nil {
+		return fmt.Errorf("failed to send heartbeat: %w", err)
+	}
+
+	log.Info("Heartbeat sent")
+	return nil
+}
@@ -0,0 +1,103 @@
+package monitor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultHistoryLimit is how many events /history shows without a count
+const defaultHistoryLimit = 10
+
+// maxHistoryLimit caps /history so the reply fits in one Telegram message
+const maxHistoryLimit = 50
+
+// eventDisplayNames maps event types to labels for bot replies
+var eventDisplayNames = map[string]string{
+	EventReclaimed:     "🔴 被回收",
+	EventStartAttempt:  "🔄 尝试启动",
+	EventStarted:       "✅ 已启动",
+	EventStartFailed:   "❌ 启动失败",
+	EventIPChanged:     "🌐 IP 变更",
+	EventUnhealthy:     "🩺 健康检查失败",
+	EventStuck:         "⏳ 状态卡住",
+	EventManualStart:   "▶️ 手动启动",
+	EventManualStop:    "⏹ 手动停止",
+	EventMuted:         "🔇 静音",
+	EventUnmuted:       "🔔 取消静音",
+	EventTrafficCap:    "🚧 流量超限",
+	EventInterruption:  "⚠️ 即将中断",
+	EventNoCapacity:    "📦 库存不足",
+	EventStartGaveUp:   "⛔ 暂停自动启动",
+	EventRecreated:     "♻️ 已重建",
+	EventPriceLimit:    "📈 接近出价上限",
+	EventRebid:         "💹 已提高出价",
+	EventSnapshot:      "📸 快照",
+	EventSecurityGroup: "🛡️ 安全组缺失规则",
+	EventCPUCredits:    "🐢 CPU 积分耗尽",
+	EventDependency:    "🔗 依赖未就绪",
+	EventReleased:      "🗑 已释放",
+}
+
+// eventDisplayName returns the label of an event type
+func eventDisplayName(eventType string) string {
+	if name, ok := eventDisplayNames[eventType]; ok {
+		return name
+	}
+	return eventType
+}
+
+// parseHistoryArgs parses "/history [instance] [n]" arguments in any order
+func parseHistoryArgs(args []string) (instance string, limit int) {
+	limit = defaultHistoryLimit
+	for _, arg := range args {
+		if n, err := strconv.Atoi(arg); err == nil && n > 0 {
+			limit = n
+			continue
+		}
+		instance = arg
+	}
+	if limit > maxHistoryLimit {
+		limit = maxHistoryLimit
+	}
+	return instance, limit
+}
+
+// handleHistoryCommand handles /history [instance] [n]
+func (m *Monitor) handleHistoryCommand(args []string) error {
+	if m.notifier == nil {
+		return fmt.Errorf("telegram notifier not initialized")
+	}
+
+	instance, limit := parseHistoryArgs(args)
+	events := m.EventHistory(instance, limit)
+	log.Debugf("Sending %d history event(s) for %q", len(events), instance)
+
+	var sb strings.Builder
+	if instance != "" {
+		sb.WriteString(fmt.Sprintf("📜 <b>事件记录</b> (%s)\n", instance))
+	} else {
+		sb.WriteString("📜 <b>事件记录</b>\n")
+	}
+	sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+
+	if len(events) == 0 {
+		sb.WriteString("暂无事件")
+		return m.notifier.Send(sb.String())
+	}
+
+	for _, event := range events {
+		sb.WriteString(fmt.Sprintf("%s %s\n", event.Time.Local().Format("01-02 15:04"), eventDisplayName(event.Type)))
+		if instance == "" {
+			sb.WriteString(fmt.Sprintf("   %s (<code>%s</code>)\n", event.InstanceName, event.InstanceID))
+		}
+		if event.Message != "" {
+			sb.WriteString(fmt.Sprintf("   %s\n", event.Message))
+		}
+	}
+
+	sb.WriteString(fmt.Sprintf("\n<i>最近 %d 条，用法: /history [实例] [条数]</i>", len(events)))
+	return m.notifier.Send(sb.String())
+}
@@ -0,0 +1,111 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	log "github.com/sirupsen/logrus"
+)
+
+// Why a stopped instance is stopped
+const (
+	stopReclaimed = "reclaimed" // by the spot market, or unknown
+	stopManual    = "manual"    // from the console or the API
+	stopByMonitor = "monitor"   // e.g. force stopped while stuck, to be started again
+)
+
+// reclaimLookback is how long ago a spot interruption still explains a stop
+const reclaimLookback = 24 * time.Hour
+
+// startWanted reports whether a stopped instance should be started: it was
+// reclaimed, stopped by the monitor, or AUTO_START_MANUAL_STOPS is set
+func (m *Monitor) startWanted(ctx context.Context, inst *aliyun.SpotInstance) bool {
+	if m.cfg.AutoStartManualStops {
+		return true
+	}
+	return m.stopCause(ctx, inst) != stopManual
+}
+
+// stopCause tells why a stopped instance is stopped, looked up once per stop.
+// A Recycling lock or a spot interruption in the last day means reclaimed;
+// failed lookups count as reclaimed so errors never keep an instance down.
+func (m *Monitor) stopCause(ctx context.Context, inst *aliyun.SpotInstance) string {
+	m.stopCausesMu.Lock()
+	cause, ok := m.stopCauses[inst.InstanceID]
+	m.stopCausesMu.Unlock()
+	if ok {
+		return cause
+	}
+
+	cause = m.lookupStopCause(ctx, inst)
+	m.setStopCause(inst.InstanceID, cause)
+	if cause != stopManual {
+		return cause
+	}
+
+	log.Infof("Instance %s (%s) was stopped from the console or API, not starting it", inst.InstanceName, inst.InstanceID)
+	m.recordEvent(inst, EventManualStop, "在控制台或通过 API 停止，未自动启动")
+	if notifier := m.notifierFor(inst.InstanceID); notifier != nil {
+		if err := notifier.Send(fmt.Sprintf("⏸ 实例 %s 已在控制台或通过 API 停止（非回收），不会自动启动\n再次启动后恢复自动启动；如需一律自动启动，设置 AUTO_START_MANUAL_STOPS=true",
+			inst.InstanceName)); err != nil {
+			log.Warnf("Failed to send manual stop notification: %v", err)
+		}
+	}
+	return cause
+}
+
+// lookupStopCause asks Aliyun whether a stopped instance was reclaimed
+func (m *Monitor) lookupStopCause(ctx context.Context, inst *aliyun.SpotInstance) string {
+	since := time.Now().Add(-reclaimLookback)
+
+	// Reported by an agent or a pushed event
+	m.agentsMu.Lock()
+	announced, ok := m.interruptions[inst.InstanceID]
+	m.agentsMu.Unlock()
+	if ok && announced.After(since) {
+		return stopReclaimed
+	}
+
+	current, err := m.ecsClient.GetInstance(ctx, inst.RegionID, inst.InstanceID)
+	if err != nil {
+		log.Warnf("Failed to get instance %s to tell why it stopped, treating it as reclaimed: %v", inst.InstanceID, err)
+		return stopReclaimed
+	}
+	for _, lock := range current.OperationLocks {
+		if lock == "Recycling" {
+			return stopReclaimed
+		}
+	}
+
+	events, err := m.ecsClient.GetInterruptionHistory(ctx, inst.RegionID, inst.InstanceID, since)
+	if err != nil {
+		log.Warnf("Failed to get interruptions of instance %s, treating it as reclaimed: %v", inst.InstanceID, err)
+		return stopReclaimed
+	}
+	if len(events) > 0 {
+		return stopReclaimed
+	}
+	return stopManual
+}
+
+// setStopCause records why an instance is stopped until it runs again,
+// persisted so a restart doesn't mistake a stop by the monitor for a manual one
+func (m *Monitor) setStopCause(instanceID, cause string) {
+	m.stopCausesMu.Lock()
+	m.stopCauses[instanceID] = cause
+	m.stopCausesMu.Unlock()
+	m.saveInstanceState(instanceID)
+}
+
+// clearStopCause forgets why an instance was stopped once it is no longer stopped
+func (m *Monitor) clearStopCause(instanceID string) {
+	m.stopCausesMu.Lock()
+	_, known := m.stopCauses[instanceID]
+	delete(m.stopCauses, instanceID)
+	m.stopCausesMu.Unlock()
+	if known {
+		m.saveInstanceState(instanceID)
+	}
+}
@@ -0,0 +1,114 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	"github.com/iliyian/aliyun-spot-manager/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// CheckInterruptions polls scheduled system events for announced spot
+// reclaims and maintenance reboots, so they are notified and the
+// pre-shutdown playbooks run before the instances go down
+func (m *Monitor) CheckInterruptions() error {
+	m.mu.RLock()
+	byRegion := make(map[string][]string)
+	instanceMap := make(map[string]*aliyun.SpotInstance)
+	for _, inst := range m.instances {
+		byRegion[inst.RegionID] = append(byRegion[inst.RegionID], inst.InstanceID)
+		instanceMap[inst.InstanceID] = inst
+	}
+	m.mu.RUnlock()
+
+	var lastErr error
+	for regionID, instanceIDs := range byRegion {
+		events, err := m.ecsClient.GetScheduledEvents(m.ctx, regionID, instanceIDs)
+		if err != nil {
+			log.Debugf("Failed to query interruption events in %s: %v", regionID, err)
+			lastErr = err
+			continue
+		}
+
+		for _, event := range events {
+			inst, ok := instanceMap[event.InstanceID]
+			if !ok || !event.IsInterruption() || !m.markEventNotified(event.EventID) {
+				continue
+			}
+			if event.EventType == aliyun.EventSpotInterruption && !m.markInterruption(inst.InstanceID, event.NotBefore) {
+				log.Debugf("Reclaim of instance %s already reported by its agent", inst.InstanceID)
+				continue
+			}
+
+			log.Warnf("Instance %s (%s) will be interrupted by %s (%s) at %s, in %s",
+				inst.InstanceName, inst.InstanceID, event.EventType, event.EventID,
+				event.NotBefore.Local().Format("15:04:05"), time.Until(event.NotBefore).Round(time.Second))
+			m.bus.publish(busMessage{Topic: topicInterruptionDue, Instance: inst, Event: event})
+		}
+	}
+
+	m.pruneNotifiedEvents()
+
+	if lastErr != nil {
+		return fmt.Errorf("failed to query some interruption events: %w", lastErr)
+	}
+	return nil
+}
+
+// announceInterruption publishes a reclaim reported outside the scheduled
+// events, unless it was already announced, and reports whether it was new
+func (m *Monitor) announceInterruption(inst *aliyun.SpotInstance, event *aliyun.SystemEvent) bool {
+	if !m.markInterruption(inst.InstanceID, event.NotBefore) {
+		return false
+	}
+	m.bus.publish(busMessage{Topic: topicInterruptionDue, Instance: inst, Event: event})
+	return true
+}
+
+// preShutdownFor returns the pre-shutdown playbook configured for an instance, if any
+func (m *Monitor) preShutdownFor(inst *aliyun.SpotInstance) []config.PlaybookStep {
+	ic := m.cfg.InstanceConfig(inst.InstanceID, inst.Tags)
+	if ic == nil {
+		return nil
+	}
+	return ic.PreShutdown
+}
+
+// runPreShutdown runs the pre-shutdown playbook of an instance about to be
+// interrupted, in the background so other instances' warnings aren't held up
+// The playbook is cut short when the interruption is due. Before a reclaim,
+// the disks are snapshotted after the playbook, so data it flushed is kept.
+func (m *Monitor) runPreShutdown(msg busMessage) {
+	inst := msg.Instance
+	steps := m.preShutdownFor(inst)
+	var snapshot *config.InstanceConfig
+	if msg.Event.EventType == aliyun.EventSpotInterruption {
+		snapshot = m.snapshotConfig(inst, snapshotReclaim)
+	}
+	if len(steps) == 0 && snapshot == nil {
+		return
+	}
+
+	go func() {
+		ctx := m.ctx
+		if !msg.Event.NotBefore.IsZero() {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, msg.Event.NotBefore)
+			defer cancel()
+		}
+
+		if len(steps) > 0 {
+			log.Infof("Running %d-step pre-shutdown playbook for instance %s", len(steps), inst.InstanceID)
+			if _, err := m.playbooks.Run(ctx, steps, inst); err != nil {
+				log.Warnf("Pre-shutdown playbook for instance %s failed: %v", inst.InstanceID, err)
+			} else {
+				log.Infof("Pre-shutdown playbook for instance %s finished", inst.InstanceID)
+			}
+		}
+		if snapshot != nil {
+			m.snapshotBeforeReclaim(inst, snapshot)
+		}
+	}()
+}
@@ -0,0 +1,94 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	log "github.com/sirupsen/logrus"
+)
+
+// launchConfigsBucket holds the captured launch configuration of each instance
+const launchConfigsBucket = "launch_configs"
+
+// launchConfigMaxAge is how long a captured launch configuration is kept
+// before it is captured again
+const launchConfigMaxAge = 24 * time.Hour
+
+// CaptureLaunchConfigs snapshots the launch configuration of each tracked
+// instance not captured within launchConfigMaxAge, and saves it as an ECS
+// launch template when LAUNCH_TEMPLATE_SYNC is enabled
+func (m *Monitor) CaptureLaunchConfigs() error {
+	captured := 0
+	for _, inst := range m.Instances(false) {
+		if m.isClosing() {
+			return nil
+		}
+		if cfg := m.launchConfig(inst.InstanceID); cfg != nil && time.Since(cfg.CapturedAt) < launchConfigMaxAge {
+			continue
+		}
+		if _, err := m.captureLaunchConfig(m.ctx, inst); err != nil {
+			log.Warnf("Failed to capture launch configuration of instance %s: %v", inst.InstanceID, err)
+			continue
+		}
+		captured++
+	}
+	if captured > 0 {
+		log.Infof("Captured the launch configuration of %d instance(s)", captured)
+	}
+	return nil
+}
+
+// captureLaunchConfig captures and stores the launch configuration of an instance
+func (m *Monitor) captureLaunchConfig(ctx context.Context, inst *aliyun.SpotInstance) (*aliyun.LaunchConfig, error) {
+	cfg, err := m.ecsClient.CaptureLaunchConfig(ctx, inst.RegionID, inst.InstanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	m.launchConfigsMu.Lock()
+	m.launchConfigs[inst.InstanceID] = cfg
+	m.launchConfigsMu.Unlock()
+	if m.store != nil {
+		if err := m.store.Put(launchConfigsBucket, inst.InstanceID, cfg); err != nil {
+			log.Warnf("Failed to save launch configuration of instance %s: %v", inst.InstanceID, err)
+		}
+	}
+	log.Debugf("Captured launch configuration of instance %s (%s, %s)", inst.InstanceID, cfg.InstanceType, cfg.ImageID)
+
+	if m.cfg.LaunchTemplateSync {
+		if err := m.ecsClient.SaveLaunchTemplate(ctx, cfg); err != nil {
+			log.Warnf("Failed to save launch template of instance %s: %v", inst.InstanceID, err)
+		}
+	}
+	return cfg, nil
+}
+
+// launchConfig returns the captured launch configuration of an instance, nil if there is none
+func (m *Monitor) launchConfig(instanceID string) *aliyun.LaunchConfig {
+	m.launchConfigsMu.Lock()
+	defer m.launchConfigsMu.Unlock()
+	return m.launchConfigs[instanceID]
+}
+
+// LaunchConfig returns the captured launch configuration of an instance
+func (m *Monitor) LaunchConfig(instanceID string) (*aliyun.LaunchConfig, error) {
+	if m.findInstance(instanceID) == nil {
+		return nil, ErrInstanceNotFound
+	}
+	return m.launchConfig(instanceID), nil
+}
+
+// loadLaunchConfigs restores the captured launch configurations
+func (m *Monitor) loadLaunchConfigs() error {
+	return m.store.ForEach(launchConfigsBucket, func(instanceID string, data []byte) error {
+		var cfg aliyun.LaunchConfig
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			log.Warnf("Ignoring corrupt launch configuration of instance %s: %v", instanceID, err)
+			return nil
+		}
+		m.launchConfigs[instanceID] = &cfg
+		return nil
+	})
+}
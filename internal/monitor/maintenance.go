@@ -0,0 +1,91 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	log "github.com/sirupsen/logrus"
+)
+
+// notifiedEventTTL is how long a notified event is remembered after Aliyun
+// last reported it, so completed or cancelled events are forgotten
+const notifiedEventTTL = 7 * 24 * time.Hour
+
+// CheckMaintenanceEvents polls scheduled system events for tracked instances
+// and notifies once per event, ahead of the maintenance window
+func (m *Monitor) CheckMaintenanceEvents() error {
+	m.mu.RLock()
+	byRegion := make(map[string][]string)
+	instanceMap := make(map[string]*aliyun.SpotInstance)
+	for _, inst := range m.instances {
+		byRegion[inst.RegionID] = append(byRegion[inst.RegionID], inst.InstanceID)
+		instanceMap[inst.InstanceID] = inst
+	}
+	m.mu.RUnlock()
+
+	var lastErr error
+	for regionID, instanceIDs := range byRegion {
+		events, err := m.ecsClient.GetScheduledEvents(m.ctx, regionID, instanceIDs)
+		if err != nil {
+			log.Warnf("Failed to query maintenance events in %s: %v", regionID, err)
+			lastErr = err
+			continue
+		}
+
+		for _, event := range events {
+			// Interruptions are left to CheckInterruptions, which runs the pre-shutdown playbooks
+			if m.cfg.InterruptionCheckInterval > 0 && event.IsInterruption() {
+				continue
+			}
+			if !m.markEventNotified(event.EventID) {
+				continue
+			}
+
+			log.Warnf("Scheduled event %s (%s) for instance %s at %s",
+				event.EventID, event.EventType, event.InstanceID, event.NotBefore.Local().Format("2006-01-02 15:04:05"))
+
+			notifier := m.notifierFor(event.InstanceID)
+			if notifier == nil {
+				continue
+			}
+			instanceName := event.InstanceID
+			if inst, ok := instanceMap[event.InstanceID]; ok {
+				instanceName = inst.InstanceName
+			}
+			if err := notifier.NotifyMaintenanceEvent(event, instanceName); err != nil {
+				log.Warnf("Failed to send maintenance notification: %v", err)
+			}
+		}
+	}
+
+	m.pruneNotifiedEvents()
+
+	if lastErr != nil {
+		return fmt.Errorf("failed to query some maintenance events: %w", lastErr)
+	}
+	return nil
+}
+
+// markEventNotified records an event as notified, returning false if it already was
+// Every call renews the event, it is remembered for as long as it is reported.
+func (m *Monitor) markEventNotified(eventID string) bool {
+	m.notifiedEventsMu.Lock()
+	defer m.notifiedEventsMu.Unlock()
+
+	_, notified := m.notifiedEvents[eventID]
+	m.notifiedEvents[eventID] = time.Now()
+	return !notified
+}
+
+// pruneNotifiedEvents forgets events that haven't been reported for notifiedEventTTL
+func (m *Monitor) pruneNotifiedEvents() {
+	m.notifiedEventsMu.Lock()
+	defer m.notifiedEventsMu.Unlock()
+
+	for eventID, lastSeen := range m.notifiedEvents {
+		if time.Since(lastSeen) > notifiedEventTTL {
+			delete(m.notifiedEvents, eventID)
+		}
+	}
+}
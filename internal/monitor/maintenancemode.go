@@ -0,0 +1,230 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maintenanceModeBucket holds the maintenance mode in the state store, so a
+// restart during maintenance doesn't start everything
+const maintenanceModeBucket = "maintenance_mode"
+
+// maintenanceModeKey is the key of the maintenance mode in maintenanceModeBucket
+const maintenanceModeKey = "state"
+
+// MaintenanceMode is the global maintenance mode. While it is on, instances
+// are still checked and events recorded, but nothing is started, rebooted or
+// force-stopped automatically and instance notifications are held back.
+type MaintenanceMode struct {
+	Enabled bool      `json:"enabled"`
+	Since   time.Time `json:"since,omitempty"`
+	Until   time.Time `json:"until,omitempty"` // zero until turned off
+}
+
+// active reports whether maintenance is on at the given time
+func (s MaintenanceMode) active(now time.Time) bool {
+	return s.Enabled && (s.Until.IsZero() || now.Before(s.Until))
+}
+
+// SetMaintenanceMode turns the maintenance mode on for the given duration, 0
+// meaning until turned off, or turns it off, announcing the change in the chat
+func (m *Monitor) SetMaintenanceMode(enabled bool, duration time.Duration) error {
+	if duration < 0 {
+		return fmt.Errorf("duration must not be negative")
+	}
+
+	now := time.Now()
+	m.maintenanceModeMu.Lock()
+	wasOn := m.maintenanceMode.active(now)
+	if enabled {
+		since := m.maintenanceMode.Since
+		if !wasOn {
+			since = now
+		}
+		m.maintenanceMode = MaintenanceMode{Enabled: true, Since: since}
+		if duration > 0 {
+			m.maintenanceMode.Until = now.Add(duration)
+		}
+	} else {
+		m.maintenanceMode = MaintenanceMode{}
+	}
+	status := m.maintenanceMode
+	m.maintenanceModeMu.Unlock()
+	m.saveMaintenanceMode(status)
+
+	var message string
+	switch {
+	case enabled && status.Until.IsZero():
+		log.Warn("Maintenance mode on until turned off, auto-start paused")
+		message = formatMaintenanceMode(status)
+	case enabled:
+		log.Warnf("Maintenance mode on until %s, auto-start paused", status.Until.Format("01-02 15:04"))
+		message = formatMaintenanceMode(status)
+	case wasOn:
+		log.Info("Maintenance mode off, auto-start resumed")
+		message = "✅ 维护模式已关闭，恢复自动启动和实例通知\n维护期间停止的实例将在下一轮检查中按常规流程处理"
+	}
+	if message != "" && m.notifier != nil {
+		if err := m.notifier.Send(message); err != nil {
+			log.Warnf("Failed to send maintenance mode notification: %v", err)
+		}
+	}
+	return nil
+}
+
+// MaintenanceMode returns the maintenance mode, zero when off
+func (m *Monitor) MaintenanceMode() MaintenanceMode {
+	m.maintenanceModeMu.Lock()
+	defer m.maintenanceModeMu.Unlock()
+	if !m.maintenanceMode.active(time.Now()) {
+		return MaintenanceMode{}
+	}
+	return m.maintenanceMode
+}
+
+// inMaintenance reports whether automatic actions are paused
+func (m *Monitor) inMaintenance() bool {
+	return m.MaintenanceMode().Enabled
+}
+
+// noteMaintenanceStop remembers a stopped instance not started because of the
+// maintenance mode, returning false if it already was
+func (m *Monitor) noteMaintenanceStop(instanceID string) bool {
+	m.maintenanceModeMu.Lock()
+	defer m.maintenanceModeMu.Unlock()
+	if m.maintenanceStops[instanceID] {
+		return false
+	}
+	m.maintenanceStops[instanceID] = true
+	return true
+}
+
+// forgetMaintenanceStop forgets a stopped instance once it is no longer stopped
+func (m *Monitor) forgetMaintenanceStop(instanceID string) {
+	m.maintenanceModeMu.Lock()
+	delete(m.maintenanceStops, instanceID)
+	m.maintenanceModeMu.Unlock()
+}
+
+// expireMaintenance ends a maintenance mode whose duration is over and says so
+func (m *Monitor) expireMaintenance() {
+	m.maintenanceModeMu.Lock()
+	expired := m.maintenanceMode.Enabled && !m.maintenanceMode.active(time.Now())
+	if expired {
+		m.maintenanceMode = MaintenanceMode{}
+	}
+	m.maintenanceModeMu.Unlock()
+	if !expired {
+		return
+	}
+
+	m.saveMaintenanceMode(MaintenanceMode{})
+	log.Info("Maintenance mode ended, auto-start resumed")
+	if m.notifier != nil {
+		if err := m.notifier.Send("🛠 维护模式已到期结束，恢复自动启动和实例通知"); err != nil {
+			log.Warnf("Failed to send maintenance mode notification: %v", err)
+		}
+	}
+}
+
+// saveMaintenanceMode persists the maintenance mode
+func (m *Monitor) saveMaintenanceMode(status MaintenanceMode) {
+	if m.store == nil {
+		return
+	}
+	var err error
+	if status.Enabled {
+		err = m.store.Put(maintenanceModeBucket, maintenanceModeKey, status)
+	} else {
+		err = m.store.Delete(maintenanceModeBucket, maintenanceModeKey)
+	}
+	if err != nil {
+		log.Warnf("Failed to save maintenance mode: %v", err)
+	}
+}
+
+// loadMaintenanceMode restores the maintenance mode
+func (m *Monitor) loadMaintenanceMode() error {
+	return m.store.ForEach(maintenanceModeBucket, func(key string, data []byte) error {
+		if key != maintenanceModeKey {
+			return nil
+		}
+		if err := json.Unmarshal(data, &m.maintenanceMode); err != nil {
+			log.Warnf("Ignoring corrupt maintenance mode: %v", err)
+			return nil
+		}
+		if m.maintenanceMode.Enabled {
+			log.Warn("Restored maintenance mode, auto-start stays paused")
+		}
+		return nil
+	})
+}
+
+// handleMaintenanceCommand handles /maintenance [on [duration]|off]
+func (m *Monitor) handleMaintenanceCommand(args []string) error {
+	if m.notifier == nil {
+		return fmt.Errorf("telegram notifier not initialized")
+	}
+	usage := "⚠️ 用法: /maintenance [on [时长]|off]\n时长如 30m、2h、1d，不填则一直维持到 /maintenance off"
+
+	if len(args) == 0 {
+		return m.notifier.Send(formatMaintenanceMode(m.MaintenanceMode()))
+	}
+	switch strings.ToLower(args[0]) {
+	case "on":
+		var duration time.Duration
+		if len(args) > 1 {
+			d, err := parseMaintenanceDuration(args[1])
+			if err != nil {
+				return m.notifier.Send(usage)
+			}
+			duration = d
+		}
+		return m.SetMaintenanceMode(true, duration)
+	case "off":
+		if !m.inMaintenance() {
+			return m.notifier.Send("ℹ️ 维护模式未开启")
+		}
+		return m.SetMaintenanceMode(false, 0)
+	default:
+		return m.notifier.Send(usage)
+	}
+}
+
+// parseMaintenanceDuration parses a duration such as "90m", "2h" or "1d"
+func parseMaintenanceDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(strings.ToLower(s), "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// formatMaintenanceMode describes the maintenance mode for the bot
+func formatMaintenanceMode(status MaintenanceMode) string {
+	if !status.Enabled {
+		return "🛠 <b>维护模式</b>: 未开启\n\n开启: /maintenance on [时长]"
+	}
+	var sb strings.Builder
+	sb.WriteString("🛠 <b>维护模式</b>: 已开启\n\n")
+	sb.WriteString(fmt.Sprintf("开始: %s\n", status.Since.Local().Format("01-02 15:04")))
+	if status.Until.IsZero() {
+		sb.WriteString("结束: 手动关闭 (/maintenance off)\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("结束: %s\n", status.Until.Local().Format("01-02 15:04")))
+	}
+	sb.WriteString("\n期间继续检查实例并记录事件，但不会自动启动、重启或强制停止实例，实例通知暂停；预算、流量和凭证告警照常发送")
+	return sb.String()
+}
@@ -0,0 +1,35 @@
+package monitor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+)
+
+// metricWarning is the utilization in percent flagged in /status
+const metricWarning = 90
+
+// formatMetrics describes the CloudMonitor utilization of an instance for /status,
+// leaving out memory and disk when the CloudMonitor agent doesn't report them
+func formatMetrics(metrics *aliyun.InstanceMetrics) string {
+	var parts []string
+	for _, metric := range []struct {
+		label string
+		value float64
+	}{
+		{"CPU", metrics.CPU},
+		{"内存", metrics.Memory},
+		{"磁盘", metrics.Disk},
+	} {
+		if metric.value < 0 {
+			continue
+		}
+		part := fmt.Sprintf("%s %.0f%%", metric.label, metric.value)
+		if metric.value >= metricWarning {
+			part += " ⚠️"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " | ")
+}
@@ -1,25 +1,55 @@
 package monitor
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
 	"github.com/iliyian/aliyun-spot-manager/internal/config"
+	"github.com/iliyian/aliyun-spot-manager/internal/dns"
+	"github.com/iliyian/aliyun-spot-manager/internal/health"
+	"github.com/iliyian/aliyun-spot-manager/internal/limit"
 	"github.com/iliyian/aliyun-spot-manager/internal/notify"
+	"github.com/iliyian/aliyun-spot-manager/internal/playbook"
+	"github.com/iliyian/aliyun-spot-manager/internal/store"
+	"github.com/iliyian/aliyun-spot-manager/internal/tracing"
+	"github.com/iliyian/aliyun-spot-manager/internal/version"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Monitor monitors spot instances and auto-starts them when stopped
 type Monitor struct {
 	cfg           *config.Config
-	ecsClient     *aliyun.ECSClient
-	billingClient *aliyun.BillingClient
-	trafficClient *aliyun.TrafficClient
+	ecsClient     aliyun.InstanceAPI
+	billingClient aliyun.BillingAPI // nil if it couldn't be created
+	trafficClient aliyun.TrafficAPI // nil if it couldn't be created
 	notifier      *notify.TelegramNotifier
 	botHandler    *notify.BotHandler
+	pinger        *notify.Pinger
+
+	// When the monitor was created, for the heartbeat
+	startedAt time.Time
+
+	// Root context of checks and starts, cancelled when shutdown gives up waiting
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// In-flight instance starts, new ones are refused once shutting down
+	starts    sync.WaitGroup
+	closing   bool
+	closingMu sync.RWMutex
+
+	// Persists per-instance state and events across restarts, nil if disabled
+	store store.Store
 
 	// Tracked instances
 	instances []*aliyun.SpotInstance
@@ -28,40 +58,381 @@ type Monitor struct {
 	// Notification cooldown tracking
 	lastNotify   map[string]time.Time
 	lastNotifyMu sync.RWMutex
+
+	// Guards credential reloads and the credential fields of cfg
+	credsMu sync.RWMutex
+
+	// Readiness: a discovery has completed and the credentials are accepted
+	discovered          bool
+	credentialsRejected bool
+	readyMu             sync.RWMutex
+
+	// Health checkers keyed by their settings, shared by instances with the same config
+	healthCheckers   map[string]health.Checker
+	healthCheckersMu sync.Mutex
+
+	// Consecutive background health check failures per instance
+	healthFailures   map[string]int
+	healthFailuresMu sync.Mutex
+
+	// Instances seen in a transitional state, for stuck detection
+	transitions   map[string]*transitionState
+	transitionsMu sync.Mutex
+
+	// Scheduled system events already notified, by when they were last reported
+	notifiedEvents   map[string]time.Time
+	notifiedEventsMu sync.Mutex
+
+	// Retries throttled Aliyun API calls and trips per-region circuit breakers
+	resilience *aliyun.Resilience
+
+	// Runs per-instance recovery playbooks after start
+	playbooks *playbook.Runner
+
+	// Bounds concurrent hooks (SSH/command checks, playbook commands and webhooks)
+	hooks *limit.Limiter
+
+	// Discovery failures and blacklist per region
+	regionHealth   map[string]*regionHealth
+	regionHealthMu sync.Mutex
+
+	// Recent lifecycle events, oldest first
+	events   []Event
+	eventsMu sync.RWMutex
+
+	// Instances whose notifications are muted
+	muted   map[string]bool
+	mutedMu sync.RWMutex
+
+	// Instances stopped on request, left alone by auto-start until started again
+	manualStops   map[string]bool
+	manualStopsMu sync.Mutex
+
+	// Why stopped instances are stopped, looked up once per stop
+	stopCauses   map[string]string
+	stopCausesMu sync.Mutex
+
+	// Carries check and start workflow messages to notifications, hooks,
+	// metrics and persistence
+	bus *bus
+
+	// Updates the Cloudflare DNS records of instances, nil without an API token
+	cloudflare *dns.Cloudflare
+
+	// Lifecycle events waiting for their hooks, nil without hooks
+	eventHooks      chan hookPayload
+	eventHookEvents map[string]bool
+
+	// Starts waiting to go out together, region -> batch collecting instances
+	startBatches   map[string]*startBatch
+	startBatchesMu sync.Mutex
+
+	// Instances being checked or started, never handled twice at once
+	busy      map[string]bool
+	handedOff map[string]bool // claims passed on to a background task
+	busyMu    sync.Mutex
+
+	// Start and end of check cycles, for the watchdog
+	checkLoop   checkLoop
+	checkLoopMu sync.Mutex
+
+	// Last status seen by the regular check, for status change messages
+	statuses   map[string]string
+	statusesMu sync.Mutex
+
+	// Transitions notified on top of the regular notifications, and the
+	// instances the monitor is starting itself
+	stateChangeNotify map[string]bool
+	selfStarts        map[string]bool
+	selfStartsMu      sync.Mutex
+
+	// Status changes and reclaims per instance over the uptime retention,
+	// oldest first
+	statusLog   map[string][]statusChange
+	reclaimLog  map[string][]time.Time
+	statusLogMu sync.Mutex
+
+	// Messages published per topic since startup
+	counters   map[string]int
+	countersMu sync.Mutex
+
+	// Pay-as-you-go prices keyed by region/zone/instance type, for the savings report
+	onDemandPrices   map[string]onDemandPrice
+	onDemandPricesMu sync.Mutex
+
+	// Traffic quota thresholds already alerted this billing cycle
+	trafficAlerts   trafficAlertState
+	trafficAlertsMu sync.Mutex
+
+	// Month-to-date traffic at the last daily report
+	trafficSnapshot   *trafficSnapshot
+	trafficSnapshotMu sync.Mutex
+
+	// Instances waiting for spot capacity, retried on a slow schedule
+	capacityWaits   map[string]*capacityWait
+	capacityWaitsMu sync.Mutex
+
+	// Instances whose start failed in consecutive checks, retried less and less often
+	startBackoffs   map[string]*startBackoff
+	startBackoffsMu sync.Mutex
+
+	// Budget thresholds already alerted this billing cycle
+	budgetAlerts   budgetAlertState
+	budgetAlertsMu sync.Mutex
+
+	// Last heartbeat of the on-instance agents and the interruption time
+	// last announced per instance
+	agents        map[string]AgentHeartbeat
+	interruptions map[string]time.Time
+	agentsMu      sync.Mutex
+
+	// Launch configuration captured per instance, to re-create it from
+	launchConfigs   map[string]*aliyun.LaunchConfig
+	launchConfigsMu sync.Mutex
+
+	// Spot price histories queried for the advisor
+	spotPrices   map[string]spotPriceHistory
+	spotPricesMu sync.Mutex
+
+	// Whether the market price of an instance was last seen near its price limit
+	priceLimitAlerts   map[string]bool
+	priceLimitAlertsMu sync.Mutex
+
+	// Burstable instances already alerted for running out of CPU credits
+	creditAlerts   map[string]bool
+	creditAlertsMu sync.Mutex
+
+	// Global maintenance mode pausing auto-start and instance notifications,
+	// and the instances found stopped during it
+	maintenanceMode   MaintenanceMode
+	maintenanceStops  map[string]bool
+	maintenanceModeMu sync.Mutex
+
+	// Static status page, the OSS bucket it is uploaded to and the spend and
+	// traffic shown on it; the mutex is held while the page is updated and
+	// Close waits for the updates in flight
+	statusPageOSS     *aliyun.OSSClient
+	statusPageCosts   statusPageCosts
+	statusPageMu      sync.Mutex
+	statusPageUpdates sync.WaitGroup
+
+	// OSS bucket the state store is backed up to
+	backupOSS *aliyun.OSSClient
+}
+
+// newTransport creates the HTTP transport shared by the Aliyun SDK clients
+func newTransport(cfg *config.Config) (*aliyun.Transport, error) {
+	transport, err := aliyun.NewTransport(aliyun.HTTPOptions{
+		ConnectTimeout:      time.Duration(cfg.HTTPConnectTimeout) * time.Second,
+		ReadTimeout:         time.Duration(cfg.HTTPReadTimeout) * time.Second,
+		KeepAlive:           time.Duration(cfg.HTTPKeepAlive) * time.Second,
+		IdleConnTimeout:     time.Duration(cfg.HTTPIdleConnTimeout) * time.Second,
+		MaxIdleConns:        cfg.HTTPMaxIdleConns,
+		MaxIdleConnsPerHost: cfg.HTTPMaxIdleConnsPerHost,
+		TLSMinVersion:       cfg.HTTPTLSMinVersion,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP transport: %w", err)
+	}
+	return transport, nil
 }
 
-// New creates a new monitor
+// Clients are the Aliyun APIs a monitor works with
+// Billing and Traffic may be nil, their features are then unavailable.
+type Clients struct {
+	Instances aliyun.InstanceAPI
+	Billing   aliyun.BillingAPI
+	Traffic   aliyun.TrafficAPI
+	Backup    *aliyun.OSSClient // bucket of the state backups, nil when disabled
+}
+
+// New creates a new monitor using the Aliyun API, or in-memory fakes in simulate mode
 func New(cfg *config.Config) (*Monitor, error) {
+	if cfg.Simulate {
+		return newSimulated(cfg)
+	}
+
+	// Shared HTTP transport for all Aliyun SDK clients
+	transport, err := newTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	resilience := aliyun.NewResilience(aliyun.RetryPolicy{
+		Retries:   cfg.APIRetryCount,
+		BaseDelay: time.Second,
+		MaxDelay:  time.Duration(cfg.APIRetryMaxDelay) * time.Second,
+	}, cfg.CircuitBreakerThreshold, time.Duration(cfg.CircuitBreakerCooldown)*time.Second)
+	log.Infof("Using Aliyun credentials: %s", aliyunCredentials(cfg))
+
+	ecsClient := aliyun.NewECSClient(aliyunCredentials(cfg), transport)
+	ecsClient.SetCacheTTL(time.Duration(cfg.InstanceCacheTTL) * time.Second)
+	ecsClient.SetResilience(resilience)
+	clients := Clients{Instances: ecsClient}
+
+	// Initialize billing client for bot commands, the API and the CLI
+	if billingClient, err := aliyun.NewBillingClient(aliyunCredentials(cfg), transport); err != nil {
+		log.Warnf("Failed to create billing client: %v", err)
+	} else {
+		billingClient.SetProducts(strings.Split(cfg.BillingProducts, ","))
+		billingClient.SetEstimateMethod(cfg.BillingEstimate)
+		billingClient.SetResilience(resilience)
+		clients.Billing = billingClient
+	}
+
+	// Initialize traffic client for bot commands, the API and the CLI
+	if trafficClient, err := aliyun.NewTrafficClient(aliyunCredentials(cfg), transport); err != nil {
+		log.Warnf("Failed to create traffic client: %v", err)
+	} else {
+		trafficClient.SetResilience(resilience)
+		clients.Traffic = trafficClient
+	}
+
+	// Created up front, an empty state store is restored from the backup
+	if cfg.BackupOSSBucket != "" {
+		backupClient, err := aliyun.NewOSSClient(aliyunCredentials(cfg), transport, cfg.BackupOSSEndpoint, cfg.BackupOSSBucket)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create backup OSS client: %w", err)
+		}
+		clients.Backup = backupClient
+	}
+
+	m, err := NewWithClients(cfg, clients)
+	if err != nil {
+		return nil, err
+	}
+	m.resilience = resilience
+
+	if cfg.StatusPageOSSBucket != "" {
+		ossClient, err := aliyun.NewOSSClient(aliyunCredentials(cfg), transport, cfg.StatusPageOSSEndpoint, cfg.StatusPageOSSBucket)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create status page OSS client: %w", err)
+		}
+		m.statusPageOSS = ossClient
+	}
+	return m, nil
+}
+
+// NewWithClients creates a monitor working with the given Aliyun APIs,
+// e.g. the fakes of the aliyun package in tests
+func NewWithClients(cfg *config.Config, clients Clients) (*Monitor, error) {
+	ctx, cancel := context.WithCancel(context.Background())
 	m := &Monitor{
-		cfg:        cfg,
-		ecsClient:  aliyun.NewECSClient(cfg.AliyunAccessKeyID, cfg.AliyunAccessKeySecret),
-		lastNotify: make(map[string]time.Time),
+		cfg:              cfg,
+		startedAt:        time.Now(),
+		ctx:              ctx,
+		cancel:           cancel,
+		ecsClient:        clients.Instances,
+		billingClient:    clients.Billing,
+		trafficClient:    clients.Traffic,
+		backupOSS:        clients.Backup,
+		lastNotify:       make(map[string]time.Time),
+		notifiedEvents:   make(map[string]time.Time),
+		regionHealth:     make(map[string]*regionHealth),
+		healthCheckers:   make(map[string]health.Checker),
+		transitions:      make(map[string]*transitionState),
+		healthFailures:   make(map[string]int),
+		muted:            make(map[string]bool),
+		manualStops:      make(map[string]bool),
+		stopCauses:       make(map[string]string),
+		busy:             make(map[string]bool),
+		handedOff:        make(map[string]bool),
+		startBatches:     make(map[string]*startBatch),
+		statuses:         make(map[string]string),
+		selfStarts:       make(map[string]bool),
+		statusLog:        make(map[string][]statusChange),
+		reclaimLog:       make(map[string][]time.Time),
+		counters:         make(map[string]int),
+		onDemandPrices:   make(map[string]onDemandPrice),
+		agents:           make(map[string]AgentHeartbeat),
+		capacityWaits:    make(map[string]*capacityWait),
+		startBackoffs:    make(map[string]*startBackoff),
+		interruptions:    make(map[string]time.Time),
+		launchConfigs:    make(map[string]*aliyun.LaunchConfig),
+		spotPrices:       make(map[string]spotPriceHistory),
+		priceLimitAlerts: make(map[string]bool),
+		creditAlerts:     make(map[string]bool),
+		maintenanceStops: make(map[string]bool),
+		hooks:            limit.New(cfg.HookConcurrency, cfg.HookConcurrencyPerHost),
+		bus:              newBus(),
 	}
+	m.subscribe()
 
 	if cfg.TelegramEnabled {
 		m.notifier = notify.NewTelegramNotifier(cfg.TelegramBotToken, cfg.TelegramChatID)
 	}
+	if cfg.HealthchecksPingURL != "" {
+		m.pinger = notify.NewPinger(cfg.HealthchecksPingURL)
+	}
 
-	// Initialize billing client for bot commands
-	if cfg.TelegramEnabled {
-		billingClient, err := aliyun.NewBillingClient(cfg.AliyunAccessKeyID, cfg.AliyunAccessKeySecret)
+	// Restore state from the previous run. Shared state is restored once
+	// leading, a standby's copy would be stale by the time it takes over.
+	if cfg.RedisURL != "" {
+		st, err := store.OpenRedis(cfg.RedisURL, cfg.RedisKeyPrefix)
 		if err != nil {
-			log.Warnf("Failed to create billing client: %v", err)
+			return nil, fmt.Errorf("failed to open Redis state store: %w", err)
+		}
+		m.store = st
+	} else if cfg.DataDir != "" {
+		st, err := store.Open(cfg.DataDir)
+		if err != nil {
+			log.Warnf("Failed to open state store, state will not survive restarts: %v", err)
 		} else {
-			m.billingClient = billingClient
+			m.store = st
 		}
 	}
+	if !cfg.LeaderElection {
+		m.RestoreState()
+	}
 
-	// Initialize traffic client for bot commands
-	if cfg.TelegramEnabled {
-		trafficClient, err := aliyun.NewTrafficClient(cfg.AliyunAccessKeyID, cfg.AliyunAccessKeySecret)
-		if err != nil {
-			log.Warnf("Failed to create traffic client: %v", err)
-		} else {
-			m.trafficClient = trafficClient
+	// Validate the global health check config early
+	if cfg.HealthCheckEnabled {
+		if _, err := newHealthChecker(cfg, m.hooks, m.healthSettingsFor(&aliyun.SpotInstance{})); err != nil {
+			return nil, fmt.Errorf("failed to create health checker: %w", err)
+		}
+		if _, err := newHealthChecker(cfg, m.hooks, m.healthSettingsFor(&aliyun.SpotInstance{OSType: "windows"})); err != nil {
+			return nil, fmt.Errorf("failed to create Windows health checker: %w", err)
 		}
 	}
 
+	// Recovery playbooks, validated up front so typos fail at startup
+	m.playbooks = m.newPlaybookRunner()
+	if cfg.File != nil {
+		for key, ic := range cfg.File.Instances {
+			if err := m.playbooks.Validate(ic.Playbook); err != nil {
+				return nil, fmt.Errorf("invalid playbook for %s: %w", key, err)
+			}
+			if err := m.playbooks.Validate(ic.PreShutdown); err != nil {
+				return nil, fmt.Errorf("invalid pre-shutdown playbook for %s: %w", key, err)
+			}
+			if _, err := parseRequiredPorts(ic.RequiredPorts); err != nil {
+				return nil, fmt.Errorf("invalid required_ports for %s: %w", key, err)
+			}
+			if ic.CloudflareRecord != "" && (ic.CloudflareZone == "" || cfg.CloudflareAPIToken == "") {
+				return nil, fmt.Errorf("cloudflare_record for %s requires cloudflare_zone and CLOUDFLARE_API_TOKEN", key)
+			}
+		}
+	}
+	if cfg.CloudflareAPIToken != "" {
+		m.cloudflare = dns.NewCloudflare(cfg.CloudflareAPIToken)
+	}
+
+	// Audit notifications of state transitions
+	stateChangeNotify, err := parseStateTransitions(cfg.StateChangeNotify)
+	if err != nil {
+		return nil, err
+	}
+	m.stateChangeNotify = stateChangeNotify
+
+	// Lifecycle event hooks
+	hooks, hookEvents, err := newEventHooks(cfg.EventHookCommand, cfg.EventHookURLs, cfg.EventHookEvents)
+	if err != nil {
+		return nil, err
+	}
+	m.eventHooks, m.eventHookEvents = hooks, hookEvents
+	if m.eventHooks != nil {
+		go m.runEventHooks()
+	}
+
 	// Initialize bot handler for commands
 	if cfg.TelegramEnabled {
 		m.botHandler = notify.NewBotHandler(cfg.TelegramBotToken, cfg.TelegramChatID)
@@ -71,22 +442,71 @@ func New(cfg *config.Config) (*Monitor, error) {
 	return m, nil
 }
 
-// StartBot starts the Telegram bot polling
-func (m *Monitor) StartBot() {
+// StartBot starts the Telegram bot polling until ctx is cancelled
+func (m *Monitor) StartBot(ctx context.Context) {
 	if m.botHandler != nil {
-		m.botHandler.StartPolling()
+		m.botHandler.StartPolling(ctx)
 	}
 }
 
 // handleBotCommand handles bot commands
-func (m *Monitor) handleBotCommand(command string) error {
+func (m *Monitor) handleBotCommand(command string, args []string) error {
 	switch command {
 	case "billing", "cost", "fee":
-		return m.SendBillingReport()
+		if len(args) > 0 && args[0] == "export" {
+			return m.handleBillingExport(args[1:])
+		}
+		group, rest := m.splitGroupArg(args)
+		period, err := parseTimeRange(rest, time.Now())
+		if err != nil {
+			return m.sendTimeRangeError(err)
+		}
+		return m.sendBillingReport(period, group)
 	case "traffic", "flow", "bandwidth":
-		return m.SendTrafficReport()
+		region, rest := splitRegionArg(args)
+		period, err := parseTimeRange(rest, time.Now())
+		if err != nil {
+			return m.sendTimeRangeError(err)
+		}
+		return m.sendTrafficReport(period, region)
+	case "daily":
+		period, err := parseTimeRange(args, time.Now())
+		if err != nil {
+			return m.sendTimeRangeError(err)
+		}
+		return m.sendDailyBilling(period)
+	case "trend":
+		return m.handleTrendCommand(args)
+	case "reconcile", "bwbill":
+		return m.SendReconciliationReport()
 	case "status":
-		return m.sendStatusReport()
+		return m.sendStatusReport(args)
+	case "regions":
+		return m.handleRegionsCommand(args)
+	case "history", "events":
+		return m.handleHistoryCommand(args)
+	case "uptime":
+		return m.handleUptimeCommand(args)
+	case "stats":
+		return m.handleStatsCommand(args)
+	case "advisor", "price":
+		return m.SendAdvisorReport()
+	case "pricelimit", "bid":
+		return m.handlePriceLimitCommand(args)
+	case "snapshot":
+		return m.handleSnapshotCommand(args)
+	case "snapshots":
+		return m.handleSnapshotsCommand(args)
+	case "disk", "disks":
+		return m.handleDiskCommand(args)
+	case "maintenance":
+		return m.handleMaintenanceCommand(args)
+	case "config":
+		return m.sendConfigDump()
+	case "version":
+		return m.sendVersion()
+	case "apistats":
+		return m.sendAPIStats()
 	case "help":
 		return m.sendHelpMessage()
 	default:
@@ -95,45 +515,168 @@ func (m *Monitor) handleBotCommand(command string) error {
 	}
 }
 
-// sendStatusReport sends a status report
-func (m *Monitor) sendStatusReport() error {
+// sendStatusReport handles /status [group|instance], sending the status of
+// the tracked instances sectioned by group, or of one group or instance
+func (m *Monitor) sendStatusReport(args []string) error {
 	if m.notifier == nil {
 		return fmt.Errorf("telegram notifier not initialized")
 	}
 
+	title := "📊 <b>实例状态</b>"
 	m.mu.RLock()
 	instances := make([]*aliyun.SpotInstance, len(m.instances))
 	copy(instances, m.instances)
 	m.mu.RUnlock()
 
+	if len(args) > 0 {
+		if group, ok := m.findGroup(args[0]); ok {
+			title = fmt.Sprintf("📊 <b>实例状态 · %s</b>", group)
+			var members []*aliyun.SpotInstance
+			for _, inst := range instances {
+				if m.groupOf(inst) == group {
+					members = append(members, inst)
+				}
+			}
+			instances = members
+		} else {
+			instances = m.matchInstances(args[0])
+			if len(instances) == 0 {
+				return m.notifier.Send(fmt.Sprintf("⚠️ 未找到分组或实例 %s", args[0]))
+			}
+		}
+	}
+
 	if len(instances) == 0 {
-		return m.notifier.Send("📊 <b>实例状态</b>\n\n暂无监控的实例")
+		return m.notifier.Send(title + "\n\n暂无监控的实例")
 	}
 
+	now := time.Now()
+	reclaims := m.reclaimTimes()
+
 	var sb strings.Builder
-	sb.WriteString("📊 <b>实例状态</b>\n")
+	sb.WriteString(title + "\n")
 	sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+	if mode := m.MaintenanceMode(); mode.Enabled {
+		if mode.Until.IsZero() {
+			sb.WriteString("🛠 <i>维护模式中，暂停自动启动</i>\n\n")
+		} else {
+			sb.WriteString(fmt.Sprintf("🛠 <i>维护模式中，暂停自动启动至 %s</i>\n\n", mode.Until.Local().Format("01-02 15:04")))
+		}
+	}
 
-	for _, inst := range instances {
-		status, err := m.ecsClient.GetInstanceStatus(inst.RegionID, inst.InstanceID)
-		if err != nil {
-			status = "Unknown"
+	if groups := m.groupInstances(instances); groups != nil && len(args) == 0 {
+		for _, group := range groups {
+			sb.WriteString(fmt.Sprintf("🏷 <b>%s</b> (%d 台)\n\n", group.name, len(group.instances)))
+			for _, inst := range group.instances {
+				m.writeInstanceStatus(&sb, inst, now, reclaims[inst.InstanceID])
+			}
+		}
+	} else {
+		for _, inst := range instances {
+			m.writeInstanceStatus(&sb, inst, now, reclaims[inst.InstanceID])
 		}
+	}
+
+	sb.WriteString(fmt.Sprintf("<i>本次运行: 回收 %d 次 | 启动成功 %d 次 | 启动失败 %d 次</i>",
+		m.topicCount(topicReclaimDetected), m.topicCount(topicHealthPassed), m.topicCount(topicStartFailed)))
+
+	return m.notifier.Send(sb.String())
+}
+
+// writeInstanceStatus writes the status block of an instance for /status
+func (m *Monitor) writeInstanceStatus(sb *strings.Builder, inst *aliyun.SpotInstance, now time.Time, reclaims []time.Time) {
+	status, err := m.ecsClient.GetInstanceStatus(m.ctx, inst.RegionID, inst.InstanceID)
+	if err != nil {
+		status = "Unknown"
+	}
+
+	statusEmoji := "🟢"
+	if status == "Stopped" {
+		statusEmoji = "🔴"
+	} else if status == "Starting" || status == "Stopping" {
+		statusEmoji = "🟡"
+	}
 
-		statusEmoji := "🟢"
-		if status == "Stopped" {
-			statusEmoji = "🔴"
-		} else if status == "Starting" || status == "Stopping" {
-			statusEmoji = "🟡"
+	sb.WriteString(fmt.Sprintf("%s <b>%s</b>\n", statusEmoji, inst.InstanceName))
+	sb.WriteString(fmt.Sprintf("   ID: <code>%s</code>\n", inst.InstanceID))
+	sb.WriteString(fmt.Sprintf("   区域: %s\n", inst.RegionID))
+	sb.WriteString(fmt.Sprintf("   状态: %s\n", status))
+	if backoff, failing := m.startBackoffOf(inst.InstanceID); failing && status == "Stopped" {
+		sb.WriteString(fmt.Sprintf("   自动启动: %s\n", formatStartBackoff(backoff)))
+	}
+	if stuck := m.stuckFor(inst.InstanceID); isTransitional(status) && stuck >= time.Minute {
+		sb.WriteString(fmt.Sprintf("   已持续: %.0f 分钟\n", stuck.Minutes()))
+	}
+	if hasPriceLimit(inst) {
+		sb.WriteString(fmt.Sprintf("   出价上限: ¥%.4f/时\n", inst.SpotPriceLimit))
+	}
+	if protection := formatProtection(inst, now); protection != "" {
+		sb.WriteString(fmt.Sprintf("   保护期: %s\n", protection))
+	}
+	if inst.Burstable() && status == "Running" {
+		if credits, err := m.ecsClient.GetCPUCredits(m.ctx, inst.RegionID, inst.InstanceID); err != nil {
+			log.Warnf("Failed to get CPU credits of instance %s: %v", inst.InstanceID, err)
+		} else if credits != nil {
+			sb.WriteString(fmt.Sprintf("   CPU 积分: %s\n", formatCredits(inst, credits, m.cfg.CPUCreditAlertThreshold)))
+		}
+	}
+	if m.cfg.StatusMetrics && status == "Running" {
+		if metrics, err := m.ecsClient.GetInstanceMetrics(m.ctx, inst.RegionID, inst.InstanceID); err != nil {
+			log.Warnf("Failed to get metrics of instance %s: %v", inst.InstanceID, err)
+		} else if metrics != nil {
+			sb.WriteString(fmt.Sprintf("   负载: %s\n", formatMetrics(metrics)))
 		}
+	}
+
+	week := m.availabilityOf(inst.InstanceID, 7*24*time.Hour, now, reclaims)
+	month := m.availabilityOf(inst.InstanceID, uptimeRetention, now, reclaims)
+	sb.WriteString(fmt.Sprintf("   可用率: %s\n", formatAvailability(week, month)))
+	sb.WriteString(fmt.Sprintf("   平均回收间隔: %s\n\n", formatMTBR(month)))
+}
 
-		sb.WriteString(fmt.Sprintf("%s <b>%s</b>\n", statusEmoji, inst.InstanceName))
-		sb.WriteString(fmt.Sprintf("   ID: <code>%s</code>\n", inst.InstanceID))
-		sb.WriteString(fmt.Sprintf("   区域: %s\n", inst.RegionID))
-		sb.WriteString(fmt.Sprintf("   状态: %s\n\n", status))
+// sendConfigDump sends the effective configuration, secrets masked, as a file
+// It is too long for a single message.
+func (m *Monitor) sendConfigDump() error {
+	if m.notifier == nil {
+		return fmt.Errorf("telegram notifier not initialized")
 	}
 
-	return m.notifier.Send(sb.String())
+	// Credential reloads update the config
+	m.credsMu.RLock()
+	dump := m.cfg.Dump()
+	m.credsMu.RUnlock()
+
+	if err := m.notifier.SendDocument("config.txt", []byte(dump), "⚙️ <b>当前生效配置</b>（敏感信息已隐藏）"); err != nil {
+		return fmt.Errorf("failed to send config dump: %w", err)
+	}
+	return nil
+}
+
+// sendVersion sends the build information and uptime
+func (m *Monitor) sendVersion() error {
+	if m.notifier == nil {
+		return fmt.Errorf("telegram notifier not initialized")
+	}
+
+	ver, commit, buildDate := version.Info()
+	if commit == "" {
+		commit = "unknown"
+	}
+	if buildDate == "" {
+		buildDate = "unknown"
+	}
+
+	message := fmt.Sprintf(`ℹ️ <b>版本信息</b>
+━━━━━━━━━━━━━━━━━━━━━━━━
+版本: <code>%s</code>
+提交: <code>%s</code>
+构建时间: %s
+Go: %s %s/%s
+已运行: %s`,
+		ver, commit, buildDate, runtime.Version(), runtime.GOOS, runtime.GOARCH,
+		humanDuration(time.Since(m.startedAt)))
+
+	return m.notifier.Send(message)
 }
 
 // sendHelpMessage sends a help message
@@ -145,45 +688,109 @@ func (m *Monitor) sendHelpMessage() error {
 	message := `🤖 <b>可用命令</b>
 ━━━━━━━━━━━━━━━━━━━━━━━━
 
-/billing - 查询本月扣费汇总
-/traffic - 查询本月流量统计
-/status - 查看实例状态
+/billing [分组] [区间|YYYY-MM] - 查询扣费汇总 (默认本月，可只看一个分组)
+/billing export [csv|json] [区间] - 导出全部计费项文件
+/traffic [区间] [区域] - 查询流量统计 (默认本月，可只看单个区域，如 /traffic 2024-11 cn-hongkong)
+/daily [区间] - 按天查看扣费，定位费用突增
+/trend [月数] - 本月估算与前几个月扣费对比
+/reconcile - 本月带宽/流量费用对账
+/status [分组|实例] - 查看实例状态 (按分组显示，可只看一个分组或实例)
+/regions - 查看区域扫描状态与黑名单
+/regions reset [区域] - 清除区域黑名单
+/apistats - 阿里云 API 调用耗时、错误与限流统计
+/history [实例] [条数] - 查看回收/启动事件记录
+/uptime [实例] - 查看 7 天/30 天可用率
+/stats [天数|all] - 按区域/可用区/规格统计回收 (默认 30 天)
+/advisor - 30 天抢占式价格趋势，推荐更便宜或更稳定的可用区/规格
+/pricelimit [实例 新出价] - 查看出价上限与市场价，或按新出价重建已停止的实例
+/snapshot 实例 - 立即为实例的所有磁盘创建快照
+/snapshots [实例] - 查看实例磁盘的最近快照
+/disk [实例] - 查看实例磁盘及文件系统用量
+/maintenance [on [时长]|off] - 维护模式，暂停自动启动和实例通知
+/config - 导出当前生效配置 (敏感信息已隐藏)
+/version - 查看版本与构建信息
 /help - 显示帮助信息
 
+区间: today, yesterday, week, this week, last week, this month
+例如: /cost today, /traffic yesterday
+
 ━━━━━━━━━━━━━━━━
-<i>别名: /cost, /fee, /flow, /bandwidth</i>`
+<i>别名: /cost, /fee, /flow, /bandwidth, /events, /price, /bid</i>`
 
 	return m.notifier.Send(message)
 }
 
+// sendTimeRangeError replies with the supported time ranges
+func (m *Monitor) sendTimeRangeError(err error) error {
+	if m.notifier == nil {
+		return fmt.Errorf("telegram notifier not initialized")
+	}
+
+	log.Debugf("Invalid report range: %v", err)
+	return m.notifier.Send("⚠️ 无法识别的时间区间\n\n支持: today, yesterday, week, this week, last week, this month，或月份 YYYY-MM（不能晚于本月）\n例如: /cost today、/billing 2024-11")
+}
+
 // DiscoverInstances discovers all spot instances across all regions
+// and sends the monitor started notification
 func (m *Monitor) DiscoverInstances() error {
-	instances, err := m.ecsClient.DiscoverAllSpotInstances()
+	if err := m.RefreshInstances(); err != nil {
+		return err
+	}
+	instances := m.Instances(false)
+
+	// Send notification
+	if m.notifier != nil && len(instances) > 0 {
+		if err := m.notifier.NotifyMonitorStarted(version.String(), len(instances), describeInstances(instances)); err != nil {
+			log.Warnf("Failed to send monitor started notification: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// RefreshInstances discovers all spot instances across all regions without notifying
+func (m *Monitor) RefreshInstances() error {
+	instances, _, err := m.scanInstances()
 	if err != nil {
-		return fmt.Errorf("failed to discover instances: %w", err)
+		return err
 	}
 
 	m.mu.Lock()
 	m.instances = instances
 	m.mu.Unlock()
+	m.markDiscovered()
+	m.saveTrackedInstances()
 
 	log.Infof("Discovered %d spot instances", len(instances))
 	for _, inst := range instances {
 		log.Infof("  - %s (%s) in %s [%s]", inst.InstanceName, inst.InstanceID, inst.RegionID, inst.Status)
 	}
+	return nil
+}
 
-	// Send notification
-	if m.notifier != nil && len(instances) > 0 {
-		instanceList := make([]string, len(instances))
-		for i, inst := range instances {
-			instanceList[i] = fmt.Sprintf("%s (%s) - %s", inst.InstanceName, inst.InstanceID, inst.RegionID)
-		}
-		if err := m.notifier.NotifyMonitorStarted(len(instances), instanceList); err != nil {
-			log.Warnf("Failed to send monitor started notification: %v", err)
-		}
+// scanInstances scans the regions for spot instances, also returning the
+// regions that were scanned successfully
+func (m *Monitor) scanInstances() ([]*aliyun.SpotInstance, map[string]bool, error) {
+	scanned := make(map[string]bool)
+	var scannedMu sync.Mutex
+	instances, err := m.ecsClient.DiscoverAllSpotInstances(m.ctx, aliyun.DiscoverOptions{
+		Regions:     splitList(m.cfg.Regions),
+		Concurrency: m.cfg.DiscoveryConcurrency,
+		SkipRegion:  m.isRegionBlacklisted,
+		OnRegion: func(regionID string, err error) {
+			m.recordRegionResult(regionID, err)
+			if err == nil {
+				scannedMu.Lock()
+				scanned[regionID] = true
+				scannedMu.Unlock()
+			}
+		},
+		Filter: m.instanceFilter(),
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to discover instances: %w", err)
 	}
-
-	return nil
+	return instances, scanned, nil
 }
 
 // Check checks all instances and starts stopped ones
@@ -192,45 +799,271 @@ func (m *Monitor) Check() error {
 	instances := make([]*aliyun.SpotInstance, len(m.instances))
 	copy(instances, m.instances)
 	m.mu.RUnlock()
+	m.sortByDependencies(instances)
+	m.expireMaintenance()
+
+	ctx, cancel := m.beginCheck()
+	defer m.endCheck(cancel)
+	ctx, span := tracing.Start(ctx, "monitor.Check", attribute.Int("instances", len(instances)))
+	defer span.End()
+
+	// Check instances concurrently so one slow API call or start doesn't
+	// delay the recovery of the others
+	var (
+		failed   int
+		failedMu sync.Mutex
+		wg       sync.WaitGroup
+		slots    = newCheckSlots(m.cfg.CheckConcurrency, m.cfg.CheckConcurrencyPerRegion)
+		pending  = instances
+	)
+	for len(pending) > 0 {
+		if m.isClosing() {
+			log.Info("Shutting down, skipping the rest of the check")
+			break
+		}
 
-	for _, inst := range instances {
-		if err := m.checkInstance(inst); err != nil {
-			log.Errorf("Failed to check instance %s: %v", inst.InstanceID, err)
+		i := slots.take(pending)
+		inst := pending[i]
+		pending = append(pending[:i:i], pending[i+1:]...)
+		wg.Add(1)
+		go func(inst *aliyun.SpotInstance) {
+			defer wg.Done()
+			defer slots.release(inst.RegionID)
+
+			if !m.claimInstance(inst.InstanceID) {
+				log.Debugf("Instance %s is still being handled, skipping", inst.InstanceID)
+				return
+			}
+			defer m.releaseInstance(inst.InstanceID)
+
+			if err := m.checkInstance(ctx, inst); err != nil {
+				log.Errorf("Failed to check instance %s: %v", inst.InstanceID, err)
+				failedMu.Lock()
+				failed++
+				failedMu.Unlock()
+			}
+		}(inst)
+	}
+	wg.Wait()
+	if m.isClosing() {
+		return nil
+	}
+	if m.statusPageEnabled() {
+		m.statusPageUpdates.Add(1)
+		go func() {
+			defer m.statusPageUpdates.Done()
+			m.publishStatusPage()
+		}()
+	}
+
+	// Only a clean cycle counts as alive, so persistent API failures also alert
+	if m.pinger != nil && failed == 0 {
+		if err := m.pinger.Ping(); err != nil {
+			log.Warnf("Failed to ping dead man's switch: %v", err)
 		}
 	}
 
 	return nil
 }
 
+// checkSlots bounds the instances checked at once, in total and per region,
+// so the instances of a slow region don't hold up the other regions
+type checkSlots struct {
+	total     int
+	perRegion int // 0 for no limit
+	running   int
+	regions   map[string]int // region -> instances being checked
+	mu        sync.Mutex
+	freed     *sync.Cond
+}
+
+// newCheckSlots creates the slots of a check cycle
+func newCheckSlots(total, perRegion int) *checkSlots {
+	s := &checkSlots{total: total, perRegion: perRegion, regions: make(map[string]int)}
+	s.freed = sync.NewCond(&s.mu)
+	return s
+}
+
+// take waits for a free slot of one of the pending instances and returns its
+// index. The earliest instance that fits goes first, so dependencies are still
+// checked before the instances depending on them.
+func (s *checkSlots) take(pending []*aliyun.SpotInstance) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for {
+		if s.running < s.total {
+			for i, inst := range pending {
+				if s.perRegion <= 0 || s.regions[inst.RegionID] < s.perRegion {
+					s.running++
+					s.regions[inst.RegionID]++
+					return i
+				}
+			}
+		}
+		s.freed.Wait()
+	}
+}
+
+// release frees the slot of an instance of the region
+func (s *checkSlots) release(regionID string) {
+	s.mu.Lock()
+	s.running--
+	if s.regions[regionID]--; s.regions[regionID] <= 0 {
+		delete(s.regions, regionID)
+	}
+	s.mu.Unlock()
+	s.freed.Broadcast()
+}
+
+// claimInstance marks an instance as being handled, reporting false if it already is
+func (m *Monitor) claimInstance(instanceID string) bool {
+	m.busyMu.Lock()
+	defer m.busyMu.Unlock()
+	if m.busy[instanceID] {
+		return false
+	}
+	m.busy[instanceID] = true
+	return true
+}
+
+// releaseInstance marks an instance as no longer being handled
+// The first release of a handed off claim is the one the task took over.
+func (m *Monitor) releaseInstance(instanceID string) {
+	m.busyMu.Lock()
+	defer m.busyMu.Unlock()
+	if m.handedOff[instanceID] {
+		delete(m.handedOff, instanceID)
+		return
+	}
+	delete(m.busy, instanceID)
+}
+
+// handOffInstance passes the caller's claim of an instance on to a background
+// task, which keeps the instance claimed until it releases it too
+func (m *Monitor) handOffInstance(instanceID string) {
+	m.busyMu.Lock()
+	m.handedOff[instanceID] = true
+	m.busyMu.Unlock()
+}
+
 // checkInstance checks a single instance and starts it if stopped
-func (m *Monitor) checkInstance(inst *aliyun.SpotInstance) error {
+func (m *Monitor) checkInstance(ctx context.Context, inst *aliyun.SpotInstance) (err error) {
+	ctx, span := tracing.Start(ctx, "monitor.checkInstance", tracing.Instance(inst.RegionID, inst.InstanceID)...)
+	defer func() { tracing.End(span, err) }()
+
 	// Get current status
-	status, err := m.ecsClient.GetInstanceStatus(inst.RegionID, inst.InstanceID)
+	status, err := m.ecsClient.GetInstanceStatus(ctx, inst.RegionID, inst.InstanceID)
+	if errors.Is(err, aliyun.ErrInstanceNotFound) {
+		m.untrackReleased(inst)
+		return nil
+	}
 	if err != nil {
+		if aliyun.IsAuthError(err) {
+			m.handleAuthError(err)
+		}
 		return fmt.Errorf("failed to get status: %w", err)
 	}
+	m.setCredentialsRejected(false)
 
 	log.Debugf("Instance %s (%s) status: %s", inst.InstanceName, inst.InstanceID, status)
+	m.observeStatus(inst, status)
+
+	// Detect instances wedged in Starting/Stopping across cycles
+	status = m.checkStuckState(inst, status)
+
+	// Started some other way, e.g. from the console
+	if status == "Running" {
+		m.endCapacityWait(inst)
+		m.endStartBackoff(inst)
+		if m.cfg.IPChangeCheckEnabled {
+			m.checkAddress(ctx, inst)
+		}
+	}
 
 	// Only handle stopped instances
 	if status != "Stopped" {
+		m.clearStopCause(inst.InstanceID)
+		m.forgetMaintenanceStop(inst.InstanceID)
+		return nil
+	}
+	if m.isManuallyStopped(inst.InstanceID) {
+		log.Debugf("Instance %s was stopped on request, not starting it", inst.InstanceID)
+		return nil
+	}
+	if !m.startWanted(ctx, inst) {
+		log.Debugf("Instance %s was stopped from the console or API, not starting it", inst.InstanceID)
+		return nil
+	}
+	if m.inMaintenance() {
+		// Recorded once per stop, the start waits until maintenance ends
+		if m.noteMaintenanceStop(inst.InstanceID) {
+			log.Warnf("Instance %s (%s) is stopped, not starting it during maintenance", inst.InstanceName, inst.InstanceID)
+			m.bus.publish(busMessage{Topic: topicReclaimDetected, Instance: inst})
+		}
 		return nil
 	}
 
-	log.Warnf("Instance %s (%s) is stopped, attempting to start", inst.InstanceName, inst.InstanceID)
-
-	// Check notification cooldown
-	if !m.canNotify(inst.InstanceID) {
-		log.Debugf("Notification cooldown active for instance %s", inst.InstanceID)
+	// Already reported, only retry on the slow schedule until capacity is back
+	if retryAt, waiting := m.capacityRetryAt(inst.InstanceID); waiting {
+		if time.Now().Before(retryAt) {
+			log.Debugf("Instance %s is waiting for capacity, next start attempt at %s", inst.InstanceID, retryAt.Format("15:04:05"))
+			return nil
+		}
+		log.Infof("Retrying start of instance %s (%s) waiting for capacity", inst.InstanceName, inst.InstanceID)
+	} else if backoff, failing := m.startBackoffOf(inst.InstanceID); failing {
+		if time.Now().Before(backoff.retryAt) {
+			log.Debugf("Instance %s failed to start in %d checks, next start attempt at %s", inst.InstanceID, backoff.failures, backoff.retryAt.Format("15:04:05"))
+			return nil
+		}
+		log.Infof("Retrying start of instance %s (%s) after %d failed checks", inst.InstanceName, inst.InstanceID, backoff.failures)
 	} else {
-		// Send reclaimed notification
-		if m.notifier != nil {
-			if err := m.notifier.NotifyInstanceReclaimed(inst.InstanceID, inst.InstanceName, inst.RegionID); err != nil {
-				log.Warnf("Failed to send reclaimed notification: %v", err)
-			}
+		log.Warnf("Instance %s (%s) is stopped, attempting to start", inst.InstanceName, inst.InstanceID)
+		m.bus.publish(busMessage{Topic: topicReclaimDetected, Instance: inst})
+	}
+
+	// Bring services back in order, e.g. the database before the app using it
+	if err := m.waitForDependencies(ctx, inst); err != nil {
+		return err
+	}
+
+	// A sold-out zone is reported on its own, it doesn't fail the check
+	err = m.startInstance(ctx, inst)
+	if !errors.Is(err, ErrNoCapacity) {
+		if err != nil && !errors.Is(err, ErrShuttingDown) && ctx.Err() == nil {
+			m.backOffStart(inst, err)
 		}
-		m.updateNotifyTime(inst.InstanceID)
+		return err
+	}
+	if m.recreateDue(inst.InstanceID) {
+		return m.recreateInstance(ctx, inst)
+	}
+	return nil
+}
+
+// observeStatus publishes a status change when the status differs from the last check
+func (m *Monitor) observeStatus(inst *aliyun.SpotInstance, status string) {
+	m.statusesMu.Lock()
+	prev, seen := m.statuses[inst.InstanceID]
+	m.statuses[inst.InstanceID] = status
+	m.statusesMu.Unlock()
+
+	if seen && prev == status {
+		return
+	}
+	m.bus.publish(busMessage{Topic: topicStatusChanged, Instance: inst, Status: status, PrevStatus: prev})
+}
+
+// startInstance starts a stopped instance with retries and waits for it to
+// become running; subscribers of the published messages verify its health
+// and notify the outcome
+func (m *Monitor) startInstance(ctx context.Context, inst *aliyun.SpotInstance) (err error) {
+	if !m.beginStart() {
+		return ErrShuttingDown
 	}
+	defer m.starts.Done()
+
+	ctx, span := tracing.Start(ctx, "monitor.startInstance", tracing.Instance(inst.RegionID, inst.InstanceID)...)
+	defer func() { tracing.End(span, err) }()
 
 	// Try to start the instance with retries
 	startTime := time.Now()
@@ -238,73 +1071,159 @@ func (m *Monitor) checkInstance(inst *aliyun.SpotInstance) error {
 	for i := 0; i < m.cfg.RetryCount; i++ {
 		if i > 0 {
 			log.Infof("Retry %d/%d for instance %s", i+1, m.cfg.RetryCount, inst.InstanceID)
-			time.Sleep(time.Duration(m.cfg.RetryInterval) * time.Second)
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Duration(m.cfg.RetryInterval) * time.Second):
+			}
+		}
+		if ctx.Err() != nil {
+			lastErr = fmt.Errorf("start cancelled: %w", ctx.Err())
+			log.Warnf("Start of instance %s cancelled", inst.InstanceID)
+			break
 		}
 
-		if err := m.ecsClient.StartInstance(inst.RegionID, inst.InstanceID); err != nil {
+		span.AddEvent("start attempt", trace.WithAttributes(attribute.Int("attempt", i+1)))
+		m.bus.publish(busMessage{Topic: topicStartRequested, Instance: inst, Attempt: i + 1, Attempts: m.cfg.RetryCount})
+		if err := m.requestStart(ctx, inst); err != nil {
 			lastErr = err
 			log.Warnf("Failed to start instance %s (attempt %d): %v", inst.InstanceID, i+1, err)
+			// Retrying soon won't help a sold-out zone
+			if m.capacityRetryInterval() > 0 && aliyun.IsCapacityError(err) {
+				m.waitForCapacity(inst, err)
+				return fmt.Errorf("%w: %v", ErrNoCapacity, err)
+			}
 			continue
 		}
+		m.endCapacityWait(inst)
 
 		log.Infof("Start command sent for instance %s", inst.InstanceID)
 
 		// Wait for instance to be running (using Aliyun API)
-		if err := m.waitForRunning(inst.RegionID, inst.InstanceID); err != nil {
+		if err := m.waitForRunning(ctx, inst); err != nil {
 			lastErr = err
 			log.Warnf("Instance %s did not reach running state: %v", inst.InstanceID, err)
 			continue
 		}
+		m.endStartBackoff(inst)
 
 		// Get updated instance info for IP
-		updatedInst, err := m.ecsClient.GetInstance(inst.RegionID, inst.InstanceID)
+		updatedInst, err := m.ecsClient.GetInstance(ctx, inst.RegionID, inst.InstanceID)
 		if err != nil {
 			log.Warnf("Failed to get updated instance info: %v", err)
 		} else {
+			m.updateTrackedInstance(inst, updatedInst)
 			inst = updatedInst
 		}
 
-		// Success!
-		duration := time.Since(startTime)
-		log.Infof("Instance %s started successfully in %.0f seconds", inst.InstanceID, duration.Seconds())
+		m.bus.publish(busMessage{Topic: topicStartSucceeded, Ctx: ctx, Instance: inst, RequestedAt: startTime})
+		return nil
+	}
 
-		if m.notifier != nil {
-			if err := m.notifier.NotifyInstanceStarted(inst.InstanceID, inst.InstanceName, inst.RegionID, inst.PublicIPAddress, duration); err != nil {
-				log.Warnf("Failed to send started notification: %v", err)
-			}
+	// All retries failed
+	if ctx.Err() == nil {
+		log.Errorf("Failed to start instance %s after %d retries", inst.InstanceID, m.cfg.RetryCount)
+	}
+	m.bus.publish(busMessage{Topic: topicStartFailed, Instance: inst, Attempts: m.cfg.RetryCount, Err: lastErr})
+
+	return lastErr
+}
+
+// updateTrackedInstance replaces a tracked instance with fresh details,
+// publishing a message when its public address changed
+func (m *Monitor) updateTrackedInstance(old, updated *aliyun.SpotInstance) {
+	if old.PublicAddresses() != updated.PublicAddresses() {
+		log.Infof("Public IP of instance %s changed from %q to %q", updated.InstanceID, old.PublicAddresses(), updated.PublicAddresses())
+		m.bus.publish(busMessage{Topic: topicAddressChanged, Instance: updated, PrevAddress: old.PublicAddresses()})
+	}
+	m.setTrackedInstance(updated)
+}
+
+// setTrackedInstance replaces a tracked instance with fresh details
+func (m *Monitor) setTrackedInstance(updated *aliyun.SpotInstance) {
+	m.mu.Lock()
+	tracked := false
+	for i, inst := range m.instances {
+		if inst.InstanceID == updated.InstanceID {
+			m.instances[i] = updated
+			tracked = true
+			break
 		}
+	}
+	m.mu.Unlock()
+	if tracked {
+		m.saveTrackedInstance(updated)
+	}
+}
 
-		return nil
+// displayAddress returns the address or a placeholder when there is none
+func displayAddress(address string) string {
+	if address == "" {
+		return "无"
 	}
+	return address
+}
 
-	// All retries failed
-	log.Errorf("Failed to start instance %s after %d retries", inst.InstanceID, m.cfg.RetryCount)
-	if m.notifier != nil {
-		if err := m.notifier.NotifyInstanceStartFailed(inst.InstanceID, inst.InstanceName, inst.RegionID, m.cfg.RetryCount, lastErr); err != nil {
-			log.Warnf("Failed to send failure notification: %v", err)
+// statusTimeoutError is returned when an instance doesn't reach a status in time
+type statusTimeoutError struct {
+	target   string
+	timeout  time.Duration
+	interval time.Duration
+}
+
+func (e *statusTimeoutError) Error() string {
+	return fmt.Sprintf("timeout waiting for instance to reach %s after %s, polled every %s", e.target, e.timeout, e.interval)
+}
+
+// startWait returns how long a started instance may take to reach Running
+// and how often it is polled, per-instance overrides taking precedence
+func (m *Monitor) startWait(inst *aliyun.SpotInstance) (timeout, interval time.Duration) {
+	timeout = time.Duration(m.cfg.StartTimeout) * time.Second
+	interval = m.pollInterval()
+	if ic := m.cfg.InstanceConfig(inst.InstanceID, inst.Tags); ic != nil {
+		if ic.StartTimeout > 0 {
+			timeout = time.Duration(ic.StartTimeout) * time.Second
+		}
+		if ic.StartPollInterval > 0 {
+			interval = time.Duration(ic.StartPollInterval) * time.Second
 		}
 	}
+	return timeout, interval
+}
 
-	return lastErr
+// pollInterval returns how often an instance changing state is polled
+func (m *Monitor) pollInterval() time.Duration {
+	return time.Duration(m.cfg.StartPollInterval) * time.Second
 }
 
-// waitForRunning waits for an instance to reach running state
-func (m *Monitor) waitForRunning(regionID, instanceID string) error {
-	timeout := time.After(2 * time.Minute)
-	ticker := time.NewTicker(5 * time.Second)
+// waitForRunning waits for a started instance to reach running state
+func (m *Monitor) waitForRunning(ctx context.Context, inst *aliyun.SpotInstance) error {
+	timeout, interval := m.startWait(inst)
+	return m.waitForStatus(ctx, inst.RegionID, inst.InstanceID, "Running", timeout, interval)
+}
+
+// waitForStatus waits for an instance to reach the target status, polling it every interval
+func (m *Monitor) waitForStatus(ctx context.Context, regionID, instanceID, target string, timeout, interval time.Duration) (err error) {
+	attrs := append(tracing.Instance(regionID, instanceID), attribute.String("target_status", target))
+	ctx, span := tracing.Start(ctx, "monitor.waitForStatus", attrs...)
+	defer func() { tracing.End(span, err) }()
+
+	deadline := time.After(timeout)
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-timeout:
-			return fmt.Errorf("timeout waiting for instance to start")
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline:
+			return &statusTimeoutError{target: target, timeout: timeout, interval: interval}
 		case <-ticker.C:
-			status, err := m.ecsClient.GetInstanceStatus(regionID, instanceID)
+			status, err := m.ecsClient.GetInstanceStatus(ctx, regionID, instanceID)
 			if err != nil {
 				log.Warnf("Failed to get instance status: %v", err)
 				continue
 			}
-			if status == "Running" {
+			if status == target {
 				return nil
 			}
 			log.Debugf("Instance %s status: %s, waiting...", instanceID, status)
@@ -328,12 +1247,20 @@ func (m *Monitor) canNotify(instanceID string) bool {
 // updateNotifyTime updates the last notification time for an instance
 func (m *Monitor) updateNotifyTime(instanceID string) {
 	m.lastNotifyMu.Lock()
-	defer m.lastNotifyMu.Unlock()
 	m.lastNotify[instanceID] = time.Now()
+	m.lastNotifyMu.Unlock()
+
+	m.saveInstanceState(instanceID)
 }
 
 // SendBillingReport sends a billing report for the current month
 func (m *Monitor) SendBillingReport() error {
+	return m.sendBillingReport(nil, "")
+}
+
+// sendBillingReport sends a billing report for the given range, or the current month if nil,
+// of a single instance group if group is set
+func (m *Monitor) sendBillingReport(period *timeRange, group string) error {
 	if m.billingClient == nil {
 		return fmt.Errorf("billing client not initialized")
 	}
@@ -343,28 +1270,19 @@ func (m *Monitor) SendBillingReport() error {
 	}
 
 	// Get instance info
-	m.mu.RLock()
-	instanceInfos := make([]aliyun.InstanceInfo, len(m.instances))
-	for i, inst := range m.instances {
-		instanceInfos[i] = aliyun.InstanceInfo{
-			InstanceID:   inst.InstanceID,
-			InstanceName: inst.InstanceName,
-			RegionID:     inst.RegionID,
-		}
-	}
-	m.mu.RUnlock()
+	instanceInfos := m.instanceInfos()
 
 	if len(instanceInfos) == 0 {
 		log.Warn("No instances to query billing for")
 		return nil
 	}
 
-	log.Infof("Querying billing for %d instances...", len(instanceInfos))
-
-	// Query billing for current month
-	summary, err := m.billingClient.QueryBilling(instanceInfos)
+	summary, err := m.queryBilling(period)
 	if err != nil {
-		return fmt.Errorf("failed to query billing: %w", err)
+		return err
+	}
+	if group != "" {
+		summary = summary.FilterGroup(group)
 	}
 
 	// Send notification
@@ -377,8 +1295,218 @@ func (m *Monitor) SendBillingReport() error {
 	return nil
 }
 
+// queryBilling queries billing of tracked instances for the given range, or the current month if nil
+func (m *Monitor) queryBilling(period *timeRange) (*aliyun.BillingSummary, error) {
+	instanceInfos := m.instanceInfos()
+	log.Infof("Querying billing for %d instances...", len(instanceInfos))
+
+	var summary *aliyun.BillingSummary
+	var err error
+	switch {
+	case period == nil:
+		// Query billing for current month
+		summary, err = m.billingClient.QueryBilling(m.ctx, instanceInfos, "")
+	case period.Cycle != "":
+		// A whole past month is queried as its billing cycle
+		summary, err = m.billingClient.QueryBilling(m.ctx, instanceInfos, period.Cycle)
+	default:
+		summary, err = m.billingClient.QueryBillingByDateRange(m.ctx, instanceInfos, period.Start, period.lastDay(), period.Label)
+	}
+	if err != nil {
+		if aliyun.IsAuthError(err) {
+			m.handleAuthError(err)
+		}
+		return nil, fmt.Errorf("failed to query billing: %w", err)
+	}
+	m.applySavings(summary)
+	return summary, nil
+}
+
+// handleBillingExport handles /billing export [csv|json] [range]
+func (m *Monitor) handleBillingExport(args []string) error {
+	if m.billingClient == nil {
+		return fmt.Errorf("billing client not initialized")
+	}
+	if m.notifier == nil {
+		return fmt.Errorf("telegram notifier not initialized")
+	}
+
+	format := aliyun.ExportCSV
+	if len(args) > 0 && (args[0] == aliyun.ExportCSV || args[0] == aliyun.ExportJSON) {
+		format = args[0]
+		args = args[1:]
+	}
+	period, err := parseTimeRange(args, time.Now())
+	if err != nil {
+		return m.sendTimeRangeError(err)
+	}
+
+	summary, err := m.queryBilling(period)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := summary.Export(&buf, format); err != nil {
+		return fmt.Errorf("failed to export billing: %w", err)
+	}
+	label := summary.BillingCycle
+	if summary.PeriodLabel != "" {
+		label = summary.PeriodLabel
+	}
+	caption := fmt.Sprintf("🧾 扣费明细 (%s)，合计 ¥%.2f", label, summary.TotalAmount)
+	if err := m.notifier.SendDocument(summary.ExportFilename(format), buf.Bytes(), caption); err != nil {
+		return fmt.Errorf("failed to send billing export: %w", err)
+	}
+
+	log.Infof("Billing export sent (%s, %d bytes)", format, buf.Len())
+	return nil
+}
+
+// sendDailyBilling sends the spend of each day in the range, or the current month if nil
+func (m *Monitor) sendDailyBilling(period *timeRange) error {
+	if m.billingClient == nil {
+		return fmt.Errorf("billing client not initialized")
+	}
+	if m.notifier == nil {
+		return fmt.Errorf("telegram notifier not initialized")
+	}
+
+	now := time.Now()
+	if period == nil {
+		period = &timeRange{Start: time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()), End: now, Label: now.Format("2006-01")}
+	}
+	start, end := period.Start, period.lastDay()
+	if end.Sub(start) >= aliyun.MaxDailyBillingDays*24*time.Hour {
+		return m.notifier.Send(fmt.Sprintf("⚠️ 每日扣费最多查询 %d 天", aliyun.MaxDailyBillingDays))
+	}
+
+	days, err := m.billingClient.QueryDailyBilling(m.ctx, m.instanceInfos(), start, end)
+	if err != nil {
+		if aliyun.IsAuthError(err) {
+			m.handleAuthError(err)
+		}
+		return fmt.Errorf("failed to query daily billing: %w", err)
+	}
+
+	if err := m.notifier.NotifyDailyBilling(period.Label, days); err != nil {
+		return fmt.Errorf("failed to send daily billing: %w", err)
+	}
+	log.Infof("Daily billing sent (%d days)", len(days))
+	return nil
+}
+
+// defaultTrendMonths is how many billing cycles /trend covers without an argument
+const defaultTrendMonths = 3
+
+// handleTrendCommand handles /trend [months]
+func (m *Monitor) handleTrendCommand(args []string) error {
+	if m.notifier == nil {
+		return fmt.Errorf("telegram notifier not initialized")
+	}
+	months := defaultTrendMonths
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 2 || n > aliyun.MaxBillingHistoryMonths {
+			return m.notifier.Send(fmt.Sprintf("⚠️ 用法: /trend [月数]\n月数为 2~%d（含本月），默认 %d", aliyun.MaxBillingHistoryMonths, defaultTrendMonths))
+		}
+		months = n
+	}
+	return m.SendBillingTrend(months)
+}
+
+// SendBillingTrend sends this month's run rate compared with the previous months,
+// covering months billing cycles including the current one
+func (m *Monitor) SendBillingTrend(months int) error {
+	if m.billingClient == nil {
+		return fmt.Errorf("billing client not initialized")
+	}
+	if m.notifier == nil {
+		return fmt.Errorf("telegram notifier not initialized")
+	}
+
+	history, err := m.billingClient.QueryBillingHistory(m.ctx, m.instanceInfos(), months)
+	if err != nil {
+		if aliyun.IsAuthError(err) {
+			m.handleAuthError(err)
+		}
+		return fmt.Errorf("failed to query billing history: %w", err)
+	}
+
+	if err := m.notifier.NotifyBillingTrend(aliyun.NewBillingTrend(history)); err != nil {
+		return fmt.Errorf("failed to send billing trend: %w", err)
+	}
+	log.Infof("Billing trend sent (%d months)", months)
+	return nil
+}
+
+// instanceInfos returns billing lookup info for all tracked instances
+func (m *Monitor) instanceInfos() []aliyun.InstanceInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	infos := make([]aliyun.InstanceInfo, len(m.instances))
+	for i, inst := range m.instances {
+		infos[i] = aliyun.InstanceInfo{
+			InstanceID:   inst.InstanceID,
+			InstanceName: inst.InstanceName,
+			RegionID:     inst.RegionID,
+		}
+		if ic := m.cfg.InstanceConfig(inst.InstanceID, inst.Tags); ic != nil {
+			infos[i].ExpectedHoursPerDay = ic.ExpectedHoursPerDay
+		}
+		infos[i].Group = m.groupOf(inst)
+	}
+	return infos
+}
+
+// SendReconciliationReport sends this month's bandwidth billing reconciliation
+func (m *Monitor) SendReconciliationReport() error {
+	if m.billingClient == nil {
+		return fmt.Errorf("billing client not initialized")
+	}
+
+	if m.notifier == nil {
+		return fmt.Errorf("telegram notifier not initialized")
+	}
+
+	// Traffic is optional, the bandwidth charges are reconciled without it
+	var traffic *aliyun.TrafficSummary
+	if m.trafficClient != nil {
+		var err error
+		traffic, err = m.trafficClient.QueryInternetTraffic(m.ctx)
+		if err != nil {
+			log.Warnf("Failed to query traffic for reconciliation: %v", err)
+		}
+	}
+
+	log.Info("Querying bandwidth charges for reconciliation...")
+
+	result, err := m.billingClient.QueryBandwidthReconciliation(m.ctx, m.instanceInfos(), traffic)
+	if err != nil {
+		if aliyun.IsAuthError(err) {
+			m.handleAuthError(err)
+		}
+		return fmt.Errorf("failed to reconcile bandwidth billing: %w", err)
+	}
+
+	if err := m.notifier.NotifyBandwidthReconciliation(result); err != nil {
+		return fmt.Errorf("failed to send reconciliation notification: %w", err)
+	}
+
+	log.Infof("Reconciliation report sent successfully (total: ¥%.4f, discrepancies: %d)",
+		result.TotalAmount, len(result.Discrepancies))
+	return nil
+}
+
 // SendTrafficReport sends a traffic report for the current month
 func (m *Monitor) SendTrafficReport() error {
+	return m.sendTrafficReport(nil, "")
+}
+
+// sendTrafficReport sends a traffic report for the given range, or the current month if nil,
+// of a single region if region is set
+func (m *Monitor) sendTrafficReport(period *timeRange, region string) error {
 	if m.trafficClient == nil {
 		return fmt.Errorf("traffic client not initialized")
 	}
@@ -387,12 +1515,12 @@ func (m *Monitor) SendTrafficReport() error {
 		return fmt.Errorf("telegram notifier not initialized")
 	}
 
-	log.Info("Querying traffic data...")
-
-	// Query traffic for current month
-	summary, err := m.trafficClient.QueryInternetTraffic()
+	summary, err := m.queryTraffic(period)
 	if err != nil {
-		return fmt.Errorf("failed to query traffic: %w", err)
+		return err
+	}
+	if region != "" {
+		summary = summary.FilterRegion(region)
 	}
 
 	// Send notification
@@ -404,3 +1532,43 @@ func (m *Monitor) SendTrafficReport() error {
 		summary.TotalTrafficGB, summary.ChinaMainland.TrafficGB, summary.NonChinaMainland.TrafficGB)
 	return nil
 }
+
+// queryTraffic queries CDT traffic for the given range, or the current month if nil
+func (m *Monitor) queryTraffic(period *timeRange) (*aliyun.TrafficSummary, error) {
+	log.Info("Querying traffic data...")
+
+	var summary *aliyun.TrafficSummary
+	var err error
+	if period != nil {
+		summary, err = m.trafficClient.QueryInternetTrafficByTimeRange(m.ctx, period.Start, period.End)
+		if err == nil {
+			summary.PeriodLabel = period.Label
+		}
+	} else {
+		// Query traffic for current month
+		summary, err = m.trafficClient.QueryInternetTraffic(m.ctx)
+	}
+	if err != nil {
+		if aliyun.IsAuthError(err) {
+			m.handleAuthError(err)
+		}
+		return nil, fmt.Errorf("failed to query traffic: %w", err)
+	}
+	summary.ChinaMainland.QuotaGB = m.cfg.TrafficQuotaChinaGB
+	summary.NonChinaMainland.QuotaGB = m.cfg.TrafficQuotaGlobalGB
+	summary.ChinaMainland.PricePerGB = m.cfg.TrafficPriceChina
+	summary.NonChinaMainland.PricePerGB = m.cfg.TrafficPriceGlobal
+	if groups := m.trafficGroups(); len(groups) > 0 {
+		summary.GroupBy(groups)
+	}
+
+	if period == nil {
+		if err := m.trafficClient.ApplyForecast(m.ctx, summary, m.cfg.TrafficForecast); err != nil {
+			log.Warnf("Failed to forecast traffic with method %s, using elapsed days: %v", m.cfg.TrafficForecast, err)
+			if err := m.trafficClient.ApplyForecast(m.ctx, summary, aliyun.TrafficForecastElapsed); err != nil {
+				log.Warnf("Failed to forecast traffic: %v", err)
+			}
+		}
+	}
+	return summary, nil
+}
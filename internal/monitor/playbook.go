@@ -0,0 +1,115 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	"github.com/iliyian/aliyun-spot-manager/internal/config"
+	"github.com/iliyian/aliyun-spot-manager/internal/health"
+	"github.com/iliyian/aliyun-spot-manager/internal/playbook"
+	"github.com/iliyian/aliyun-spot-manager/internal/tracing"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// newPlaybookRunner creates the playbook runner with monitor-backed step types
+func (m *Monitor) newPlaybookRunner() *playbook.Runner {
+	runner := playbook.NewRunner(m.hooks)
+	runner.Register("wait_healthy", m.waitHealthyStep)
+	runner.Register("eip", m.eipStep)
+	runner.Register("dns", m.dnsStep)
+	runner.Register("cloud_assistant", m.cloudAssistantStep)
+	runner.OnStep = m.notifyPlaybookStep
+	runner.Lookup = m.findInstance
+	return runner
+}
+
+// playbookFor returns the playbook configured for an instance, if any
+func (m *Monitor) playbookFor(inst *aliyun.SpotInstance) []config.PlaybookStep {
+	ic := m.cfg.InstanceConfig(inst.InstanceID, inst.Tags)
+	if ic == nil {
+		return nil
+	}
+	return ic.Playbook
+}
+
+// waitHealthyStep waits for the instance's health checks to pass
+func (m *Monitor) waitHealthyStep(ctx context.Context, step config.PlaybookStep, inst *aliyun.SpotInstance) error {
+	host := inst.HealthCheckAddress()
+	if host == "" {
+		return fmt.Errorf("instance has no public IP")
+	}
+
+	checker, settings, err := m.healthCheckerFor(inst)
+	if err != nil {
+		return fmt.Errorf("invalid health check config: %w", err)
+	}
+
+	timeout := settings.timeout
+	if step.Timeout > 0 {
+		timeout = time.Duration(step.Timeout) * time.Second
+	}
+	return health.WaitForHealth(ctx, checker, host, timeout, settings.interval)
+}
+
+// notifyPlaybookStep sends a progress notification for a finished step
+func (m *Monitor) notifyPlaybookStep(inst *aliyun.SpotInstance, result playbook.StepResult) {
+	if result.Err != nil {
+		log.Warnf("Playbook step %d/%d (%s) for %s failed after %d attempt(s): %v",
+			result.Index, result.Total, result.Step.DisplayName(), inst.InstanceID, result.Attempts, result.Err)
+	} else {
+		log.Infof("Playbook step %d/%d (%s) for %s succeeded in %.0f seconds",
+			result.Index, result.Total, result.Step.DisplayName(), inst.InstanceID, result.Duration.Seconds())
+	}
+
+	notifier := m.notifierFor(inst.InstanceID)
+	if notifier == nil {
+		return
+	}
+	if err := notifier.NotifyPlaybookStep(inst.InstanceName, result.Index, result.Total, result.Step.DisplayName(), result.Attempts, result.Duration, result.Err); err != nil {
+		log.Warnf("Failed to send playbook step notification: %v", err)
+	}
+}
+
+// runPlaybook runs the recovery playbook for a freshly started instance and
+// publishes the overall result in place of the health check outcome
+func (m *Monitor) runPlaybook(ctx context.Context, inst *aliyun.SpotInstance, steps []config.PlaybookStep, startTime time.Time) {
+	log.Infof("Running %d-step playbook for instance %s", len(steps), inst.InstanceID)
+
+	ctx, span := tracing.Start(ctx, "monitor.runPlaybook", attribute.Int("steps", len(steps)))
+	results, err := m.playbooks.Run(ctx, steps, inst)
+	tracing.End(span, err)
+	duration := time.Since(startTime)
+	if latest := m.findInstance(inst.InstanceID); latest != nil {
+		inst = latest
+	}
+
+	if err != nil {
+		// The instance is running, so don't retry the start; just alert
+		log.Warnf("Playbook for instance %s failed: %v", inst.InstanceID, err)
+		m.bus.publish(busMessage{Topic: topicHealthFailed, Instance: inst, Playbook: true, Err: err})
+		return
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	status := fmt.Sprintf("剧本完成 ✓ (%d 步)", len(results))
+	if failed > 0 {
+		status = fmt.Sprintf("剧本完成 (%d/%d 步失败已跳过)", failed, len(results))
+	}
+
+	log.Infof("Instance %s recovered by playbook in %.0f seconds", inst.InstanceID, duration.Seconds())
+	m.bus.publish(busMessage{
+		Topic:    topicHealthPassed,
+		Instance: inst,
+		Duration: duration,
+		Check:    status,
+		Playbook: true,
+	})
+}
@@ -0,0 +1,194 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	"github.com/iliyian/aliyun-spot-manager/internal/tracing"
+	log "github.com/sirupsen/logrus"
+)
+
+// marketPriceWindow is how far back the latest market price is looked up
+const marketPriceWindow = 7 * 24 * time.Hour
+
+// ErrNoPriceLimit is returned when re-bidding an instance without a price limit
+var ErrNoPriceLimit = errors.New("instance has no spot price limit")
+
+// hasPriceLimit reports whether an instance is reclaimed when the market price exceeds its bid
+func hasPriceLimit(inst *aliyun.SpotInstance) bool {
+	return inst.SpotStrategy == "SpotWithPriceLimit" && inst.SpotPriceLimit > 0
+}
+
+// marketPrice returns the latest spot price of the instance type in the instance's zone
+func (m *Monitor) marketPrice(ctx context.Context, inst *aliyun.SpotInstance) (float64, error) {
+	prices, err := m.ecsClient.GetSpotPriceHistory(ctx, inst.RegionID, inst.InstanceType, inst.OSType, time.Now().Add(-marketPriceWindow))
+	if err != nil {
+		return 0, err
+	}
+	for i := len(prices) - 1; i >= 0; i-- {
+		if prices[i].ZoneID == inst.ZoneID {
+			return prices[i].Price, nil
+		}
+	}
+	return 0, fmt.Errorf("no spot price of %s in %s", inst.InstanceType, inst.ZoneID)
+}
+
+// CheckPriceLimits alerts once when the market price of a SpotWithPriceLimit
+// instance reaches PRICE_LIMIT_ALERT_RATIO of its bid, and again after it
+// fell back below. Instances in their protection period can't be reclaimed
+// and are skipped.
+func (m *Monitor) CheckPriceLimits() error {
+	for _, inst := range m.Instances(false) {
+		if !hasPriceLimit(inst) || m.isClosing() {
+			continue
+		}
+		if inProtection(inst, time.Now()) {
+			log.Debugf("Instance %s is protected until %s, skipping price limit check", inst.InstanceID, inst.ProtectedUntil().Format(time.RFC3339))
+			continue
+		}
+		price, err := m.marketPrice(m.ctx, inst)
+		if err != nil {
+			log.Warnf("Failed to get market price for instance %s: %v", inst.InstanceID, err)
+			continue
+		}
+
+		near := price >= inst.SpotPriceLimit*m.cfg.PriceLimitAlertRatio
+		m.priceLimitAlertsMu.Lock()
+		alerted := m.priceLimitAlerts[inst.InstanceID]
+		m.priceLimitAlerts[inst.InstanceID] = near
+		m.priceLimitAlertsMu.Unlock()
+
+		log.Debugf("Market price of instance %s is %.4f, limit %.4f", inst.InstanceID, price, inst.SpotPriceLimit)
+		if near && !alerted {
+			log.Warnf("Market price of instance %s (%s) is %.4f, %.0f%% of its limit %.4f",
+				inst.InstanceName, inst.InstanceID, price, price/inst.SpotPriceLimit*100, inst.SpotPriceLimit)
+			m.bus.publish(busMessage{Topic: topicPriceLimitNear, Instance: inst, Price: price})
+		}
+	}
+	return nil
+}
+
+// RebidInstance re-creates a stopped SpotWithPriceLimit instance in its zone
+// with a new price limit in the background, as ECS can't change the bid of an
+// existing instance. The outcome is reported through the usual notifications.
+func (m *Monitor) RebidInstance(instanceID string, priceLimit float64) error {
+	inst := m.findInstance(instanceID)
+	if inst == nil {
+		return ErrInstanceNotFound
+	}
+	if !hasPriceLimit(inst) {
+		return ErrNoPriceLimit
+	}
+
+	status, err := m.ecsClient.GetInstanceStatus(m.ctx, inst.RegionID, inst.InstanceID)
+	if err != nil {
+		return fmt.Errorf("failed to get status: %w", err)
+	}
+	if status != "Stopped" {
+		return fmt.Errorf("%w (status: %s)", ErrInstanceNotStopped, status)
+	}
+
+	if m.isClosing() {
+		return ErrShuttingDown
+	}
+	if !m.claimInstance(inst.InstanceID) {
+		return ErrInstanceBusy
+	}
+
+	log.Infof("Re-bid requested for instance %s (%s): %.4f -> %.4f", inst.InstanceName, inst.InstanceID, inst.SpotPriceLimit, priceLimit)
+	go func() {
+		defer m.releaseInstance(inst.InstanceID)
+		ctx, span := tracing.Start(m.ctx, "monitor.RebidInstance", tracing.Instance(inst.RegionID, inst.InstanceID)...)
+
+		requestedAt := time.Now()
+		replacement, address, err := m.replaceTracked(ctx, inst, aliyun.ReplaceOptions{SameZone: true, SpotPriceLimit: priceLimit})
+		tracing.End(span, err)
+		if err != nil {
+			log.Errorf("Re-bid of instance %s failed: %v", inst.InstanceID, err)
+			m.bus.publish(busMessage{Topic: topicStartFailed, Ctx: ctx, Instance: inst, Attempts: 1, Err: err})
+			return
+		}
+
+		m.priceLimitAlertsMu.Lock()
+		delete(m.priceLimitAlerts, inst.InstanceID)
+		m.priceLimitAlertsMu.Unlock()
+		m.bus.publish(busMessage{Topic: topicRebid, Instance: inst, Replacement: replacement, Address: address})
+		m.bus.publish(busMessage{Topic: topicStartSucceeded, Ctx: ctx, Instance: replacement, RequestedAt: requestedAt})
+	}()
+	return nil
+}
+
+// handlePriceLimitCommand handles /pricelimit [instance price]
+func (m *Monitor) handlePriceLimitCommand(args []string) error {
+	if m.notifier == nil {
+		return fmt.Errorf("telegram notifier not initialized")
+	}
+	if len(args) == 0 {
+		return m.sendPriceLimits()
+	}
+
+	usage := "⚠️ 用法: /pricelimit [实例 新出价]\n例如: /pricelimit hz-build 0.5（元/时）"
+	if len(args) != 2 {
+		return m.notifier.Send(usage)
+	}
+	priceLimit, err := strconv.ParseFloat(args[1], 64)
+	if err != nil || priceLimit <= 0 {
+		return m.notifier.Send(usage)
+	}
+
+	var inst *aliyun.SpotInstance
+	for _, candidate := range m.Instances(false) {
+		if candidate.InstanceID == args[0] || candidate.InstanceName == args[0] {
+			inst = candidate
+			break
+		}
+	}
+	if inst == nil {
+		return m.notifier.Send(fmt.Sprintf("⚠️ 未找到实例 %s", args[0]))
+	}
+
+	switch err := m.RebidInstance(inst.InstanceID, priceLimit); {
+	case errors.Is(err, ErrNoPriceLimit):
+		return m.notifier.Send(fmt.Sprintf("⚠️ 实例 %s 未设置出价上限（%s），无需调整", inst.InstanceName, inst.SpotStrategy))
+	case errors.Is(err, ErrInstanceNotStopped):
+		return m.notifier.Send(fmt.Sprintf("⚠️ 实例 %s 未停止\n阿里云不支持修改已有实例的出价上限，需在实例被回收或手动停止后按新出价重建", inst.InstanceName))
+	case err != nil:
+		return m.notifier.Send(fmt.Sprintf("❌ 无法调整实例 %s 的出价: %v", inst.InstanceName, err))
+	}
+	return m.notifier.Send(fmt.Sprintf("💹 正在按出价上限 ¥%.4f/时 在 %s 重建实例 %s，完成后通知", priceLimit, inst.ZoneID, inst.InstanceName))
+}
+
+// sendPriceLimits sends the bid and market price of each SpotWithPriceLimit instance
+func (m *Monitor) sendPriceLimits() error {
+	var sb strings.Builder
+	sb.WriteString("💹 <b>出价上限</b>\n")
+	sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+
+	found := false
+	for _, inst := range m.Instances(false) {
+		if !hasPriceLimit(inst) {
+			continue
+		}
+		found = true
+		sb.WriteString(fmt.Sprintf("<b>%s</b> (%s, %s)\n", inst.InstanceName, inst.InstanceType, inst.ZoneID))
+		sb.WriteString(fmt.Sprintf("   出价上限: ¥%.4f/时\n", inst.SpotPriceLimit))
+		if price, err := m.marketPrice(m.ctx, inst); err != nil {
+			log.Warnf("Failed to get market price for instance %s: %v", inst.InstanceID, err)
+			sb.WriteString("   市场价: 查询失败\n\n")
+		} else {
+			sb.WriteString(fmt.Sprintf("   市场价: ¥%.4f/时 (出价的 %.0f%%)\n\n", price, price/inst.SpotPriceLimit*100))
+		}
+	}
+	if !found {
+		sb.WriteString("没有设置出价上限 (SpotWithPriceLimit) 的实例")
+		return m.notifier.Send(sb.String())
+	}
+
+	sb.WriteString("<i>市场价超过出价上限时实例会被回收；/pricelimit 实例 新出价 可在实例停止后按新出价重建</i>")
+	return m.notifier.Send(sb.String())
+}
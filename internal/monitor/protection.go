@@ -0,0 +1,29 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+)
+
+// inProtection reports whether an instance is within the spot protection
+// period after its creation, during which ECS doesn't reclaim it
+func inProtection(inst *aliyun.SpotInstance, now time.Time) bool {
+	return now.Before(inst.ProtectedUntil())
+}
+
+// formatProtection describes the spot protection period of an instance,
+// empty when its creation time is unknown
+func formatProtection(inst *aliyun.SpotInstance, now time.Time) string {
+	switch {
+	case inst.CreationTime.IsZero():
+		return ""
+	case inst.SpotDuration <= 0:
+		return "无（随时可能被回收）"
+	case inProtection(inst, now):
+		return fmt.Sprintf("🛡 剩余 %s（期间不会被回收）", humanDuration(inst.ProtectedUntil().Sub(now)))
+	default:
+		return fmt.Sprintf("⚠️ 已于 %s 结束，可能随时被回收", inst.ProtectedUntil().Local().Format("01-02 15:04"))
+	}
+}
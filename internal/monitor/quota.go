@@ -0,0 +1,246 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	log "github.com/sirupsen/logrus"
+)
+
+// trafficBucket holds the traffic quota thresholds already alerted in the state store
+const trafficBucket = "traffic"
+
+// trafficAlertKey is the key of the alert state in trafficBucket
+const trafficAlertKey = "alerts"
+
+// trafficThresholds are the quota percentages alerted on, in ascending order
+var trafficThresholds = []int{70, 90, 100}
+
+// trafficAlertState records the highest quota threshold alerted per region group
+// in a billing cycle, so each threshold is alerted once a month, and whether the
+// hard cap action ran
+type trafficAlertState struct {
+	Cycle            string `json:"cycle"`
+	ChinaMainland    int    `json:"china_mainland"`
+	NonChinaMainland int    `json:"non_china_mainland"`
+	CapEnforced      bool   `json:"cap_enforced"` // hard cap action already applied this cycle
+}
+
+// trafficQuotaGroup is a region group with its CDT free quota
+type trafficQuotaGroup struct {
+	name    string
+	usedGB  float64
+	quotaGB float64
+	alerted *int // highest threshold alerted, in the alert state
+}
+
+// CheckTrafficQuota queries this month's CDT traffic, alerts when a region
+// group crosses a new threshold of its free quota and enforces the hard cap
+func (m *Monitor) CheckTrafficQuota() error {
+	if m.trafficClient == nil {
+		return fmt.Errorf("traffic client not initialized")
+	}
+	if m.notifier == nil {
+		return fmt.Errorf("telegram notifier not initialized")
+	}
+
+	summary, err := m.queryTraffic(nil)
+	if err != nil {
+		return err
+	}
+
+	m.trafficAlertsMu.Lock()
+	defer m.trafficAlertsMu.Unlock()
+
+	state := m.trafficAlerts
+	if state.Cycle != summary.BillingCycle {
+		state = trafficAlertState{Cycle: summary.BillingCycle}
+	}
+
+	alertErr := m.alertTrafficQuota(summary, &state)
+
+	var capErr error
+	if limit := m.cfg.TrafficHardCapGB; limit > 0 && !state.CapEnforced && summary.TotalTrafficGB >= limit {
+		capErr = m.enforceTrafficCap(summary)
+		state.CapEnforced = true
+	}
+
+	if state != m.trafficAlerts {
+		m.trafficAlerts = state
+		if m.store != nil {
+			if err := m.store.Put(trafficBucket, trafficAlertKey, state); err != nil {
+				log.Warnf("Failed to save traffic alert state: %v", err)
+			}
+		}
+	}
+
+	if alertErr != nil {
+		return alertErr
+	}
+	return capErr
+}
+
+// alertTrafficQuota sends an alert for the quota thresholds newly crossed,
+// recording them in state once sent
+func (m *Monitor) alertTrafficQuota(summary *aliyun.TrafficSummary, state *trafficAlertState) error {
+	alerted := *state
+	groups := []trafficQuotaGroup{
+		{name: "中国大陆", usedGB: summary.ChinaMainland.TrafficGB, quotaGB: m.cfg.TrafficQuotaChinaGB, alerted: &alerted.ChinaMainland},
+		{name: "非中国大陆", usedGB: summary.NonChinaMainland.TrafficGB, quotaGB: m.cfg.TrafficQuotaGlobalGB, alerted: &alerted.NonChinaMainland},
+	}
+
+	level := 0
+	var crossed []string
+	for _, g := range groups {
+		if g.quotaGB <= 0 {
+			continue
+		}
+		threshold := crossedThreshold(g.usedGB, g.quotaGB, trafficThresholds)
+		if threshold <= *g.alerted {
+			continue
+		}
+		*g.alerted = threshold
+		if threshold > level {
+			level = threshold
+		}
+		if threshold >= 100 {
+			crossed = append(crossed, fmt.Sprintf("❗ %s流量已超出免费额度，超出部分按量计费", g.name))
+		} else {
+			crossed = append(crossed, fmt.Sprintf("%s流量已达免费额度的 %d%%", g.name, threshold))
+		}
+	}
+	if len(crossed) == 0 {
+		log.Debugf("Traffic quota check: China %.2f GB, non-China %.2f GB, no new threshold",
+			summary.ChinaMainland.TrafficGB, summary.NonChinaMainland.TrafficGB)
+		return nil
+	}
+
+	icon := "ℹ️"
+	switch {
+	case level >= 100:
+		icon = "🚨"
+	case level >= 90:
+		icon = "⚠️"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s <b>流量额度告警 (%s)</b>\n", icon, summary.BillingCycle))
+	sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+	for _, g := range groups {
+		if g.quotaGB <= 0 {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("%s: %.2f / %.0f GB (%.0f%%)\n", g.name, g.usedGB, g.quotaGB, g.usedGB/g.quotaGB*100))
+	}
+	sb.WriteString("\n")
+	sb.WriteString(strings.Join(crossed, "\n"))
+
+	if err := m.notifier.Send(sb.String()); err != nil {
+		return fmt.Errorf("failed to send traffic quota alert: %w", err)
+	}
+	log.Infof("Traffic quota alert sent (China %.2f GB, non-China %.2f GB)",
+		summary.ChinaMainland.TrafficGB, summary.NonChinaMainland.TrafficGB)
+
+	*state = alerted
+	return nil
+}
+
+// trafficCapTargets returns the instances the hard cap action applies to:
+// those listed in TRAFFIC_CAP_INSTANCES, or all tracked instances
+func (m *Monitor) trafficCapTargets() (targets []*aliyun.SpotInstance, unknown []string) {
+	ids := splitList(m.cfg.TrafficCapInstances)
+	if len(ids) == 0 {
+		return m.Instances(false), nil
+	}
+	for _, id := range ids {
+		if inst := m.findInstance(id); inst != nil {
+			targets = append(targets, inst)
+		} else {
+			unknown = append(unknown, id)
+		}
+	}
+	return targets, unknown
+}
+
+// enforceTrafficCap stops the designated instances, or caps their public
+// bandwidth, once month-to-date traffic passes the hard cap, and reports
+// what was done
+func (m *Monitor) enforceTrafficCap(summary *aliyun.TrafficSummary) error {
+	action := m.cfg.TrafficCapAction
+	log.Warnf("Traffic %.2f GB passed the hard cap of %.0f GB, applying action %q",
+		summary.TotalTrafficGB, m.cfg.TrafficHardCapGB, action)
+
+	targets, unknown := m.trafficCapTargets()
+
+	var results []string
+	ctx := m.ctx
+	for _, inst := range targets {
+		name := fmt.Sprintf("<b>%s</b> (<code>%s</code>)", inst.InstanceName, inst.InstanceID)
+
+		if action == "bandwidth" {
+			if err := m.ecsClient.SetInternetBandwidth(ctx, inst.RegionID, inst.InstanceID, m.cfg.TrafficCapBandwidth); err != nil {
+				log.Errorf("Failed to cap bandwidth of instance %s: %v", inst.InstanceID, err)
+				results = append(results, fmt.Sprintf("❌ %s: 降低带宽失败: %v", name, err))
+				continue
+			}
+			m.recordEvent(inst, EventTrafficCap, fmt.Sprintf("流量超出上限，公网带宽降至 %d Mbps", m.cfg.TrafficCapBandwidth))
+			results = append(results, fmt.Sprintf("📉 %s: 公网带宽已降至 %d Mbps", name, m.cfg.TrafficCapBandwidth))
+			continue
+		}
+
+		status, err := m.ecsClient.GetInstanceStatus(ctx, inst.RegionID, inst.InstanceID)
+		if err != nil {
+			log.Errorf("Failed to get status of instance %s: %v", inst.InstanceID, err)
+			results = append(results, fmt.Sprintf("❌ %s: 获取状态失败: %v", name, err))
+			continue
+		}
+		// Keep auto-start away from the instance whether or not it is running now
+		m.setManuallyStopped(inst.InstanceID, true)
+		if status != "Running" {
+			results = append(results, fmt.Sprintf("⏸ %s: 当前状态 %s，已暂停自动启动", name, status))
+			continue
+		}
+		if err := m.ecsClient.StopInstance(ctx, inst.RegionID, inst.InstanceID, false); err != nil {
+			log.Errorf("Failed to stop instance %s: %v", inst.InstanceID, err)
+			results = append(results, fmt.Sprintf("❌ %s: 停止失败: %v", name, err))
+			continue
+		}
+		m.recordEvent(inst, EventTrafficCap, "流量超出上限，已停止并暂停自动启动")
+		results = append(results, fmt.Sprintf("⏹ %s: 已停止，暂停自动启动", name))
+	}
+	for _, id := range unknown {
+		results = append(results, fmt.Sprintf("❓ <code>%s</code>: 不是监控中的实例", id))
+	}
+	if len(results) == 0 {
+		results = append(results, "没有需要处理的实例")
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🚧 <b>流量超出上限 (%s)</b>\n", summary.BillingCycle))
+	sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+	sb.WriteString(fmt.Sprintf("本月流量: %.2f GB / 上限 %.0f GB\n\n", summary.TotalTrafficGB, m.cfg.TrafficHardCapGB))
+	sb.WriteString(strings.Join(results, "\n"))
+	if action == "stop" {
+		sb.WriteString("\n\n手动启动（控制台或 API）后恢复自动启动")
+	}
+
+	if err := m.notifier.Send(sb.String()); err != nil {
+		return fmt.Errorf("failed to send traffic cap notification: %w", err)
+	}
+	return nil
+}
+
+// loadTrafficAlerts restores the traffic quota thresholds already alerted
+func (m *Monitor) loadTrafficAlerts() error {
+	return m.store.ForEach(trafficBucket, func(key string, data []byte) error {
+		if key != trafficAlertKey {
+			return nil
+		}
+		if err := json.Unmarshal(data, &m.trafficAlerts); err != nil {
+			log.Warnf("Ignoring corrupt traffic alert state: %v", err)
+		}
+		return nil
+	})
+}
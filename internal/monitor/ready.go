@@ -0,0 +1,42 @@
+package monitor
+
+import (
+	"errors"
+)
+
+var (
+	// errNotDiscovered is reported until the first discovery completes
+	errNotDiscovered = errors.New("no instance discovery completed yet")
+	// errCredentialsRejected is reported while Aliyun rejects the AccessKey
+	errCredentialsRejected = errors.New("aliyun credentials were rejected")
+)
+
+// Ready reports whether the monitor can do its job: the Aliyun credentials
+// are accepted and at least one discovery has completed
+func (m *Monitor) Ready() error {
+	m.readyMu.RLock()
+	defer m.readyMu.RUnlock()
+
+	if !m.discovered {
+		return errNotDiscovered
+	}
+	if m.credentialsRejected {
+		return errCredentialsRejected
+	}
+	return nil
+}
+
+// markDiscovered records a completed discovery, which also proves the credentials work
+func (m *Monitor) markDiscovered() {
+	m.readyMu.Lock()
+	defer m.readyMu.Unlock()
+	m.discovered = true
+	m.credentialsRejected = false
+}
+
+// setCredentialsRejected records whether the last Aliyun API call failed authentication
+func (m *Monitor) setCredentialsRejected(rejected bool) {
+	m.readyMu.Lock()
+	defer m.readyMu.Unlock()
+	m.credentialsRejected = rejected
+}
@@ -0,0 +1,102 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	log "github.com/sirupsen/logrus"
+)
+
+// Rediscover scans the regions again and notifies the instances added or
+// removed since the last discovery. Instances in regions that failed or
+// were skipped this time are kept, as their absence proves nothing.
+func (m *Monitor) Rediscover() error {
+	found, scanned, err := m.scanInstances()
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	previous := m.instances
+	known := make(map[string]*aliyun.SpotInstance, len(previous))
+	for _, inst := range previous {
+		known[inst.InstanceID] = inst
+	}
+
+	instances := found
+	seen := make(map[string]bool, len(found))
+	var added, removed []*aliyun.SpotInstance
+	var moved [][2]*aliyun.SpotInstance // running instances whose address changed, old and new
+	for _, inst := range found {
+		seen[inst.InstanceID] = true
+		old, ok := known[inst.InstanceID]
+		if !ok {
+			added = append(added, inst)
+		} else if inst.Status == "Running" && old.PublicAddresses() != inst.PublicAddresses() {
+			moved = append(moved, [2]*aliyun.SpotInstance{old, inst})
+		}
+	}
+	for _, inst := range previous {
+		if seen[inst.InstanceID] {
+			continue
+		}
+		if !scanned[inst.RegionID] {
+			log.Debugf("Region %s was not scanned, keeping instance %s", inst.RegionID, inst.InstanceID)
+			instances = append(instances, inst)
+			continue
+		}
+		removed = append(removed, inst)
+	}
+	m.instances = instances
+	m.mu.Unlock()
+	m.markDiscovered()
+	m.saveTrackedInstances()
+
+	if m.cfg.IPChangeCheckEnabled {
+		for _, pair := range moved {
+			m.observeAddress(pair[0], pair[1])
+		}
+	}
+
+	if len(added) == 0 && len(removed) == 0 {
+		log.Debugf("Re-discovery found no changes (%d instances)", len(instances))
+		return nil
+	}
+
+	log.Infof("Re-discovery: %d added, %d removed, %d tracked", len(added), len(removed), len(instances))
+	for _, inst := range added {
+		log.Infof("  + %s (%s) in %s", inst.InstanceName, inst.InstanceID, inst.RegionID)
+	}
+	// Released instances are notified on their own; no longer matching the
+	// filters is not the same as gone
+	var unmatched []*aliyun.SpotInstance
+	for _, inst := range removed {
+		log.Infof("  - %s (%s) in %s", inst.InstanceName, inst.InstanceID, inst.RegionID)
+		if _, err := m.ecsClient.GetInstanceStatus(m.ctx, inst.RegionID, inst.InstanceID); errors.Is(err, aliyun.ErrInstanceNotFound) {
+			m.untrackReleased(inst)
+		} else {
+			unmatched = append(unmatched, inst)
+		}
+	}
+	removed = unmatched
+	if len(added) == 0 && len(removed) == 0 {
+		return nil
+	}
+
+	if m.notifier != nil && !m.inMaintenance() {
+		if err := m.notifier.NotifyInstancesChanged(describeInstances(added), describeInstances(removed)); err != nil {
+			log.Warnf("Failed to send instance change notification: %v", err)
+		}
+	}
+	return nil
+}
+
+// describeInstances formats instances as "name (id) - region" lines
+func describeInstances(instances []*aliyun.SpotInstance) []string {
+	lines := make([]string, len(instances))
+	for i, inst := range instances {
+		lines[i] = fmt.Sprintf("%s (%s) - %s", inst.InstanceName, inst.InstanceID, inst.RegionID)
+	}
+	return lines
+}
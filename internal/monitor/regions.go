@@ -0,0 +1,175 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	log "github.com/sirupsen/logrus"
+)
+
+// regionHealth tracks discovery failures for a region
+type regionHealth struct {
+	failures         int
+	lastError        string
+	blacklistedUntil time.Time
+}
+
+// isRegionBlacklisted reports whether discovery should skip a region
+func (m *Monitor) isRegionBlacklisted(regionID string) bool {
+	m.regionHealthMu.Lock()
+	defer m.regionHealthMu.Unlock()
+
+	rh, ok := m.regionHealth[regionID]
+	if !ok || rh.blacklistedUntil.IsZero() {
+		return false
+	}
+	if time.Now().After(rh.blacklistedUntil) {
+		// Blacklist expired, give the region another chance
+		log.Infof("Region %s blacklist expired, scanning it again", regionID)
+		rh.blacklistedUntil = time.Time{}
+		rh.failures = 0
+		return false
+	}
+	return true
+}
+
+// recordRegionResult counts consecutive discovery failures and blacklists
+// a region once it reaches the configured threshold
+func (m *Monitor) recordRegionResult(regionID string, err error) {
+	if m.cfg.RegionFailureThreshold <= 0 {
+		return
+	}
+
+	m.regionHealthMu.Lock()
+	if err == nil {
+		delete(m.regionHealth, regionID)
+		m.regionHealthMu.Unlock()
+		return
+	}
+
+	rh, ok := m.regionHealth[regionID]
+	if !ok {
+		rh = &regionHealth{}
+		m.regionHealth[regionID] = rh
+	}
+	rh.failures++
+	rh.lastError = aliyun.ErrorCode(err)
+	if rh.lastError == "" {
+		rh.lastError = err.Error()
+	}
+
+	if rh.failures < m.cfg.RegionFailureThreshold || !rh.blacklistedUntil.IsZero() {
+		m.regionHealthMu.Unlock()
+		return
+	}
+	rh.blacklistedUntil = time.Now().Add(time.Duration(m.cfg.RegionBlacklistHours) * time.Hour)
+	failures, until, lastError := rh.failures, rh.blacklistedUntil, rh.lastError
+	m.regionHealthMu.Unlock()
+
+	log.Warnf("Region %s failed %d times in a row (%s), skipping it until %s",
+		regionID, failures, lastError, until.Format("2006-01-02 15:04"))
+
+	if m.notifier != nil {
+		if err := m.notifier.NotifyRegionBlacklisted(regionID, failures, until, lastError); err != nil {
+			log.Warnf("Failed to send region blacklist notification: %v", err)
+		}
+	}
+}
+
+// resetRegions clears failures and the blacklist for one region, or all if empty
+func (m *Monitor) resetRegions(regionID string) int {
+	m.regionHealthMu.Lock()
+	defer m.regionHealthMu.Unlock()
+
+	if regionID == "" {
+		n := len(m.regionHealth)
+		m.regionHealth = make(map[string]*regionHealth)
+		return n
+	}
+	if _, ok := m.regionHealth[regionID]; !ok {
+		return 0
+	}
+	delete(m.regionHealth, regionID)
+	return 1
+}
+
+// handleRegionsCommand handles /regions and /regions reset [region]
+func (m *Monitor) handleRegionsCommand(args []string) error {
+	if m.notifier == nil {
+		return fmt.Errorf("telegram notifier not initialized")
+	}
+
+	if len(args) > 0 && args[0] == "reset" {
+		region := ""
+		if len(args) > 1 {
+			region = args[1]
+		}
+		n := m.resetRegions(region)
+		log.Infof("Reset %d region(s) via bot command", n)
+		if region == "" {
+			return m.notifier.Send(fmt.Sprintf("✅ 已清除 %d 个区域的失败记录和黑名单", n))
+		}
+		if n == 0 {
+			return m.notifier.Send(fmt.Sprintf("ℹ️ 区域 %s 没有失败记录", region))
+		}
+		return m.notifier.Send(fmt.Sprintf("✅ 已清除区域 %s 的失败记录和黑名单", region))
+	}
+
+	return m.notifier.Send(m.regionsReport())
+}
+
+// regionsReport formats the current region failure and blacklist state
+func (m *Monitor) regionsReport() string {
+	m.regionHealthMu.Lock()
+	regions := make([]string, 0, len(m.regionHealth))
+	for region := range m.regionHealth {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+
+	var sb strings.Builder
+	sb.WriteString("🌏 <b>区域状态</b>\n")
+	sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	sb.WriteString(fmt.Sprintf("并发扫描: %d | 黑名单阈值: %d 次 | 时长: %d 小时\n\n",
+		m.cfg.DiscoveryConcurrency, m.cfg.RegionFailureThreshold, m.cfg.RegionBlacklistHours))
+
+	if len(regions) == 0 {
+		sb.WriteString("所有区域均正常")
+	}
+	for _, region := range regions {
+		rh := m.regionHealth[region]
+		if !rh.blacklistedUntil.IsZero() {
+			sb.WriteString(fmt.Sprintf("⛔ <b>%s</b> 已拉黑至 %s\n", region, rh.blacklistedUntil.Format("01-02 15:04")))
+		} else {
+			sb.WriteString(fmt.Sprintf("⚠️ <b>%s</b> 连续失败 %d 次\n", region, rh.failures))
+		}
+		sb.WriteString(fmt.Sprintf("   错误: %s\n", rh.lastError))
+	}
+	m.regionHealthMu.Unlock()
+
+	// API circuit breakers, shorter lived than the discovery blacklist
+	if breakers := m.resilience.States(); len(breakers) > 0 {
+		sb.WriteString("\n\n<b>API 熔断</b>\n")
+		for _, b := range breakers {
+			icon := "🟢"
+			if b.Open {
+				icon = "🔴"
+			} else if b.Failures > 0 {
+				icon = "🟡"
+			}
+			sb.WriteString(fmt.Sprintf("%s <b>%s</b> 连续失败 %d 次 | 熔断 %d 次 | 限流重试 %d 次", icon, b.Region, b.Failures, b.Trips, b.Retries))
+			if b.Open {
+				sb.WriteString(fmt.Sprintf(" | 跳过至 %s", b.OpenUntil.Format("15:04")))
+			}
+			sb.WriteString("\n")
+		}
+	}
+
+	if len(regions) > 0 {
+		sb.WriteString("\n<i>使用 /regions reset [区域] 清除黑名单</i>")
+	}
+	return sb.String()
+}
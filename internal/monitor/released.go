@@ -0,0 +1,44 @@
+package monitor
+
+import (
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	log "github.com/sirupsen/logrus"
+)
+
+// untrackReleased stops tracking an instance that no longer exists and
+// notifies it once. Its retry state is dropped; mutes and manual stops are
+// kept in case a re-discovery finds it after all.
+func (m *Monitor) untrackReleased(inst *aliyun.SpotInstance) {
+	m.mu.Lock()
+	tracked := false
+	for i, other := range m.instances {
+		if other.InstanceID == inst.InstanceID {
+			m.instances = append(m.instances[:i:i], m.instances[i+1:]...)
+			tracked = true
+			break
+		}
+	}
+	m.mu.Unlock()
+
+	m.statusesMu.Lock()
+	delete(m.statuses, inst.InstanceID)
+	m.statusesMu.Unlock()
+	m.startBackoffsMu.Lock()
+	delete(m.startBackoffs, inst.InstanceID)
+	m.startBackoffsMu.Unlock()
+	m.capacityWaitsMu.Lock()
+	delete(m.capacityWaits, inst.InstanceID)
+	m.capacityWaitsMu.Unlock()
+	m.takeSelfStart(inst.InstanceID)
+	m.clearStopCause(inst.InstanceID)
+	m.forgetMaintenanceStop(inst.InstanceID)
+	m.saveInstanceState(inst.InstanceID)
+	m.forgetTrackedInstance(inst.InstanceID)
+
+	if tracked {
+		log.Warnf("Instance %s (%s) in %s was released or deleted, no longer tracking it", inst.InstanceName, inst.InstanceID, inst.RegionID)
+	} else {
+		log.Infof("Instance %s (%s) in %s was released or deleted", inst.InstanceName, inst.InstanceID, inst.RegionID)
+	}
+	m.bus.publish(busMessage{Topic: topicInstanceReleased, Instance: inst})
+}
@@ -0,0 +1,71 @@
+package monitor
+
+import (
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	log "github.com/sirupsen/logrus"
+)
+
+// onDemandPriceTTL is how long a pay-as-you-go price is reused
+const onDemandPriceTTL = 24 * time.Hour
+
+// onDemandPrice is a cached pay-as-you-go hourly price
+type onDemandPrice struct {
+	price     float64
+	fetchedAt time.Time
+}
+
+// applySavings compares the compute cost of each instance with what the same
+// running hours would have cost at pay-as-you-go rates
+func (m *Monitor) applySavings(summary *aliyun.BillingSummary) {
+	for i := range summary.Instances {
+		inst := &summary.Instances[i]
+		if inst.RunningHours <= 0 || inst.ComputeAmount <= 0 {
+			continue
+		}
+
+		regionID, zoneID, instanceType := inst.Region, "", inst.InstanceSpec
+		if tracked := m.findInstance(inst.InstanceID); tracked != nil {
+			regionID, zoneID = tracked.RegionID, tracked.ZoneID
+			if tracked.InstanceType != "" {
+				instanceType = tracked.InstanceType
+			}
+		}
+		if regionID == "" || instanceType == "" {
+			continue
+		}
+
+		price, ok := m.onDemandPrice(regionID, zoneID, instanceType)
+		if !ok {
+			continue
+		}
+		inst.OnDemandAmount = price * inst.RunningHours
+		summary.OnDemandAmount += inst.OnDemandAmount
+		summary.SavedAmount += inst.OnDemandAmount - inst.ComputeAmount
+	}
+}
+
+// onDemandPrice returns the cached pay-as-you-go hourly price of an instance type,
+// querying it when missing or expired
+func (m *Monitor) onDemandPrice(regionID, zoneID, instanceType string) (float64, bool) {
+	key := regionID + "/" + zoneID + "/" + instanceType
+
+	m.onDemandPricesMu.Lock()
+	cached, ok := m.onDemandPrices[key]
+	m.onDemandPricesMu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < onDemandPriceTTL {
+		return cached.price, true
+	}
+
+	price, err := m.ecsClient.GetOnDemandPrice(m.ctx, regionID, zoneID, instanceType)
+	if err != nil {
+		log.Warnf("Failed to query pay-as-you-go price of %s in %s: %v", instanceType, regionID, err)
+		return 0, false
+	}
+
+	m.onDemandPricesMu.Lock()
+	m.onDemandPrices[key] = onDemandPrice{price: price, fetchedAt: time.Now()}
+	m.onDemandPricesMu.Unlock()
+	return price, true
+}
@@ -0,0 +1,123 @@
+package monitor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	"github.com/iliyian/aliyun-spot-manager/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// requiredPort is a port the security groups of an instance must let in
+type requiredPort struct {
+	protocol string // tcp or udp
+	port     int
+}
+
+func (p requiredPort) String() string {
+	return fmt.Sprintf("%s:%d", p.protocol, p.port)
+}
+
+// parseRequiredPorts parses required_ports like "22,443,udp:51820", TCP unless stated
+func parseRequiredPorts(spec string) ([]requiredPort, error) {
+	var ports []requiredPort
+	for _, item := range splitList(spec) {
+		protocol, port := "tcp", item
+		if p, rest, ok := strings.Cut(item, ":"); ok {
+			protocol, port = strings.ToLower(p), rest
+		}
+		if protocol != "tcp" && protocol != "udp" {
+			return nil, fmt.Errorf("invalid protocol in required port %q, must be tcp or udp", item)
+		}
+		n, err := strconv.Atoi(port)
+		if err != nil || n < 1 || n > 65535 {
+			return nil, fmt.Errorf("invalid required port %q", item)
+		}
+		ports = append(ports, requiredPort{protocol: protocol, port: n})
+	}
+	return ports, nil
+}
+
+// closedPorts returns the ports no accept rule lets in, or that a drop rule
+// for any source blocks with the same or a higher priority. Accept rules for
+// any source count, so ports deliberately limited to some addresses pass.
+func closedPorts(rules []aliyun.SecurityGroupRule, ports []requiredPort) []requiredPort {
+	var closed []requiredPort
+	for _, p := range ports {
+		accept, drop := 0, 0 // best priority of each, 0 for none
+		for _, rule := range rules {
+			if !rule.Covers(p.protocol, p.port) {
+				continue
+			}
+			if rule.Accept && (accept == 0 || rule.Priority < accept) {
+				accept = rule.Priority
+			}
+			if !rule.Accept && rule.FromAnywhere() && (drop == 0 || rule.Priority < drop) {
+				drop = rule.Priority
+			}
+		}
+		if accept == 0 || (drop != 0 && drop <= accept) {
+			closed = append(closed, p)
+		}
+	}
+	return closed
+}
+
+// auditSecurityGroups warns when the security groups of a started instance no
+// longer let in its required ports, so a "recovered" instance that can't be
+// reached doesn't go unnoticed
+func (m *Monitor) auditSecurityGroups(msg busMessage) {
+	inst := msg.Instance
+	ic := m.carriedInstanceConfig(inst, func(ic *config.InstanceConfig) bool { return ic.RequiredPorts != "" })
+	if ic == nil {
+		return
+	}
+	ports, err := parseRequiredPorts(ic.RequiredPorts)
+	if err != nil {
+		log.Warnf("Invalid required ports of instance %s: %v", inst.InstanceID, err)
+		return
+	}
+
+	ctx := msg.Ctx
+	if ctx == nil {
+		ctx = m.ctx
+	}
+	groups := inst.SecurityGroupIDs
+	if len(groups) == 0 {
+		current, err := m.ecsClient.GetInstance(ctx, inst.RegionID, inst.InstanceID)
+		if err != nil {
+			log.Warnf("Failed to get security groups of instance %s: %v", inst.InstanceID, err)
+			return
+		}
+		groups = current.SecurityGroupIDs
+	}
+	if len(groups) == 0 {
+		log.Debugf("Instance %s has no security groups to audit", inst.InstanceID)
+		return
+	}
+
+	rules, err := m.ecsClient.DescribeSecurityGroupRules(ctx, inst.RegionID, groups)
+	if err != nil {
+		log.Warnf("Failed to audit security groups of instance %s: %v", inst.InstanceID, err)
+		return
+	}
+	closed := closedPorts(rules, ports)
+	if len(closed) == 0 {
+		log.Debugf("Security groups of instance %s let in %s", inst.InstanceID, ic.RequiredPorts)
+		return
+	}
+
+	names := make([]string, len(closed))
+	for i, p := range closed {
+		names[i] = p.String()
+	}
+	log.Warnf("Security groups %s of instance %s don't let in %s", strings.Join(groups, ", "), inst.InstanceID, strings.Join(names, ", "))
+	m.recordEvent(inst, EventSecurityGroup, fmt.Sprintf("安全组未放行 %s", strings.Join(names, ", ")))
+	if notifier := m.notifierFor(inst.InstanceID); notifier != nil {
+		if err := notifier.NotifySecurityGroupDrift(inst.InstanceID, inst.InstanceName, inst.RegionID, groups, names); err != nil {
+			log.Warnf("Failed to send security group notification: %v", err)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// beginStart registers an in-flight start, or reports false when shutting down
+func (m *Monitor) beginStart() bool {
+	m.closingMu.RLock()
+	defer m.closingMu.RUnlock()
+	if m.closing {
+		return false
+	}
+	m.starts.Add(1)
+	return true
+}
+
+// isClosing reports whether shutdown has begun
+func (m *Monitor) isClosing() bool {
+	m.closingMu.RLock()
+	defer m.closingMu.RUnlock()
+	return m.closing
+}
+
+// Shutdown refuses new instance starts and waits for the in-flight ones to
+// finish. When ctx expires first they are cancelled, which stops their retry
+// and wait loops before the next API call.
+func (m *Monitor) Shutdown(ctx context.Context) error {
+	m.closingMu.Lock()
+	m.closing = true
+	m.closingMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.starts.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		m.cancel()
+		return nil
+	case <-ctx.Done():
+	}
+
+	log.Warn("Shutdown timeout reached, cancelling in-flight instance starts")
+	m.cancel()
+
+	// A status poll already sent is not cancellable, give it time to return
+	select {
+	case <-done:
+		return nil
+	case <-time.After(10 * time.Second):
+		return fmt.Errorf("in-flight instance starts did not stop after cancellation")
+	}
+}
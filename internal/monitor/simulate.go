@@ -0,0 +1,34 @@
+package monitor
+
+import (
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	"github.com/iliyian/aliyun-spot-manager/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// newSimulated creates a monitor of in-memory demo instances that are
+// reclaimed at random and fail to start at SIMULATE_START_FAILURE_RATE, to
+// try out recoveries, notifications, cooldowns and retry settings without
+// an Aliyun account
+func newSimulated(cfg *config.Config) (*Monitor, error) {
+	log.Warn("Simulate mode: using in-memory demo instances, no Aliyun API is called")
+
+	cloud := aliyun.NewFakeCloud(aliyun.DemoInstances(cfg.SimulateInstances)...)
+	cloud.StartFailureRate = cfg.SimulateStartFailure
+	cloud.ReclaimWarning = time.Duration(cfg.SimulateReclaimWarning) * time.Second
+	m, err := NewWithClients(cfg, Clients{
+		Instances: cloud,
+		Billing:   aliyun.NewFakeBilling(cloud),
+		Traffic:   aliyun.NewFakeTraffic(cloud),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.SimulateReclaimInterval > 0 {
+		go cloud.ReclaimRandomly(m.ctx, time.Duration(cfg.SimulateReclaimInterval)*time.Second)
+	}
+	return m, nil
+}
@@ -0,0 +1,257 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	"github.com/iliyian/aliyun-spot-manager/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// Why snapshots were taken, part of the snapshot names
+const (
+	snapshotScheduled = "scheduled"
+	snapshotReclaim   = "reclaim"
+	snapshotManual    = "manual"
+)
+
+// maxListedSnapshots is how many snapshots /snapshots shows per instance
+const maxListedSnapshots = 5
+
+// snapshotConfig returns the overrides of an instance with snapshots enabled for a reason
+func (m *Monitor) snapshotConfig(inst *aliyun.SpotInstance, reason string) *config.InstanceConfig {
+	return m.carriedInstanceConfig(inst, func(ic *config.InstanceConfig) bool {
+		if reason == snapshotReclaim {
+			return ic.SnapshotOnReclaim
+		}
+		return ic.SnapshotOnSchedule
+	})
+}
+
+// TakeSnapshots snapshots the disks of the instances with snapshot_on_schedule
+// and sends one notification with the results
+func (m *Monitor) TakeSnapshots() error {
+	var lines []string
+	var lastErr error
+	for _, inst := range m.Instances(false) {
+		ic := m.snapshotConfig(inst, snapshotScheduled)
+		if ic == nil {
+			continue
+		}
+		line, err := m.snapshotInstance(m.ctx, inst, snapshotScheduled, m.snapshotRetention(ic))
+		if err != nil {
+			lastErr = err
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	if m.notifier != nil {
+		if err := m.notifier.NotifySnapshots("定时快照", lines); err != nil {
+			log.Warnf("Failed to send snapshot notification: %v", err)
+		}
+	}
+	if lastErr != nil {
+		return fmt.Errorf("failed to snapshot some instances: %w", lastErr)
+	}
+	return nil
+}
+
+// snapshotBeforeReclaim snapshots the disks of an instance about to be
+// reclaimed, so their data survives when they are released with it
+func (m *Monitor) snapshotBeforeReclaim(inst *aliyun.SpotInstance, ic *config.InstanceConfig) {
+	line, _ := m.snapshotInstance(m.ctx, inst, snapshotReclaim, m.snapshotRetention(ic))
+	if notifier := m.notifierFor(inst.InstanceID); notifier != nil {
+		if err := notifier.NotifySnapshots("回收前快照", []string{line}); err != nil {
+			log.Warnf("Failed to send snapshot notification: %v", err)
+		}
+	}
+}
+
+// snapshotRetention returns how many snapshots to keep per disk of an instance
+func (m *Monitor) snapshotRetention(ic *config.InstanceConfig) int {
+	if ic != nil && ic.SnapshotRetention > 0 {
+		return ic.SnapshotRetention
+	}
+	return m.cfg.SnapshotRetention
+}
+
+// snapshotInstance snapshots the disks of an instance and prunes its old
+// snapshots beyond retention, none when it is 0. It returns a summary line
+// for the notification.
+func (m *Monitor) snapshotInstance(ctx context.Context, inst *aliyun.SpotInstance, reason string, retention int) (string, error) {
+	log.Infof("Taking %s snapshots of instance %s", reason, inst.InstanceID)
+	snapshots, err := m.ecsClient.CreateInstanceSnapshots(ctx, inst.RegionID, inst.InstanceID, reason)
+
+	ids := make([]string, len(snapshots))
+	for i, snapshot := range snapshots {
+		ids[i] = snapshot.SnapshotID
+	}
+	if err != nil {
+		log.Warnf("Failed to snapshot instance %s: %v", inst.InstanceID, err)
+		m.recordEvent(inst, EventSnapshot, fmt.Sprintf("快照失败: %v", err))
+		if len(ids) == 0 {
+			return fmt.Sprintf("❌ %s: %v", inst.InstanceName, err), err
+		}
+		return fmt.Sprintf("⚠️ %s: %s，部分磁盘失败: %v", inst.InstanceName, strings.Join(ids, ", "), err), err
+	}
+	m.recordEvent(inst, EventSnapshot, fmt.Sprintf("已创建快照 %s", strings.Join(ids, ", ")))
+
+	line := fmt.Sprintf("✅ %s: %s", inst.InstanceName, strings.Join(ids, ", "))
+	if retention == 0 {
+		return line, nil
+	}
+	pruned, err := m.pruneSnapshots(ctx, inst, retention)
+	if err != nil {
+		log.Warnf("Failed to prune snapshots of instance %s: %v", inst.InstanceID, err)
+		line += fmt.Sprintf("（清理旧快照失败: %v）", err)
+	} else if pruned > 0 {
+		line += fmt.Sprintf("（已清理 %d 个旧快照）", pruned)
+	}
+	return line, nil
+}
+
+// pruneSnapshots deletes the finished snapshots the manager took of each disk
+// of an instance beyond the newest keep. Snapshots still in progress are left alone and
+// don't count, so a snapshot that fails never pushes out a good one.
+func (m *Monitor) pruneSnapshots(ctx context.Context, inst *aliyun.SpotInstance, keep int) (int, error) {
+	snapshots, err := m.ecsClient.DescribeSnapshots(ctx, inst.RegionID, inst.InstanceID)
+	if err != nil {
+		return 0, err
+	}
+
+	kept := make(map[string]int)
+	pruned := 0
+	var lastErr error
+	for _, snapshot := range snapshots {
+		if !snapshot.Managed || snapshot.Status != "accomplished" {
+			continue
+		}
+		if kept[snapshot.DiskID] < keep {
+			kept[snapshot.DiskID]++
+			continue
+		}
+		if err := m.ecsClient.DeleteSnapshot(ctx, inst.RegionID, snapshot.SnapshotID); err != nil {
+			lastErr = err
+			continue
+		}
+		log.Infof("Deleted snapshot %s of instance %s beyond the newest %d", snapshot.SnapshotID, inst.InstanceID, keep)
+		pruned++
+	}
+	return pruned, lastErr
+}
+
+// matchInstances returns the tracked instances matching an ID or name, all when it is empty
+func (m *Monitor) matchInstances(instance string) []*aliyun.SpotInstance {
+	var matched []*aliyun.SpotInstance
+	for _, inst := range m.Instances(false) {
+		if instance == "" || inst.InstanceID == instance || inst.InstanceName == instance {
+			matched = append(matched, inst)
+		}
+	}
+	return matched
+}
+
+// handleSnapshotCommand handles /snapshot <instance>, snapshotting its disks now
+// Manual snapshots count towards the retention but don't prune older ones.
+func (m *Monitor) handleSnapshotCommand(args []string) error {
+	if m.notifier == nil {
+		return fmt.Errorf("telegram notifier not initialized")
+	}
+	if len(args) != 1 {
+		return m.notifier.Send("⚠️ 用法: /snapshot 实例\n例如: /snapshot hk-proxy")
+	}
+	instances := m.matchInstances(args[0])
+	if len(instances) == 0 {
+		return m.notifier.Send(fmt.Sprintf("⚠️ 未找到实例 %s", args[0]))
+	}
+
+	inst := instances[0]
+	line, err := m.snapshotInstance(m.ctx, inst, snapshotManual, 0)
+	if err != nil {
+		return m.notifier.NotifySnapshots("手动快照", []string{line})
+	}
+	return m.notifier.NotifySnapshots("手动快照", []string{line, "", fmt.Sprintf("快照在后台完成，可用 /snapshots %s 查看进度", inst.InstanceName)})
+}
+
+// handleSnapshotsCommand handles /snapshots [instance], listing the recent
+// snapshots of each instance's disks
+func (m *Monitor) handleSnapshotsCommand(args []string) error {
+	if m.notifier == nil {
+		return fmt.Errorf("telegram notifier not initialized")
+	}
+	instance := ""
+	if len(args) > 0 {
+		instance = args[0]
+	}
+	instances := m.matchInstances(instance)
+	if len(instances) == 0 {
+		return m.notifier.Send("📸 <b>磁盘快照</b>\n\n暂无匹配的实例")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("📸 <b>磁盘快照</b>\n")
+	sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+
+	for _, inst := range instances {
+		sb.WriteString(fmt.Sprintf("<b>%s</b> (<code>%s</code>)\n", inst.InstanceName, inst.InstanceID))
+		snapshots, err := m.ecsClient.DescribeSnapshots(m.ctx, inst.RegionID, inst.InstanceID)
+		if err != nil {
+			log.Warnf("Failed to list snapshots of instance %s: %v", inst.InstanceID, err)
+			sb.WriteString("   查询失败\n\n")
+			continue
+		}
+		if len(snapshots) == 0 {
+			sb.WriteString("   暂无快照\n\n")
+			continue
+		}
+
+		for i, snapshot := range snapshots {
+			if i == maxListedSnapshots {
+				sb.WriteString(fmt.Sprintf("   …共 %d 个快照\n", len(snapshots)))
+				break
+			}
+			sb.WriteString(fmt.Sprintf("   %s <code>%s</code> %s %s", snapshotStatusEmoji(snapshot.Status),
+				snapshot.SnapshotID, diskTypeName(snapshot.DiskType), snapshot.CreationTime.Local().Format("01-02 15:04")))
+			if snapshot.Status == "progressing" {
+				sb.WriteString(" " + snapshot.Progress)
+			}
+			if !snapshot.Managed {
+				sb.WriteString(" (非本程序创建)")
+			}
+			sb.WriteString("\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("<i>/snapshot 实例 立即创建快照</i>")
+	return m.notifier.Send(sb.String())
+}
+
+// snapshotStatusEmoji returns the emoji of a snapshot status
+func snapshotStatusEmoji(status string) string {
+	switch status {
+	case "accomplished":
+		return "✅"
+	case "progressing":
+		return "⏳"
+	default:
+		return "❌"
+	}
+}
+
+// diskTypeName returns the label of a disk type
+func diskTypeName(diskType string) string {
+	switch diskType {
+	case "system":
+		return "系统盘"
+	case "data":
+		return "数据盘"
+	default:
+		return diskType
+	}
+}
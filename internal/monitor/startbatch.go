@@ -0,0 +1,83 @@
+package monitor
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	log "github.com/sirupsen/logrus"
+)
+
+// startBatch collects the instances of a region to start with one StartInstances call
+type startBatch struct {
+	ids     []string
+	results map[string]error // instance ID -> its outcome, set once done is closed
+	done    chan struct{}
+	once    sync.Once
+}
+
+// requestStart sends the start of an instance. Starts requested for the same
+// region within StartBatchWindow, e.g. after a mass reclaim, go out together
+// in one StartInstances call; each caller still gets the outcome of its own
+// instance and carries on with its own retries and wait.
+func (m *Monitor) requestStart(ctx context.Context, inst *aliyun.SpotInstance) error {
+	window := time.Duration(m.cfg.StartBatchWindow) * time.Second
+	if window <= 0 {
+		return m.ecsClient.StartInstance(ctx, inst.RegionID, inst.InstanceID)
+	}
+
+	m.startBatchesMu.Lock()
+	batch := m.startBatches[inst.RegionID]
+	if batch == nil {
+		batch = &startBatch{done: make(chan struct{})}
+		m.startBatches[inst.RegionID] = batch
+		time.AfterFunc(window, func() { m.flushStartBatch(inst.RegionID, batch) })
+	}
+	batch.ids = append(batch.ids, inst.InstanceID)
+	full := len(batch.ids) == aliyun.MaxStartBatch
+	if full {
+		delete(m.startBatches, inst.RegionID)
+	}
+	m.startBatchesMu.Unlock()
+
+	if full {
+		go m.flushStartBatch(inst.RegionID, batch)
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-batch.done:
+		return batch.results[inst.InstanceID]
+	}
+}
+
+// flushStartBatch starts the instances of a batch, once
+func (m *Monitor) flushStartBatch(regionID string, batch *startBatch) {
+	m.startBatchesMu.Lock()
+	if m.startBatches[regionID] == batch {
+		delete(m.startBatches, regionID)
+	}
+	m.startBatchesMu.Unlock()
+
+	batch.once.Do(func() {
+		defer close(batch.done)
+
+		if len(batch.ids) == 1 {
+			id := batch.ids[0]
+			batch.results = map[string]error{id: m.ecsClient.StartInstance(m.ctx, regionID, id)}
+			return
+		}
+
+		log.Infof("Starting %d instances in %s with one StartInstances call", len(batch.ids), regionID)
+		results, err := m.ecsClient.StartInstances(m.ctx, regionID, batch.ids)
+		if err != nil {
+			results = make(map[string]error, len(batch.ids))
+			for _, id := range batch.ids {
+				results[id] = err
+			}
+		}
+		batch.results = results
+	})
+}
@@ -0,0 +1,216 @@
+package monitor
+
+import (
+	"encoding/json"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Buckets in the state store
+const (
+	instancesBucket = "instances"
+	eventsBucket    = "events"
+)
+
+// maxStoredEvents is how many events are kept in the state store
+const maxStoredEvents = 5000
+
+// instanceState is the per-instance state persisted across restarts, so a
+// monitor restarting in a loop neither repeats notifications nor retries
+// failing starts early
+type instanceState struct {
+	LastNotify      time.Time          `json:"last_notify,omitempty"`
+	Muted           bool               `json:"muted,omitempty"`
+	ManuallyStopped bool               `json:"manually_stopped,omitempty"`
+	StartBackoff    *startBackoffState `json:"start_backoff,omitempty"`
+	CapacityWait    *capacityWaitState `json:"capacity_wait,omitempty"`
+	StopCause       string             `json:"stop_cause,omitempty"`
+}
+
+// startBackoffState is the persisted form of a startBackoff
+type startBackoffState struct {
+	Since    time.Time `json:"since"`
+	Failures int       `json:"failures"`
+	RetryAt  time.Time `json:"retry_at"`
+	GaveUp   bool      `json:"gave_up,omitempty"`
+}
+
+// capacityWaitState is the persisted form of a capacityWait
+type capacityWaitState struct {
+	Since    time.Time `json:"since"`
+	RetryAt  time.Time `json:"retry_at"`
+	Attempts int       `json:"attempts"`
+}
+
+// isZero reports whether there is nothing worth persisting
+func (s instanceState) isZero() bool {
+	return s.LastNotify.IsZero() && !s.Muted && !s.ManuallyStopped && s.StartBackoff == nil && s.CapacityWait == nil && s.StopCause == ""
+}
+
+// RestoreState restores the state of the previous run, or of the previous
+// leader when several replicas share a Redis state store. An empty store is
+// first filled from the OSS backup if there is one.
+func (m *Monitor) RestoreState() {
+	if err := m.restoreBackup(); err != nil {
+		log.Warnf("Failed to restore state backup: %v", err)
+	}
+	if err := m.loadState(); err != nil {
+		log.Warnf("Failed to restore state: %v", err)
+	}
+}
+
+// loadState restores per-instance state and recent events from the state store
+func (m *Monitor) loadState() error {
+	if m.store == nil {
+		return nil
+	}
+
+	instances := 0
+	err := m.store.ForEach(instancesBucket, func(instanceID string, data []byte) error {
+		var state instanceState
+		if err := json.Unmarshal(data, &state); err != nil {
+			log.Warnf("Ignoring corrupt state of instance %s: %v", instanceID, err)
+			return nil
+		}
+		instances++
+		if !state.LastNotify.IsZero() {
+			m.lastNotify[instanceID] = state.LastNotify
+		}
+		if state.Muted {
+			m.muted[instanceID] = true
+		}
+		if state.ManuallyStopped {
+			m.manualStops[instanceID] = true
+		}
+		if b := state.StartBackoff; b != nil {
+			m.startBackoffs[instanceID] = &startBackoff{since: b.Since, failures: b.Failures, retryAt: b.RetryAt, gaveUp: b.GaveUp}
+		}
+		if w := state.CapacityWait; w != nil {
+			m.capacityWaits[instanceID] = &capacityWait{since: w.Since, retryAt: w.RetryAt, attempts: w.Attempts}
+		}
+		if state.StopCause != "" {
+			m.stopCauses[instanceID] = state.StopCause
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = m.store.Tail(eventsBucket, maxRecentEvents, func(data []byte) error {
+		var event Event
+		if err := json.Unmarshal(data, &event); err != nil {
+			log.Warnf("Ignoring corrupt event: %v", err)
+			return nil
+		}
+		m.events = append(m.events, event)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := m.loadStatusLog(); err != nil {
+		return err
+	}
+	if err := m.loadReclaimLog(); err != nil {
+		return err
+	}
+	if err := m.loadBudgetAlerts(); err != nil {
+		return err
+	}
+	if err := m.loadTrafficAlerts(); err != nil {
+		return err
+	}
+	if err := m.loadTrafficSnapshot(); err != nil {
+		return err
+	}
+	if err := m.loadLaunchConfigs(); err != nil {
+		return err
+	}
+	if err := m.loadMaintenanceMode(); err != nil {
+		return err
+	}
+	if err := m.loadTrackedInstances(); err != nil {
+		return err
+	}
+
+	log.Infof("Restored state of %d instance(s) and %d event(s)", instances, len(m.events))
+	return nil
+}
+
+// saveInstanceState persists the current state of an instance
+func (m *Monitor) saveInstanceState(instanceID string) {
+	if m.store == nil {
+		return
+	}
+
+	m.lastNotifyMu.RLock()
+	state := instanceState{LastNotify: m.lastNotify[instanceID]}
+	m.lastNotifyMu.RUnlock()
+	state.Muted = m.IsMuted(instanceID)
+	state.ManuallyStopped = m.isManuallyStopped(instanceID)
+	if b, failing := m.startBackoffOf(instanceID); failing {
+		state.StartBackoff = &startBackoffState{Since: b.since, Failures: b.failures, RetryAt: b.retryAt, GaveUp: b.gaveUp}
+	}
+	m.capacityWaitsMu.Lock()
+	if w, waiting := m.capacityWaits[instanceID]; waiting {
+		state.CapacityWait = &capacityWaitState{Since: w.since, RetryAt: w.retryAt, Attempts: w.attempts}
+	}
+	m.capacityWaitsMu.Unlock()
+	m.stopCausesMu.Lock()
+	state.StopCause = m.stopCauses[instanceID]
+	m.stopCausesMu.Unlock()
+
+	var err error
+	if state.isZero() {
+		err = m.store.Delete(instancesBucket, instanceID)
+	} else {
+		err = m.store.Put(instancesBucket, instanceID, state)
+	}
+	if err != nil {
+		log.Warnf("Failed to save state of instance %s: %v", instanceID, err)
+	}
+}
+
+// saveEvent appends an event to the state store
+func (m *Monitor) saveEvent(event Event) {
+	if m.store == nil {
+		return
+	}
+	if err := m.store.Append(eventsBucket, event, maxStoredEvents); err != nil {
+		log.Warnf("Failed to save event: %v", err)
+	}
+}
+
+// storedEvents returns all events in the state store, oldest first
+func (m *Monitor) storedEvents() ([]Event, error) {
+	var events []Event
+	err := m.store.Tail(eventsBucket, 0, func(data []byte) error {
+		var event Event
+		if err := json.Unmarshal(data, &event); err != nil {
+			return nil // skip corrupt entries, loadState already warned about them
+		}
+		events = append(events, event)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if events == nil {
+		events = []Event{}
+	}
+	return events, nil
+}
+
+// Close waits for status page updates in flight, e.g. of a one-shot check,
+// then cancels remaining work and releases the state store
+func (m *Monitor) Close() error {
+	m.statusPageUpdates.Wait()
+	m.cancel()
+	if m.store == nil {
+		return nil
+	}
+	return m.store.Close()
+}
@@ -0,0 +1,113 @@
+package monitor
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Transitions STATE_CHANGE_NOTIFY can enable
+const (
+	transitionStopped   = "stopped"    // any status to Stopped
+	transitionStarted   = "started"    // Stopped to Running, started outside the monitor
+	transitionIPChanged = "ip_changed" // new public address, also after a start
+)
+
+// stateTransitions are the transitions of STATE_CHANGE_NOTIFY
+var stateTransitions = []string{transitionStopped, transitionStarted, transitionIPChanged}
+
+// parseStateTransitions validates STATE_CHANGE_NOTIFY, "all" enabling every transition
+func parseStateTransitions(list string) (map[string]bool, error) {
+	enabled := make(map[string]bool)
+	for _, name := range splitList(list) {
+		if name == "all" {
+			for _, transition := range stateTransitions {
+				enabled[transition] = true
+			}
+			continue
+		}
+		known := false
+		for _, transition := range stateTransitions {
+			known = known || transition == name
+		}
+		if !known {
+			names := append([]string{"all"}, stateTransitions...)
+			return nil, fmt.Errorf("unknown transition %q in STATE_CHANGE_NOTIFY, must be one of %s", name, strings.Join(names, ", "))
+		}
+		enabled[name] = true
+	}
+	return enabled, nil
+}
+
+// noteSelfStart remembers a start made by the monitor, so the instance
+// coming up isn't reported as started from outside
+func (m *Monitor) noteSelfStart(msg busMessage) {
+	m.selfStartsMu.Lock()
+	defer m.selfStartsMu.Unlock()
+	if msg.Topic == topicStartRequested {
+		m.selfStarts[msg.Instance.InstanceID] = true
+	} else {
+		delete(m.selfStarts, msg.Instance.InstanceID)
+	}
+}
+
+// takeSelfStart reports whether the monitor started an instance, forgetting the start
+func (m *Monitor) takeSelfStart(instanceID string) bool {
+	m.selfStartsMu.Lock()
+	defer m.selfStartsMu.Unlock()
+	started := m.selfStarts[instanceID]
+	delete(m.selfStarts, instanceID)
+	return started
+}
+
+// notifyStateChange sends the audit notification of an enabled transition.
+// It comes on top of the regular notifications and skips the notification
+// cooldown, so the chat keeps a full trail of what happened to the instances.
+func (m *Monitor) notifyStateChange(msg busMessage) {
+	inst := msg.Instance
+	var transition, change string
+	switch msg.Topic {
+	case topicStatusChanged:
+		if msg.PrevStatus == "" || msg.PrevStatus == msg.Status {
+			return
+		}
+		switch {
+		case msg.Status == "Stopped":
+			transition = transitionStopped
+		case msg.Status == "Running":
+			if m.takeSelfStart(inst.InstanceID) {
+				return
+			}
+			transition = transitionStarted
+		default:
+			return
+		}
+		change = fmt.Sprintf("%s → %s", msg.PrevStatus, msg.Status)
+	case topicAddressChanged:
+		// Unexpected changes are always notified on their own
+		if msg.Unexpected {
+			return
+		}
+		transition = transitionIPChanged
+		change = fmt.Sprintf("公网IP %s → %s", displayAddress(msg.PrevAddress), displayAddress(inst.PublicAddresses()))
+	}
+	if !m.stateChangeNotify[transition] {
+		return
+	}
+
+	notifier := m.notifierFor(inst.InstanceID)
+	if notifier == nil {
+		return
+	}
+	if err := notifier.NotifyStateChange(inst.InstanceID, inst.InstanceName, inst.RegionID, transitionTitles[transition], change); err != nil {
+		log.Warnf("Failed to send %s state change notification for %s: %v", transition, inst.InstanceID, err)
+	}
+}
+
+// transitionTitles are the notification titles of the transitions
+var transitionTitles = map[string]string{
+	transitionStopped:   "🔴 实例已停止",
+	transitionStarted:   "🟢 实例已在外部启动",
+	transitionIPChanged: "🔀 公网IP已变化",
+}
@@ -0,0 +1,224 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultStatsDays is the window /stats covers without an argument
+const defaultStatsDays = 30
+
+// maxStatsGroups caps the rows shown per grouping
+const maxStatsGroups = 10
+
+// reclaimGroup aggregates the reclaims of one region, zone or instance type
+type reclaimGroup struct {
+	key       string
+	reclaims  int
+	survival  time.Duration // sum of measured survival times
+	survivals int           // reclaims with a known preceding start
+}
+
+// averageSurvival returns how long instances ran on average before being reclaimed
+func (g *reclaimGroup) averageSurvival() time.Duration {
+	if g.survivals == 0 {
+		return 0
+	}
+	return g.survival / time.Duration(g.survivals)
+}
+
+// reclaimStats summarizes reclaims within a window
+type reclaimStats struct {
+	since    time.Time
+	until    time.Time
+	observed time.Duration // part of the window covered by the event history
+	total    reclaimGroup
+	byRegion []*reclaimGroup
+	byZone   []*reclaimGroup
+	byType   []*reclaimGroup
+}
+
+// perWeek returns the reclaim rate of a group over the observed time
+func (s *reclaimStats) perWeek(g *reclaimGroup) float64 {
+	weeks := s.observed.Hours() / (7 * 24)
+	if weeks < 1 {
+		weeks = 1
+	}
+	return float64(g.reclaims) / weeks
+}
+
+// computeReclaimStats aggregates the reclaims in [since, until) from events
+// in chronological order. Survival is measured from the last successful start
+// of the same instance, which may precede the window.
+func computeReclaimStats(events []Event, since, until time.Time) *reclaimStats {
+	stats := &reclaimStats{since: since, until: until}
+	regions := make(map[string]*reclaimGroup)
+	zones := make(map[string]*reclaimGroup)
+	types := make(map[string]*reclaimGroup)
+	lastStart := make(map[string]time.Time)
+
+	first := until
+	for _, event := range events {
+		if !event.Time.Before(until) {
+			break
+		}
+		if !event.Time.Before(since) && event.Time.Before(first) {
+			first = event.Time
+		}
+
+		switch event.Type {
+		case EventStarted:
+			lastStart[event.InstanceID] = event.Time
+		case EventReclaimed:
+			started, known := lastStart[event.InstanceID]
+			delete(lastStart, event.InstanceID)
+			if event.Time.Before(since) {
+				continue
+			}
+
+			groups := []*reclaimGroup{
+				&stats.total,
+				groupFor(regions, event.RegionID),
+				groupFor(zones, event.ZoneID),
+				groupFor(types, event.InstanceType),
+			}
+			for _, g := range groups {
+				g.reclaims++
+				if known {
+					g.survival += event.Time.Sub(started)
+					g.survivals++
+				}
+			}
+		}
+	}
+
+	stats.observed = until.Sub(first)
+	stats.byRegion = sortedGroups(regions)
+	stats.byZone = sortedGroups(zones)
+	stats.byType = sortedGroups(types)
+	return stats
+}
+
+// groupFor returns the group for key, creating it on first use
+func groupFor(groups map[string]*reclaimGroup, key string) *reclaimGroup {
+	if key == "" {
+		key = "未知"
+	}
+	g, ok := groups[key]
+	if !ok {
+		g = &reclaimGroup{key: key}
+		groups[key] = g
+	}
+	return g
+}
+
+// sortedGroups returns groups with the most reclaims first
+func sortedGroups(groups map[string]*reclaimGroup) []*reclaimGroup {
+	sorted := make([]*reclaimGroup, 0, len(groups))
+	for _, g := range groups {
+		sorted = append(sorted, g)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].reclaims != sorted[j].reclaims {
+			return sorted[i].reclaims > sorted[j].reclaims
+		}
+		return sorted[i].key < sorted[j].key
+	})
+	return sorted
+}
+
+// chronologicalEvents returns the whole event history, oldest first
+func (m *Monitor) chronologicalEvents() []Event {
+	events := m.EventHistory("", 0)
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+	return events
+}
+
+// formatReclaimStats formats reclaim statistics for Telegram
+func formatReclaimStats(title string, stats *reclaimStats) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📉 <b>%s</b>\n", title))
+	sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+
+	if stats.total.reclaims == 0 {
+		sb.WriteString("该时段内没有实例被回收 🎉")
+		return sb.String()
+	}
+
+	sb.WriteString(fmt.Sprintf("总回收: %d 次 (%.1f 次/周)\n", stats.total.reclaims, stats.perWeek(&stats.total)))
+	if survival := stats.total.averageSurvival(); survival > 0 {
+		sb.WriteString(fmt.Sprintf("平均存活: %s\n", humanDuration(survival)))
+	}
+
+	writeGroups := func(name string, groups []*reclaimGroup) {
+		sb.WriteString(fmt.Sprintf("\n<b>按%s</b>\n", name))
+		for i, g := range groups {
+			if i == maxStatsGroups {
+				sb.WriteString(fmt.Sprintf("   ... 另有 %d 项\n", len(groups)-maxStatsGroups))
+				break
+			}
+			line := fmt.Sprintf("   %s: %d 次 (%.1f 次/周)", g.key, g.reclaims, stats.perWeek(g))
+			if survival := g.averageSurvival(); survival > 0 {
+				line += fmt.Sprintf("，平均存活 %s", humanDuration(survival))
+			}
+			sb.WriteString(line + "\n")
+		}
+	}
+	writeGroups("区域", stats.byRegion)
+	writeGroups("可用区", stats.byZone)
+	writeGroups("实例规格", stats.byType)
+
+	sb.WriteString("\n<i>平均存活为自动启动成功到下次回收的时长，回收次数少的可用区更稳定</i>")
+	return sb.String()
+}
+
+// handleStatsCommand handles /stats [days|all]
+func (m *Monitor) handleStatsCommand(args []string) error {
+	if m.notifier == nil {
+		return fmt.Errorf("telegram notifier not initialized")
+	}
+
+	now := time.Now()
+	since := now.AddDate(0, 0, -defaultStatsDays)
+	title := fmt.Sprintf("回收统计 (最近 %d 天)", defaultStatsDays)
+	if len(args) > 0 {
+		if args[0] == "all" {
+			since = time.Time{}
+			title = "回收统计 (全部记录)"
+		} else if days, err := strconv.Atoi(args[0]); err == nil && days > 0 {
+			since = now.AddDate(0, 0, -days)
+			title = fmt.Sprintf("回收统计 (最近 %d 天)", days)
+		} else {
+			return m.notifier.Send("⚠️ 用法: /stats [天数|all]\n例如: /stats 7")
+		}
+	}
+
+	stats := computeReclaimStats(m.chronologicalEvents(), since, now)
+	return m.notifier.Send(formatReclaimStats(title, stats))
+}
+
+// SendMonthlyReclaimStats sends the reclaim statistics of the previous month
+func (m *Monitor) SendMonthlyReclaimStats() error {
+	if m.notifier == nil {
+		return fmt.Errorf("telegram notifier not initialized")
+	}
+
+	now := time.Now()
+	until := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	since := until.AddDate(0, -1, 0)
+
+	stats := computeReclaimStats(m.chronologicalEvents(), since, until)
+	if err := m.notifier.Send(formatReclaimStats(fmt.Sprintf("上月回收统计 (%s)", since.Format("2006-01")), stats)); err != nil {
+		return fmt.Errorf("failed to send reclaim statistics: %w", err)
+	}
+
+	log.Infof("Monthly reclaim statistics sent (%d reclaims)", stats.total.reclaims)
+	return nil
+}
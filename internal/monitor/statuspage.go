@@ -0,0 +1,261 @@
+package monitor
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// statusPageCostTTL is how long the spend and traffic on the status page are reused
+const statusPageCostTTL = time.Hour
+
+// statusPageEvents is how many recent events the status page lists
+const statusPageEvents = 20
+
+// ipAddressPattern matches IPv4 and full or compressed IPv6 addresses
+var ipAddressPattern = regexp.MustCompile(`\b\d{1,3}(\.\d{1,3}){3}\b|\b[0-9a-fA-F]{0,4}(:[0-9a-fA-F]{0,4}){3,7}\b`)
+
+//go:embed statuspage.html
+var statusPageHTML string
+
+// statusPageTemplate renders the HTML status page
+var statusPageTemplate = template.Must(template.New("statuspage").Funcs(template.FuncMap{
+	"eventName": eventDisplayName,
+	"formatTime": func(t time.Time) string {
+		return t.Local().Format("2006-01-02 15:04")
+	},
+	"percent": func(used, quota float64) string {
+		return fmt.Sprintf("%.0f%%", used/quota*100)
+	},
+}).Parse(statusPageHTML))
+
+// statusPage is what the status page shows, also written as status.json
+type statusPage struct {
+	GeneratedAt time.Time            `json:"generated_at"`
+	Instances   []statusPageInstance `json:"instances"`
+	Events      []statusPageEvent    `json:"events"`
+	Billing     *statusPageBilling   `json:"billing,omitempty"`
+	Traffic     *statusPageTraffic   `json:"traffic,omitempty"`
+	ShowIP      bool                 `json:"-"`
+}
+
+// statusPageInstance is an instance on the status page
+type statusPageInstance struct {
+	Name         string `json:"name"`
+	Region       string `json:"region"`
+	Zone         string `json:"zone"`
+	InstanceType string `json:"instance_type"`
+	Status       string `json:"status"`
+	PublicIP     string `json:"public_ip,omitempty"`
+}
+
+// statusPageEvent is a recent event on the status page
+type statusPageEvent struct {
+	Time     time.Time `json:"time"`
+	Type     string    `json:"type"`
+	Instance string    `json:"instance"`
+	Message  string    `json:"message"`
+}
+
+// statusPageBilling is the month-to-date spend on the status page
+type statusPageBilling struct {
+	BillingCycle    string  `json:"billing_cycle"`
+	TotalAmount     float64 `json:"total_amount"`
+	MonthlyEstimate float64 `json:"monthly_estimate"`
+	SavedAmount     float64 `json:"saved_amount"`
+}
+
+// statusPageTraffic is the month-to-date CDT traffic on the status page,
+// shown per region group like /traffic when TRAFFIC_GROUPS is set
+type statusPageTraffic struct {
+	BillingCycle            string                   `json:"billing_cycle"`
+	ChinaMainlandGB         float64                  `json:"china_mainland_gb"`
+	ChinaMainlandQuotaGB    float64                  `json:"china_mainland_quota_gb"`
+	NonChinaMainlandGB      float64                  `json:"non_china_mainland_gb"`
+	NonChinaMainlandQuotaGB float64                  `json:"non_china_mainland_quota_gb"`
+	Groups                  []statusPageTrafficGroup `json:"groups,omitempty"`
+}
+
+// statusPageTrafficGroup is the traffic of a user-defined region group
+type statusPageTrafficGroup struct {
+	Name      string  `json:"name"`
+	TrafficGB float64 `json:"traffic_gb"`
+}
+
+// statusPageCosts caches the spend and traffic between status page updates
+type statusPageCosts struct {
+	billing   *statusPageBilling
+	traffic   *statusPageTraffic
+	fetchedAt time.Time
+}
+
+// statusPageEnabled reports whether a status page is written or uploaded
+func (m *Monitor) statusPageEnabled() bool {
+	return m.cfg.StatusPagePath != "" || m.statusPageOSS != nil
+}
+
+// publishStatusPage renders the status page and writes or uploads it. An
+// update still running when the next check completes is not waited for,
+// that check's update is skipped.
+func (m *Monitor) publishStatusPage() {
+	if !m.statusPageMu.TryLock() {
+		log.Debug("Status page update still running, skipping")
+		return
+	}
+	defer m.statusPageMu.Unlock()
+
+	page := m.buildStatusPage()
+	var html bytes.Buffer
+	if err := statusPageTemplate.Execute(&html, page); err != nil {
+		log.Errorf("Failed to render status page: %v", err)
+		return
+	}
+	data, err := json.MarshalIndent(page, "", "  ")
+	if err != nil {
+		log.Errorf("Failed to encode status page: %v", err)
+		return
+	}
+	files := []struct {
+		name, contentType string
+		data              []byte
+	}{
+		{"index.html", "text/html; charset=utf-8", html.Bytes()},
+		{"status.json", "application/json", data},
+	}
+
+	if dir := m.cfg.StatusPagePath; dir != "" {
+		for _, file := range files {
+			if err := writeFileAtomic(filepath.Join(dir, file.name), file.data); err != nil {
+				log.Warnf("Failed to write status page: %v", err)
+				break
+			}
+		}
+	}
+	if m.statusPageOSS != nil {
+		for _, file := range files {
+			if err := m.statusPageOSS.PutObject(m.ctx, m.cfg.StatusPageOSSPrefix+file.name, file.contentType, file.data); err != nil {
+				log.Warnf("Failed to upload status page: %v", err)
+				break
+			}
+		}
+	}
+	log.Debug("Status page updated")
+}
+
+// buildStatusPage collects the instances, recent events and costs.
+// The caller must hold m.statusPageMu.
+func (m *Monitor) buildStatusPage() *statusPage {
+	page := &statusPage{
+		GeneratedAt: time.Now(),
+		Instances:   []statusPageInstance{},
+		Events:      []statusPageEvent{},
+		ShowIP:      m.cfg.StatusPageShowIP,
+	}
+	for _, inst := range m.Instances(false) {
+		status := m.lastStatus(inst.InstanceID)
+		if status == "" {
+			status = inst.Status
+		}
+		entry := statusPageInstance{
+			Name:         inst.InstanceName,
+			Region:       inst.RegionID,
+			Zone:         inst.ZoneID,
+			InstanceType: inst.InstanceType,
+			Status:       status,
+		}
+		if page.ShowIP {
+			entry.PublicIP = inst.PublicAddresses()
+		}
+		page.Instances = append(page.Instances, entry)
+	}
+
+	for _, event := range m.EventHistory("", statusPageEvents) {
+		message := event.Message
+		if !page.ShowIP {
+			message = ipAddressPattern.ReplaceAllString(message, "***")
+		}
+		page.Events = append(page.Events, statusPageEvent{
+			Time:     event.Time,
+			Type:     event.Type,
+			Instance: event.InstanceName,
+			Message:  message,
+		})
+	}
+
+	if time.Since(m.statusPageCosts.fetchedAt) >= statusPageCostTTL {
+		m.statusPageCosts = m.queryStatusPageCosts()
+	}
+	page.Billing, page.Traffic = m.statusPageCosts.billing, m.statusPageCosts.traffic
+	return page
+}
+
+// queryStatusPageCosts queries the month-to-date spend and traffic, leaving
+// out what can't be queried
+func (m *Monitor) queryStatusPageCosts() statusPageCosts {
+	costs := statusPageCosts{fetchedAt: time.Now()}
+	if m.billingClient != nil {
+		if summary, err := m.queryBilling(nil); err != nil {
+			log.Warnf("Failed to query billing for the status page: %v", err)
+		} else {
+			costs.billing = &statusPageBilling{
+				BillingCycle:    summary.BillingCycle,
+				TotalAmount:     summary.TotalAmount,
+				MonthlyEstimate: summary.MonthlyEstimate,
+				SavedAmount:     summary.SavedAmount,
+			}
+		}
+	}
+	if m.trafficClient != nil {
+		if summary, err := m.queryTraffic(nil); err != nil {
+			log.Warnf("Failed to query traffic for the status page: %v", err)
+		} else {
+			costs.traffic = &statusPageTraffic{
+				BillingCycle:            summary.BillingCycle,
+				ChinaMainlandGB:         summary.ChinaMainland.TrafficGB,
+				ChinaMainlandQuotaGB:    summary.ChinaMainland.QuotaGB,
+				NonChinaMainlandGB:      summary.NonChinaMainland.TrafficGB,
+				NonChinaMainlandQuotaGB: summary.NonChinaMainland.QuotaGB,
+			}
+			for _, group := range summary.Groups {
+				costs.traffic.Groups = append(costs.traffic.Groups, statusPageTrafficGroup{Name: group.Name, TrafficGB: group.TrafficGB})
+			}
+		}
+	}
+	return costs
+}
+
+// writeFileAtomic replaces a file by renaming a complete temporary file over
+// it, so a web server never serves a half-written page
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
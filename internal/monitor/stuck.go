@@ -0,0 +1,134 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	"github.com/iliyian/aliyun-spot-manager/internal/tracing"
+	log "github.com/sirupsen/logrus"
+)
+
+// transitionState tracks how long an instance has been in a transitional state
+type transitionState struct {
+	status   string
+	since    time.Time
+	notified bool
+}
+
+// isTransitional checks if a status is a transitional (Starting/Stopping) state
+func isTransitional(status string) bool {
+	return status == "Starting" || status == "Stopping"
+}
+
+// checkStuckState records transitional states across check cycles and, when an
+// instance has been stuck for too long, alerts and optionally force-stops it.
+// Returns the status the rest of the check should act on.
+func (m *Monitor) checkStuckState(inst *aliyun.SpotInstance, status string) string {
+	m.transitionsMu.Lock()
+	state, tracked := m.transitions[inst.InstanceID]
+	if !isTransitional(status) {
+		delete(m.transitions, inst.InstanceID)
+		m.transitionsMu.Unlock()
+		return status
+	}
+	if !tracked || state.status != status {
+		m.transitions[inst.InstanceID] = &transitionState{status: status, since: time.Now()}
+		m.transitionsMu.Unlock()
+		return status
+	}
+
+	stuckFor := time.Since(state.since)
+	threshold := time.Duration(m.cfg.StuckStateTimeout) * time.Second
+	if m.cfg.StuckStateTimeout <= 0 || stuckFor < threshold || state.notified {
+		m.transitionsMu.Unlock()
+		return status
+	}
+	state.notified = true
+	m.transitionsMu.Unlock()
+
+	log.Warnf("Instance %s (%s) has been %s for %s", inst.InstanceName, inst.InstanceID, status, stuckFor.Round(time.Second))
+
+	m.recordEvent(inst, EventStuck, fmt.Sprintf("%s 持续 %.0f 分钟", status, stuckFor.Minutes()))
+
+	if notifier := m.notifierFor(inst.InstanceID); notifier != nil {
+		if err := notifier.NotifyInstanceStuck(inst.InstanceID, inst.InstanceName, inst.RegionID, status, stuckFor, m.cfg.StuckStateRemediate); err != nil {
+			log.Warnf("Failed to send stuck notification: %v", err)
+		}
+	}
+
+	if !m.cfg.StuckStateRemediate {
+		return status
+	}
+	if m.inMaintenance() {
+		log.Warnf("Maintenance mode is on, not force stopping stuck instance %s", inst.InstanceID)
+		return status
+	}
+	if !m.beginStart() {
+		return status
+	}
+
+	// The wait for the stop can take minutes, the check carries on with the
+	// other instances while this one stays claimed
+	m.handOffInstance(inst.InstanceID)
+	go func() {
+		defer m.starts.Done()
+		defer m.releaseInstance(inst.InstanceID)
+		m.remediateStuck(inst, status)
+	}()
+	return status
+}
+
+// remediateStuck force stops a stuck instance. One stuck Starting is started
+// again by the next check; one stuck Stopping was on its way down anyway, so
+// whether it is started again depends on who stopped it, as for any stop.
+func (m *Monitor) remediateStuck(inst *aliyun.SpotInstance, status string) {
+	ctx, span := tracing.Start(m.ctx, "monitor.remediateStuck", tracing.Instance(inst.RegionID, inst.InstanceID)...)
+	var err error
+	defer func() { tracing.End(span, err) }()
+
+	log.Warnf("Force stopping stuck instance %s", inst.InstanceID)
+	if err = m.ecsClient.StopInstance(ctx, inst.RegionID, inst.InstanceID, true); err != nil {
+		log.Errorf("Failed to force stop instance %s: %v", inst.InstanceID, err)
+		m.stuckRemediationFailed(inst, err)
+		return
+	}
+	if err = m.waitForStatus(ctx, inst.RegionID, inst.InstanceID, "Stopped", 5*time.Minute, m.pollInterval()); err != nil {
+		log.Errorf("Instance %s did not stop after force stop: %v", inst.InstanceID, err)
+		m.stuckRemediationFailed(inst, err)
+		return
+	}
+
+	m.transitionsMu.Lock()
+	delete(m.transitions, inst.InstanceID)
+	m.transitionsMu.Unlock()
+
+	if status == "Starting" {
+		m.setStopCause(inst.InstanceID, stopByMonitor)
+		log.Infof("Stuck instance %s stopped, it is started by the next check", inst.InstanceID)
+		return
+	}
+	log.Infof("Stuck instance %s stopped", inst.InstanceID)
+}
+
+// stuckRemediationFailed reports a stuck instance the force stop didn't unwedge,
+// it is left alone until it changes state
+func (m *Monitor) stuckRemediationFailed(inst *aliyun.SpotInstance, err error) {
+	m.recordEvent(inst, EventStuck, fmt.Sprintf("强制停止失败: %v", err))
+	if notifier := m.notifierFor(inst.InstanceID); notifier != nil {
+		if err := notifier.NotifyStuckRemediationFailed(inst.InstanceID, inst.InstanceName, inst.RegionID, err); err != nil {
+			log.Warnf("Failed to send stuck notification: %v", err)
+		}
+	}
+}
+
+// stuckFor returns how long an instance has been in its current transitional
+// state across checks, 0 when it isn't in one
+func (m *Monitor) stuckFor(instanceID string) time.Duration {
+	m.transitionsMu.Lock()
+	defer m.transitionsMu.Unlock()
+	if state, ok := m.transitions[instanceID]; ok {
+		return time.Since(state.since)
+	}
+	return 0
+}
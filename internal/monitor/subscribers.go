@@ -0,0 +1,244 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	log "github.com/sirupsen/logrus"
+)
+
+// subscribe wires the bus subscribers. Order matters within a topic: the
+// event is persisted and notified before hooks run, so the "starting"
+// notification goes out before a long health check or playbook.
+func (m *Monitor) subscribe() {
+	for _, topic := range []string{
+		topicReclaimDetected, topicStartRequested, topicStartFailed, topicStartGaveUp,
+		topicAddressChanged, topicHealthPassed, topicHealthFailed,
+		topicInterruptionDue, topicNoCapacity, topicRecreated, topicPriceLimitNear, topicRebid,
+		topicNoCredits, topicInstanceReleased,
+	} {
+		m.bus.subscribe(topic, m.persistMessage)
+	}
+
+	for _, topic := range []string{
+		topicReclaimDetected, topicStartSucceeded, topicStartFailed, topicStartGaveUp, topicAddressChanged,
+		topicHealthPassed, topicHealthFailed, topicInterruptionDue, topicNoCapacity,
+		topicRecreated, topicPriceLimitNear, topicRebid, topicNoCredits, topicInstanceReleased,
+	} {
+		m.bus.subscribe(topic, m.notifyMessage)
+	}
+
+	for _, topic := range []string{topicStatusChanged, topicAddressChanged} {
+		m.bus.subscribe(topic, m.notifyStateChange)
+	}
+	m.bus.subscribe(topicStartRequested, m.noteSelfStart)
+	m.bus.subscribe(topicStartFailed, m.noteSelfStart)
+
+	for topic := range hookEvents {
+		m.bus.subscribe(topic, m.queueEventHook)
+	}
+
+	m.bus.subscribe(topicStartSucceeded, m.updateDNS)
+	m.bus.subscribe(topicAddressChanged, m.updateDNS)
+	m.bus.subscribe(topicStartSucceeded, m.auditSecurityGroups)
+	m.bus.subscribe(topicStartSucceeded, m.runStartHooks)
+	m.bus.subscribe(topicInterruptionDue, m.runPreShutdown)
+	m.bus.subscribe(topicStatusChanged, logStatusChange)
+	m.bus.subscribe(topicStatusChanged, m.recordStatusChange)
+	m.bus.subscribe(topicReclaimDetected, m.recordReclaim)
+	m.bus.subscribe(topicAll, m.countMessage)
+}
+
+// persistMessage records a message in the event history
+func (m *Monitor) persistMessage(msg busMessage) {
+	inst := msg.Instance
+	switch msg.Topic {
+	case topicReclaimDetected:
+		m.recordEvent(inst, EventReclaimed, "实例被回收")
+	case topicStartRequested:
+		m.recordEvent(inst, EventStartAttempt, fmt.Sprintf("第 %d/%d 次尝试启动", msg.Attempt, msg.Attempts))
+	case topicStartFailed:
+		m.recordEvent(inst, EventStartFailed, fmt.Sprintf("重试 %d 次后启动失败: %v", msg.Attempts, msg.Err))
+	case topicStartGaveUp:
+		m.recordEvent(inst, EventStartGaveUp, fmt.Sprintf("连续 %d 次检查启动失败，暂停至 %s", msg.Attempts, msg.Until.Local().Format("01-02 15:04")))
+	case topicAddressChanged:
+		m.recordEvent(inst, EventIPChanged, fmt.Sprintf("公网IP %s → %s", displayAddress(msg.PrevAddress), displayAddress(inst.PublicAddresses())))
+	case topicHealthPassed:
+		message := fmt.Sprintf("启动成功，耗时 %.0f 秒", msg.Duration.Seconds())
+		if msg.Playbook {
+			message = fmt.Sprintf("%s (%s)", message, msg.Check)
+		}
+		if msg.EIP != "" {
+			message = fmt.Sprintf("%s，EIP %s", message, msg.EIP)
+		}
+		if msg.Bootstrap != nil {
+			message = fmt.Sprintf("%s，启动脚本 %s", message, msg.Bootstrap.status)
+		}
+		m.recordEvent(inst, EventStarted, message)
+	case topicHealthFailed:
+		if msg.Playbook {
+			m.recordEvent(inst, EventUnhealthy, fmt.Sprintf("恢复剧本失败: %v", msg.Err))
+		} else {
+			m.recordEvent(inst, EventUnhealthy, fmt.Sprintf("已启动但健康检查 %s 未通过", msg.Check))
+		}
+	case topicInterruptionDue:
+		m.recordEvent(inst, EventInterruption, fmt.Sprintf("%s，计划于 %s",
+			aliyun.GetEventTypeDisplayName(msg.Event.EventType), msg.Event.NotBefore.Local().Format("15:04:05")))
+	case topicRecreated:
+		m.recordEvent(inst, EventRecreated, fmt.Sprintf("库存不足，已在 %s 重建为 %s", msg.Replacement.ZoneID, msg.Replacement.InstanceID))
+	case topicPriceLimitNear:
+		m.recordEvent(inst, EventPriceLimit, fmt.Sprintf("市场价 ¥%.4f/时，出价上限 ¥%.4f/时", msg.Price, inst.SpotPriceLimit))
+	case topicNoCredits:
+		m.recordEvent(inst, EventCPUCredits, fmt.Sprintf("CPU 积分耗尽（剩余 %.1f），性能受限于基准", msg.Credits.Balance))
+	case topicRebid:
+		m.recordEvent(inst, EventRebid, fmt.Sprintf("出价上限 ¥%.4f/时，已重建为 %s", msg.Replacement.SpotPriceLimit, msg.Replacement.InstanceID))
+	case topicInstanceReleased:
+		m.recordEvent(inst, EventReleased, "实例已释放或删除，不再监控")
+	case topicNoCapacity:
+		m.recordEvent(inst, EventNoCapacity, fmt.Sprintf("可用区库存不足，每 %s 重试一次: %v", humanDuration(m.capacityRetryInterval()), msg.Err))
+	}
+}
+
+// notifyMessage sends the Telegram notification for a message
+func (m *Monitor) notifyMessage(msg busMessage) {
+	inst := msg.Instance
+	notifier := m.notifierFor(inst.InstanceID)
+	if notifier == nil {
+		return
+	}
+
+	var err error
+	switch msg.Topic {
+	case topicReclaimDetected:
+		if !m.canNotify(inst.InstanceID) {
+			log.Debugf("Notification cooldown active for instance %s", inst.InstanceID)
+			return
+		}
+		err = notifier.NotifyInstanceReclaimed(inst.InstanceID, inst.InstanceName, inst.RegionID)
+		m.updateNotifyTime(inst.InstanceID)
+	case topicStartSucceeded:
+		// Only worth a message when a health check or playbook keeps us waiting
+		if steps := m.playbookFor(inst); len(steps) > 0 {
+			err = notifier.NotifyPlaybookStarted(inst.InstanceID, inst.InstanceName, inst.RegionID, len(steps))
+		} else if m.cfg.HealthCheckEnabled && inst.HealthCheckAddress() != "" {
+			err = notifier.NotifyInstanceStarting(inst.InstanceID, inst.InstanceName, inst.RegionID)
+		}
+	case topicAddressChanged:
+		// A start reports the new address itself
+		if msg.Unexpected {
+			err = notifier.NotifyAddressChanged(inst.InstanceID, inst.InstanceName, inst.RegionID, displayAddress(msg.PrevAddress), displayAddress(inst.PublicAddresses()))
+		}
+	case topicStartFailed:
+		// Only the first failure is worth a message, later ones end in a summary when giving up
+		if backoff, failing := m.startBackoffOf(inst.InstanceID); failing {
+			log.Debugf("Instance %s already failed to start in %d checks, not notifying", inst.InstanceID, backoff.failures)
+			break
+		}
+		// Say how long it waited so a slow-booting type can be given more time
+		var waitTimeout, pollInterval time.Duration
+		var timeoutErr *statusTimeoutError
+		if errors.As(msg.Err, &timeoutErr) {
+			waitTimeout, pollInterval = timeoutErr.timeout, timeoutErr.interval
+		}
+		err = notifier.NotifyInstanceStartFailed(inst.InstanceID, inst.InstanceName, inst.RegionID, msg.Attempts, msg.Err, waitTimeout, pollInterval)
+	case topicHealthPassed:
+		var bootstrap, bootstrapOutput string
+		if msg.Bootstrap != nil {
+			bootstrap, bootstrapOutput = msg.Bootstrap.status, msg.Bootstrap.output
+		}
+		err = notifier.NotifyInstanceStarted(inst.InstanceID, inst.InstanceName, inst.RegionID, inst.PublicAddresses(),
+			msg.Duration, msg.Check, msg.EIP, bootstrap, bootstrapOutput)
+	case topicHealthFailed:
+		if msg.Playbook {
+			err = notifier.NotifyPlaybookFailed(inst.InstanceID, inst.InstanceName, inst.RegionID, msg.Err)
+		} else {
+			err = notifier.NotifyHealthCheckTimeout(inst.InstanceID, inst.InstanceName, inst.RegionID, inst.PublicAddresses(), msg.Check, int(msg.Timeout.Seconds()))
+		}
+	case topicInterruptionDue:
+		err = notifier.NotifyInterruptionWarning(msg.Event, inst.InstanceName, len(m.preShutdownFor(inst)))
+	case topicRecreated:
+		err = notifier.NotifyInstanceRecreated(inst.InstanceID, inst.InstanceName, inst.RegionID, inst.ZoneID,
+			msg.Replacement.InstanceID, msg.Replacement.ZoneID, msg.Replacement.PublicAddresses(), msg.Address != "")
+	case topicPriceLimitNear:
+		err = notifier.NotifyPriceLimitNear(inst.InstanceID, inst.InstanceName, inst.RegionID, inst.ZoneID, msg.Price, inst.SpotPriceLimit)
+	case topicNoCredits:
+		err = notifier.NotifyCPUCreditsExhausted(inst.InstanceID, inst.InstanceName, inst.RegionID, inst.InstanceType, msg.Credits.Balance, msg.Credits.Usage)
+	case topicRebid:
+		err = notifier.NotifyInstanceRebid(inst.InstanceID, inst.InstanceName, inst.RegionID, msg.Replacement.InstanceID,
+			msg.Replacement.PublicAddresses(), inst.SpotPriceLimit, msg.Replacement.SpotPriceLimit, msg.Address != "")
+	case topicStartGaveUp:
+		err = notifier.NotifyStartGivenUp(inst.InstanceID, inst.InstanceName, inst.RegionID, msg.Attempts, msg.RequestedAt, msg.Until, msg.Err)
+	case topicInstanceReleased:
+		err = notifier.NotifyInstanceReleased(inst.InstanceID, inst.InstanceName, inst.RegionID)
+	case topicNoCapacity:
+		err = notifier.NotifyNoCapacity(inst.InstanceID, inst.InstanceName, inst.RegionID, inst.ZoneID, m.capacityRetryInterval(), msg.Err)
+	}
+	if err != nil {
+		log.Warnf("Failed to send %s notification for %s: %v", msg.Topic, inst.InstanceID, err)
+	}
+}
+
+// runStartHooks verifies a freshly running instance with its recovery
+// playbook or health checks and publishes the outcome. A playbook replaces
+// the EIP, DNS and bootstrap handling, which it does with its own steps.
+func (m *Monitor) runStartHooks(msg busMessage) {
+	if steps := m.playbookFor(msg.Instance); len(steps) > 0 {
+		m.runPlaybook(msg.Ctx, msg.Instance, steps, msg.RequestedAt)
+		return
+	}
+
+	inst, eip := m.ensureEIP(msg.Ctx, msg.Instance)
+	result := m.waitForHealthy(msg.Ctx, inst)
+	if result.err != nil && msg.Ctx.Err() != nil {
+		// Cancelled on shutdown, the instance itself is not unhealthy
+		log.Warnf("Health check of instance %s cancelled: %v", inst.InstanceID, msg.Ctx.Err())
+		return
+	}
+	if result.err != nil {
+		// The instance is running, so don't retry the start; just alert
+		log.Warnf("Instance %s is running but failed health check %s: %v", inst.InstanceID, result.name, result.err)
+		m.bus.publish(busMessage{
+			Topic:    topicHealthFailed,
+			Instance: inst,
+			Check:    result.name,
+			Timeout:  result.timeout,
+			Err:      result.err,
+		})
+		return
+	}
+
+	duration := time.Since(msg.RequestedAt)
+	log.Infof("Instance %s started successfully in %.0f seconds", inst.InstanceID, duration.Seconds())
+	m.bus.publish(busMessage{
+		Topic:     topicHealthPassed,
+		Instance:  inst,
+		Duration:  duration,
+		Check:     result.summary(),
+		EIP:       eip,
+		Bootstrap: m.runBootstrap(msg.Ctx, inst),
+	})
+}
+
+// logStatusChange logs status transitions seen by the regular check
+func logStatusChange(msg busMessage) {
+	if msg.PrevStatus == "" {
+		return
+	}
+	log.Infof("Instance %s (%s) status changed: %s → %s", msg.Instance.InstanceName, msg.Instance.InstanceID, msg.PrevStatus, msg.Status)
+}
+
+// countMessage counts published messages per topic
+func (m *Monitor) countMessage(msg busMessage) {
+	m.countersMu.Lock()
+	defer m.countersMu.Unlock()
+	m.counters[msg.Topic]++
+}
+
+// topicCount returns how many messages of a topic were published since startup
+func (m *Monitor) topicCount(topic string) int {
+	m.countersMu.Lock()
+	defer m.countersMu.Unlock()
+	return m.counters[topic]
+}
@@ -0,0 +1,94 @@
+package monitor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+)
+
+// monthPattern matches a billing cycle argument such as "2024-11"
+var monthPattern = regexp.MustCompile(`^\d{4}-\d{1,2}$`)
+
+// timeRange is a report period parsed from bot command arguments
+// End is exclusive
+type timeRange struct {
+	Start time.Time
+	End   time.Time
+	Label string
+	Cycle string // billing cycle (YYYY-MM) when the range is a whole past month
+}
+
+// parseTimeRange parses relative time arguments such as "today", "yesterday",
+// "last week" or "this month" (Chinese aliases are accepted too)
+// or a month such as "2024-11"
+// A nil range without error means the default period, the current month
+func parseTimeRange(args []string, now time.Time) (*timeRange, error) {
+	if len(args) == 1 && monthPattern.MatchString(args[0]) {
+		return parseMonth(args[0], now)
+	}
+
+	expr := strings.ToLower(strings.Join(args, " "))
+	expr = strings.NewReplacer("-", " ", "_", " ").Replace(expr)
+	expr = strings.Join(strings.Fields(expr), " ")
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	// Weeks start on Monday
+	weekday := (int(today.Weekday()) + 6) % 7
+	thisWeek := today.AddDate(0, 0, -weekday)
+
+	switch expr {
+	case "", "month", "this month", "本月":
+		return nil, nil
+	case "today", "今天", "今日":
+		return &timeRange{Start: today, End: now, Label: "今天"}, nil
+	case "yesterday", "昨天", "昨日":
+		return &timeRange{Start: today.AddDate(0, 0, -1), End: today, Label: "昨天"}, nil
+	case "week", "7d", "last 7 days", "最近7天", "近7天":
+		return &timeRange{Start: today.AddDate(0, 0, -6), End: now, Label: "最近7天"}, nil
+	case "this week", "本周":
+		return &timeRange{Start: thisWeek, End: now, Label: "本周"}, nil
+	case "last week", "lastweek", "上周":
+		return &timeRange{Start: thisWeek.AddDate(0, 0, -7), End: thisWeek, Label: "上周"}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTimeRange, strings.Join(args, " "))
+	}
+}
+
+// splitRegionArg separates a region ID such as "cn-hongkong" from time range arguments
+func splitRegionArg(args []string) (region string, rest []string) {
+	for _, arg := range args {
+		if region == "" && aliyun.IsRegionID(strings.ToLower(arg)) {
+			region = strings.ToLower(arg)
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return region, rest
+}
+
+// lastDay returns the last calendar day covered by the range
+func (r *timeRange) lastDay() time.Time {
+	return r.End.Add(-time.Nanosecond)
+}
+
+// parseMonth parses a billing cycle (YYYY-MM) into the range of that month
+// The current month returns nil like the default period, future months are rejected
+func parseMonth(arg string, now time.Time) (*timeRange, error) {
+	start, err := time.ParseInLocation("2006-1", arg, now.Location())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTimeRange, arg)
+	}
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	if start.Equal(thisMonth) {
+		return nil, nil
+	}
+	if start.After(thisMonth) {
+		return nil, fmt.Errorf("%w: %s is in the future", ErrInvalidTimeRange, arg)
+	}
+
+	cycle := start.Format("2006-01")
+	return &timeRange{Start: start, End: start.AddDate(0, 1, 0), Label: cycle, Cycle: cycle}, nil
+}
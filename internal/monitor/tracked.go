@@ -0,0 +1,90 @@
+package monitor
+
+import (
+	"encoding/json"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	log "github.com/sirupsen/logrus"
+)
+
+// trackedBucket holds the tracked instances, keyed by instance ID, so they
+// are known before the first discovery, e.g. to a replica taking over
+const trackedBucket = "tracked"
+
+// saveTrackedInstances persists the tracked instance set, dropping
+// instances that are no longer tracked
+func (m *Monitor) saveTrackedInstances() {
+	if m.store == nil {
+		return
+	}
+
+	m.mu.RLock()
+	instances := make(map[string]*aliyun.SpotInstance, len(m.instances))
+	for _, inst := range m.instances {
+		instances[inst.InstanceID] = inst
+	}
+	m.mu.RUnlock()
+
+	var stale []string
+	err := m.store.ForEach(trackedBucket, func(instanceID string, data []byte) error {
+		if _, tracked := instances[instanceID]; !tracked {
+			stale = append(stale, instanceID)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Warnf("Failed to read tracked instances: %v", err)
+	}
+	for _, instanceID := range stale {
+		m.forgetTrackedInstance(instanceID)
+	}
+	for _, inst := range instances {
+		m.saveTrackedInstance(inst)
+	}
+}
+
+// saveTrackedInstance persists fresh details of a tracked instance
+func (m *Monitor) saveTrackedInstance(inst *aliyun.SpotInstance) {
+	if m.store == nil {
+		return
+	}
+	if err := m.store.Put(trackedBucket, inst.InstanceID, inst); err != nil {
+		log.Warnf("Failed to save tracked instance %s: %v", inst.InstanceID, err)
+	}
+}
+
+// forgetTrackedInstance removes an instance no longer tracked from the store
+func (m *Monitor) forgetTrackedInstance(instanceID string) {
+	if m.store == nil {
+		return
+	}
+	if err := m.store.Delete(trackedBucket, instanceID); err != nil {
+		log.Warnf("Failed to forget tracked instance %s: %v", instanceID, err)
+	}
+}
+
+// loadTrackedInstances restores the tracked instances unless a discovery
+// already found them
+func (m *Monitor) loadTrackedInstances() error {
+	var instances []*aliyun.SpotInstance
+	err := m.store.ForEach(trackedBucket, func(instanceID string, data []byte) error {
+		var inst aliyun.SpotInstance
+		if err := json.Unmarshal(data, &inst); err != nil {
+			log.Warnf("Ignoring corrupt tracked instance %s: %v", instanceID, err)
+			return nil
+		}
+		instances = append(instances, &inst)
+		return nil
+	})
+	if err != nil || len(instances) == 0 {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.instances) == 0 {
+		m.instances = instances
+		log.Infof("Restored %d tracked instance(s), refreshed by the next discovery", len(instances))
+	}
+	return nil
+}
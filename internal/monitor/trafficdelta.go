@@ -0,0 +1,196 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	log "github.com/sirupsen/logrus"
+)
+
+// trafficSnapshotKey is the key of the traffic snapshot in trafficBucket
+const trafficSnapshotKey = "snapshot"
+
+// trafficSnapshot is the month-to-date traffic per region group and product
+// at the time of the last daily report
+type trafficSnapshot struct {
+	Time             time.Time        `json:"time"`
+	Cycle            string           `json:"cycle"`
+	ChinaMainland    map[string]int64 `json:"china_mainland"`     // product -> bytes
+	NonChinaMainland map[string]int64 `json:"non_china_mainland"` // product -> bytes
+}
+
+// newTrafficSnapshot captures the product traffic of a month-to-date summary
+func newTrafficSnapshot(summary *aliyun.TrafficSummary, at time.Time) *trafficSnapshot {
+	snapshot := &trafficSnapshot{
+		Time:             at,
+		Cycle:            summary.BillingCycle,
+		ChinaMainland:    make(map[string]int64),
+		NonChinaMainland: make(map[string]int64),
+	}
+	for product, traffic := range summary.ChinaMainland.ProductDetails {
+		snapshot.ChinaMainland[product] = traffic
+	}
+	for product, traffic := range summary.NonChinaMainland.ProductDetails {
+		snapshot.NonChinaMainland[product] = traffic
+	}
+	return snapshot
+}
+
+// trafficDelta is the traffic consumed per product since a snapshot
+type trafficDelta map[string]int64
+
+// total returns the traffic of all products
+func (d trafficDelta) total() int64 {
+	var total int64
+	for _, traffic := range d {
+		total += traffic
+	}
+	return total
+}
+
+// diffTraffic returns current minus previous per product, adding carry (the
+// remainder of the previous cycle on a month rollover)
+func diffTraffic(current, previous, carry map[string]int64) trafficDelta {
+	delta := make(trafficDelta)
+	for product, traffic := range current {
+		delta[product] += traffic - previous[product]
+	}
+	for product, traffic := range previous {
+		if _, ok := current[product]; !ok {
+			delta[product] -= traffic
+		}
+	}
+	for product, traffic := range carry {
+		delta[product] += traffic
+	}
+	for product, traffic := range delta {
+		// CDT data can be revised downwards, never report negative usage
+		if traffic <= 0 {
+			delete(delta, product)
+		}
+	}
+	return delta
+}
+
+// SendDailyTrafficReport reports the traffic consumed since the last report,
+// per region group and product, and saves the new month-to-date snapshot
+func (m *Monitor) SendDailyTrafficReport() error {
+	if m.trafficClient == nil {
+		return fmt.Errorf("traffic client not initialized")
+	}
+	if m.notifier == nil {
+		return fmt.Errorf("telegram notifier not initialized")
+	}
+
+	now := time.Now()
+	summary, err := m.queryTraffic(nil)
+	if err != nil {
+		return err
+	}
+	current := newTrafficSnapshot(summary, now)
+
+	m.trafficSnapshotMu.Lock()
+	defer m.trafficSnapshotMu.Unlock()
+
+	previous := m.trafficSnapshot
+	if previous == nil {
+		message := fmt.Sprintf("📶 <b>每日流量</b>\n━━━━━━━━━━━━━━━━\n\n已记录本月流量 %s，下次报告起显示每日增量",
+			aliyun.FormatTrafficSize(summary.TotalTraffic))
+		if err := m.notifier.Send(message); err != nil {
+			return fmt.Errorf("failed to send daily traffic report: %w", err)
+		}
+		m.saveTrafficSnapshot(current)
+		return nil
+	}
+
+	// On a month rollover the remainder of the previous cycle is counted too
+	var carryChina, carryGlobal map[string]int64
+	if previous.Cycle != current.Cycle {
+		start, err := time.ParseInLocation("2006-01", previous.Cycle, time.UTC)
+		if err != nil {
+			return fmt.Errorf("invalid snapshot cycle %q: %w", previous.Cycle, err)
+		}
+		last, err := m.trafficClient.QueryInternetTrafficByTimeRange(m.ctx, start, start.AddDate(0, 1, 0))
+		if err != nil {
+			return err
+		}
+		lastCycle := newTrafficSnapshot(last, now)
+		carryChina = diffTraffic(lastCycle.ChinaMainland, previous.ChinaMainland, nil)
+		carryGlobal = diffTraffic(lastCycle.NonChinaMainland, previous.NonChinaMainland, nil)
+		previous = &trafficSnapshot{Time: previous.Time}
+	}
+	china := diffTraffic(current.ChinaMainland, previous.ChinaMainland, carryChina)
+	global := diffTraffic(current.NonChinaMainland, previous.NonChinaMainland, carryGlobal)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📶 <b>每日流量</b> (过去 %s)\n", humanDuration(now.Sub(previous.Time))))
+	sb.WriteString("━━━━━━━━━━━━━━━━\n")
+	sb.WriteString(fmt.Sprintf("📅 %s ~ %s\n", previous.Time.Format("01-02 15:04"), now.Format("01-02 15:04")))
+	sb.WriteString("━━━━━━━━━━━━━━━━\n\n")
+	writeTrafficDelta(&sb, "🇨🇳 <b>中国大陆</b>", china)
+	writeTrafficDelta(&sb, "🌏 <b>非中国大陆</b>", global)
+	sb.WriteString("━━━━━━━━━━━━━━━━\n")
+	sb.WriteString(fmt.Sprintf("📈 <b>合计: %s</b>\n", aliyun.FormatTrafficSize(china.total()+global.total())))
+	sb.WriteString(fmt.Sprintf("📊 本月累计: %s", aliyun.FormatTrafficSize(summary.TotalTraffic)))
+
+	if err := m.notifier.Send(sb.String()); err != nil {
+		return fmt.Errorf("failed to send daily traffic report: %w", err)
+	}
+	log.Infof("Daily traffic report sent (China %s, non-China %s)",
+		aliyun.FormatTrafficSize(china.total()), aliyun.FormatTrafficSize(global.total()))
+
+	m.saveTrafficSnapshot(current)
+	return nil
+}
+
+// writeTrafficDelta writes a region group section of the daily traffic report
+func writeTrafficDelta(sb *strings.Builder, title string, delta trafficDelta) {
+	sb.WriteString(title + "\n")
+	if len(delta) == 0 {
+		sb.WriteString("   暂无流量\n\n")
+		return
+	}
+	sb.WriteString(fmt.Sprintf("   📊 流量: <b>%s</b>\n", aliyun.FormatTrafficSize(delta.total())))
+
+	products := make([]string, 0, len(delta))
+	for product := range delta {
+		products = append(products, product)
+	}
+	sort.Slice(products, func(i, j int) bool { return delta[products[i]] > delta[products[j]] })
+	for _, product := range products {
+		sb.WriteString(fmt.Sprintf("      • %s: %s\n", product, aliyun.FormatTrafficSize(delta[product])))
+	}
+	sb.WriteString("\n")
+}
+
+// saveTrafficSnapshot keeps the snapshot for the next daily report
+// Callers hold trafficSnapshotMu
+func (m *Monitor) saveTrafficSnapshot(snapshot *trafficSnapshot) {
+	m.trafficSnapshot = snapshot
+	if m.store == nil {
+		return
+	}
+	if err := m.store.Put(trafficBucket, trafficSnapshotKey, snapshot); err != nil {
+		log.Warnf("Failed to save traffic snapshot: %v", err)
+	}
+}
+
+// loadTrafficSnapshot restores the traffic snapshot of the last daily report
+func (m *Monitor) loadTrafficSnapshot() error {
+	return m.store.ForEach(trafficBucket, func(key string, data []byte) error {
+		if key != trafficSnapshotKey {
+			return nil
+		}
+		var snapshot trafficSnapshot
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			log.Warnf("Ignoring corrupt traffic snapshot: %v", err)
+			return nil
+		}
+		m.trafficSnapshot = &snapshot
+		return nil
+	})
+}
@@ -0,0 +1,290 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	log "github.com/sirupsen/logrus"
+)
+
+// statusBucket holds the status change log in the state store
+const statusBucket = "status"
+
+// reclaimsBucket holds the reclaim times of each instance over the uptime
+// retention, kept apart from the capped event log so busy accounts don't
+// lose older reclaims
+const reclaimsBucket = "reclaims"
+
+// maxStoredStatusChanges is how many status changes are kept in the state store
+const maxStoredStatusChanges = 20000
+
+// uptimeRetention is the longest window availability is reported for
+const uptimeRetention = 30 * 24 * time.Hour
+
+// statusChange is a status observed by the regular check
+// The instance is assumed to keep the status until the next change
+type statusChange struct {
+	Time       time.Time `json:"time"`
+	InstanceID string    `json:"instance_id"`
+	Status     string    `json:"status"`
+}
+
+// availability summarizes an instance's uptime over a window
+type availability struct {
+	Observed time.Duration // part of the window covered by status polls
+	Running  time.Duration
+	Reclaims int
+}
+
+// percent returns the share of the observed time the instance was running
+func (a availability) percent() float64 {
+	if a.Observed <= 0 {
+		return 0
+	}
+	return float64(a.Running) / float64(a.Observed) * 100
+}
+
+// meanTimeBetweenReclaims returns the running time per reclaim, 0 without reclaims
+func (a availability) meanTimeBetweenReclaims() time.Duration {
+	if a.Reclaims == 0 {
+		return 0
+	}
+	return a.Running / time.Duration(a.Reclaims)
+}
+
+// recordStatusChange appends a status change to the log and the state store
+func (m *Monitor) recordStatusChange(msg busMessage) {
+	change := statusChange{Time: msg.Time, InstanceID: msg.Instance.InstanceID, Status: msg.Status}
+
+	m.statusLogMu.Lock()
+	m.statusLog[change.InstanceID] = pruneStatusLog(append(m.statusLog[change.InstanceID], change), change.Time)
+	m.statusLogMu.Unlock()
+
+	if m.store == nil {
+		return
+	}
+	if err := m.store.Append(statusBucket, change, maxStoredStatusChanges); err != nil {
+		log.Warnf("Failed to save status change: %v", err)
+	}
+}
+
+// recordReclaim adds a reclaim to the reclaim log of the instance and the state store
+func (m *Monitor) recordReclaim(msg busMessage) {
+	instanceID := msg.Instance.InstanceID
+
+	m.statusLogMu.Lock()
+	times := pruneReclaims(append(m.reclaimLog[instanceID], msg.Time), msg.Time)
+	m.reclaimLog[instanceID] = times
+	saved := append([]time.Time(nil), times...)
+	m.statusLogMu.Unlock()
+
+	if m.store == nil {
+		return
+	}
+	if err := m.store.Put(reclaimsBucket, instanceID, saved); err != nil {
+		log.Warnf("Failed to save reclaim of instance %s: %v", instanceID, err)
+	}
+}
+
+// pruneReclaims drops reclaims older than the retention
+func pruneReclaims(times []time.Time, now time.Time) []time.Time {
+	cutoff := now.Add(-uptimeRetention)
+	drop := 0
+	for drop < len(times) && times[drop].Before(cutoff) {
+		drop++
+	}
+	return times[drop:]
+}
+
+// pruneStatusLog drops changes older than the retention, keeping the last one
+// before the cutoff since it gives the status at the start of the window
+func pruneStatusLog(changes []statusChange, now time.Time) []statusChange {
+	cutoff := now.Add(-uptimeRetention)
+	drop := 0
+	for drop+1 < len(changes) && !changes[drop+1].Time.After(cutoff) {
+		drop++
+	}
+	return changes[drop:]
+}
+
+// loadStatusLog restores the status change log from the state store
+func (m *Monitor) loadStatusLog() error {
+	now := time.Now()
+	count := 0
+	err := m.store.Tail(statusBucket, 0, func(data []byte) error {
+		var change statusChange
+		if err := json.Unmarshal(data, &change); err != nil {
+			log.Warnf("Ignoring corrupt status change: %v", err)
+			return nil
+		}
+		m.statusLog[change.InstanceID] = append(m.statusLog[change.InstanceID], change)
+		count++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for instanceID, changes := range m.statusLog {
+		m.statusLog[instanceID] = pruneStatusLog(changes, now)
+	}
+	log.Debugf("Restored %d status change(s)", count)
+	return nil
+}
+
+// loadReclaimLog restores the reclaim log from the state store. A store
+// without one, written before reclaims were kept apart, is seeded from the
+// event history.
+func (m *Monitor) loadReclaimLog() error {
+	now := time.Now()
+	err := m.store.ForEach(reclaimsBucket, func(instanceID string, data []byte) error {
+		var times []time.Time
+		if err := json.Unmarshal(data, &times); err != nil {
+			log.Warnf("Ignoring corrupt reclaims of instance %s: %v", instanceID, err)
+			return nil
+		}
+		if times = pruneReclaims(times, now); len(times) > 0 {
+			m.reclaimLog[instanceID] = times
+		}
+		return nil
+	})
+	if err != nil || len(m.reclaimLog) > 0 {
+		return err
+	}
+
+	events, err := m.storedEvents()
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		if event.Type == EventReclaimed && !event.Time.Before(now.Add(-uptimeRetention)) {
+			m.reclaimLog[event.InstanceID] = append(m.reclaimLog[event.InstanceID], event.Time)
+		}
+	}
+	for instanceID, times := range m.reclaimLog {
+		if err := m.store.Put(reclaimsBucket, instanceID, times); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// availabilityOf computes the availability of an instance over the window ending now
+func (m *Monitor) availabilityOf(instanceID string, window time.Duration, now time.Time, reclaims []time.Time) availability {
+	start := now.Add(-window)
+
+	m.statusLogMu.Lock()
+	changes := make([]statusChange, len(m.statusLog[instanceID]))
+	copy(changes, m.statusLog[instanceID])
+	m.statusLogMu.Unlock()
+
+	var a availability
+	for i, change := range changes {
+		from, to := change.Time, now
+		if i+1 < len(changes) {
+			to = changes[i+1].Time
+		}
+		if from.Before(start) {
+			from = start
+		}
+		if !to.After(from) {
+			continue
+		}
+		a.Observed += to.Sub(from)
+		if change.Status == "Running" {
+			a.Running += to.Sub(from)
+		}
+	}
+
+	for _, t := range reclaims {
+		if t.After(start) {
+			a.Reclaims++
+		}
+	}
+	return a
+}
+
+// reclaimTimes returns the reclaim times of each instance over the uptime retention
+func (m *Monitor) reclaimTimes() map[string][]time.Time {
+	m.statusLogMu.Lock()
+	defer m.statusLogMu.Unlock()
+	times := make(map[string][]time.Time, len(m.reclaimLog))
+	for instanceID, reclaims := range m.reclaimLog {
+		times[instanceID] = append([]time.Time(nil), reclaims...)
+	}
+	return times
+}
+
+// formatAvailability formats the 7-day and 30-day availability of an instance
+func formatAvailability(week, month availability) string {
+	format := func(a availability) string {
+		if a.Observed <= 0 {
+			return "无数据"
+		}
+		return fmt.Sprintf("%.2f%%", a.percent())
+	}
+	return fmt.Sprintf("7天 %s | 30天 %s", format(week), format(month))
+}
+
+// formatMTBR formats the mean time between reclaims
+func formatMTBR(a availability) string {
+	if a.Reclaims == 0 {
+		return "无回收"
+	}
+	return fmt.Sprintf("%s (%d 次回收)", humanDuration(a.meanTimeBetweenReclaims()), a.Reclaims)
+}
+
+// humanDuration formats a duration in days, hours or minutes
+func humanDuration(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%.1f 天", d.Hours()/24)
+	case d >= time.Hour:
+		return fmt.Sprintf("%.1f 小时", d.Hours())
+	default:
+		return fmt.Sprintf("%.0f 分钟", d.Minutes())
+	}
+}
+
+// handleUptimeCommand handles /uptime [instance]
+func (m *Monitor) handleUptimeCommand(args []string) error {
+	if m.notifier == nil {
+		return fmt.Errorf("telegram notifier not initialized")
+	}
+
+	m.mu.RLock()
+	instances := make([]*aliyun.SpotInstance, 0, len(m.instances))
+	for _, inst := range m.instances {
+		if len(args) == 0 || inst.InstanceID == args[0] || inst.InstanceName == args[0] {
+			instances = append(instances, inst)
+		}
+	}
+	m.mu.RUnlock()
+
+	if len(instances) == 0 {
+		return m.notifier.Send("📈 <b>可用率</b>\n\n暂无匹配的实例")
+	}
+
+	now := time.Now()
+	reclaims := m.reclaimTimes()
+
+	var sb strings.Builder
+	sb.WriteString("📈 <b>可用率</b>\n")
+	sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+
+	for _, inst := range instances {
+		week := m.availabilityOf(inst.InstanceID, 7*24*time.Hour, now, reclaims[inst.InstanceID])
+		month := m.availabilityOf(inst.InstanceID, uptimeRetention, now, reclaims[inst.InstanceID])
+
+		sb.WriteString(fmt.Sprintf("<b>%s</b> (<code>%s</code>)\n", inst.InstanceName, inst.InstanceID))
+		sb.WriteString(fmt.Sprintf("   可用率: %s\n", formatAvailability(week, month)))
+		sb.WriteString(fmt.Sprintf("   30天运行: %s / 观测 %s\n", humanDuration(month.Running), humanDuration(month.Observed)))
+		sb.WriteString(fmt.Sprintf("   平均回收间隔: %s\n\n", formatMTBR(month)))
+	}
+
+	sb.WriteString("<i>根据状态轮询计算，监控未运行的时段按最后已知状态计</i>")
+	return m.notifier.Send(sb.String())
+}
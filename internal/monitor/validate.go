@@ -0,0 +1,123 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	"github.com/iliyian/aliyun-spot-manager/internal/config"
+	"github.com/iliyian/aliyun-spot-manager/internal/limit"
+	"github.com/iliyian/aliyun-spot-manager/internal/notify"
+)
+
+// ValidationResult is the outcome of one check of Validate
+type ValidationResult struct {
+	Name    string
+	Detail  string // what was verified, on success
+	Err     error
+	Skipped bool // the feature is disabled
+}
+
+// Validate checks a loaded configuration without starting the monitor: health
+// checks and playbooks, the Aliyun credentials with a DescribeRegions call and
+// the Telegram bot token and chat. With testMessage a Telegram message is sent too.
+func Validate(cfg *config.Config, testMessage bool) []ValidationResult {
+	var results []ValidationResult
+	add := func(name, detail string, err error) {
+		results = append(results, ValidationResult{Name: name, Detail: detail, Err: err})
+	}
+	skip := func(name, reason string) {
+		results = append(results, ValidationResult{Name: name, Detail: reason, Skipped: true})
+	}
+
+	// Monitor with just enough set up to build health checkers and playbooks
+	m := &Monitor{cfg: cfg, hooks: limit.New(cfg.HookConcurrency, cfg.HookConcurrencyPerHost)}
+
+	if cfg.HealthCheckEnabled {
+		_, err := newHealthChecker(cfg, m.hooks, m.healthSettingsFor(&aliyun.SpotInstance{}))
+		if err == nil {
+			_, err = newHealthChecker(cfg, m.hooks, m.healthSettingsFor(&aliyun.SpotInstance{OSType: "windows"}))
+		}
+		add("健康检查", cfg.HealthChecks, err)
+	} else {
+		skip("健康检查", "HEALTH_CHECK_ENABLED=false")
+	}
+
+	if cfg.File != nil && len(cfg.File.Instances) > 0 {
+		runner := m.newPlaybookRunner()
+		var errs []string
+		for key, ic := range cfg.File.Instances {
+			if err := runner.Validate(ic.Playbook); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", key, err))
+			}
+			if err := runner.Validate(ic.PreShutdown); err != nil {
+				errs = append(errs, fmt.Sprintf("%s pre_shutdown: %v", key, err))
+			}
+			if _, err := parseRequiredPorts(ic.RequiredPorts); err != nil {
+				errs = append(errs, fmt.Sprintf("%s required_ports: %v", key, err))
+			}
+		}
+		var err error
+		if len(errs) > 0 {
+			err = fmt.Errorf("invalid instance config: %s", strings.Join(errs, "; "))
+		}
+		add("配置文件", fmt.Sprintf("%s，%d 条实例配置", cfg.ConfigFile, len(cfg.File.Instances)), err)
+	} else {
+		skip("配置文件", "未配置实例覆盖")
+	}
+
+	transport, err := newTransport(cfg)
+	if cfg.Simulate {
+		skip("阿里云凭证", "SIMULATE=true")
+	} else if err != nil {
+		add("阿里云凭证", "", err)
+	} else {
+		ecsClient := aliyun.NewECSClient(aliyunCredentials(cfg), transport)
+		regions, err := ecsClient.GetAllRegions(context.Background())
+		if err == nil {
+			err = checkRegions(splitList(cfg.Regions), regions)
+		}
+		add("阿里云凭证", fmt.Sprintf("%s 可访问 %d 个区域", aliyunCredentials(cfg), len(regions)), err)
+	}
+
+	if !cfg.TelegramEnabled {
+		skip("Telegram", "TELEGRAM_ENABLED=false")
+		return results
+	}
+	notifier := notify.NewTelegramNotifier(cfg.TelegramBotToken, cfg.TelegramChatID)
+	username, err := notifier.GetMe()
+	if err != nil {
+		add("Telegram Bot", "", err)
+		return results
+	}
+	add("Telegram Bot", "@"+username, nil)
+
+	chat, err := notifier.GetChat()
+	add("Telegram 会话", chat, err)
+	if err == nil && testMessage {
+		add("Telegram 测试消息", "已发送", notifier.Send("✅ <b>配置校验</b>\n\n这是一条测试消息，通知配置正常"))
+	}
+	return results
+}
+
+// checkRegions reports configured regions that are not available to the account
+func checkRegions(configured, available []string) error {
+	var unknown []string
+	for _, region := range configured {
+		found := false
+		for _, r := range available {
+			if r == region {
+				found = true
+				break
+			}
+		}
+		if !found {
+			unknown = append(unknown, region)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown regions in REGIONS: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxStackDump bounds the goroutine dump logged when the check loop stalls
+const maxStackDump = 64 << 10
+
+// checkLoop tracks the check cycles for the watchdog
+type checkLoop struct {
+	started  time.Time // start of the running cycle, zero when idle
+	finished time.Time // end of the last completed cycle
+	cancel   context.CancelFunc
+	stalled  bool // reported, waiting for a cycle to complete
+}
+
+// beginCheck records the start of a check cycle, returning its context
+func (m *Monitor) beginCheck() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.checkLoopMu.Lock()
+	m.checkLoop.started = time.Now()
+	m.checkLoop.cancel = cancel
+	m.checkLoopMu.Unlock()
+	return ctx, cancel
+}
+
+// endCheck records the end of a check cycle, reporting the recovery of a stalled loop
+func (m *Monitor) endCheck(cancel context.CancelFunc) {
+	cancel()
+	m.checkLoopMu.Lock()
+	took := time.Since(m.checkLoop.started)
+	m.checkLoop.started = time.Time{}
+	m.checkLoop.finished = time.Now()
+	m.checkLoop.cancel = nil
+	recovered := m.checkLoop.stalled
+	m.checkLoop.stalled = false
+	m.checkLoopMu.Unlock()
+
+	if !recovered {
+		return
+	}
+	log.Infof("Check loop recovered, the stalled check took %s", took.Round(time.Second))
+	if m.notifier != nil {
+		if err := m.notifier.Send(fmt.Sprintf("✅ <b>检查循环已恢复</b>\n\n卡住的检查耗时 %.0f 分钟后结束", took.Minutes())); err != nil {
+			log.Warnf("Failed to send watchdog notification: %v", err)
+		}
+	}
+}
+
+// watchdogTimeout returns how long a check cycle may run, WATCHDOG_MULTIPLIER
+// check intervals but at least as long as the longest start a cycle may wait
+// for: every retry timing out, the health check and the dependencies
+func (m *Monitor) watchdogTimeout() time.Duration {
+	timeout := time.Duration(m.cfg.WatchdogMultiplier*m.cfg.CheckInterval) * time.Second
+	start := time.Duration(m.cfg.RetryCount*m.cfg.StartTimeout+(m.cfg.RetryCount-1)*m.cfg.RetryInterval) * time.Second
+	if m.cfg.HealthCheckEnabled {
+		start += time.Duration(m.cfg.HealthCheckTimeout) * time.Second
+	}
+	start += time.Duration(m.cfg.DependencyTimeout) * time.Second
+	if start > timeout {
+		return start
+	}
+	return timeout
+}
+
+// StartWatchdog watches the check loop until ctx is cancelled. When no cycle
+// completes within the watchdog timeout, e.g. after a deadlock or an API call
+// that never returns, it logs the goroutines and sends a critical alert. With
+// WATCHDOG_RESTART the stalled cycle is cancelled so the next one can run, and
+// if it is still stuck one timeout later the process exits for the service
+// manager to restart it.
+func (m *Monitor) StartWatchdog(ctx context.Context) {
+	if m.cfg.WatchdogMultiplier <= 0 {
+		return
+	}
+
+	timeout := m.watchdogTimeout()
+	m.checkLoopMu.Lock()
+	m.checkLoop.finished = time.Now()
+	m.checkLoopMu.Unlock()
+
+	go func() {
+		log.Infof("Watchdog expects a completed check every %s", timeout)
+		ticker := time.NewTicker(time.Duration(m.cfg.CheckInterval) * time.Second)
+		defer ticker.Stop()
+		var cancelledAt time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			m.checkLoopMu.Lock()
+			since := time.Since(m.checkLoop.finished)
+			running := !m.checkLoop.started.IsZero()
+			firstReport := since >= timeout && !m.checkLoop.stalled
+			if since < timeout {
+				cancelledAt = time.Time{}
+			}
+			if firstReport {
+				m.checkLoop.stalled = true
+			}
+			cancel := m.checkLoop.cancel
+			m.checkLoopMu.Unlock()
+
+			if since < timeout || m.isClosing() {
+				continue
+			}
+			if firstReport {
+				m.reportStalledCheck(since, running)
+			}
+			if !m.cfg.WatchdogRestart {
+				continue
+			}
+
+			switch {
+			case cancelledAt.IsZero():
+				cancelledAt = time.Now()
+				if cancel != nil {
+					log.Warn("Watchdog cancelling the stalled check")
+					cancel()
+				}
+			case time.Since(cancelledAt) >= timeout:
+				if m.notifier != nil {
+					if err := m.notifier.Send("🛑 <b>检查循环仍然卡住</b>\n\n取消后仍未恢复，监控进程即将退出，由 systemd / Docker 重启"); err != nil {
+						log.Warnf("Failed to send watchdog notification: %v", err)
+					}
+				}
+				log.Fatalf("Check loop still stalled %s after cancelling it, exiting so the service manager restarts the monitor",
+					time.Since(cancelledAt).Round(time.Second))
+			}
+		}
+	}()
+}
+
+// reportStalledCheck logs the goroutines and alerts that no check completed in time
+func (m *Monitor) reportStalledCheck(since time.Duration, running bool) {
+	buf := make([]byte, maxStackDump)
+	buf = buf[:runtime.Stack(buf, true)]
+	log.Errorf("No check completed for %s (check running: %t), goroutines:\n%s", since.Round(time.Second), running, buf)
+
+	if m.notifier == nil {
+		return
+	}
+	reason := "检查没有按计划运行"
+	if running {
+		reason = "检查已开始但一直没有结束，可能是 API 调用无响应或死锁"
+	}
+	action := "请检查日志中的 goroutine 堆栈，必要时重启监控"
+	if m.cfg.WatchdogRestart {
+		action = "将取消卡住的检查，仍未恢复时退出进程由 systemd / Docker 重启"
+	}
+	message := fmt.Sprintf("🚨 <b>检查循环卡住</b>\n\n已 %.0f 分钟没有完成检查（检查间隔 %d 秒）\n%s\n\n%s",
+		since.Minutes(), m.cfg.CheckInterval, reason, action)
+	if err := m.notifier.Send(message); err != nil {
+		log.Warnf("Failed to send watchdog notification: %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package notify
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -16,7 +17,7 @@ type BotHandler struct {
 	botToken   string
 	chatID     string
 	client     *http.Client
-	commandHandler func(command string) error
+	commandHandler func(command string, args []string) error
 	lastUpdateID int64
 }
 
@@ -33,7 +34,7 @@ func NewBotHandler(botToken, chatID string) *BotHandler {
 }
 
 // SetCommandHandler sets the command handler function
-func (b *BotHandler) SetCommandHandler(handler func(command string) error) {
+func (b *BotHandler) SetCommandHandler(handler func(command string, args []string) error) {
 	b.commandHandler = handler
 }
 
@@ -72,12 +73,16 @@ type TelegramUpdatesResponse struct {
 }
 
 // PollUpdates polls for new updates from Telegram
-func (b *BotHandler) PollUpdates() error {
+func (b *BotHandler) PollUpdates(ctx context.Context) error {
 	url := fmt.Sprintf("https://api.telegram.org/bot%s/getUpdates?offset=%d&timeout=30", b.botToken, b.lastUpdateID+1)
 
 	log.Debugf("Polling updates with offset=%d", b.lastUpdateID+1)
 
-	resp, err := b.client.Get(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := b.client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to get updates: %w", err)
 	}
@@ -111,15 +116,18 @@ func (b *BotHandler) PollUpdates() error {
 
 		// Process command
 		if strings.HasPrefix(update.Message.Text, "/") {
-			command := strings.TrimPrefix(update.Message.Text, "/")
-			command = strings.Split(command, " ")[0] // Get first word
-			command = strings.Split(command, "@")[0] // Remove bot username if present
+			fields := strings.Fields(strings.TrimPrefix(update.Message.Text, "/"))
+			if len(fields) == 0 {
+				continue
+			}
+			command := strings.Split(fields[0], "@")[0] // Remove bot username if present
+			args := fields[1:]
 			
 			log.Infof("Received command: /%s from chat %d (update_id=%d, msg_id=%d)",
 				command, update.Message.Chat.ID, update.UpdateID, update.Message.MessageID)
 			
 			if b.commandHandler != nil {
-				if err := b.commandHandler(command); err != nil {
+				if err := b.commandHandler(command, args); err != nil {
 					log.Errorf("Failed to handle command /%s: %v", command, err)
 				}
 			}
@@ -129,16 +137,23 @@ func (b *BotHandler) PollUpdates() error {
 	return nil
 }
 
-// StartPolling starts polling for updates in a goroutine
-func (b *BotHandler) StartPolling() {
+// StartPolling starts polling for updates in a goroutine until ctx is cancelled
+func (b *BotHandler) StartPolling(ctx context.Context) {
 	go func() {
 		log.Info("Starting Telegram bot polling...")
-		for {
-			if err := b.PollUpdates(); err != nil {
+		for ctx.Err() == nil {
+			if err := b.PollUpdates(ctx); err != nil {
+				if ctx.Err() != nil {
+					break
+				}
 				log.Warnf("Failed to poll updates: %v", err)
-				time.Sleep(5 * time.Second)
+				select {
+				case <-ctx.Done():
+				case <-time.After(5 * time.Second):
+				}
 				continue
 			}
 		}
+		log.Info("Telegram bot polling stopped")
 	}()
 }
\ No newline at end of file
@@ -0,0 +1,76 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// telegramResponse is the envelope of Telegram Bot API responses
+type telegramResponse struct {
+	OK          bool            `json:"ok"`
+	Description string          `json:"description"`
+	Result      json.RawMessage `json:"result"`
+}
+
+// call invokes a Bot API method with query parameters and decodes its result
+func (t *TelegramNotifier) call(method string, params url.Values, result interface{}) error {
+	endpoint := fmt.Sprintf("https://api.telegram.org/bot%s/%s?%s", t.botToken, method, params.Encode())
+
+	resp, err := t.client.Get(endpoint)
+	if err != nil {
+		// The URL holds the token, keep it out of the error
+		return fmt.Errorf("failed to call %s: %w", method, errorWithoutURL(err))
+	}
+	defer resp.Body.Close()
+
+	var body telegramResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("failed to decode %s response (status %d): %w", method, resp.StatusCode, err)
+	}
+	if !body.OK || resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("telegram %s failed: %s", method, body.Description)
+	}
+	return json.Unmarshal(body.Result, result)
+}
+
+// errorWithoutURL strips the request URL from an HTTP client error
+func errorWithoutURL(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		return urlErr.Err
+	}
+	return err
+}
+
+// GetMe returns the username of the bot, verifying the bot token
+func (t *TelegramNotifier) GetMe() (string, error) {
+	var user struct {
+		Username string `json:"username"`
+	}
+	if err := t.call("getMe", url.Values{}, &user); err != nil {
+		return "", err
+	}
+	return user.Username, nil
+}
+
+// GetChat returns the title of the configured chat, verifying the bot can reach it
+func (t *TelegramNotifier) GetChat() (string, error) {
+	var chat struct {
+		Title     string `json:"title"`
+		Username  string `json:"username"`
+		FirstName string `json:"first_name"`
+		Type      string `json:"type"`
+	}
+	if err := t.call("getChat", url.Values{"chat_id": {t.chatID}}, &chat); err != nil {
+		return "", err
+	}
+	name := chat.Title
+	if name == "" {
+		name = chat.FirstName
+	}
+	if name == "" {
+		name = "@" + chat.Username
+	}
+	return fmt.Sprintf("%s (%s)", name, chat.Type), nil
+}
@@ -0,0 +1,40 @@
+package notify
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Pinger pings a dead man's switch such as healthchecks.io, which alerts
+// when the pings stop arriving
+type Pinger struct {
+	url    string
+	client *http.Client
+}
+
+// NewPinger creates a pinger for the given ping URL
+func NewPinger(url string) *Pinger {
+	return &Pinger{
+		url: url,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// Ping signals that the monitor is alive
+func (p *Pinger) Ping() error {
+	resp, err := p.client.Get(p.url)
+	if err != nil {
+		return fmt.Errorf("failed to ping: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("ping returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -4,7 +4,10 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"html"
+	"mime/multipart"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
@@ -64,6 +67,41 @@ func (t *TelegramNotifier) Send(message string) error {
 	return nil
 }
 
+// SendDocument sends a file as a Telegram document with an optional HTML caption
+func (t *TelegramNotifier) SendDocument(filename string, data []byte, caption string) error {
+	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendDocument", t.botToken)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("chat_id", t.chatID)
+	if caption != "" {
+		mw.WriteField("caption", caption)
+		mw.WriteField("parse_mode", "HTML")
+	}
+	part, err := mw.CreateFormFile("document", filename)
+	if err != nil {
+		return fmt.Errorf("failed to create document part: %w", err)
+	}
+	if _, err := part.Write(data); err != nil {
+		return fmt.Errorf("failed to write document: %w", err)
+	}
+	if err := mw.Close(); err != nil {
+		return fmt.Errorf("failed to encode document: %w", err)
+	}
+
+	resp, err := t.client.Post(url, mw.FormDataContentType(), &body)
+	if err != nil {
+		return fmt.Errorf("failed to send document: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("telegram API returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
 // NotifyInstanceReclaimed sends a notification when an instance is reclaimed
 func (t *TelegramNotifier) NotifyInstanceReclaimed(instanceID, instanceName, region string) error {
 	message := fmt.Sprintf(`🔴 <b>实例被回收</b>
@@ -95,44 +133,323 @@ ID: <code>%s</code>
 }
 
 // NotifyInstanceStarted sends a notification when an instance is successfully started
-func (t *TelegramNotifier) NotifyInstanceStarted(instanceID, instanceName, region, publicIP string, duration time.Duration) error {
+// bootstrap is the outcome of the bootstrap script with the end of its output, empty if none ran
+func (t *TelegramNotifier) NotifyInstanceStarted(instanceID, instanceName, region, publicIP string, duration time.Duration, healthStatus, eipResult, bootstrap, bootstrapOutput string) error {
 	ipInfo := "无公网IP"
 	if publicIP != "" {
 		ipInfo = publicIP
 	}
+	eipInfo := ""
+	if eipResult != "" {
+		eipInfo = fmt.Sprintf("\nEIP: %s", eipResult)
+	}
+	bootstrapInfo := ""
+	if bootstrap != "" {
+		bootstrapInfo = fmt.Sprintf("\n启动脚本: %s", html.EscapeString(bootstrap))
+		if bootstrapOutput != "" {
+			bootstrapInfo += fmt.Sprintf("\n<pre>%s</pre>", html.EscapeString(bootstrapOutput))
+		}
+	}
 
 	message := fmt.Sprintf(`✅ <b>实例已启动</b>
 ━━━━━━━━━━━━━━━
 实例: %s
 ID: <code>%s</code>
 区域: %s
-公网IP: <code>%s</code>
+公网IP: <code>%s</code>%s
 状态: Running ✓
-启动耗时: %.0f 秒
+健康检查: %s
+启动耗时: %.0f 秒%s
 ━━━━━━━━━━━━━━━`,
-		instanceName, instanceID, region, ipInfo, duration.Seconds())
+		instanceName, instanceID, region, ipInfo, eipInfo, healthStatus, duration.Seconds(), bootstrapInfo)
+
+	return t.Send(message)
+}
+
+// NotifyAddressChanged sends a notification when the public IP of a running instance changed
+func (t *TelegramNotifier) NotifyAddressChanged(instanceID, instanceName, region, oldAddress, newAddress string) error {
+	message := fmt.Sprintf(`🔀 <b>公网IP已变化</b>
+━━━━━━━━━━━━━━━
+实例: %s
+ID: <code>%s</code>
+区域: %s
+原IP: %s
+新IP: <code>%s</code>
+━━━━━━━━━━━━━━━
+请更新依赖该地址的客户端或解析记录`,
+		instanceName, instanceID, region, oldAddress, newAddress)
+
+	return t.Send(message)
+}
+
+// NotifyStateChange sends the audit notification of a state transition
+func (t *TelegramNotifier) NotifyStateChange(instanceID, instanceName, region, title, change string) error {
+	message := fmt.Sprintf(`<b>%s</b>
+━━━━━━━━━━━━━━━
+实例: %s
+ID: <code>%s</code>
+区域: %s
+变化: %s
+时间: %s
+━━━━━━━━━━━━━━━`,
+		title, instanceName, instanceID, region, change, time.Now().Format("2006-01-02 15:04:05"))
+
+	return t.Send(message)
+}
+
+// NotifySecurityGroupDrift sends a warning when the security groups of a started instance don't let in its required ports
+func (t *TelegramNotifier) NotifySecurityGroupDrift(instanceID, instanceName, region string, groups, ports []string) error {
+	message := fmt.Sprintf(`🛡️ <b>安全组规则缺失</b>
+━━━━━━━━━━━━━━━
+实例: %s
+ID: <code>%s</code>
+区域: %s
+安全组: %s
+未放行: %s
+━━━━━━━━━━━━━━━
+实例已启动，但这些端口可能无法访问，请检查安全组规则`,
+		instanceName, instanceID, region, strings.Join(groups, ", "), strings.Join(ports, ", "))
+
+	return t.Send(message)
+}
+
+// NotifySnapshots sends the results of taking disk snapshots, one line per instance
+func (t *TelegramNotifier) NotifySnapshots(title string, lines []string) error {
+	message := fmt.Sprintf(`📸 <b>%s</b>
+━━━━━━━━━━━━━━━
+%s`,
+		title, html.EscapeString(strings.Join(lines, "\n")))
 
 	return t.Send(message)
 }
 
 // NotifyInstanceStartFailed sends a notification when an instance fails to start
-func (t *TelegramNotifier) NotifyInstanceStartFailed(instanceID, instanceName, region string, retryCount int, err error) error {
+// waitTimeout and pollInterval are set when the last attempt timed out waiting for Running.
+func (t *TelegramNotifier) NotifyInstanceStartFailed(instanceID, instanceName, region string, retryCount int, err error, waitTimeout, pollInterval time.Duration) error {
+	wait := ""
+	if waitTimeout > 0 {
+		wait = fmt.Sprintf("\n等待: %.0f 秒未进入 Running（每 %.0f 秒查询）", waitTimeout.Seconds(), pollInterval.Seconds())
+	}
 	message := fmt.Sprintf(`❌ <b>启动失败</b>
 ━━━━━━━━━━━━━━━
 实例: %s
 ID: <code>%s</code>
 区域: %s
 错误: %s
-重试: %d 次均失败
+重试: %d 次均失败%s
 ━━━━━━━━━━━━━━━
 请手动检查！`,
-		instanceName, instanceID, region, err.Error(), retryCount)
+		instanceName, instanceID, region, err.Error(), retryCount, wait)
+
+	return t.Send(message)
+}
+
+// NotifyStartGivenUp sends a summary when auto-start of an instance is paused after failing in consecutive checks
+func (t *TelegramNotifier) NotifyStartGivenUp(instanceID, instanceName, region string, failures int, since, until time.Time, err error) error {
+	message := fmt.Sprintf(`⛔ <b>暂停自动启动</b>
+━━━━━━━━━━━━━━━
+实例: %s
+ID: <code>%s</code>
+区域: %s
+连续失败: %d 次检查（自 %s 起）
+最后错误: %s
+恢复尝试: %s
+━━━━━━━━━━━━━━━
+期间不再自动启动，也不再发送失败通知；排查后可通过 Web 面板或 HTTP API 手动启动，实例运行后自动恢复`,
+		instanceName, instanceID, region, failures, since.Local().Format("01-02 15:04"), err.Error(), until.Local().Format("01-02 15:04"))
+
+	return t.Send(message)
+}
+
+// NotifyNoCapacity sends a notification when an instance can't start because its zone is sold out
+func (t *TelegramNotifier) NotifyNoCapacity(instanceID, instanceName, region, zone string, retryInterval time.Duration, err error) error {
+	message := fmt.Sprintf(`📦 <b>库存不足</b>
+━━━━━━━━━━━━━━━
+实例: %s
+ID: <code>%s</code>
+区域: %s
+可用区: %s
+错误: %s
+━━━━━━━━━━━━━━━
+可用区暂无抢占式实例库存，之后每 %.0f 分钟重试一次，恢复后通知启动结果`,
+		instanceName, instanceID, region, zone, err.Error(), retryInterval.Minutes())
+
+	return t.Send(message)
+}
+
+// NotifyInstanceRecreated sends a notification when an instance without capacity was re-created in another zone
+func (t *TelegramNotifier) NotifyInstanceRecreated(instanceID, instanceName, region, zone, newInstanceID, newZone, publicIP string, eipMoved bool) error {
+	ipInfo := publicIP
+	if ipInfo == "" {
+		ipInfo = "无"
+	}
+	if eipMoved {
+		ipInfo += " (EIP 已迁移)"
+	}
+
+	message := fmt.Sprintf(`♻️ <b>实例已在其他可用区重建</b>
+━━━━━━━━━━━━━━━
+实例: %s
+区域: %s
+原实例: <code>%s</code> (%s)
+新实例: <code>%s</code> (%s)
+公网IP: %s
+━━━━━━━━━━━━━━━
+原实例已保留为停止状态，不再自动启动，确认后请手动释放`,
+		instanceName, region, instanceID, zone, newInstanceID, newZone, ipInfo)
+
+	return t.Send(message)
+}
+
+// NotifyCPUCreditsExhausted sends a notification when a burstable instance runs out of CPU credits
+func (t *TelegramNotifier) NotifyCPUCreditsExhausted(instanceID, instanceName, region, instanceType string, balance, usage float64) error {
+	message := fmt.Sprintf(`🐢 <b>CPU 积分耗尽</b>
+━━━━━━━━━━━━━━━
+实例: %s
+ID: <code>%s</code>
+区域: %s
+规格: %s
+积分余额: %.1f（每分钟消耗 %.1f）
+━━━━━━━━━━━━━━━
+实例仍在运行，CPU 已被限制到基准性能，响应变慢并非回收；积分会随空闲时间恢复，也可改为无性能约束模式`,
+		instanceName, instanceID, region, instanceType, balance, usage)
+
+	return t.Send(message)
+}
+
+// NotifyPriceLimitNear sends a notification when the market price approaches the bid of a SpotWithPriceLimit instance
+func (t *TelegramNotifier) NotifyPriceLimitNear(instanceID, instanceName, region, zone string, marketPrice, priceLimit float64) error {
+	message := fmt.Sprintf(`📈 <b>市场价接近出价上限</b>
+━━━━━━━━━━━━━━━
+实例: %s
+ID: <code>%s</code>
+区域: %s (%s)
+市场价: ¥%.4f/时
+出价上限: ¥%.4f/时 (已达 %.0f%%)
+━━━━━━━━━━━━━━━
+市场价超过出价上限后实例将被回收，可使用 /pricelimit %s &lt;价格&gt; 提高出价`,
+		instanceName, instanceID, region, zone, marketPrice, priceLimit, marketPrice/priceLimit*100, instanceID)
+
+	return t.Send(message)
+}
+
+// NotifyInstanceRebid sends a notification when an instance was re-created with a higher bid
+func (t *TelegramNotifier) NotifyInstanceRebid(instanceID, instanceName, region, newInstanceID, publicIP string, oldLimit, newLimit float64, eipMoved bool) error {
+	ipInfo := publicIP
+	if ipInfo == "" {
+		ipInfo = "无"
+	}
+	if eipMoved {
+		ipInfo += " (EIP 已迁移)"
+	}
+
+	message := fmt.Sprintf(`💹 <b>已按新出价重建实例</b>
+━━━━━━━━━━━━━━━
+实例: %s
+区域: %s
+出价上限: ¥%.4f → ¥%.4f /时
+原实例: <code>%s</code>
+新实例: <code>%s</code>
+公网IP: %s
+━━━━━━━━━━━━━━━
+原实例已保留为停止状态，不再自动启动，确认后请手动释放`,
+		instanceName, region, oldLimit, newLimit, instanceID, newInstanceID, ipInfo)
+
+	return t.Send(message)
+}
+
+// NotifyInstanceStuck sends a notification when an instance is stuck in a transitional state
+func (t *TelegramNotifier) NotifyInstanceStuck(instanceID, instanceName, region, status string, duration time.Duration, remediate bool) error {
+	action := "请手动检查！"
+	if remediate {
+		action = "正在强制停止后重新启动..."
+	}
+
+	message := fmt.Sprintf(`⏳ <b>实例状态卡住</b>
+━━━━━━━━━━━━━━━
+实例: %s
+ID: <code>%s</code>
+区域: %s
+状态: %s
+持续时间: %.0f 分钟
+━━━━━━━━━━━━━━━
+%s`,
+		instanceName, instanceID, region, status, duration.Minutes(), action)
+
+	return t.Send(message)
+}
+
+// NotifyStuckRemediationFailed sends a notification when force stopping a stuck instance failed
+func (t *TelegramNotifier) NotifyStuckRemediationFailed(instanceID, instanceName, region string, err error) error {
+	message := fmt.Sprintf(`🧱 <b>强制停止失败</b>
+━━━━━━━━━━━━━━━
+实例: %s
+ID: <code>%s</code>
+区域: %s
+错误: %s
+━━━━━━━━━━━━━━━
+实例仍卡在中间状态，不会再次自动处理，请在控制台检查！`,
+		instanceName, instanceID, region, err.Error())
+
+	return t.Send(message)
+}
+
+// NotifyPlaybookStarted sends a notification when a recovery playbook starts
+func (t *TelegramNotifier) NotifyPlaybookStarted(instanceID, instanceName, region string, steps int) error {
+	message := fmt.Sprintf(`📋 <b>开始执行恢复剧本</b>
+━━━━━━━━━━━━━━━
+实例: %s
+ID: <code>%s</code>
+区域: %s
+步骤数: %d
+━━━━━━━━━━━━━━━`,
+		instanceName, instanceID, region, steps)
+
+	return t.Send(message)
+}
+
+// NotifyPlaybookStep sends a progress notification for a finished playbook step
+func (t *TelegramNotifier) NotifyPlaybookStep(instanceName string, index, total int, stepName string, attempts int, duration time.Duration, stepErr error) error {
+	if stepErr != nil {
+		return t.Send(fmt.Sprintf("❌ <b>%s</b> [%d/%d] %s 失败 (尝试 %d 次)\n<code>%s</code>",
+			instanceName, index, total, stepName, attempts, stepErr.Error()))
+	}
+	return t.Send(fmt.Sprintf("✅ <b>%s</b> [%d/%d] %s 完成 (%.0f 秒)",
+		instanceName, index, total, stepName, duration.Seconds()))
+}
+
+// NotifyPlaybookFailed sends a notification when a recovery playbook aborts
+func (t *TelegramNotifier) NotifyPlaybookFailed(instanceID, instanceName, region string, err error) error {
+	message := fmt.Sprintf(`🔴 <b>恢复剧本失败</b>
+━━━━━━━━━━━━━━━
+实例: %s
+ID: <code>%s</code>
+区域: %s
+错误: %s
+━━━━━━━━━━━━━━━
+实例已启动，但后续步骤未完成，请手动检查！`,
+		instanceName, instanceID, region, err.Error())
+
+	return t.Send(message)
+}
+
+// NotifyRegionBlacklisted sends a notification when a region is skipped after repeated failures
+func (t *TelegramNotifier) NotifyRegionBlacklisted(region string, failures int, until time.Time, lastError string) error {
+	message := fmt.Sprintf(`⛔ <b>区域已暂停扫描</b>
+━━━━━━━━━━━━━━━
+区域: %s
+连续失败: %d 次
+错误: %s
+恢复时间: %s
+━━━━━━━━━━━━━━━
+可能是账号未开通该区域，可使用 /regions reset %s 提前恢复`,
+		region, failures, lastError, until.Format("2006-01-02 15:04:05"), region)
 
 	return t.Send(message)
 }
 
 // NotifyHealthCheckTimeout sends a notification when health check times out
-func (t *TelegramNotifier) NotifyHealthCheckTimeout(instanceID, instanceName, region, publicIP string, timeout int) error {
+func (t *TelegramNotifier) NotifyHealthCheckTimeout(instanceID, instanceName, region, publicIP, checkName string, timeout int) error {
 	ipInfo := "无公网IP"
 	if publicIP != "" {
 		ipInfo = publicIP
@@ -144,17 +461,162 @@ func (t *TelegramNotifier) NotifyHealthCheckTimeout(instanceID, instanceName, re
 ID: <code>%s</code>
 区域: %s
 公网IP: <code>%s</code>
-检查类型: Ping
+检查类型: %s
 等待时间: %d 秒
 ━━━━━━━━━━━━━━━
 实例已启动但可能未就绪，请手动检查！`,
-		instanceName, instanceID, region, ipInfo, timeout)
+		instanceName, instanceID, region, ipInfo, checkName, timeout)
+
+	return t.Send(message)
+}
+
+// NotifyCredentialsRefreshed sends a notification when the AccessKey has been reloaded
+func (t *TelegramNotifier) NotifyCredentialsRefreshed(maskedKeyID, reason string) error {
+	message := fmt.Sprintf(`🔑 <b>凭证已刷新</b>
+━━━━━━━━━━━━━━━
+AccessKey: <code>%s</code>
+原因: %s
+时间: %s
+━━━━━━━━━━━━━━━
+所有阿里云客户端已使用新凭证重建`,
+		maskedKeyID, reason, time.Now().Format("2006-01-02 15:04:05"))
+
+	return t.Send(message)
+}
+
+// NotifyMaintenanceEvent sends a notification about a scheduled maintenance event
+func (t *TelegramNotifier) NotifyMaintenanceEvent(event *aliyun.SystemEvent, instanceName string) error {
+	reason := event.Reason
+	if reason == "" {
+		reason = "-"
+	}
+
+	message := fmt.Sprintf(`🛠 <b>计划维护事件</b>
+━━━━━━━━━━━━━━━
+实例: %s
+ID: <code>%s</code>
+区域: %s
+事件: %s
+事件ID: <code>%s</code>
+计划时间: %s
+原因: %s
+━━━━━━━━━━━━━━━
+这是阿里云计划运维，并非抢占式回收`,
+		instanceName, event.InstanceID, event.RegionID,
+		aliyun.GetEventTypeDisplayName(event.EventType), event.EventID,
+		event.NotBefore.Local().Format("2006-01-02 15:04:05"), reason)
+
+	return t.Send(message)
+}
+
+// NotifyInterruptionWarning sends a notification about an announced reclaim or maintenance reboot
+func (t *TelegramNotifier) NotifyInterruptionWarning(event *aliyun.SystemEvent, instanceName string, preShutdownSteps int) error {
+	action := "实例中断后将自动启动"
+	if preShutdownSteps > 0 {
+		action = fmt.Sprintf("正在执行关机前剧本 (%d 步)，实例中断后将自动启动", preShutdownSteps)
+	}
+
+	remaining := "-"
+	if until := time.Until(event.NotBefore); !event.NotBefore.IsZero() && until > 0 {
+		remaining = fmt.Sprintf("约 %d 分钟", int(until.Round(time.Minute).Minutes()))
+	}
+
+	message := fmt.Sprintf(`⚠️ <b>实例即将中断</b>
+━━━━━━━━━━━━━━━
+实例: %s
+ID: <code>%s</code>
+区域: %s
+事件: %s
+计划时间: %s (剩余 %s)
+━━━━━━━━━━━━━━━
+%s`,
+		instanceName, event.InstanceID, event.RegionID,
+		aliyun.GetEventTypeDisplayName(event.EventType),
+		event.NotBefore.Local().Format("2006-01-02 15:04:05"), remaining, action)
+
+	return t.Send(message)
+}
+
+// NotifyInstanceUnhealthy sends a notification when a running instance stops responding
+func (t *TelegramNotifier) NotifyInstanceUnhealthy(instanceID, instanceName, region, publicIP, checkName string, failures int, err error, reboot bool) error {
+	action := "实例在 ECS 中为 Running，但网络不可达，请手动检查！"
+	if reboot {
+		action = "实例在 ECS 中为 Running，但网络不可达，正在自动重启..."
+	}
+
+	message := fmt.Sprintf(`🩺 <b>实例无响应</b>
+━━━━━━━━━━━━━━━
+实例: %s
+ID: <code>%s</code>
+区域: %s
+公网IP: <code>%s</code>
+检查类型: %s
+连续失败: %d 次
+错误: %s
+━━━━━━━━━━━━━━━
+%s`,
+		instanceName, instanceID, region, publicIP, checkName, failures, err.Error(), action)
+
+	return t.Send(message)
+}
+
+// NotifyInstanceRecovered sends a notification when an unhealthy instance responds again
+func (t *TelegramNotifier) NotifyInstanceRecovered(instanceID, instanceName, region, checkName string) error {
+	message := fmt.Sprintf(`💚 <b>实例已恢复响应</b>
+━━━━━━━━━━━━━━━
+实例: %s
+ID: <code>%s</code>
+区域: %s
+检查类型: %s
+时间: %s
+━━━━━━━━━━━━━━━`,
+		instanceName, instanceID, region, checkName, time.Now().Format("2006-01-02 15:04:05"))
+
+	return t.Send(message)
+}
+
+// NotifyInstancesChanged sends a notification when re-discovery finds added or removed instances
+func (t *TelegramNotifier) NotifyInstancesChanged(added, removed []string) error {
+	var sb strings.Builder
+	sb.WriteString("🔄 <b>实例列表变更</b>\n")
+	sb.WriteString("━━━━━━━━━━━━━━━\n")
+	if len(added) > 0 {
+		sb.WriteString(fmt.Sprintf("<b>新增 %d 台:</b>", len(added)))
+		for _, inst := range added {
+			sb.WriteString(fmt.Sprintf("\n• %s", inst))
+		}
+		sb.WriteString("\n")
+	}
+	if len(removed) > 0 {
+		sb.WriteString(fmt.Sprintf("<b>移除 %d 台:</b>", len(removed)))
+		for _, inst := range removed {
+			sb.WriteString(fmt.Sprintf("\n• %s", inst))
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("━━━━━━━━━━━━━━━\n")
+	sb.WriteString(fmt.Sprintf("时间: %s", time.Now().Format("2006-01-02 15:04:05")))
+
+	return t.Send(sb.String())
+}
+
+// NotifyInstanceReleased sends a notification when a tracked instance no longer exists
+func (t *TelegramNotifier) NotifyInstanceReleased(instanceID, instanceName, region string) error {
+	message := fmt.Sprintf(`🗑 <b>实例已释放</b>
+━━━━━━━━━━━━━━━
+实例: %s
+ID: <code>%s</code>
+区域: %s
+时间: %s
+━━━━━━━━━━━━━━━
+实例已被释放或删除，不再监控`,
+		instanceName, instanceID, region, time.Now().Format("2006-01-02 15:04:05"))
 
 	return t.Send(message)
 }
 
 // NotifyMonitorStarted sends a notification when the monitor starts
-func (t *TelegramNotifier) NotifyMonitorStarted(instanceCount int, instances []string) error {
+func (t *TelegramNotifier) NotifyMonitorStarted(version string, instanceCount int, instances []string) error {
 	instanceList := ""
 	for _, inst := range instances {
 		instanceList += fmt.Sprintf("\n• %s", inst)
@@ -162,18 +624,40 @@ func (t *TelegramNotifier) NotifyMonitorStarted(instanceCount int, instances []s
 
 	message := fmt.Sprintf(`🚀 <b>监控已启动</b>
 ━━━━━━━━━━━━━━━
+版本: %s
 监控实例数: %d
 时间: %s
 ━━━━━━━━━━━━━━━
 <b>实例列表:</b>%s`,
-		instanceCount, time.Now().Format("2006-01-02 15:04:05"), instanceList)
+		version, instanceCount, time.Now().Format("2006-01-02 15:04:05"), instanceList)
 
 	return t.Send(message)
 }
 
 // NotifyBillingSummary sends a billing summary notification with monthly data and estimate
 func (t *TelegramNotifier) NotifyBillingSummary(summary *aliyun.BillingSummary) error {
-	if summary == nil || len(summary.Instances) == 0 {
+	if summary != nil && summary.PeriodLabel != "" && len(summary.Instances) == 0 && len(summary.Products) == 0 {
+		message := fmt.Sprintf(`📊 <b>扣费汇总</b> (%s)
+━━━━━━━━━━━━━━━━━━━━━━━━
+
+暂无扣费记录
+
+━━━━━━━━━━━━━━━━━━━━━━━━
+💰 区间合计: ¥0.00`, summary.PeriodLabel)
+		return t.Send(message)
+	}
+	if summary != nil && summary.Closed && len(summary.Instances) == 0 && len(summary.Products) == 0 {
+		message := fmt.Sprintf(`📊 <b>扣费汇总</b> (%s)
+━━━━━━━━━━━━━━━━━━━━━━━━
+
+该月没有监控实例的扣费记录
+<i>可能早于账号开通或实例创建时间</i>
+
+━━━━━━━━━━━━━━━━━━━━━━━━
+💰 该月合计: ¥0.00`, summary.BillingCycle)
+		return t.Send(message)
+	}
+	if summary == nil || (len(summary.Instances) == 0 && len(summary.Products) == 0) {
 		message := fmt.Sprintf(`📊 <b>扣费汇总</b> (%s)
 ━━━━━━━━━━━━━━━━━━━━━━━━
 
@@ -186,45 +670,88 @@ func (t *TelegramNotifier) NotifyBillingSummary(summary *aliyun.BillingSummary)
 	}
 
 	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("📊 <b>扣费汇总</b> (%s)\n", summary.BillingCycle))
-	sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━\n")
-	
-	// Statistics section
-	sb.WriteString(fmt.Sprintf("📅 统计区间: %s 01日 ~ %s\n",
-		summary.BillingCycle,
-		summary.EndTime.Format("02日 15:04")))
-	sb.WriteString(fmt.Sprintf("⏱ 已过天数: %d 天\n", summary.ElapsedDays))
+	if summary.PeriodLabel != "" {
+		sb.WriteString(fmt.Sprintf("📊 <b>扣费汇总</b> (%s)\n", summary.PeriodLabel))
+		sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━\n")
+		sb.WriteString(fmt.Sprintf("📅 统计区间: %s\n", formatPeriod(summary.StartTime, summary.EndTime)))
+		sb.WriteString(fmt.Sprintf("⏱ 统计天数: %d 天\n", summary.ElapsedDays))
+	} else if summary.Closed {
+		sb.WriteString(fmt.Sprintf("📊 <b>扣费汇总</b> (%s)\n", summary.BillingCycle))
+		sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━\n")
+		sb.WriteString(fmt.Sprintf("📅 统计区间: %s 01日 ~ %02d日 (已结束)\n", summary.BillingCycle, summary.ElapsedDays))
+		sb.WriteString(fmt.Sprintf("⏱ 账期天数: %d 天\n", summary.ElapsedDays))
+	} else {
+		sb.WriteString(fmt.Sprintf("📊 <b>扣费汇总</b> (%s)\n", summary.BillingCycle))
+		sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━\n")
+
+		// Statistics section
+		sb.WriteString(fmt.Sprintf("📅 统计区间: %s 01日 ~ %s\n",
+			summary.BillingCycle,
+			summary.EndTime.Format("02日 15:04")))
+		sb.WriteString(fmt.Sprintf("⏱ 已过天数: %d 天\n", summary.ElapsedDays))
+	}
+	if summary.Group != "" {
+		sb.WriteString(fmt.Sprintf("🏷 分组: %s\n", summary.Group))
+	}
 	sb.WriteString(fmt.Sprintf("🕐 总运行时长: %.1f 小时\n", summary.TotalRunningHours))
 	sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
 
-	for _, inst := range summary.Instances {
-		// Instance header with spec
-		if inst.InstanceSpec != "" {
-			sb.WriteString(fmt.Sprintf("🖥 <b>%s</b> [%s]\n", inst.InstanceName, inst.InstanceSpec))
-		} else {
-			sb.WriteString(fmt.Sprintf("🖥 <b>%s</b>\n", inst.InstanceName))
-		}
-		sb.WriteString(fmt.Sprintf("   <code>%s</code> | %s\n", inst.InstanceID, inst.Region))
-
-		// Billing items
-		for i, item := range inst.Items {
-			prefix := "├─"
-			if i == len(inst.Items)-1 {
-				prefix = "└─"
+	if groups := summary.GroupInstances(); groups != nil {
+		for _, group := range groups {
+			sb.WriteString(fmt.Sprintf("🏷 <b>%s</b> ¥%.4f", group.Name, group.TotalAmount))
+			if !summary.Closed && summary.PeriodLabel == "" {
+				sb.WriteString(fmt.Sprintf("，月度估算 ¥%.2f", group.MonthlyEstimate))
 			}
-			sb.WriteString(fmt.Sprintf("   %s %s: ¥%.4f\n", prefix, item.BillingItemName, item.PretaxAmount))
+			sb.WriteString("\n\n")
+			for _, inst := range group.Instances {
+				writeInstanceBilling(&sb, inst)
+			}
+		}
+	} else {
+		for _, inst := range summary.Instances {
+			writeInstanceBilling(&sb, inst)
 		}
+	}
 
-		// Instance subtotal with hourly cost
-		if inst.RunningHours > 0 && inst.HourlyCost > 0 {
-			sb.WriteString(fmt.Sprintf("   <b>小计: ¥%.4f</b> (%.1fh, ¥%.4f/h)\n\n", inst.TotalAmount, inst.RunningHours, inst.HourlyCost))
-		} else {
-			sb.WriteString(fmt.Sprintf("   <b>小计: ¥%.4f</b>\n\n", inst.TotalAmount))
+	// Non-ECS products cover all resources in the account
+	if len(summary.Products) > 0 {
+		sb.WriteString("📦 <b>其他产品</b>\n\n")
+		for _, product := range summary.Products {
+			name := product.ProductName
+			if name == "" {
+				name = product.ProductCode
+			}
+			sb.WriteString(fmt.Sprintf("🔹 <b>%s</b> [%s]", name, product.ProductCode))
+			if product.Resources > 0 {
+				sb.WriteString(fmt.Sprintf(" %d 个资源", product.Resources))
+			}
+			sb.WriteString("\n")
+			for i, item := range product.Items {
+				prefix := "├─"
+				if i == len(product.Items)-1 {
+					prefix = "└─"
+				}
+				sb.WriteString(fmt.Sprintf("   %s %s: ¥%.4f\n", prefix, item.BillingItemName, item.PretaxAmount))
+			}
+			sb.WriteString(fmt.Sprintf("   <b>小计: ¥%.4f</b>\n\n", product.TotalAmount))
 		}
 	}
 
 	sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━\n")
-	sb.WriteString(fmt.Sprintf("💰 <b>本月累计: ¥%.4f</b>\n", summary.TotalAmount))
+	if len(summary.Products) > 0 {
+		sb.WriteString(fmt.Sprintf("🖥 实例: ¥%.4f\n", summary.InstanceAmount))
+		sb.WriteString(fmt.Sprintf("📦 其他产品: ¥%.4f\n", summary.OtherAmount))
+	}
+	if summary.PeriodLabel != "" {
+		sb.WriteString(fmt.Sprintf("💰 <b>区间合计: ¥%.4f</b>\n", summary.TotalAmount))
+	} else if summary.Closed {
+		sb.WriteString(fmt.Sprintf("💰 <b>该月合计: ¥%.4f</b>\n", summary.TotalAmount))
+		writeSavings(&sb, summary)
+		return t.Send(sb.String())
+	} else {
+		sb.WriteString(fmt.Sprintf("💰 <b>本月累计: ¥%.4f</b>\n", summary.TotalAmount))
+	}
+	writeSavings(&sb, summary)
 	sb.WriteString(fmt.Sprintf("📈 <b>月度估算: ¥%.2f</b>\n", summary.MonthlyEstimate))
 	
 	// Show calculation method
@@ -235,6 +762,221 @@ func (t *TelegramNotifier) NotifyBillingSummary(summary *aliyun.BillingSummary)
 	return t.Send(sb.String())
 }
 
+// writeInstanceBilling writes the billing items and subtotal of an instance
+func writeInstanceBilling(sb *strings.Builder, inst aliyun.InstanceBillingSummary) {
+	// Instance header with spec
+	if inst.InstanceSpec != "" {
+		sb.WriteString(fmt.Sprintf("🖥 <b>%s</b> [%s]\n", inst.InstanceName, inst.InstanceSpec))
+	} else {
+		sb.WriteString(fmt.Sprintf("🖥 <b>%s</b>\n", inst.InstanceName))
+	}
+	sb.WriteString(fmt.Sprintf("   <code>%s</code> | %s\n", inst.InstanceID, inst.Region))
+
+	// Billing items
+	for i, item := range inst.Items {
+		prefix := "├─"
+		if i == len(inst.Items)-1 {
+			prefix = "└─"
+		}
+		sb.WriteString(fmt.Sprintf("   %s %s: ¥%.4f\n", prefix, item.BillingItemName, item.PretaxAmount))
+	}
+
+	// Instance subtotal with hourly cost
+	if inst.RunningHours > 0 && inst.HourlyCost > 0 {
+		sb.WriteString(fmt.Sprintf("   <b>小计: ¥%.4f</b> (%.1fh, ¥%.4f/h)\n", inst.TotalAmount, inst.RunningHours, inst.HourlyCost))
+	} else {
+		sb.WriteString(fmt.Sprintf("   <b>小计: ¥%.4f</b>\n", inst.TotalAmount))
+	}
+	if inst.OnDemandAmount > 0 {
+		sb.WriteString(fmt.Sprintf("   💸 计算费用按量付费约 ¥%.2f，节省 ¥%.2f\n", inst.OnDemandAmount, inst.OnDemandAmount-inst.ComputeAmount))
+	}
+	sb.WriteString("\n")
+}
+
+// sparkBlocks are the sparkline levels from lowest to highest
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// maxDailyBar is the width of the largest bar in the daily billing table
+const maxDailyBar = 12
+
+// NotifyDailyBilling sends the spend of each day as a sparkline and a table
+func (t *TelegramNotifier) NotifyDailyBilling(label string, days []aliyun.DailyBilling) error {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📅 <b>每日扣费</b> (%s)\n", label))
+	sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━\n")
+
+	var total, peak float64
+	peakIndex := -1
+	for i, day := range days {
+		total += day.Amount
+		if peakIndex < 0 || day.Amount > peak {
+			peak, peakIndex = day.Amount, i
+		}
+	}
+	if peakIndex < 0 || total <= 0 {
+		sb.WriteString("\n暂无扣费记录")
+		return t.Send(sb.String())
+	}
+
+	spark := make([]rune, len(days))
+	for i, day := range days {
+		spark[i] = sparkBlocks[int(day.Amount/peak*float64(len(sparkBlocks)-1)+0.5)]
+	}
+	sb.WriteString(fmt.Sprintf("<code>%s</code>\n", string(spark)))
+	sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+
+	weekdays := []string{"日", "一", "二", "三", "四", "五", "六"}
+	sb.WriteString("<code>")
+	for _, day := range days {
+		bar := strings.Repeat("█", int(day.Amount/peak*maxDailyBar+0.5))
+		sb.WriteString(fmt.Sprintf("%s %s ¥%8.2f %s\n", day.Date.Format("01-02"), weekdays[day.Date.Weekday()], day.Amount, bar))
+	}
+	sb.WriteString("</code>\n")
+
+	average := total / float64(len(days))
+	sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	sb.WriteString(fmt.Sprintf("💰 <b>合计: ¥%.2f</b>，日均 ¥%.2f\n", total, average))
+	peakDay := days[peakIndex]
+	sb.WriteString(fmt.Sprintf("🔺 最高: %s ¥%.2f", peakDay.Date.Format("01-02"), peakDay.Amount))
+	if average > 0 {
+		sb.WriteString(fmt.Sprintf(" (日均的 %.1f 倍)", peakDay.Amount/average))
+	}
+	sb.WriteString("\n")
+
+	// Break the peak day down by instance so the cause of a spike is visible
+	names := make([]string, 0, len(peakDay.ByInstance))
+	for name, amount := range peakDay.ByInstance {
+		if amount > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return peakDay.ByInstance[names[i]] > peakDay.ByInstance[names[j]] })
+	for i, name := range names {
+		if i == 3 {
+			break
+		}
+		sb.WriteString(fmt.Sprintf("   • %s: ¥%.2f\n", name, peakDay.ByInstance[name]))
+	}
+
+	return t.Send(sb.String())
+}
+
+// writeSavings adds the savings against pay-as-you-go pricing, if known
+func writeSavings(sb *strings.Builder, summary *aliyun.BillingSummary) {
+	if summary.OnDemandAmount <= 0 {
+		return
+	}
+	sb.WriteString(fmt.Sprintf("💸 <b>比按量付费节省: ¥%.2f (%.0f%%)</b>\n", summary.SavedAmount, summary.SavedPercent()))
+	sb.WriteString(fmt.Sprintf("   按量付费计算费用约 ¥%.2f\n", summary.OnDemandAmount))
+}
+
+// NotifyBandwidthReconciliation sends the bandwidth billing reconciliation
+func (t *TelegramNotifier) NotifyBandwidthReconciliation(r *aliyun.BandwidthReconciliation) error {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🧾 <b>带宽费用对账</b> (%s)\n", r.BillingCycle))
+	sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	sb.WriteString(fmt.Sprintf("📅 统计区间: %s 01日 ~ %s\n", r.BillingCycle, r.EndTime.Format("02日 15:04")))
+	sb.WriteString(fmt.Sprintf("📶 CDT 流量: %.2f GB\n", r.TrafficGB))
+	sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+
+	if len(r.Charges) == 0 {
+		sb.WriteString("本月暂无带宽/流量扣费\n\n")
+	}
+	for _, charge := range r.Charges {
+		icon := "🖥"
+		name := charge.InstanceName
+		switch {
+		case charge.ProductCode == "cdt":
+			icon = "🌐"
+			name = "CDT 流量"
+		case !charge.Tracked:
+			icon = "❓"
+			name = "未监控资源"
+		}
+		sb.WriteString(fmt.Sprintf("%s <b>%s</b> [%s]\n", icon, name, charge.ProductCode))
+		sb.WriteString(fmt.Sprintf("   <code>%s</code>", charge.ResourceID))
+		if charge.InternetIP != "" {
+			sb.WriteString(fmt.Sprintf(" | %s", charge.InternetIP))
+		}
+		sb.WriteString("\n")
+		sb.WriteString(fmt.Sprintf("   └─ %s: ¥%.4f\n\n", strings.Join(charge.BillingItems, "、"), charge.Amount))
+	}
+
+	sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	sb.WriteString(fmt.Sprintf("🖥 监控实例: ¥%.4f\n", r.TrackedAmount))
+	sb.WriteString(fmt.Sprintf("🌐 CDT 流量: ¥%.4f\n", r.CDTAmount))
+	sb.WriteString(fmt.Sprintf("❓ 未监控资源: ¥%.4f\n", r.UntrackedAmount))
+	sb.WriteString(fmt.Sprintf("💰 <b>带宽合计: ¥%.4f</b>\n", r.TotalAmount))
+
+	if len(r.Discrepancies) > 0 {
+		sb.WriteString("\n⚠️ <b>需要关注</b>\n")
+		for _, d := range r.Discrepancies {
+			sb.WriteString(fmt.Sprintf("• %s\n", d))
+		}
+	} else {
+		sb.WriteString("\n✅ 带宽费用与流量一致")
+	}
+
+	return t.Send(sb.String())
+}
+
+// NotifyBillingTrend sends this month's run rate compared with previous months
+func (t *TelegramNotifier) NotifyBillingTrend(trend *aliyun.BillingTrend) error {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📈 <b>扣费趋势</b> (%s", trend.Current.BillingCycle))
+	if n := len(trend.Previous); n > 0 {
+		sb.WriteString(fmt.Sprintf(" vs 前 %d 个月", n))
+	}
+	sb.WriteString(")\n")
+	sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	sb.WriteString(fmt.Sprintf("📅 本月已过 %d 天，本月按当前速度估算整月费用\n", trend.Current.ElapsedDays))
+	sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+
+	if len(trend.Instances) == 0 {
+		sb.WriteString("暂无扣费记录\n\n")
+	}
+	for _, inst := range trend.Instances {
+		sb.WriteString(fmt.Sprintf("🖥 <b>%s</b>\n", inst.InstanceName))
+		sb.WriteString(fmt.Sprintf("   ├─ 本月估算: ¥%.2f", inst.RunRate))
+		if amount, percent, ok := inst.Change(); ok {
+			sb.WriteString(fmt.Sprintf(" (%s)", formatBillingChange(amount, percent)))
+		} else if len(inst.Previous) > 0 && inst.RunRate > 0 {
+			sb.WriteString(" (上月无扣费)")
+		}
+		sb.WriteString("\n")
+		for i, amount := range inst.Previous {
+			prefix := "├─"
+			if i == len(inst.Previous)-1 {
+				prefix = "└─"
+			}
+			sb.WriteString(fmt.Sprintf("   %s %s: ¥%.2f\n", prefix, trend.Previous[i].BillingCycle, amount))
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	sb.WriteString(fmt.Sprintf("📈 <b>本月估算: ¥%.2f</b>", trend.Current.MonthlyEstimate))
+	if amount, percent, ok := trend.Change(); ok {
+		sb.WriteString(fmt.Sprintf(" (%s)", formatBillingChange(amount, percent)))
+	}
+	sb.WriteString("\n")
+	for _, summary := range trend.Previous {
+		sb.WriteString(fmt.Sprintf("💰 %s 合计: ¥%.2f\n", summary.BillingCycle, summary.TotalAmount))
+	}
+	sb.WriteString("<i>变化为本月估算与上月实际扣费之差</i>")
+
+	return t.Send(sb.String())
+}
+
+// formatBillingChange formats a cost change such as "🔺 +12.30, +15.2%"
+func formatBillingChange(amount, percent float64) string {
+	icon := "🔺"
+	if amount < 0 {
+		icon = "🔻"
+	}
+	return fmt.Sprintf("%s %+.2f, %+.1f%%", icon, amount, percent)
+}
+
 // NotifyTrafficSummary sends a traffic summary notification
 func (t *TelegramNotifier) NotifyTrafficSummary(summary *aliyun.TrafficSummary) error {
 	if summary == nil {
@@ -247,21 +989,43 @@ func (t *TelegramNotifier) NotifyTrafficSummary(summary *aliyun.TrafficSummary)
 		return t.Send(message)
 	}
 
+	title := "📶 <b>流量统计</b>"
+	if summary.Region != "" {
+		title = fmt.Sprintf("📶 <b>流量统计 · %s</b>", aliyun.GetRegionDisplayName(summary.Region))
+	}
+
 	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("📶 <b>流量统计</b> (%s)\n", summary.BillingCycle))
-	sb.WriteString("━━━━━━━━━━━━━━━━\n")
-	
-	// Statistics section
-	sb.WriteString(fmt.Sprintf("📅 统计区间: %s 01日 ~ %s\n",
-		summary.BillingCycle,
-		summary.EndTime.Format("02日 15:04")))
+	if summary.PeriodLabel != "" {
+		sb.WriteString(fmt.Sprintf("%s (%s)\n", title, summary.PeriodLabel))
+		sb.WriteString("━━━━━━━━━━━━━━━━\n")
+		sb.WriteString(fmt.Sprintf("📅 统计区间: %s\n", formatPeriod(summary.StartTime, summary.EndTime)))
+	} else {
+		sb.WriteString(fmt.Sprintf("%s (%s)\n", title, summary.BillingCycle))
+		sb.WriteString("━━━━━━━━━━━━━━━━\n")
+
+		// Statistics section
+		sb.WriteString(fmt.Sprintf("📅 统计区间: %s 01日 ~ %s\n",
+			summary.BillingCycle,
+			summary.EndTime.Format("02日 15:04")))
+	}
 	sb.WriteString("━━━━━━━━━━━━━━━━\n\n")
 
+	if len(summary.Groups) > 0 {
+		writeTrafficGroups(&sb, summary)
+		return t.Send(sb.String())
+	}
+
 	// China Mainland section
 	sb.WriteString("🇨🇳 <b>中国大陆</b>\n")
 	if summary.ChinaMainland.Traffic > 0 {
 		sb.WriteString(fmt.Sprintf("   📊 总流量: <b>%s</b>\n", aliyun.FormatTrafficSize(summary.ChinaMainland.Traffic)))
 		sb.WriteString(fmt.Sprintf("   🌐 区域数: %d\n", summary.ChinaMainland.RegionCount))
+		if quota := summary.ChinaMainland.QuotaGB; quota > 0 && summary.PeriodLabel == "" {
+			sb.WriteString(fmt.Sprintf("   🎁 免费额度: %.2f / %.0f GB (%.0f%%)\n", summary.ChinaMainland.TrafficGB, quota, summary.ChinaMainland.TrafficGB/quota*100))
+		}
+		if summary.ForecastMethod != "" {
+			writeTrafficForecast(&sb, summary.ChinaMainland)
+		}
 		// Product details
 		if len(summary.ChinaMainland.ProductDetails) > 0 {
 			sb.WriteString("   📦 产品明细:\n")
@@ -289,6 +1053,12 @@ func (t *TelegramNotifier) NotifyTrafficSummary(summary *aliyun.TrafficSummary)
 	if summary.NonChinaMainland.Traffic > 0 {
 		sb.WriteString(fmt.Sprintf("   📊 总流量: <b>%s</b>\n", aliyun.FormatTrafficSize(summary.NonChinaMainland.Traffic)))
 		sb.WriteString(fmt.Sprintf("   🌐 区域数: %d\n", summary.NonChinaMainland.RegionCount))
+		if quota := summary.NonChinaMainland.QuotaGB; quota > 0 && summary.PeriodLabel == "" {
+			sb.WriteString(fmt.Sprintf("   🎁 免费额度: %.2f / %.0f GB (%.0f%%)\n", summary.NonChinaMainland.TrafficGB, quota, summary.NonChinaMainland.TrafficGB/quota*100))
+		}
+		if summary.ForecastMethod != "" {
+			writeTrafficForecast(&sb, summary.NonChinaMainland)
+		}
 		// Product details
 		if len(summary.NonChinaMainland.ProductDetails) > 0 {
 			sb.WriteString("   📦 产品明细:\n")
@@ -322,6 +1092,68 @@ func (t *TelegramNotifier) NotifyTrafficSummary(summary *aliyun.TrafficSummary)
 		nonChinaPercent := float64(summary.NonChinaMainland.Traffic) / float64(summary.TotalTraffic) * 100
 		sb.WriteString(fmt.Sprintf("📊 中国大陆: %.1f%% | 非中国大陆: %.1f%%", chinaPercent, nonChinaPercent))
 	}
+	if summary.ForecastMethod != "" {
+		if cost := summary.ChinaMainland.OverageCost() + summary.NonChinaMainland.OverageCost(); cost > 0 {
+			sb.WriteString(fmt.Sprintf("\n💸 <b>预计超额费用: ¥%.2f</b>", cost))
+		}
+	}
 
 	return t.Send(sb.String())
-}
\ No newline at end of file
+}
+
+// writeTrafficGroups writes the user-defined region groups and the totals of a traffic summary
+func writeTrafficGroups(sb *strings.Builder, summary *aliyun.TrafficSummary) {
+	for _, group := range summary.Groups {
+		sb.WriteString(fmt.Sprintf("📍 <b>%s</b>\n", group.Name))
+		sb.WriteString(fmt.Sprintf("   📊 总流量: <b>%s</b>\n", aliyun.FormatTrafficSize(group.Traffic)))
+		if len(group.ProductDetails) > 0 {
+			sb.WriteString("   📦 产品明细:\n")
+			for product, traffic := range group.ProductDetails {
+				if traffic > 0 {
+					sb.WriteString(fmt.Sprintf("      • %s: %s\n", product, aliyun.FormatTrafficSize(traffic)))
+				}
+			}
+		}
+		sb.WriteString("   🌐 区域明细:\n")
+		for _, detail := range summary.RegionDetails {
+			for _, region := range group.Regions {
+				if detail.BusinessRegionId == region && detail.Traffic > 0 {
+					sb.WriteString(fmt.Sprintf("      • %s: %s\n", aliyun.GetRegionDisplayName(region), aliyun.FormatTrafficSize(detail.Traffic)))
+				}
+			}
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("━━━━━━━━━━━━━━━━\n")
+	sb.WriteString(fmt.Sprintf("📈 <b>总流量: %s</b>\n", aliyun.FormatTrafficSize(summary.TotalTraffic)))
+	if summary.TotalTraffic > 0 {
+		parts := make([]string, 0, len(summary.Groups))
+		for _, group := range summary.Groups {
+			parts = append(parts, fmt.Sprintf("%s: %.1f%%", group.Name, float64(group.Traffic)/float64(summary.TotalTraffic)*100))
+		}
+		sb.WriteString("📊 " + strings.Join(parts, " | "))
+	}
+	if summary.ForecastMethod != "" {
+		if cost := summary.ChinaMainland.OverageCost() + summary.NonChinaMainland.OverageCost(); cost > 0 {
+			sb.WriteString(fmt.Sprintf("\n💸 <b>预计超额费用: ¥%.2f</b>", cost))
+		}
+	}
+}
+
+// writeTrafficForecast writes the month-end forecast of a region group
+func writeTrafficForecast(sb *strings.Builder, region aliyun.TrafficRegionSummary) {
+	sb.WriteString(fmt.Sprintf("   🔮 月末预计: %.2f GB\n", region.ForecastGB))
+	if overage := region.OverageGB(); overage > 0 {
+		if region.PricePerGB > 0 {
+			sb.WriteString(fmt.Sprintf("   💸 预计超额: %.2f GB ≈ ¥%.2f\n", overage, region.OverageCost()))
+		} else {
+			sb.WriteString(fmt.Sprintf("   💸 预计超额: %.2f GB\n", overage))
+		}
+	}
+}
+
+// formatPeriod formats a report time range, e.g. "01-06 00:00 ~ 01-07 00:00"
+func formatPeriod(start, end time.Time) string {
+	return fmt.Sprintf("%s ~ %s", start.Format("01-02 15:04"), end.Format("01-02 15:04"))
+}
@@ -0,0 +1,214 @@
+package playbook
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	"github.com/iliyian/aliyun-spot-manager/internal/config"
+	"github.com/iliyian/aliyun-spot-manager/internal/limit"
+	log "github.com/sirupsen/logrus"
+)
+
+// StepFunc executes a single attempt of a playbook step against an instance
+type StepFunc func(ctx context.Context, step config.PlaybookStep, inst *aliyun.SpotInstance) error
+
+// StepResult is the outcome of a playbook step
+type StepResult struct {
+	Index    int // 1-based
+	Total    int
+	Step     config.PlaybookStep
+	Attempts int
+	Duration time.Duration
+	Err      error
+}
+
+// Runner runs playbooks using a registry of step types
+type Runner struct {
+	steps   map[string]StepFunc
+	limiter *limit.Limiter
+
+	// OnStep is called after each step finishes, e.g. to send progress notifications
+	OnStep func(inst *aliyun.SpotInstance, result StepResult)
+
+	// Lookup returns the latest details of an instance before each step, so
+	// steps after one that changed its address see the new one; nil or a nil
+	// result keeps the details the playbook started with
+	Lookup func(instanceID string) *aliyun.SpotInstance
+}
+
+// NewRunner creates a runner with the built-in http, command and sleep steps
+// http and command steps share the limiter's slots; a nil limiter means unlimited
+func NewRunner(limiter *limit.Limiter) *Runner {
+	r := &Runner{steps: make(map[string]StepFunc), limiter: limiter}
+	r.Register("http", r.limited(httpStep, httpStepHost))
+	r.Register("command", r.limited(commandStep, func(_ config.PlaybookStep, inst *aliyun.SpotInstance) string {
+		return instanceHost(inst)
+	}))
+	r.Register("sleep", sleepStep)
+	return r
+}
+
+// limited wraps a step so each attempt holds a limiter slot for the host returned by hostFn
+func (r *Runner) limited(fn StepFunc, hostFn func(config.PlaybookStep, *aliyun.SpotInstance) string) StepFunc {
+	return func(ctx context.Context, step config.PlaybookStep, inst *aliyun.SpotInstance) error {
+		release, err := r.limiter.Acquire(ctx, hostFn(step, inst))
+		if err != nil {
+			return fmt.Errorf("waiting for a free hook slot: %w", err)
+		}
+		defer release()
+		return fn(ctx, step, inst)
+	}
+}
+
+// Register adds or replaces a step type
+func (r *Runner) Register(kind string, fn StepFunc) {
+	r.steps[kind] = fn
+}
+
+// Validate checks that every step has a known type
+func (r *Runner) Validate(steps []config.PlaybookStep) error {
+	for i, step := range steps {
+		if _, ok := r.steps[step.Type]; !ok {
+			return fmt.Errorf("step %d (%s): unknown type %q", i+1, step.DisplayName(), step.Type)
+		}
+	}
+	return nil
+}
+
+// Run executes the steps in order, retrying failed steps as configured
+// It stops at the first failing step unless that step has continue_on_error set
+func (r *Runner) Run(ctx context.Context, steps []config.PlaybookStep, inst *aliyun.SpotInstance) ([]StepResult, error) {
+	results := make([]StepResult, 0, len(steps))
+
+	for i, step := range steps {
+		if r.Lookup != nil {
+			if latest := r.Lookup(inst.InstanceID); latest != nil {
+				inst = latest
+			}
+		}
+		result := r.runStep(ctx, step, inst)
+		result.Index = i + 1
+		result.Total = len(steps)
+		results = append(results, result)
+
+		if r.OnStep != nil {
+			r.OnStep(inst, result)
+		}
+
+		if result.Err != nil {
+			if step.ContinueOnError {
+				log.Warnf("Playbook step %d/%d (%s) for %s failed, continuing: %v",
+					result.Index, result.Total, step.DisplayName(), inst.InstanceID, result.Err)
+				continue
+			}
+			return results, fmt.Errorf("step %d/%d (%s) failed: %w", result.Index, result.Total, step.DisplayName(), result.Err)
+		}
+	}
+
+	return results, nil
+}
+
+// runStep runs one step with retries
+func (r *Runner) runStep(ctx context.Context, step config.PlaybookStep, inst *aliyun.SpotInstance) StepResult {
+	result := StepResult{Step: step}
+	fn, ok := r.steps[step.Type]
+	if !ok {
+		result.Err = fmt.Errorf("unknown step type %q", step.Type)
+		return result
+	}
+
+	interval := time.Duration(step.RetryInterval) * time.Second
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	start := time.Now()
+	for attempt := 0; attempt <= step.Retries; attempt++ {
+		if attempt > 0 {
+			log.Infof("Retrying playbook step %s for %s (%d/%d)", step.DisplayName(), inst.InstanceID, attempt, step.Retries)
+			select {
+			case <-ctx.Done():
+				result.Err = ctx.Err()
+				result.Duration = time.Since(start)
+				return result
+			case <-time.After(interval):
+			}
+		}
+
+		result.Attempts++
+		result.Err = r.attempt(ctx, fn, step, inst)
+		if result.Err == nil {
+			break
+		}
+		log.Debugf("Playbook step %s for %s failed: %v", step.DisplayName(), inst.InstanceID, result.Err)
+	}
+	result.Duration = time.Since(start)
+	return result
+}
+
+// attempt runs a single attempt, applying the step timeout if set
+func (r *Runner) attempt(ctx context.Context, fn StepFunc, step config.PlaybookStep, inst *aliyun.SpotInstance) error {
+	if step.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(step.Timeout)*time.Second)
+		defer cancel()
+	}
+	return fn(ctx, step, inst)
+}
+
+// Expand replaces {id}, {name}, {region} and {ip} placeholders with instance values
+func Expand(s string, inst *aliyun.SpotInstance) string {
+	return expand(s, inst, inst.HealthCheckAddress())
+}
+
+// ExpandShell is Expand for shell commands, each value is single-quoted
+func ExpandShell(s string, inst *aliyun.SpotInstance) string {
+	return strings.NewReplacer(
+		"{id}", shellQuote(inst.InstanceID),
+		"{name}", shellQuote(inst.InstanceName),
+		"{region}", shellQuote(inst.RegionID),
+		"{ip}", shellQuote(inst.HealthCheckAddress()),
+	).Replace(s)
+}
+
+// shellQuote quotes s as a single shell word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// ExpandURL is Expand for URLs. {ip} in the host is bracketed when it is an
+// IPv6 address, so "http://{ip}:8080/" stays a valid URL, while "?ip={ip}"
+// in the query gets the bare address.
+func ExpandURL(s string, inst *aliyun.SpotInstance) string {
+	ip := inst.HealthCheckAddress()
+	parsed := net.ParseIP(ip)
+	if parsed == nil || parsed.To4() != nil {
+		return Expand(s, inst)
+	}
+
+	// The host runs from the scheme to the path, query or fragment
+	hostStart := strings.Index(s, "://")
+	if hostStart < 0 {
+		return Expand(s, inst)
+	}
+	hostStart += len("://")
+	hostEnd := len(s)
+	if i := strings.IndexAny(s[hostStart:], "/?#"); i >= 0 {
+		hostEnd = hostStart + i
+	}
+	return expand(s[:hostStart], inst, ip) + expand(s[hostStart:hostEnd], inst, "["+ip+"]") + expand(s[hostEnd:], inst, ip)
+}
+
+// expand replaces the placeholders, {ip} with the given address
+func expand(s string, inst *aliyun.SpotInstance, ip string) string {
+	return strings.NewReplacer(
+		"{id}", inst.InstanceID,
+		"{name}", inst.InstanceName,
+		"{region}", inst.RegionID,
+		"{ip}", ip,
+	).Replace(s)
+}
@@ -0,0 +1,122 @@
+package playbook
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/aliyun"
+	"github.com/iliyian/aliyun-spot-manager/internal/config"
+)
+
+// withDefaultTimeout applies a timeout when the context has no deadline
+func withDefaultTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// instanceHost returns the instance address, which commands usually talk to
+func instanceHost(inst *aliyun.SpotInstance) string {
+	if host := inst.HealthCheckAddress(); host != "" {
+		return host
+	}
+	return inst.InstanceID
+}
+
+// httpStepHost returns the host an http step calls, e.g. a webhook endpoint
+func httpStepHost(step config.PlaybookStep, inst *aliyun.SpotInstance) string {
+	u, err := url.Parse(ExpandURL(step.URL, inst))
+	if err != nil || u.Hostname() == "" {
+		return instanceHost(inst)
+	}
+	return u.Hostname()
+}
+
+// httpStep requests a URL and checks the status code and body
+func httpStep(ctx context.Context, step config.PlaybookStep, inst *aliyun.SpotInstance) error {
+	if step.URL == "" {
+		return fmt.Errorf("http step requires a url")
+	}
+	ctx, cancel := withDefaultTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	url := ExpandURL(step.URL, inst)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("invalid url %s: %w", url, err)
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			// Freshly started instances are often addressed by IP, so certificates can't match
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+	defer client.CloseIdleConnections()
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to %s failed: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if step.ExpectStatus != 0 {
+		if resp.StatusCode != step.ExpectStatus {
+			return fmt.Errorf("%s returned status %d, expected %d", url, resp.StatusCode, step.ExpectStatus)
+		}
+	} else if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s returned status %d", url, resp.StatusCode)
+	}
+
+	if step.ExpectBody != "" {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if err != nil {
+			return fmt.Errorf("failed to read response from %s: %w", url, err)
+		}
+		if !strings.Contains(string(body), step.ExpectBody) {
+			return fmt.Errorf("response from %s does not contain %q", url, step.ExpectBody)
+		}
+	}
+	return nil
+}
+
+// commandStep runs a local shell command with the instance details in its environment
+// Placeholders are shell-quoted, so instance names can't inject commands
+func commandStep(ctx context.Context, step config.PlaybookStep, inst *aliyun.SpotInstance) error {
+	if step.Command == "" {
+		return fmt.Errorf("command step requires a command")
+	}
+	ctx, cancel := withDefaultTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", ExpandShell(step.Command, inst))
+	cmd.Env = append(os.Environ(),
+		"SPOT_INSTANCE_ID="+inst.InstanceID,
+		"SPOT_INSTANCE_NAME="+inst.InstanceName,
+		"SPOT_INSTANCE_REGION="+inst.RegionID,
+		"SPOT_INSTANCE_IP="+inst.HealthCheckAddress(),
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("command failed: %w (output: %s)", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// sleepStep waits for the configured number of seconds
+func sleepStep(ctx context.Context, step config.PlaybookStep, _ *aliyun.SpotInstance) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Duration(step.Seconds) * time.Second):
+		return nil
+	}
+}
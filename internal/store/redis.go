@@ -0,0 +1,292 @@
+package store
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redisTimeout bounds connecting and each command
+const redisTimeout = 5 * time.Second
+
+// redisIdempotent lists the commands that are safe to send twice, e.g. when
+// the connection broke after the server may already have run them
+var redisIdempotent = map[string]bool{
+	"PING":    true,
+	"HSET":    true,
+	"HDEL":    true,
+	"HGETALL": true,
+	"LRANGE":  true,
+	"LTRIM":   true,
+}
+
+// redisError is an error reply of the server
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// redisStore keeps the state in Redis so several processes can share it.
+// Buckets are hashes, logs are lists, named with the key prefix first.
+type redisStore struct {
+	addr     string
+	useTLS   bool
+	username string
+	password string
+	db       int
+	prefix   string
+
+	mu   sync.Mutex // one command at a time on the connection
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+// OpenRedis connects to the Redis server at rawURL, e.g.
+// redis://:password@host:6379/0 or rediss:// for TLS. Keys start with prefix.
+func OpenRedis(rawURL, prefix string) (Store, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("invalid Redis URL scheme %q, must be redis or rediss", u.Scheme)
+	}
+
+	s := &redisStore{addr: u.Host, useTLS: u.Scheme == "rediss", prefix: prefix}
+	if u.Port() == "" {
+		s.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		s.username = u.User.Username()
+		s.password, _ = u.User.Password()
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		if s.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid Redis database %q", db)
+		}
+	}
+
+	// Fail at startup rather than on the first write
+	if _, err := s.do("PING"); err != nil {
+		return nil, fmt.Errorf("failed to connect to Redis at %s: %w", s.addr, err)
+	}
+	return s, nil
+}
+
+// Put stores v as JSON under key
+func (s *redisStore) Put(bucket, key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s/%s: %w", bucket, key, err)
+	}
+	_, err = s.do("HSET", s.prefix+bucket, key, string(data))
+	return err
+}
+
+// Delete removes key from a bucket
+func (s *redisStore) Delete(bucket, key string) error {
+	_, err := s.do("HDEL", s.prefix+bucket, key)
+	return err
+}
+
+// ForEach calls fn for every key in a bucket; a missing bucket is empty
+func (s *redisStore) ForEach(bucket string, fn func(key string, data []byte) error) error {
+	reply, err := s.do("HGETALL", s.prefix+bucket)
+	if err != nil {
+		return err
+	}
+	fields, _ := reply.([]interface{})
+	for i := 0; i+1 < len(fields); i += 2 {
+		key, _ := fields[i].(string)
+		data, _ := fields[i+1].(string)
+		if err := fn(key, []byte(data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Append adds v to the end of a log bucket and drops the oldest entries beyond keep
+func (s *redisStore) Append(bucket string, v interface{}, keep int) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s entry: %w", bucket, err)
+	}
+	if _, err := s.do("RPUSH", s.prefix+bucket, string(data)); err != nil {
+		return err
+	}
+	if keep <= 0 {
+		return nil
+	}
+	_, err = s.do("LTRIM", s.prefix+bucket, strconv.Itoa(-keep), "-1")
+	return err
+}
+
+// Tail calls fn for the last n entries of a log bucket, oldest first
+func (s *redisStore) Tail(bucket string, n int, fn func(data []byte) error) error {
+	start := 0
+	if n > 0 {
+		start = -n
+	}
+	reply, err := s.do("LRANGE", s.prefix+bucket, strconv.Itoa(start), "-1")
+	if err != nil {
+		return err
+	}
+	entries, _ := reply.([]interface{})
+	for _, entry := range entries {
+		data, _ := entry.(string)
+		if err := fn([]byte(data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the connection
+func (s *redisStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// do sends a command and returns its reply, connecting first if needed. A
+// broken connection is dropped and the command retried once on a new one,
+// unless it isn't idempotent and the server may have run it already: a
+// retried RPUSH would append the entry twice.
+func (s *redisStore) do(args ...string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if s.conn == nil {
+			if err = s.connect(); err != nil {
+				continue
+			}
+		}
+		var reply interface{}
+		reply, err = s.roundTrip(args)
+		var replyErr redisError
+		if err == nil || errors.As(err, &replyErr) {
+			return reply, err
+		}
+		s.conn.Close()
+		s.conn = nil
+		if !redisIdempotent[args[0]] {
+			break
+		}
+	}
+	return nil, err
+}
+
+// connect dials the server, authenticates and selects the database
+// The caller must hold s.mu.
+func (s *redisStore) connect() error {
+	dialer := &net.Dialer{Timeout: redisTimeout}
+	var conn net.Conn
+	var err error
+	if s.useTLS {
+		host, _, _ := net.SplitHostPort(s.addr)
+		conn, err = tls.DialWithDialer(dialer, "tcp", s.addr, &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12})
+	} else {
+		conn, err = dialer.Dial("tcp", s.addr)
+	}
+	if err != nil {
+		return err
+	}
+	s.conn, s.rd = conn, bufio.NewReader(conn)
+
+	var setup [][]string
+	if s.password != "" {
+		if s.username != "" {
+			setup = append(setup, []string{"AUTH", s.username, s.password})
+		} else {
+			setup = append(setup, []string{"AUTH", s.password})
+		}
+	}
+	if s.db != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(s.db)})
+	}
+	for _, args := range setup {
+		if _, err := s.roundTrip(args); err != nil {
+			conn.Close()
+			s.conn = nil
+			return fmt.Errorf("%s failed: %w", args[0], err)
+		}
+	}
+	return nil
+}
+
+// roundTrip writes a command in RESP and reads the reply
+// The caller must hold s.mu.
+func (s *redisStore) roundTrip(args []string) (interface{}, error) {
+	if err := s.conn.SetDeadline(time.Now().Add(redisTimeout)); err != nil {
+		return nil, err
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("*%d\r\n", len(args)))
+	for _, arg := range args {
+		sb.WriteString(fmt.Sprintf("$%d\r\n%s\r\n", len(arg), arg))
+	}
+	if _, err := io.WriteString(s.conn, sb.String()); err != nil {
+		return nil, err
+	}
+	return s.readReply()
+}
+
+// readReply reads one RESP reply: strings, integers, nil or arrays of them
+func (s *redisStore) readReply() (interface{}, error) {
+	line, err := s.rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(s.rd, data); err != nil {
+			return nil, err
+		}
+		return string(data[:size]), nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil || count < 0 {
+			return nil, err
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			if items[i], err = s.readReply(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
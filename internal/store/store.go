@@ -0,0 +1,172 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// fileName is the database file inside the data directory
+const fileName = "state.db"
+
+// Store persists monitor state so it survives restarts
+// Values are stored as JSON in named buckets, keyed maps or append-only logs.
+type Store interface {
+	// Put stores v as JSON under key
+	Put(bucket, key string, v interface{}) error
+	// Delete removes key from a bucket
+	Delete(bucket, key string) error
+	// ForEach calls fn for every key in a bucket; a missing bucket is empty
+	ForEach(bucket string, fn func(key string, data []byte) error) error
+	// Append adds v to the end of a log bucket and drops the oldest entries
+	// beyond keep; a keep of 0 or less keeps everything
+	Append(bucket string, v interface{}, keep int) error
+	// Tail calls fn for the last n entries of a log bucket, oldest first;
+	// an n of 0 or less returns every entry
+	Tail(bucket string, n int, fn func(data []byte) error) error
+	// Close releases the store
+	Close() error
+}
+
+// boltStore keeps the state in a BoltDB file in the data directory
+type boltStore struct {
+	db *bolt.DB
+}
+
+// Open opens (or creates) the state database in dataDir
+func Open(dataDir string) (Store, error) {
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create data dir %s: %w", dataDir, err)
+	}
+
+	path := filepath.Join(dataDir, fileName)
+	// A second process holding the lock would otherwise block forever
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state database %s: %w", path, err)
+	}
+	return &boltStore{db: db}, nil
+}
+
+// Close closes the database
+func (s *boltStore) Close() error {
+	return s.db.Close()
+}
+
+// Put stores v as JSON under key
+func (s *boltStore) Put(bucket, key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s/%s: %w", bucket, key, err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(key), data)
+	})
+}
+
+// Delete removes key from a bucket
+func (s *boltStore) Delete(bucket, key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+		return b.Delete([]byte(key))
+	})
+}
+
+// ForEach calls fn for every key in a bucket; a missing bucket is empty
+func (s *boltStore) ForEach(bucket string, fn func(key string, data []byte) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			return fn(string(k), v)
+		})
+	})
+}
+
+// Append adds v to the end of a log bucket and drops the oldest entries beyond keep
+// A keep of 0 or less keeps everything
+func (s *boltStore) Append(bucket string, v interface{}, keep int) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s entry: %w", bucket, err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		if err != nil {
+			return err
+		}
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		if err := b.Put(sequenceKey(seq), data); err != nil {
+			return err
+		}
+
+		if keep <= 0 || seq <= uint64(keep) {
+			return nil
+		}
+		// Sequence numbers only grow, so everything up to seq-keep is too old
+		cutoff := sequenceKey(seq - uint64(keep))
+		// Deleting moves the cursor, so start from the first key again each time
+		c := b.Cursor()
+		for k, _ := c.First(); k != nil && bytes.Compare(k, cutoff) <= 0; k, _ = c.First() {
+			if err := c.Delete(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Tail calls fn for the last n entries of a log bucket, oldest first
+// An n of 0 or less returns every entry
+func (s *boltStore) Tail(bucket string, n int, fn func(data []byte) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+
+		c := b.Cursor()
+		k, _ := c.Last()
+		for i := 1; k != nil && (n <= 0 || i < n); i++ {
+			prev, _ := c.Prev()
+			if prev == nil {
+				break
+			}
+			k = prev
+		}
+		if k == nil {
+			return nil
+		}
+		for k, v := c.Seek(k); k != nil; k, v = c.Next() {
+			if err := fn(v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// sequenceKey encodes a sequence number so keys sort in insertion order
+func sequenceKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
@@ -0,0 +1,60 @@
+// Package version holds the build information set via -ldflags
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Set at build time, e.g.
+// go build -ldflags "-X github.com/iliyian/aliyun-spot-manager/internal/version.Version=v1.2.0"
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildDate = ""
+)
+
+// Info returns the build information, falling back to the VCS stamp
+// embedded by the Go toolchain when the commit was not set
+func Info() (version, commit, buildDate string) {
+	version, commit, buildDate = Version, Commit, BuildDate
+	if commit != "" {
+		return
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				commit = setting.Value
+			case "vcs.time":
+				if buildDate == "" {
+					buildDate = setting.Value
+				}
+			}
+		}
+	}
+	return
+}
+
+// ShortCommit returns the first 7 characters of the commit
+func ShortCommit() string {
+	_, commit, _ := Info()
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
+
+// String returns a one-line description of the build
+func String() string {
+	version, _, buildDate := Info()
+	s := version
+	if commit := ShortCommit(); commit != "" {
+		s += " (" + commit + ")"
+	}
+	if buildDate != "" {
+		s += " built " + buildDate
+	}
+	return fmt.Sprintf("%s %s/%s %s", s, runtime.GOOS, runtime.GOARCH, runtime.Version())
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/config"
+	"github.com/iliyian/aliyun-spot-manager/internal/leader"
+	log "github.com/sirupsen/logrus"
+)
+
+// acquireLeadership blocks until this replica holds the Lease, returning
+// the Lease to release after shutting down and a context cancelled when the
+// leadership is lost so the replica shuts down and restarts as a standby.
+// It fails only when ctx is cancelled first.
+func acquireLeadership(ctx context.Context, cfg *config.Config) (*leader.Lease, context.Context, error) {
+	lease, err := leader.NewLease(leader.Options{
+		Name:      cfg.LeaderElectionLease,
+		Namespace: cfg.LeaderElectionNamespace,
+		Duration:  time.Duration(cfg.LeaderElectionDuration) * time.Second,
+	})
+	if err != nil {
+		log.Fatalf("Failed to setup leader election: %v", err)
+	}
+
+	log.Infof("Waiting for leadership as %s...", lease.Identity())
+	leaderCtx, err := lease.Acquire(ctx)
+	return lease, leaderCtx, err
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// logSink is a logrus hook that writes entries at or above its own level
+// to a writer with its own formatter, so stdout and the log file can differ
+type logSink struct {
+	writer    io.Writer
+	formatter log.Formatter
+	level     log.Level
+	mu        sync.Mutex
+}
+
+// Levels implements log.Hook
+func (s *logSink) Levels() []log.Level {
+	return log.AllLevels[:s.level+1]
+}
+
+// Fire implements log.Hook
+func (s *logSink) Fire(entry *log.Entry) error {
+	data, err := s.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.writer.Write(data)
+	return err
+}
+
+func setupLogging(cfg *config.Config) {
+	stdoutLevel := parseLogLevel(cfg.LogLevel, log.InfoLevel)
+	stdoutSink := &logSink{
+		writer:    os.Stdout,
+		formatter: newLogFormatter(cfg.LogFormat),
+		level:     stdoutLevel,
+	}
+
+	// All output goes through the sinks
+	log.SetOutput(io.Discard)
+	log.AddHook(stdoutSink)
+	maxLevel := stdoutLevel
+
+	// Set log file output
+	if cfg.LogFile != "" {
+		file, err := os.OpenFile(cfg.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+		if err != nil {
+			log.SetLevel(maxLevel)
+			log.Warnf("Failed to open log file %s, using stdout only: %v", cfg.LogFile, err)
+			return
+		}
+
+		fileLevel := parseLogLevel(cfg.LogFileLevel, stdoutLevel)
+		log.AddHook(&logSink{
+			writer:    file,
+			formatter: newLogFormatter(cfg.LogFileFormat),
+			level:     fileLevel,
+		})
+		if fileLevel > maxLevel {
+			maxLevel = fileLevel
+		}
+	}
+
+	// The logger must let through the most verbose level any sink wants
+	log.SetLevel(maxLevel)
+}
+
+// parseLogLevel parses a level name, falling back to def if empty or invalid
+func parseLogLevel(name string, def log.Level) log.Level {
+	if name == "" {
+		return def
+	}
+	level, err := log.ParseLevel(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid log level %q, using %s\n", name, def)
+		return def
+	}
+	return level
+}
+
+// newLogFormatter returns a JSON or human-readable text formatter
+func newLogFormatter(format string) log.Formatter {
+	if format == "json" {
+		return &log.JSONFormatter{
+			TimestampFormat: "2006-01-02T15:04:05.000Z07:00",
+		}
+	}
+	return &log.TextFormatter{
+		FullTimestamp:   true,
+		TimestampFormat: "2006-01-02 15:04:05",
+	}
+}
@@ -1,23 +1,70 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/iliyian/aliyun-spot-manager/internal/api"
 	"github.com/iliyian/aliyun-spot-manager/internal/config"
+	"github.com/iliyian/aliyun-spot-manager/internal/leader"
 	"github.com/iliyian/aliyun-spot-manager/internal/monitor"
+	"github.com/iliyian/aliyun-spot-manager/internal/tracing"
+	"github.com/iliyian/aliyun-spot-manager/internal/version"
 	"github.com/joho/godotenv"
 	"github.com/robfig/cron/v3"
 	log "github.com/sirupsen/logrus"
 )
 
 func main() {
+	opts, overrides, err := parseFlags(os.Args[1:])
+	if err != nil {
+		if err == flag.ErrHelp {
+			os.Exit(0)
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if opts.command == "version" {
+		fmt.Printf("aliyun-spot-manager %s\n", version.String())
+		return
+	}
+
 	// Load .env file
-	if err := godotenv.Load(); err != nil {
-		log.Warn("No .env file found, using environment variables")
+	if err := godotenv.Load(opts.envFile); err != nil {
+		log.Warnf("No %s file found, using environment variables", opts.envFile)
+	}
+	applyOverrides(overrides)
+
+	switch opts.command {
+	case "run":
+		runDaemon()
+	case "validate":
+		os.Exit(runValidate(opts.testMessage))
+	case "agent":
+		os.Exit(runAgent())
+	case "agent-token":
+		os.Exit(runAgentToken(opts.args))
+	case "config":
+		// Print the effective configuration
+		cfg, err := config.Load()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Print(cfg.Dump())
+	default:
+		os.Exit(runCommand(opts))
 	}
+}
 
+// runDaemon runs the monitor until interrupted
+func runDaemon() {
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -27,7 +74,17 @@ func main() {
 	// Setup logging
 	setupLogging(cfg)
 
-	log.Info("Starting Aliyun Spot Instance Monitor")
+	log.Infof("Starting Aliyun Spot Instance Monitor %s", version.String())
+
+	// Export traces of the check and start workflows
+	var shutdownTracing func(context.Context) error
+	if cfg.TracingEnabled {
+		shutdownTracing, err = tracing.Setup(context.Background())
+		if err != nil {
+			log.Fatalf("Failed to setup tracing: %v", err)
+		}
+		log.Info("OpenTelemetry tracing enabled")
+	}
 
 	// Create monitor
 	mon, err := monitor.New(cfg)
@@ -35,6 +92,38 @@ func main() {
 		log.Fatalf("Failed to create monitor: %v", err)
 	}
 
+	// Start the HTTP API first so /healthz answers during discovery
+	var apiServer *api.Server
+	if cfg.APIEnabled {
+		apiServer = api.NewServer(cfg.APIListen, cfg.APIToken, cfg.AgentSecret, cfg.DashboardEnabled, cfg.CloudEventsEnabled, mon)
+		apiServer.Start()
+	}
+	var grpcServer *api.GRPCServer
+	if cfg.GRPCEnabled {
+		grpcServer = api.NewGRPCServer(cfg.GRPCListen, cfg.APIToken, mon)
+		grpcServer.Start()
+	}
+
+	// Cancelled on the first interrupt; a second one kills the process
+	signalCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Of several replicas only the leader goes on, the others stand by
+	// without discovering, so /readyz keeps traffic away from them
+	ctx := signalCtx
+	var lease *leader.Lease
+	if cfg.LeaderElection {
+		lease, ctx, err = acquireLeadership(signalCtx, cfg)
+		if err != nil {
+			log.Info("Interrupted while standing by, exiting")
+			if err := mon.Close(); err != nil {
+				log.Warnf("Failed to close state store: %v", err)
+			}
+			return
+		}
+		mon.RestoreState()
+	}
+
 	// Run initial check
 	log.Info("Running initial instance discovery...")
 	if err := mon.DiscoverInstances(); err != nil {
@@ -42,52 +131,304 @@ func main() {
 	}
 
 	// Start Telegram bot for commands
-	mon.StartBot()
+	mon.StartBot(ctx)
+
+	// Reload credentials when they are rotated
+	mon.StartCredentialWatcher(ctx)
+
+	// Alert when the check loop stalls
+	mon.StartWatchdog(ctx)
 
-	// Setup cron scheduler
+	// Setup cron scheduler, jobs skip a run while the previous one is in progress
 	c := cron.New()
-	_, err = c.AddFunc(cfg.CronSchedule, func() {
+	_, err = c.AddFunc(cfg.CronSchedule, singleRun("check", func() {
 		if err := mon.Check(); err != nil {
 			log.Errorf("Check failed: %v", err)
 		}
-	})
+	}))
 	if err != nil {
 		log.Fatalf("Failed to setup cron: %v", err)
 	}
 
+	// Pick up instances created or released since startup
+	if cfg.RediscoveryInterval > 0 {
+		_, err = c.AddFunc(fmt.Sprintf("@every %ds", cfg.RediscoveryInterval), singleRun("re-discovery", func() {
+			if err := mon.Rediscover(); err != nil {
+				log.Warnf("Re-discovery failed: %v", err)
+			}
+		}))
+		if err != nil {
+			log.Fatalf("Failed to setup re-discovery cron: %v", err)
+		}
+	}
+
+	// Snapshot launch configurations for re-creation and disaster recovery
+	if cfg.LaunchConfigCapture {
+		capture := singleRun("launch configuration capture", func() {
+			if err := mon.CaptureLaunchConfigs(); err != nil {
+				log.Warnf("Launch configuration capture failed: %v", err)
+			}
+		})
+		go capture()
+		_, err = c.AddFunc("@every 1h", capture)
+		if err != nil {
+			log.Fatalf("Failed to setup launch configuration cron: %v", err)
+		}
+	}
+
+	// Poll scheduled maintenance events
+	if cfg.MaintenanceCheckInterval > 0 {
+		_, err = c.AddFunc(fmt.Sprintf("@every %ds", cfg.MaintenanceCheckInterval), singleRun("maintenance event check", func() {
+			if err := mon.CheckMaintenanceEvents(); err != nil {
+				log.Warnf("Maintenance event check failed: %v", err)
+			}
+		}))
+		if err != nil {
+			log.Fatalf("Failed to setup maintenance cron: %v", err)
+		}
+	}
+
+	// Poll for announced reclaims and reboots, a few minutes ahead of them
+	if cfg.InterruptionCheckInterval > 0 {
+		_, err = c.AddFunc(fmt.Sprintf("@every %ds", cfg.InterruptionCheckInterval), singleRun("interruption check", func() {
+			if err := mon.CheckInterruptions(); err != nil {
+				log.Warnf("Interruption check failed: %v", err)
+			}
+		}))
+		if err != nil {
+			log.Fatalf("Failed to setup interruption cron: %v", err)
+		}
+	}
+
+	// Health-check running instances in the background
+	if cfg.HealthMonitorEnabled {
+		_, err = c.AddFunc(fmt.Sprintf("@every %ds", cfg.HealthMonitorInterval), singleRun("background health check", func() {
+			if err := mon.CheckRunningHealth(); err != nil {
+				log.Warnf("Background health check failed: %v", err)
+			}
+		}))
+		if err != nil {
+			log.Fatalf("Failed to setup health monitor cron: %v", err)
+		}
+	}
+
+	// Send a daily summary so silence means the monitor is down, not that all is fine
+	if cfg.TelegramEnabled && cfg.HeartbeatEnabled {
+		_, err = c.AddFunc(cfg.HeartbeatSchedule, singleRun("heartbeat", func() {
+			if err := mon.SendHeartbeat(); err != nil {
+				log.Warnf("Heartbeat failed: %v", err)
+			}
+		}))
+		if err != nil {
+			log.Fatalf("Failed to setup heartbeat cron: %v", err)
+		}
+	}
+
+	// Alert when CDT traffic approaches the free quota
+	if cfg.TelegramEnabled && cfg.TrafficCheckInterval > 0 && (cfg.TrafficQuotaChinaGB > 0 || cfg.TrafficQuotaGlobalGB > 0 || cfg.TrafficHardCapGB > 0) {
+		_, err = c.AddFunc(fmt.Sprintf("@every %ds", cfg.TrafficCheckInterval), singleRun("traffic quota check", func() {
+			if err := mon.CheckTrafficQuota(); err != nil {
+				log.Warnf("Traffic quota check failed: %v", err)
+			}
+		}))
+		if err != nil {
+			log.Fatalf("Failed to setup traffic quota cron: %v", err)
+		}
+	}
+
+	// Report the traffic consumed since the previous day
+	if cfg.TelegramEnabled && cfg.TrafficDailyReport {
+		_, err = c.AddFunc(cfg.TrafficDailyReportSchedule, singleRun("daily traffic report", func() {
+			if err := mon.SendDailyTrafficReport(); err != nil {
+				log.Warnf("Daily traffic report failed: %v", err)
+			}
+		}))
+		if err != nil {
+			log.Fatalf("Failed to setup daily traffic report cron: %v", err)
+		}
+	}
+
+	// Alert when spend approaches the monthly budget
+	if cfg.TelegramEnabled && cfg.MonthlyBudget > 0 {
+		_, err = c.AddFunc(fmt.Sprintf("@every %ds", cfg.BudgetCheckInterval), singleRun("budget check", func() {
+			if err := mon.CheckBudget(); err != nil {
+				log.Warnf("Budget check failed: %v", err)
+			}
+		}))
+		if err != nil {
+			log.Fatalf("Failed to setup budget cron: %v", err)
+		}
+	}
+
+	// Compare yesterday's spend with the trailing week once the daily bill is out
+	if cfg.TelegramEnabled && cfg.CostAnomalyMultiplier > 0 {
+		_, err = c.AddFunc("0 10 * * *", singleRun("cost anomaly check", func() {
+			if err := mon.CheckCostAnomaly(); err != nil {
+				log.Warnf("Cost anomaly check failed: %v", err)
+			}
+		}))
+		if err != nil {
+			log.Fatalf("Failed to setup cost anomaly cron: %v", err)
+		}
+	}
+
+	// Warn before SpotWithPriceLimit instances get outbid
+	if cfg.PriceLimitCheckInterval > 0 {
+		_, err = c.AddFunc(fmt.Sprintf("@every %ds", cfg.PriceLimitCheckInterval), singleRun("price limit check", func() {
+			if err := mon.CheckPriceLimits(); err != nil {
+				log.Warnf("Price limit check failed: %v", err)
+			}
+		}))
+		if err != nil {
+			log.Fatalf("Failed to setup price limit cron: %v", err)
+		}
+	}
+
+	// Warn when burstable instances run out of CPU credits
+	if cfg.CPUCreditCheckInterval > 0 {
+		_, err = c.AddFunc(fmt.Sprintf("@every %ds", cfg.CPUCreditCheckInterval), singleRun("CPU credit check", func() {
+			if err := mon.CheckCPUCredits(); err != nil {
+				log.Warnf("CPU credit check failed: %v", err)
+			}
+		}))
+		if err != nil {
+			log.Fatalf("Failed to setup CPU credit cron: %v", err)
+		}
+	}
+
+	// Recommend cheaper or more stable zones from the spot price history
+	if cfg.TelegramEnabled && cfg.SpotAdvisorSchedule != "" {
+		_, err = c.AddFunc(cfg.SpotAdvisorSchedule, singleRun("spot price advisor", func() {
+			if err := mon.SendAdvisorReport(); err != nil {
+				log.Warnf("Spot price advisor report failed: %v", err)
+			}
+		}))
+		if err != nil {
+			log.Fatalf("Failed to setup spot price advisor cron: %v", err)
+		}
+	}
+
+	// Snapshot the disks of the instances opted in through the config file
+	if cfg.SnapshotSchedule != "" {
+		_, err = c.AddFunc(cfg.SnapshotSchedule, singleRun("snapshot", func() {
+			if err := mon.TakeSnapshots(); err != nil {
+				log.Warnf("Snapshots failed: %v", err)
+			}
+		}))
+		if err != nil {
+			log.Fatalf("Failed to setup snapshot cron: %v", err)
+		}
+	}
+
+	// Back the state and event history up to OSS
+	if cfg.BackupOSSBucket != "" {
+		_, err = c.AddFunc(cfg.BackupSchedule, singleRun("state backup", func() {
+			if err := mon.BackupState(); err != nil {
+				log.Warnf("State backup failed: %v", err)
+			}
+		}))
+		if err != nil {
+			log.Fatalf("Failed to setup state backup cron: %v", err)
+		}
+	}
+
+	// Summarize last month's reclaims on the 1st of every month
+	if cfg.TelegramEnabled && cfg.ReclaimStatsMonthly {
+		_, err = c.AddFunc("0 9 1 * *", singleRun("monthly reclaim statistics", func() {
+			if err := mon.SendMonthlyReclaimStats(); err != nil {
+				log.Warnf("Monthly reclaim statistics failed: %v", err)
+			}
+		}))
+		if err != nil {
+			log.Fatalf("Failed to setup reclaim statistics cron: %v", err)
+		}
+	}
+
 	c.Start()
 	log.Infof("Scheduler started, checking every %d seconds", cfg.CheckInterval)
 
 	// Wait for interrupt signal
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	<-ctx.Done()
+	if signalCtx.Err() == nil {
+		log.Warn("Lost leadership, shutting down to restart as a standby")
+	}
+	stop()
 
 	log.Info("Shutting down...")
-	c.Stop()
-}
+	cronCtx := c.Stop()
 
-func setupLogging(cfg *config.Config) {
-	// Set log level
-	level, err := log.ParseLevel(cfg.LogLevel)
-	if err != nil {
-		level = log.InfoLevel
+	// Let in-flight instance starts finish, cancelling them after the timeout.
+	// Without the leadership the new leader may already be starting the same
+	// instances, so they are cancelled right away.
+	shutdownTimeout := time.Duration(cfg.ShutdownTimeout) * time.Second
+	if signalCtx.Err() == nil {
+		shutdownTimeout = 0
+	}
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelShutdown()
+	if err := mon.Shutdown(shutdownCtx); err != nil {
+		log.Warnf("Failed to stop in-flight instance starts: %v", err)
 	}
-	log.SetLevel(level)
 
-	// Set log format
-	log.SetFormatter(&log.TextFormatter{
-		FullTimestamp:   true,
-		TimestampFormat: "2006-01-02 15:04:05",
-	})
+	// Running jobs return soon once no start is in flight
+	select {
+	case <-cronCtx.Done():
+	case <-time.After(10 * time.Second):
+		log.Warn("Scheduled jobs still running, shutting down anyway")
+	}
 
-	// Set log output
-	if cfg.LogFile != "" {
-		file, err := os.OpenFile(cfg.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
-		if err != nil {
-			log.Warnf("Failed to open log file %s, using stdout: %v", cfg.LogFile, err)
-		} else {
-			log.SetOutput(file)
+	// Hand the leadership over only now that no start is in flight
+	if lease != nil {
+		lease.Release()
+	}
+
+	if apiServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := apiServer.Shutdown(ctx); err != nil {
+			log.Warnf("Failed to shut down API server: %v", err)
+		}
+	}
+	if grpcServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := grpcServer.Shutdown(ctx); err != nil {
+			log.Warnf("Failed to shut down gRPC server: %v", err)
+		}
+	}
+
+	// A last backup, the machine may be gone after this. A replica that lost
+	// its leadership leaves it to the new leader.
+	if cfg.BackupOSSBucket != "" && signalCtx.Err() != nil {
+		if err := mon.BackupState(); err != nil {
+			log.Warnf("State backup failed: %v", err)
+		}
+	}
+
+	if err := mon.Close(); err != nil {
+		log.Warnf("Failed to close state store: %v", err)
+	}
+
+	if shutdownTracing != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			log.Warnf("Failed to flush traces: %v", err)
 		}
 	}
-}
\ No newline at end of file
+}
+
+// singleRun wraps a scheduled job so it is skipped while its previous run is
+// still in progress, e.g. a check waiting minutes for an instance to start
+func singleRun(name string, job func()) func() {
+	running := make(chan struct{}, 1)
+	return func() {
+		select {
+		case running <- struct{}{}:
+			defer func() { <-running }()
+			job()
+		default:
+			log.Warnf("Previous %s is still running, skipping this run", name)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/iliyian/aliyun-spot-manager/internal/config"
+	"github.com/iliyian/aliyun-spot-manager/internal/monitor"
+	log "github.com/sirupsen/logrus"
+)
+
+// runValidate checks the configuration, prints a report and returns the exit code
+// With testMessage a Telegram test message is sent too.
+func runValidate(testMessage bool) int {
+	// Keep the report readable
+	log.SetLevel(log.WarnLevel)
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("❌ 配置: %v\n", err)
+		return 1
+	}
+	fmt.Println("✅ 配置: 环境变量加载成功")
+
+	failed := 0
+	for _, result := range monitor.Validate(cfg, testMessage) {
+		switch {
+		case result.Skipped:
+			fmt.Printf("⏭️  %s: 跳过 (%s)\n", result.Name, result.Detail)
+		case result.Err != nil:
+			failed++
+			fmt.Printf("❌ %s: %v\n", result.Name, result.Err)
+		default:
+			fmt.Printf("✅ %s: %s\n", result.Name, result.Detail)
+		}
+	}
+
+	if failed > 0 {
+		fmt.Printf("\n%d 项检查失败\n", failed)
+		return 1
+	}
+	fmt.Println("\n配置校验通过")
+	return 0
+}